@@ -0,0 +1,354 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 类型定义
+// ═══════════════════════════════════════════════════════════════════════════
+
+// DefaultMaxSteps 默认模型调用次数上限
+const DefaultMaxSteps = 10
+
+// ToolFunc 工具处理函数
+//
+// 返回的字符串作为 ToolResultBlock.Content 回传给模型；
+// 返回错误时错误信息作为内容回传，并标记 IsError。
+type ToolFunc = llm.ToolFunc
+
+// ErrMaxSteps 模型调用次数达到 MaxSteps 仍未完成
+var ErrMaxSteps = errors.New("agent: max steps exceeded")
+
+// StopCondition 循环终止条件，返回 true 时在该步之后结束循环
+//
+// 在每次模型响应后调用，不论 FinishReason 为何；返回 true 时该响应中的工具调用
+// 不再执行，Run 以该响应作为 Result.Response 返回。
+type StopCondition func(resp *llm.Response) bool
+
+// DefaultStopCondition 默认终止条件：响应不包含工具调用时结束
+func DefaultStopCondition(resp *llm.Response) bool {
+	return !resp.Message.HasToolCalls()
+}
+
+// StopOnTool 返回在模型调用指定工具时结束的终止条件（如 "final_answer"）
+//
+// 同时保留默认行为：响应不包含工具调用时也结束。
+func StopOnTool(names ...string) StopCondition {
+	return func(resp *llm.Response) bool {
+		for _, call := range resp.Message.GetToolCalls() {
+			if slices.Contains(names, call.Name) {
+				return true
+			}
+		}
+		return DefaultStopCondition(resp)
+	}
+}
+
+// BudgetExceededError token 预算耗尽错误
+type BudgetExceededError struct {
+	Budget int64 // 预算（Agent.MaxTokens）
+	Used   int64 // 已使用的 token 数
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("agent: token budget exceeded (used %d of %d)", e.Used, e.Budget)
+}
+
+// Agent 工具调用循环
+//
+// 零值字段使用默认行为，Agent 本身无状态，可被多个 goroutine 并发 Run。
+type Agent struct {
+	Provider llm.Provider        // 模型 Provider（必填）
+	Options  *llm.Options        // 每次调用的选项（Tools 等）
+	Handlers map[string]ToolFunc // 工具名 → 处理函数
+	Tools    *llm.ToolRegistry   // 工具注册表，设置后其定义覆盖 Options.Tools，处理函数优先于 Handlers
+
+	MaxSteps    int           // 模型调用次数上限，<= 0 时使用 DefaultMaxSteps
+	MaxTokens   int64         // 累计 TotalTokens 预算，<= 0 表示不限
+	ToolTimeout time.Duration // 单个工具调用超时，<= 0 表示不限
+
+	// 单步内并发执行的工具调用数上限，<= 0 表示全部并行，1 为依次执行
+	MaxConcurrentTools int
+
+	// 循环终止条件，nil 时使用 DefaultStopCondition。
+	// 响应不包含工具调用时没有可执行的内容，无论返回值如何循环都会结束
+	StopCondition StopCondition
+}
+
+// Result 运行结果
+type Result struct {
+	Response *llm.Response  // 最后一次模型响应
+	Messages []llm.Message  // 完整对话历史（含输入消息与新增消息）
+	Steps    int            // 模型调用次数
+	Usage    llm.TokenUsage // 累计用量
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 运行
+// ═══════════════════════════════════════════════════════════════════════════
+
+// Run 运行工具调用循环
+//
+// 每一步调用一次模型；响应包含工具调用时执行处理函数并继续，否则结束。
+// StopCondition 返回 true 时提前结束，不执行该响应中的工具调用。
+//
+// 预算检查在下一次模型调用之前进行：由于对话历史只增不减，下一次调用的
+// 消耗至少与上一次相当，因此当 已用 + 上一步用量 > MaxTokens 时停止，
+// 返回当前结果与 [BudgetExceededError]。
+func (a *Agent) Run(ctx context.Context, messages []llm.Message) (*Result, error) {
+	if a.Provider == nil {
+		return nil, llm.NewConfigError("agent provider is required", nil)
+	}
+
+	maxSteps := a.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	opts := a.options()
+	result := &Result{Messages: append([]llm.Message(nil), messages...)}
+	var lastStepTokens int64
+
+	for result.Steps < maxSteps {
+		// 预算检查
+		if a.MaxTokens > 0 && result.Steps > 0 && result.Usage.TotalTokens+lastStepTokens > a.MaxTokens {
+			return result, &BudgetExceededError{Budget: a.MaxTokens, Used: result.Usage.TotalTokens}
+		}
+
+		resp, err := a.Provider.Complete(ctx, result.Messages, opts)
+		if err != nil {
+			return result, err
+		}
+		result.Steps++
+		result.Response = resp
+		result.Messages = append(result.Messages, resp.Message)
+
+		lastStepTokens = 0
+		if resp.Usage != nil {
+			lastStepTokens = resp.Usage.TotalTokens
+			addUsage(&result.Usage, resp.Usage)
+		}
+
+		calls := resp.Message.GetToolCalls()
+		if len(calls) == 0 || a.shouldStop(resp) {
+			return result, nil
+		}
+
+		result.Messages = append(result.Messages, a.executeTools(ctx, calls))
+	}
+
+	return result, ErrMaxSteps
+}
+
+// RunStream 以流式方式运行工具调用循环
+//
+// 每一步调用 Provider.Stream，模型输出的事件（done 除外）原样转发到同一个通道；
+// 响应包含工具调用时执行处理函数，依次发出 tool_result 事件与 step_boundary
+// 事件（Index 为已完成的步数），然后开始下一步的流式调用。最终回答结束
+// （或 StopCondition 返回 true）后发出最后一步的 done 事件并关闭通道。
+//
+// 首次调用失败时直接返回错误；之后的错误（包括超出 MaxSteps）以 error 事件
+// 发出后关闭通道。流式事件不携带用量，MaxTokens 对 RunStream 不生效。
+func (a *Agent) RunStream(ctx context.Context, messages []llm.Message) (<-chan *llm.Event, error) {
+	if a.Provider == nil {
+		return nil, llm.NewConfigError("agent provider is required", nil)
+	}
+
+	maxSteps := a.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	opts := a.options()
+	history := append([]llm.Message(nil), messages...)
+	events, err := a.Provider.Stream(ctx, history, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *llm.Event)
+	go func() {
+		defer close(out)
+
+		send := func(event *llm.Event) bool {
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for step := 1; ; step++ {
+			resp, done, ok := forwardStep(events, send)
+			if !ok {
+				return
+			}
+
+			calls := resp.Message.GetToolCalls()
+			if len(calls) == 0 || a.shouldStop(resp) {
+				send(done)
+				return
+			}
+			if step >= maxSteps {
+				send(&llm.Event{Type: llm.EventTypeError, Error: ErrMaxSteps, ErrorMessage: ErrMaxSteps.Error()})
+				return
+			}
+
+			results := a.executeTools(ctx, calls)
+			for i, block := range results.GetToolResults() {
+				if !send(&llm.Event{Type: llm.EventTypeToolResult, Index: i, ToolResult: &llm.ToolResult{
+					ToolID:  block.ToolUseID,
+					Name:    calls[i].Name,
+					Content: block.Content,
+					IsError: block.IsError,
+				}}) {
+					return
+				}
+			}
+			history = append(history, resp.Message, results)
+
+			if !send(&llm.Event{Type: llm.EventTypeStepBoundary, Index: step}) {
+				return
+			}
+
+			events, err = a.Provider.Stream(ctx, history, opts)
+			if err != nil {
+				send(&llm.Event{Type: llm.EventTypeError, Error: err, ErrorMessage: err.Error()})
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// forwardStep 转发单步的流式事件并聚合响应
+//
+// done 事件不转发，作为返回值交由调用方决定是否发出。流中出现错误事件或
+// 转发被取消时 ok 为 false（错误事件已转发）。
+func forwardStep(events <-chan *llm.Event, send func(*llm.Event) bool) (*llm.Response, *llm.Event, bool) {
+	collected := make(chan *llm.Event, 1)
+	type collectResult struct {
+		result *core.StreamResult
+		err    error
+	}
+	resultCh := make(chan collectResult, 1)
+	go func() {
+		result, err := core.CollectStream(collected)
+		resultCh <- collectResult{result, err}
+	}()
+
+	done := &llm.Event{Type: llm.EventTypeDone, FinishReason: "stop"}
+	forwarding := true
+	for event := range events {
+		collected <- event
+		if event.Type == llm.EventTypeDone {
+			done = event
+			continue
+		}
+		if forwarding && !send(event) {
+			// 继续消费以释放 Provider 的 goroutine
+			forwarding = false
+		}
+	}
+	close(collected)
+
+	r := <-resultCh
+	if r.err != nil || !forwarding {
+		return nil, nil, false
+	}
+	return r.result.Response, done, true
+}
+
+// shouldStop 判断是否在当前响应后结束循环
+func (a *Agent) shouldStop(resp *llm.Response) bool {
+	if a.StopCondition == nil {
+		return DefaultStopCondition(resp)
+	}
+	return a.StopCondition(resp)
+}
+
+// options 返回每次调用使用的选项
+//
+// 设置了 Tools 时复制 Options 并以注册表中的工具定义替换 Options.Tools。
+func (a *Agent) options() *llm.Options {
+	if a.Tools == nil {
+		return a.Options
+	}
+
+	var opts llm.Options
+	if a.Options != nil {
+		opts = *a.Options
+	}
+	opts.Tools = a.Tools.Schemas()
+	return &opts
+}
+
+// executeTools 并发执行工具调用，结果合并为一条用户消息
+//
+// 并发数受 MaxConcurrentTools 限制。等待全部调用结束后按工具调用顺序返回结果，
+// 单个调用失败以 IsError 结果回传，不影响其他调用。
+func (a *Agent) executeTools(ctx context.Context, calls []*llm.ToolCall) llm.Message {
+	limit := a.MaxConcurrentTools
+	if limit <= 0 || limit > len(calls) {
+		limit = len(calls)
+	}
+
+	blocks := make([]llm.ContentBlock, len(calls))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			content, err := a.invoke(ctx, call)
+			if err != nil {
+				blocks[i] = &llm.ToolResultBlock{ToolUseID: call.ID, Name: call.Name, Content: err.Error(), IsError: true}
+				return
+			}
+			blocks[i] = &llm.ToolResultBlock{ToolUseID: call.ID, Name: call.Name, Content: content}
+		})
+	}
+	wg.Wait()
+	return llm.Message{Role: llm.RoleUser, ContentBlocks: blocks}
+}
+
+// invoke 执行单个工具调用
+func (a *Agent) invoke(ctx context.Context, call *llm.ToolCall) (string, error) {
+	fn, ok := a.Handlers[call.Name]
+	if a.Tools != nil {
+		if registered, found := a.Tools.Lookup(call.Name); found {
+			fn, ok = registered, true
+		}
+	}
+	if !ok {
+		return "", fmt.Errorf("%w: %s", llm.ErrUnknownTool, call.Name)
+	}
+
+	if a.ToolTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.ToolTimeout)
+		defer cancel()
+	}
+	return fn(ctx, call.Input)
+}
+
+// addUsage 累加用量
+func addUsage(total, u *llm.TokenUsage) {
+	total.InputTokens += u.InputTokens
+	total.OutputTokens += u.OutputTokens
+	total.TotalTokens += u.TotalTokens
+	total.CachedTokens += u.CachedTokens
+	total.ReasoningTokens += u.ReasoningTokens
+	total.CacheWriteTokens += u.CacheWriteTokens
+}
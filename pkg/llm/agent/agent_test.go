@@ -0,0 +1,361 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/mock"
+)
+
+// toolCallOnce 首次调用返回工具调用，之后返回文本
+func toolCallOnce(messages []llm.Message, callCount int) llm.Message {
+	if callCount == 1 {
+		return llm.Message{ContentBlocks: []llm.ContentBlock{
+			&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+		}}
+	}
+	return llm.Message{Content: "It is sunny."}
+}
+
+// toolCallAlways 每次都返回工具调用
+func toolCallAlways(messages []llm.Message, callCount int) llm.Message {
+	return llm.Message{ContentBlocks: []llm.ContentBlock{
+		&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Rome"}},
+	}}
+}
+
+func weather(ctx context.Context, input map[string]any) (string, error) {
+	return "Sunny in " + input["city"].(string), nil
+}
+
+func TestAgent_Run(t *testing.T) {
+	p := mock.New(mock.WithMessageFunc(toolCallOnce))
+	a := &Agent{Provider: p, Handlers: map[string]ToolFunc{"get_weather": weather}}
+
+	result, err := a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Steps)
+	assert.Equal(t, "It is sunny.", result.Response.Message.Content)
+	require.Len(t, result.Messages, 4)
+
+	tr := result.Messages[2].GetToolResults()
+	require.Len(t, tr, 1)
+	assert.Equal(t, "call_1", tr[0].ToolUseID)
+	assert.Equal(t, "Sunny in Paris", tr[0].Content)
+	assert.Positive(t, result.Usage.TotalTokens)
+}
+
+func TestAgent_Run_ToolErrors(t *testing.T) {
+	p := mock.New(mock.WithMessageFunc(toolCallOnce))
+	a := &Agent{
+		Provider:    p,
+		ToolTimeout: 10 * time.Millisecond,
+		Handlers: map[string]ToolFunc{
+			"get_weather": func(ctx context.Context, input map[string]any) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			},
+		},
+	}
+
+	result, err := a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}})
+	require.NoError(t, err)
+
+	tr := result.Messages[2].GetToolResults()
+	require.Len(t, tr, 1)
+	assert.True(t, tr[0].IsError)
+	assert.Contains(t, tr[0].Content, "deadline exceeded")
+
+	// 未注册的工具
+	a = &Agent{Provider: mock.New(mock.WithMessageFunc(toolCallOnce))}
+	result, err = a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}})
+	require.NoError(t, err)
+	assert.Equal(t, "unknown tool: get_weather", result.Messages[2].GetToolResults()[0].Content)
+}
+
+func TestAgent_Run_ParallelTools(t *testing.T) {
+	// 首次调用返回三个并行工具调用
+	threeCalls := func(messages []llm.Message, callCount int) llm.Message {
+		if callCount == 1 {
+			return llm.Message{ContentBlocks: []llm.ContentBlock{
+				&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+				&llm.ToolCall{ID: "call_2", Name: "fail", Input: map[string]any{}},
+				&llm.ToolCall{ID: "call_3", Name: "get_weather", Input: map[string]any{"city": "Rome"}},
+			}}
+		}
+		return llm.Message{Content: "Done."}
+	}
+
+	run := func(t *testing.T, maxConcurrent int) (*Result, int32) {
+		t.Helper()
+		var running, peak atomic.Int32
+		track := func(fn ToolFunc) ToolFunc {
+			return func(ctx context.Context, input map[string]any) (string, error) {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				return fn(ctx, input)
+			}
+		}
+
+		a := &Agent{
+			Provider:           mock.New(mock.WithMessageFunc(threeCalls)),
+			MaxConcurrentTools: maxConcurrent,
+			Handlers: map[string]ToolFunc{
+				"get_weather": track(weather),
+				"fail": track(func(context.Context, map[string]any) (string, error) {
+					return "", errors.New("service unavailable")
+				}),
+			},
+		}
+		result, err := a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}})
+		require.NoError(t, err)
+		return result, peak.Load()
+	}
+
+	assertResults := func(t *testing.T, result *Result) {
+		t.Helper()
+		require.Len(t, result.Messages, 4)
+		tr := result.Messages[2].GetToolResults()
+		require.Len(t, tr, 3, "全部结果合并为一条用户消息")
+		assert.Equal(t, []string{"call_1", "call_2", "call_3"}, []string{tr[0].ToolUseID, tr[1].ToolUseID, tr[2].ToolUseID})
+		assert.Equal(t, "Sunny in Paris", tr[0].Content)
+		assert.True(t, tr[1].IsError)
+		assert.Equal(t, "service unavailable", tr[1].Content)
+		assert.Equal(t, "Sunny in Rome", tr[2].Content)
+	}
+
+	t.Run("默认全部并行", func(t *testing.T) {
+		result, peak := run(t, 0)
+		assertResults(t, result)
+		assert.Equal(t, int32(3), peak)
+	})
+
+	t.Run("限制并发数", func(t *testing.T) {
+		result, peak := run(t, 1)
+		assertResults(t, result)
+		assert.Equal(t, int32(1), peak)
+	})
+}
+
+func TestAgent_Run_ToolRegistry(t *testing.T) {
+	reg := llm.NewToolRegistry()
+	require.NoError(t, reg.Register(llm.ToolSchema{Name: "get_weather", Description: "Get weather"}, weather))
+
+	p := mock.New(mock.WithMessageFunc(toolCallOnce))
+	options := &llm.Options{MaxTokens: 100}
+	a := &Agent{Provider: p, Options: options, Tools: reg}
+
+	result, err := a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Sunny in Paris", result.Messages[2].GetToolResults()[0].Content)
+	assert.Equal(t, "get_weather", result.Messages[2].GetToolResults()[0].Name)
+
+	// 请求携带注册表中的工具定义，且不修改调用方的 Options
+	calls := p.Calls()
+	require.Len(t, calls, 2)
+	require.Len(t, calls[0].Options.Tools, 1)
+	assert.Equal(t, "get_weather", calls[0].Options.Tools[0].Name)
+	assert.Equal(t, 100, calls[0].Options.MaxTokens)
+	assert.Nil(t, options.Tools)
+}
+
+func TestAgent_Run_MaxSteps(t *testing.T) {
+	p := mock.New(mock.WithMessageFunc(toolCallAlways))
+	a := &Agent{Provider: p, MaxSteps: 3, Handlers: map[string]ToolFunc{"get_weather": weather}}
+
+	result, err := a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Loop"}})
+
+	require.ErrorIs(t, err, ErrMaxSteps)
+	assert.Equal(t, 3, result.Steps)
+}
+
+func TestAgent_Run_MaxTokens(t *testing.T) {
+	p := mock.New(
+		mock.WithMessageFunc(toolCallAlways),
+		mock.WithUsage(llm.TokenUsage{InputTokens: 80, OutputTokens: 20, TotalTokens: 100}),
+	)
+	a := &Agent{
+		Provider:  p,
+		MaxSteps:  10,
+		MaxTokens: 250,
+		Handlers:  map[string]ToolFunc{"get_weather": weather},
+	}
+
+	result, err := a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Loop"}})
+
+	// 第 3 次调用预计使用 300 > 250，在调用前停止
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, int64(250), budgetErr.Budget)
+	assert.Equal(t, int64(200), budgetErr.Used)
+	assert.Equal(t, 2, result.Steps)
+	assert.Equal(t, 2, p.CallCount())
+	require.NotNil(t, result.Response)
+	assert.Equal(t, int64(200), result.Usage.TotalTokens)
+}
+
+func TestAgent_RunStream(t *testing.T) {
+	p := mock.New()
+	p.UseScenario("agent_loop")
+	echo := func(name string) ToolFunc {
+		return func(ctx context.Context, input map[string]any) (string, error) { return name + " ok", nil }
+	}
+	a := &Agent{Provider: p, Handlers: map[string]ToolFunc{
+		"read_file":    echo("read_file"),
+		"analyze_code": echo("analyze_code"),
+	}}
+
+	events, err := a.RunStream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "帮我分析这个代码文件"}})
+	require.NoError(t, err)
+
+	var (
+		text       strings.Builder
+		toolCalls  []string
+		results    []string
+		boundaries []int
+		done       []string
+	)
+	for e := range events {
+		switch e.Type {
+		case llm.EventTypeText:
+			text.WriteString(e.TextDelta)
+		case llm.EventTypeToolCall:
+			toolCalls = append(toolCalls, e.ToolCall.Name)
+		case llm.EventTypeToolResult:
+			results = append(results, e.ToolResult.Content)
+		case llm.EventTypeStepBoundary:
+			boundaries = append(boundaries, e.Index)
+		case llm.EventTypeDone:
+			done = append(done, e.FinishReason)
+		case llm.EventTypeError:
+			t.Fatalf("unexpected error event: %v", e.Error)
+		}
+	}
+
+	assert.Equal(t, []string{"read_file", "analyze_code"}, toolCalls)
+	assert.Equal(t, []string{"read_file ok", "analyze_code ok"}, results)
+	assert.Equal(t, []int{1, 2}, boundaries)
+	assert.Equal(t, []string{"stop"}, done)
+	assert.True(t, strings.HasPrefix(text.String(), "好的，让我先读取文件内容。"))
+	assert.True(t, strings.HasSuffix(text.String(), "代码分析完成！这是一个结构良好的 Go 程序，包含主函数和几个辅助函数。"))
+	assert.Equal(t, 3, p.CallCount())
+
+	// 第二次调用携带第一步的工具调用与结果
+	history := p.Calls()[1].Messages
+	require.Len(t, history, 3)
+	assert.Len(t, history[1].GetToolCalls(), 1)
+	assert.Equal(t, "read_file ok", history[2].GetToolResults()[0].Content)
+}
+
+func TestAgent_RunStream_MaxSteps(t *testing.T) {
+	p := mock.New(mock.WithMessageFunc(toolCallAlways))
+	a := &Agent{Provider: p, MaxSteps: 2, Handlers: map[string]ToolFunc{"get_weather": weather}}
+
+	events, err := a.RunStream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Loop"}})
+	require.NoError(t, err)
+
+	var last *llm.Event
+	for e := range events {
+		last = e
+	}
+
+	require.NotNil(t, last)
+	assert.Equal(t, llm.EventTypeError, last.Type)
+	require.ErrorIs(t, last.Error, ErrMaxSteps)
+	assert.Equal(t, 2, p.CallCount())
+}
+
+func TestAgent_StopCondition(t *testing.T) {
+	// 第一步调用 get_weather，第二步调用 final_answer
+	steps := func(messages []llm.Message, callCount int) llm.Message {
+		if callCount == 1 {
+			return llm.Message{ContentBlocks: []llm.ContentBlock{
+				&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+			}}
+		}
+		return llm.Message{ContentBlocks: []llm.ContentBlock{
+			&llm.ToolCall{ID: "call_2", Name: "final_answer", Input: map[string]any{"answer": "Sunny"}},
+		}}
+	}
+	finalAnswerCalled := false
+	handlers := map[string]ToolFunc{
+		"get_weather": weather,
+		"final_answer": func(context.Context, map[string]any) (string, error) {
+			finalAnswerCalled = true
+			return "", nil
+		},
+	}
+
+	t.Run("Run", func(t *testing.T) {
+		a := &Agent{Provider: mock.New(mock.WithMessageFunc(steps)), Handlers: handlers, StopCondition: StopOnTool("final_answer")}
+
+		result, err := a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, result.Steps)
+		require.Len(t, result.Messages, 4)
+		calls := result.Response.Message.GetToolCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "Sunny", calls[0].Input["answer"])
+		assert.False(t, finalAnswerCalled, "终止后不执行工具")
+	})
+
+	t.Run("RunStream", func(t *testing.T) {
+		a := &Agent{Provider: mock.New(mock.WithMessageFunc(steps)), Handlers: handlers, StopCondition: StopOnTool("final_answer")}
+
+		events, err := a.RunStream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}})
+		require.NoError(t, err)
+
+		var boundaries, done int
+		for e := range events {
+			switch e.Type {
+			case llm.EventTypeStepBoundary:
+				boundaries++
+			case llm.EventTypeDone:
+				done++
+			}
+		}
+		assert.Equal(t, 1, boundaries)
+		assert.Equal(t, 1, done)
+		assert.False(t, finalAnswerCalled)
+	})
+
+	t.Run("自定义条件先于工具调用", func(t *testing.T) {
+		var seen int
+		a := &Agent{
+			Provider: mock.New(mock.WithMessageFunc(toolCallAlways)),
+			Handlers: handlers,
+			StopCondition: func(resp *llm.Response) bool {
+				seen++
+				return seen == 3
+			},
+		}
+
+		result, err := a.Run(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Steps)
+	})
+}
+
+func TestAgent_Run_NoProvider(t *testing.T) {
+	_, err := (&Agent{}).Run(context.Background(), nil)
+
+	assert.True(t, llm.IsConfigError(err))
+}
@@ -0,0 +1,70 @@
+// Package agent 提供基于 [llm.Provider] 的工具调用循环
+//
+// [Agent] 反复调用模型：模型返回工具调用时执行对应处理函数，将结果
+// 作为下一轮用户消息回传，直到模型给出最终回答或触发限制。
+//
+// # 快速开始
+//
+//	a := &agent.Agent{
+//	    Provider: p,
+//	    Options:  &llm.Options{Tools: tools},
+//	    Handlers: map[string]agent.ToolFunc{
+//	        "get_weather": func(ctx context.Context, input map[string]any) (string, error) {
+//	            return "Sunny", nil
+//	        },
+//	    },
+//	}
+//
+//	result, err := a.Run(ctx, []llm.Message{{Role: llm.RoleUser, Content: "Weather in Paris?"}})
+//	fmt.Println(result.Response.Message.Content)
+//
+// # 工具注册表
+//
+// 使用 [llm.ToolRegistry] 将工具定义与处理函数成对注册，避免两者不一致：
+//
+//	reg := llm.NewToolRegistry()
+//	_ = reg.Register(weatherSchema, getWeather)
+//
+//	a := &agent.Agent{Provider: p, Tools: reg}
+//
+// 设置 Tools 后，每次调用的 Options.Tools 由注册表生成；未在注册表中的工具回退到 Handlers。
+//
+// # 流式运行
+//
+// [Agent.RunStream] 将多步调用串联为同一个事件通道：每一步的模型事件原样转发，
+// 工具执行后依次发出 tool_result 与 step_boundary 事件，最终回答结束后发出 done：
+//
+//	events, err := a.RunStream(ctx, messages)
+//	for e := range events {
+//	    switch e.Type {
+//	    case llm.EventTypeText:
+//	        fmt.Print(e.TextDelta)
+//	    case llm.EventTypeStepBoundary:
+//	        fmt.Printf("\n--- step %d ---\n", e.Index)
+//	    }
+//	}
+//
+// # 并行工具调用
+//
+// 模型一次返回多个工具调用时，处理函数并发执行（MaxConcurrentTools 限制并发数，
+// 访问共享资源时可设为 1 依次执行）。全部结束后结果按工具调用顺序合并为一条用户消息
+// 回传；单个调用失败以 IsError 结果回传，不影响其他调用的结果。
+//
+// # 终止条件
+//
+// 默认在响应不包含工具调用时结束（[DefaultStopCondition]）。Agent.StopCondition
+// 可在任意一步提前结束，不论 FinishReason，例如模型调用 "final_answer" 工具时：
+//
+//	a.StopCondition = agent.StopOnTool("final_answer")
+//
+// 提前结束时不执行该响应中的工具调用，最终答案从 Result.Response 的工具调用参数中读取。
+//
+// # 限制
+//
+//   - MaxSteps: 模型调用次数上限（默认 10），超出返回 [ErrMaxSteps]
+//   - MaxTokens: 累计 token 预算，预计超出时在下一次调用前停止并返回 [BudgetExceededError]
+//   - ToolTimeout: 单个工具调用的超时时间
+//   - MaxConcurrentTools: 单步内并发执行的工具调用数（默认全部并行）
+//
+// 触发限制时 Run 仍返回已完成部分的 [Result]（最后一次响应、完整历史、累计用量）。
+package agent
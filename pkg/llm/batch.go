@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 并发批量调用
+// ═══════════════════════════════════════════════════════════════════════════
+
+// BatchRequest 批量调用中的单个请求
+type BatchRequest struct {
+	Messages []Message
+	Options  *Options
+}
+
+// BatchResult 批量调用中单个请求的结果
+type BatchResult struct {
+	Index    int       // 对应请求在输入中的下标
+	Response *Response // 成功时的响应
+	Err      error     // 失败原因，单个请求失败不影响其他请求
+}
+
+// CompleteBatch 并发执行多个 Complete 请求，按输入顺序返回结果
+//
+// concurrency 为同时进行的请求数上限，<= 0 表示全部并发。结果按下标写入，
+// Results[i] 与 Errors[i] 始终对应 requests[i]，与完成顺序无关。ctx 取消后
+// 尚未开始的请求以 ctx.Err() 作为错误返回。
+//
+// 使用示例：
+//
+//	res := llm.CompleteBatch(ctx, p, requests, 4)
+//	if !res.AllOK() {
+//	    _, failed := res.Partition()
+//	    log.Printf("%d/%d failed, first: %v", len(failed), len(requests), res.FirstError())
+//	}
+//
+// 需要在请求完成时即时处理（如显示进度）时使用 [CompleteBatchStream]。
+func CompleteBatch(ctx context.Context, p Provider, requests []BatchRequest, concurrency int) MultiResult[*Response] {
+	results := newMultiResult[*Response](len(requests))
+	for r := range CompleteBatchStream(ctx, p, requests, concurrency) {
+		results.set(r.Index, r.Response, r.Err)
+	}
+	return results
+}
+
+// CompleteBatchStream 并发执行多个 Complete 请求，按完成顺序发送结果
+//
+// 每个结果携带 Index 标识对应的输入请求；全部请求结束后 channel 关闭，
+// 共发送 len(requests) 个结果。concurrency 与取消语义同 [CompleteBatch]。
+//
+// 使用示例：
+//
+//	for r := range llm.CompleteBatchStream(ctx, p, requests, 4) {
+//	    fmt.Printf("[%d/%d] done\n", r.Index+1, len(requests))
+//	}
+func CompleteBatchStream(ctx context.Context, p Provider, requests []BatchRequest, concurrency int) <-chan BatchResult {
+	if concurrency <= 0 || concurrency > len(requests) {
+		concurrency = len(requests)
+	}
+
+	// 缓冲可容纳全部结果，调用方停止读取时工作 goroutine 不会阻塞
+	out := make(chan BatchResult, len(requests))
+	go func() {
+		defer close(out)
+
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for i, req := range requests {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				out <- BatchResult{Index: i, Err: ctx.Err()}
+				continue
+			}
+			wg.Go(func() {
+				defer func() { <-sem }()
+				resp, err := p.Complete(ctx, req.Messages, req.Options)
+				out <- BatchResult{Index: i, Response: resp, Err: err}
+			})
+		}
+		wg.Wait()
+	}()
+	return out
+}
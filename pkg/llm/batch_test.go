@@ -0,0 +1,106 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/mock"
+)
+
+// slowEchoProvider 回显请求内容，请求内容为延迟毫秒数，"fail" 返回错误
+type slowEchoProvider struct {
+	llm.Provider
+}
+
+func (p slowEchoProvider) Complete(ctx context.Context, messages []llm.Message, opts *llm.Options) (*llm.Response, error) {
+	content := messages[0].Content
+	if content == "fail" {
+		return nil, errors.New("boom")
+	}
+	ms, _ := strconv.Atoi(content)
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &llm.Response{Message: llm.Message{Role: llm.RoleAssistant, Content: content}}, nil
+}
+
+// batchRequests 构造请求，内容依次为 delays
+func batchRequests(delays ...string) []llm.BatchRequest {
+	requests := make([]llm.BatchRequest, 0, len(delays))
+	for _, d := range delays {
+		requests = append(requests, llm.BatchRequest{Messages: []llm.Message{{Role: llm.RoleUser, Content: d}}})
+	}
+	return requests
+}
+
+func TestCompleteBatch_Order(t *testing.T) {
+	p := slowEchoProvider{Provider: mock.New()}
+	// 越靠前的请求越晚完成
+	requests := batchRequests("40", "30", "fail", "10", "0")
+
+	res := llm.CompleteBatch(context.Background(), p, requests, 0)
+
+	require.Len(t, res.Results, len(requests))
+	require.Len(t, res.Errors, len(requests))
+	assert.Equal(t, 4, res.OKCount)
+	for i, resp := range res.Results {
+		if i == 2 {
+			require.EqualError(t, res.Errors[i], "boom", "单个请求失败不影响其他请求")
+			assert.Nil(t, resp)
+			continue
+		}
+		require.NoError(t, res.Errors[i])
+		assert.Equal(t, requests[i].Messages[0].Content, resp.Message.Content)
+	}
+}
+
+func TestCompleteBatchStream_Index(t *testing.T) {
+	p := slowEchoProvider{Provider: mock.New()}
+	requests := batchRequests("40", "20", "0")
+
+	var order []int
+	seen := make(map[int]bool)
+	for r := range llm.CompleteBatchStream(context.Background(), p, requests, 0) {
+		require.NoError(t, r.Err)
+		assert.Equal(t, requests[r.Index].Messages[0].Content, r.Response.Message.Content, "结果携带对应请求的下标")
+		assert.False(t, seen[r.Index])
+		seen[r.Index] = true
+		order = append(order, r.Index)
+	}
+	assert.Equal(t, []int{2, 1, 0}, order, "按完成顺序发送")
+}
+
+func TestCompleteBatchStream_Concurrency(t *testing.T) {
+	p := slowEchoProvider{Provider: mock.New()}
+	requests := batchRequests("30", "0", "0")
+
+	// 并发数为 1 时依次执行，完成顺序即输入顺序
+	var order []int
+	for r := range llm.CompleteBatchStream(context.Background(), p, requests, 1) {
+		order = append(order, r.Index)
+	}
+	assert.Equal(t, []int{0, 1, 2}, order)
+}
+
+func TestCompleteBatch_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res := llm.CompleteBatch(ctx, slowEchoProvider{Provider: mock.New()}, batchRequests("10", "10"), 1)
+
+	require.Len(t, res.Errors, 2)
+	assert.Zero(t, res.OKCount)
+	for i, err := range res.Errors {
+		require.ErrorIs(t, err, context.Canceled, fmt.Sprintf("request %d", i))
+	}
+}
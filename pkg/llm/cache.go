@@ -0,0 +1,293 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 响应缓存装饰器
+// ═══════════════════════════════════════════════════════════════════════════
+
+// Cache 响应缓存接口
+//
+// 实现必须并发安全。ttl <= 0 表示永不过期。
+type Cache interface {
+	Get(key string) (*Response, bool)
+	Set(key string, resp *Response, ttl time.Duration)
+}
+
+// CachedProvider 包装 Provider，对相同请求复用已缓存的响应
+//
+// 缓存键为 (Options.Model, 消息, 选项) 的哈希，适用于幂等提示词
+// （如对同一输入重复执行的分类提示）。与 core.RequestHash 一致，Headers、Metadata
+// 等不影响模型输出的选项不参与哈希；Options.APIKey 以指纹参与，不同 Key 互不命中。
+//
+//   - Complete: 命中时直接返回缓存响应（不调用 API）；未命中时调用并缓存成功结果
+//   - Stream: 命中时从缓存重放（一次性发送完整文本、工具调用与完成事件）；
+//     未命中时直接透传，不写入缓存
+//
+// 写入与命中时均深拷贝响应（内容块、用量、元数据等），调用方修改返回值不影响缓存内容。
+//
+// 使用示例：
+//
+//	p = llm.CachedProvider(p, llm.NewLRUCache(1000), time.Hour)
+func CachedProvider(p Provider, cache Cache, ttl time.Duration) Provider {
+	return &cachedProvider{Provider: p, cache: cache, ttl: ttl}
+}
+
+// cachedProvider 响应缓存装饰器
+type cachedProvider struct {
+	Provider
+
+	cache Cache
+	ttl   time.Duration
+}
+
+// Complete 实现 Provider 接口
+func (c *cachedProvider) Complete(ctx context.Context, messages []Message, opts *Options) (*Response, error) {
+	key, ok := cacheKey(messages, opts)
+	if ok {
+		if resp, hit := c.cache.Get(key); hit {
+			return cloneResponse(resp), nil
+		}
+	}
+
+	resp, err := c.Provider.Complete(ctx, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		c.cache.Set(key, cloneResponse(resp), c.ttl)
+	}
+	return resp, nil
+}
+
+// cloneResponse 深拷贝响应（消息内容块、用量、引用、对数概率与元数据），
+// 保证缓存内容与调用方持有的响应互不影响
+func cloneResponse(resp *Response) *Response {
+	r := *resp
+	r.Message = cloneMessages([]Message{resp.Message})[0]
+	if resp.Usage != nil {
+		usage := *resp.Usage
+		r.Usage = &usage
+	}
+	r.Citations = slices.Clone(resp.Citations)
+	r.Logprobs = cloneLogprobs(resp.Logprobs)
+	r.Metadata = maps.Clone(resp.Metadata)
+	if resp.PromptTokensDetail != nil {
+		detail := *resp.PromptTokensDetail
+		detail.Segments = slices.Clone(detail.Segments)
+		detail.Tokens = slices.Clone(detail.Tokens)
+		r.PromptTokensDetail = &detail
+	}
+	return &r
+}
+
+// cloneLogprobs 深拷贝对数概率（含候选列表）
+func cloneLogprobs(logprobs []TokenLogprob) []TokenLogprob {
+	if logprobs == nil {
+		return nil
+	}
+	cloned := make([]TokenLogprob, len(logprobs))
+	for i, lp := range logprobs {
+		cloned[i] = lp
+		cloned[i].Bytes = slices.Clone(lp.Bytes)
+		cloned[i].TopLogprobs = cloneLogprobs(lp.TopLogprobs)
+	}
+	return cloned
+}
+
+// Stream 实现 Provider 接口
+func (c *cachedProvider) Stream(ctx context.Context, messages []Message, opts *Options) (<-chan *Event, error) {
+	if key, ok := cacheKey(messages, opts); ok {
+		if resp, hit := c.cache.Get(key); hit {
+			return replayResponse(resp), nil
+		}
+	}
+	return c.Provider.Stream(ctx, messages, opts)
+}
+
+// APIKeyFingerprint 返回 API Key 的不可逆指纹（SHA-256 前 16 个十六进制字符），空 Key 返回空串
+//
+// 用于在缓存键与请求哈希中区分 Options.APIKey，而不在其中保存凭据本身。
+func APIKeyFingerprint(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// replayResponse 将缓存响应转为事件流
+func replayResponse(resp *Response) <-chan *Event {
+	calls := resp.Message.GetToolCalls()
+	out := make(chan *Event, len(calls)+3)
+
+	if resp.Reasoning != "" {
+		out <- &Event{Type: EventTypeReasoning, Reasoning: &ReasoningDelta{ThoughtDelta: resp.Reasoning}}
+	}
+	if text := resp.Message.GetContent(); text != "" {
+		out <- &Event{Type: EventTypeText, TextDelta: text}
+	}
+	for i, call := range calls {
+		args, _ := json.Marshal(call.Input) //nolint:errchkjson // best effort
+		out <- &Event{Type: EventTypeToolCall, ToolCall: &ToolCallDelta{
+			Index:          i,
+			ID:             call.ID,
+			Name:           call.Name,
+			ArgumentsDelta: string(args),
+		}}
+	}
+	out <- &Event{Type: EventTypeDone, FinishReason: resp.FinishReason}
+	close(out)
+
+	return out
+}
+
+// cacheKey 计算请求的缓存键，无法序列化时返回 false（不缓存）
+func cacheKey(messages []Message, opts *Options) (string, bool) {
+	type block struct {
+		Type string       `json:"type"`
+		Data ContentBlock `json:"data"`
+	}
+	type message struct {
+		Role       Role    `json:"role"`
+		Content    string  `json:"content,omitempty"`
+		ToolCallID string  `json:"tool_call_id,omitempty"`
+		Blocks     []block `json:"blocks,omitempty"`
+	}
+
+	// ContentBlock 为接口，显式记录块类型以区分结构相同的不同块
+	msgs := make([]message, 0, len(messages))
+	for _, m := range messages {
+		msg := message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, b := range m.ContentBlocks {
+			msg.Blocks = append(msg.Blocks, block{Type: b.BlockType(), Data: b})
+		}
+		msgs = append(msgs, msg)
+	}
+
+	// 与 core.RequestHash 一致，排除不影响模型输出的字段（幂等键、关联 ID 等）；
+	// 单次 API Key 以指纹区分，避免不同租户（或已失效的凭据）命中他人的缓存
+	var key string
+	if opts != nil {
+		key = APIKeyFingerprint(opts.APIKey)
+		o := *opts
+		o.Headers = nil
+		o.Metadata = nil
+		o.EndUserID = ""
+		o.ReturnPromptTokens = false
+		opts = &o
+	}
+
+	data, err := json.Marshal(struct {
+		Messages []message `json:"messages"`
+		Options  *Options  `json:"options,omitempty"`
+		APIKey   string    `json:"api_key,omitempty"`
+	}{msgs, opts, key})
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 内存 LRU 缓存
+// ═══════════════════════════════════════════════════════════════════════════
+
+// LRUCache 并发安全的内存 LRU 缓存
+//
+// 超出容量时淘汰最久未使用的条目；过期条目在读取时惰性删除。
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // 队首为最近使用
+
+	now func() time.Time // 时钟（测试可替换）
+}
+
+// lruEntry LRU 缓存条目
+type lruEntry struct {
+	key       string
+	resp      *Response
+	expiresAt time.Time // 零值表示永不过期
+}
+
+// NewLRUCache 创建容量为 capacity 的 LRU 缓存（capacity <= 0 时为 128）
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		capacity = 128
+	}
+	return &LRUCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get 实现 Cache 接口
+func (c *LRUCache) Get(key string) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry) //nolint:forcetypeassert // 内部仅存放 *lruEntry
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.resp, true
+}
+
+// Set 实现 Cache 接口
+func (c *LRUCache) Set(key string, resp *Response, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry) //nolint:forcetypeassert // 内部仅存放 *lruEntry
+		entry.resp, entry.expiresAt = resp, expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, resp: resp, expiresAt: expiresAt})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key) //nolint:forcetypeassert // 内部仅存放 *lruEntry
+	}
+}
+
+// Len 返回当前条目数（含尚未清理的过期条目）
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// 确保 LRUCache 实现了 Cache 接口
+var _ Cache = (*LRUCache)(nil)
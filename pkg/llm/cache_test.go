@@ -0,0 +1,238 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProvider 记录调用次数的测试 Provider
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Complete(_ context.Context, messages []Message, _ *Options) (*Response, error) {
+	p.calls++
+	return &Response{
+		Message:      Message{Role: RoleAssistant, Content: "echo: " + messages[len(messages)-1].Content},
+		FinishReason: "stop",
+	}, nil
+}
+
+func (p *countingProvider) Stream(context.Context, []Message, *Options) (<-chan *Event, error) {
+	p.calls++
+	ch := make(chan *Event)
+	close(ch)
+	return ch, nil
+}
+
+func (p *countingProvider) Close() error { return nil }
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestCachedProvider_HitMiss(t *testing.T) {
+	inner := &countingProvider{}
+	p := CachedProvider(inner, NewLRUCache(10), 0)
+	ctx := context.Background()
+	msgs := []Message{{Role: RoleUser, Content: "classify this"}}
+
+	resp, err := p.Complete(ctx, msgs, &Options{Temperature: 0})
+	require.NoError(t, err)
+	assert.Equal(t, "echo: classify this", resp.Message.Content)
+	assert.Equal(t, 1, inner.calls)
+
+	// 相同请求命中缓存
+	resp.Message.Content = "mutated"
+	resp, err = p.Complete(ctx, msgs, &Options{Temperature: 0})
+	require.NoError(t, err)
+	assert.Equal(t, "echo: classify this", resp.Message.Content)
+	assert.Equal(t, 1, inner.calls)
+
+	// 选项、模型或消息不同均未命中
+	_, _ = p.Complete(ctx, msgs, &Options{Temperature: 0.5})
+	_, _ = p.Complete(ctx, msgs, &Options{Temperature: 0, Model: "other"})
+	_, _ = p.Complete(ctx, []Message{{Role: RoleUser, Content: "other input"}}, &Options{Temperature: 0})
+	assert.Equal(t, 4, inner.calls)
+
+	// 请求头与元数据不影响命中
+	_, _ = p.Complete(ctx, msgs, &Options{Temperature: 0, Headers: map[string]string{"Idempotency-Key": "k1"}, Metadata: map[string]any{"trace": "t1"}})
+	assert.Equal(t, 4, inner.calls)
+}
+
+// blockProvider 返回包含内容块、用量与元数据的响应
+type blockProvider struct {
+	calls int
+}
+
+func (p *blockProvider) Complete(context.Context, []Message, *Options) (*Response, error) {
+	p.calls++
+	return &Response{
+		Message: Message{Role: RoleAssistant, ContentBlocks: []ContentBlock{
+			&TextBlock{Text: "Checking"},
+			&ToolCall{ID: "call_1", Name: "search", Input: map[string]any{"q": "go"}},
+		}},
+		FinishReason: "tool_calls",
+		Usage:        &TokenUsage{InputTokens: 10, OutputTokens: 5},
+		Logprobs:     []TokenLogprob{{Token: "Check", TopLogprobs: []TokenLogprob{{Token: "Check"}}}},
+		Metadata:     map[string]any{"id": "resp-1"},
+	}, nil
+}
+
+func (p *blockProvider) Stream(context.Context, []Message, *Options) (<-chan *Event, error) {
+	return nil, nil
+}
+
+func (p *blockProvider) Close() error { return nil }
+
+func TestCachedProvider_DeepCopy(t *testing.T) {
+	inner := &blockProvider{}
+	p := CachedProvider(inner, NewLRUCache(10), 0)
+	ctx := context.Background()
+	msgs := []Message{{Role: RoleUser, Content: "search go"}}
+
+	mutate := func(resp *Response) {
+		resp.Message.ContentBlocks[0].(*TextBlock).Text = "mutated"
+		resp.Message.ContentBlocks[1].(*ToolCall).Input["q"] = "mutated"
+		resp.Usage.InputTokens = 999
+		resp.Logprobs[0].TopLogprobs[0].Token = "mutated"
+		resp.Metadata["id"] = "mutated"
+	}
+
+	// 修改写入缓存时返回的响应
+	resp, err := p.Complete(ctx, msgs, nil)
+	require.NoError(t, err)
+	mutate(resp)
+
+	// 修改命中缓存时返回的响应
+	resp, err = p.Complete(ctx, msgs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Checking", resp.Message.ContentBlocks[0].(*TextBlock).Text)
+	mutate(resp)
+
+	resp, err = p.Complete(ctx, msgs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.calls)
+	assert.Equal(t, "Checking", resp.Message.ContentBlocks[0].(*TextBlock).Text)
+	assert.Equal(t, "go", resp.Message.ContentBlocks[1].(*ToolCall).Input["q"])
+	assert.Equal(t, int64(10), resp.Usage.InputTokens)
+	assert.Equal(t, "Check", resp.Logprobs[0].TopLogprobs[0].Token)
+	assert.Equal(t, "resp-1", resp.Metadata["id"])
+}
+
+func TestCachedProvider_APIKey(t *testing.T) {
+	inner := &countingProvider{}
+	p := CachedProvider(inner, NewLRUCache(10), 0)
+	ctx := context.Background()
+	msgs := []Message{{Role: RoleUser, Content: "classify this"}}
+
+	_, _ = p.Complete(ctx, msgs, &Options{APIKey: "tenant-a-key"})
+	_, _ = p.Complete(ctx, msgs, &Options{APIKey: "tenant-a-key"})
+	assert.Equal(t, 1, inner.calls)
+
+	// 不同租户的单次 Key 不命中彼此的缓存，也不命中客户端默认 Key 的缓存
+	_, _ = p.Complete(ctx, msgs, &Options{APIKey: "tenant-b-key"})
+	_, _ = p.Complete(ctx, msgs, nil)
+	assert.Equal(t, 3, inner.calls)
+}
+
+func TestAPIKeyFingerprint(t *testing.T) {
+	fp := APIKeyFingerprint("sk-secret")
+	assert.Len(t, fp, 16)
+	assert.NotContains(t, fp, "secret")
+	assert.Equal(t, fp, APIKeyFingerprint("sk-secret"))
+	assert.NotEqual(t, fp, APIKeyFingerprint("sk-other"))
+	assert.Empty(t, APIKeyFingerprint(""))
+}
+
+func TestCachedProvider_BlockTypeInKey(t *testing.T) {
+	inner := &countingProvider{}
+	p := CachedProvider(inner, NewLRUCache(10), 0)
+	ctx := context.Background()
+
+	text := []Message{{Role: RoleUser, Content: "x", ContentBlocks: []ContentBlock{&TextBlock{Text: "a"}}}}
+	thinking := []Message{{Role: RoleUser, Content: "x", ContentBlocks: []ContentBlock{&ThinkingBlock{Thinking: "a"}}}}
+
+	_, _ = p.Complete(ctx, text, nil)
+	_, _ = p.Complete(ctx, thinking, nil)
+
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestCachedProvider_StreamReplay(t *testing.T) {
+	inner := &countingProvider{}
+	p := CachedProvider(inner, NewLRUCache(10), 0)
+	ctx := context.Background()
+	msgs := []Message{{Role: RoleUser, Content: "hi"}}
+
+	// 未命中：透传
+	_, err := p.Stream(ctx, msgs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.calls)
+
+	// Complete 写入缓存后，Stream 从缓存重放
+	_, _ = p.Complete(ctx, msgs, nil)
+	events, err := p.Stream(ctx, msgs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+
+	var text string
+	var done *Event
+	for e := range events {
+		switch e.Type {
+		case EventTypeText:
+			text += e.TextDelta
+		case EventTypeDone:
+			done = e
+		}
+	}
+	assert.Equal(t, "echo: hi", text)
+	require.NotNil(t, done)
+	assert.Equal(t, "stop", done.FinishReason)
+}
+
+func TestLRUCache_TTL(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	cache := NewLRUCache(10)
+	cache.now = clock.Now
+
+	inner := &countingProvider{}
+	p := CachedProvider(inner, cache, time.Minute)
+	ctx := context.Background()
+	msgs := []Message{{Role: RoleUser, Content: "hi"}}
+
+	_, _ = p.Complete(ctx, msgs, nil)
+	clock.Advance(59 * time.Second)
+	_, _ = p.Complete(ctx, msgs, nil)
+	assert.Equal(t, 1, inner.calls)
+
+	// 过期后重新请求
+	clock.Advance(time.Second)
+	_, _ = p.Complete(ctx, msgs, nil)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestLRUCache_Eviction(t *testing.T) {
+	cache := NewLRUCache(2)
+	cache.Set("a", &Response{Model: "a"}, 0)
+	cache.Set("b", &Response{Model: "b"}, 0)
+
+	// 访问 a 使 b 成为最久未使用
+	_, ok := cache.Get("a")
+	require.True(t, ok)
+	cache.Set("c", &Response{Model: "c"}, 0)
+
+	assert.Equal(t, 2, cache.Len())
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 模型能力注册表
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ModelInfo 模型能力信息
+type ModelInfo struct {
+	SupportsTools   bool // 是否支持工具调用 (Function Calling)
+	ContextWindow   int  // 上下文窗口大小 (tokens)，0 表示未知
+	MaxOutputTokens int  // 最大输出 tokens，0 表示未知
+}
+
+// modelRegistry 按模型名前缀索引的能力表
+var (
+	modelRegistryMu sync.RWMutex
+	modelRegistry   = map[string]ModelInfo{
+		// OpenAI
+		"gpt-4o":     {SupportsTools: true, ContextWindow: 128000, MaxOutputTokens: 16384},
+		"gpt-4.1":    {SupportsTools: true, ContextWindow: 1047576, MaxOutputTokens: 32768},
+		"o1-mini":    {SupportsTools: false, ContextWindow: 128000, MaxOutputTokens: 65536},
+		"o1-preview": {SupportsTools: false, ContextWindow: 128000, MaxOutputTokens: 32768},
+		"o3":         {SupportsTools: true, ContextWindow: 200000, MaxOutputTokens: 100000},
+
+		// Anthropic
+		"claude-3":        {SupportsTools: true, ContextWindow: 200000, MaxOutputTokens: 4096},
+		"claude-3-5":      {SupportsTools: true, ContextWindow: 200000, MaxOutputTokens: 8192},
+		"claude-sonnet-4": {SupportsTools: true, ContextWindow: 200000, MaxOutputTokens: 64000},
+		"claude-opus-4":   {SupportsTools: true, ContextWindow: 200000, MaxOutputTokens: 32000},
+
+		// Gemini
+		"gemini-1.5": {SupportsTools: true, ContextWindow: 1048576, MaxOutputTokens: 8192},
+		"gemini-2.0": {SupportsTools: true, ContextWindow: 1048576, MaxOutputTokens: 8192},
+		"gemini-2.5": {SupportsTools: true, ContextWindow: 1048576, MaxOutputTokens: 65536},
+		"gemma":      {SupportsTools: false, ContextWindow: 8192},
+
+		// DeepSeek
+		"deepseek-chat":     {SupportsTools: true, ContextWindow: 65536, MaxOutputTokens: 8192},
+		"deepseek-reasoner": {SupportsTools: false, ContextWindow: 65536, MaxOutputTokens: 8192},
+	}
+)
+
+// RegisterModel 注册（或覆盖）模型能力
+//
+// prefix 为模型名前缀，查找时取最长匹配。可用于补充内置表未覆盖的模型：
+//
+//	llm.RegisterModel("my-local-model", llm.ModelInfo{SupportsTools: false})
+func RegisterModel(prefix string, info ModelInfo) {
+	modelRegistryMu.Lock()
+	defer modelRegistryMu.Unlock()
+	modelRegistry[prefix] = info
+}
+
+// LookupModel 查找模型能力
+//
+// 忽略 OpenRouter 风格的 "vendor/" 前缀，按最长前缀匹配。未注册的模型返回 false。
+func LookupModel(model string) (ModelInfo, bool) {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	modelRegistryMu.RLock()
+	defer modelRegistryMu.RUnlock()
+
+	var (
+		best    string
+		info    ModelInfo
+		matched bool
+	)
+	for prefix, mi := range modelRegistry {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, info, matched = prefix, mi, true
+		}
+	}
+	return info, matched
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 能力检查
+// ═══════════════════════════════════════════════════════════════════════════
+
+// CheckCapabilities 按模型能力检查并调整请求选项
+//
+// 当请求声明了工具而模型不支持工具调用时：
+//   - 工具调用降级为 ToolFallbackModeAuto（或 opts.ToolFallback 为 true）：返回
+//     ToolFallbackMode 为 [ToolFallbackModeJSON] 的副本，由 core.ApplyToolFallback
+//     改写请求、core.ParseToolFallback 还原工具调用
+//   - opts.StrictCapabilities 为 true：返回指明模型的 RequestError
+//   - 否则原样返回，由 API 自行报错
+//
+// ToolFallbackMode 已为 json 时工具调用总是降级，不做检查。未注册的模型视为
+// 能力未知，不做处理。返回的 Options 为副本，不修改调用方对象。
+func CheckCapabilities(model string, _ []Message, opts *Options) (*Options, error) {
+	if opts == nil || len(opts.Tools) == 0 || opts.ToolFallbackMode == ToolFallbackModeJSON {
+		return opts, nil
+	}
+	auto := opts.ToolFallback || opts.ToolFallbackMode == ToolFallbackModeAuto
+	if !opts.StrictCapabilities && !auto {
+		return opts, nil
+	}
+
+	info, ok := LookupModel(model)
+	if !ok || info.SupportsTools {
+		return opts, nil
+	}
+
+	if !auto {
+		return nil, NewRequestError("validate", fmt.Errorf("model %q does not support tool calling", model))
+	}
+
+	downgraded := *opts
+	downgraded.ToolFallback = false
+	downgraded.ToolFallbackMode = ToolFallbackModeJSON
+	return &downgraded, nil
+}
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// LookupModel 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		model         string
+		wantFound     bool
+		supportsTools bool
+	}{
+		{"gpt-4o-mini", true, true},
+		{"o1-mini", true, false},
+		{"openai/o1-mini", true, false}, // OpenRouter 前缀
+		{"claude-3-5-haiku-latest", true, true},
+		{"gemma-2-9b-it", true, false},
+		{"unknown-model", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			info, ok := LookupModel(tt.model)
+			assert.Equal(t, tt.wantFound, ok)
+			assert.Equal(t, tt.supportsTools, info.SupportsTools)
+		})
+	}
+}
+
+func TestRegisterModel_LongestPrefixWins(t *testing.T) {
+	RegisterModel("gpt-4o-custom", ModelInfo{SupportsTools: false, ContextWindow: 4096})
+
+	info, ok := LookupModel("gpt-4o-custom-v2")
+	require.True(t, ok)
+	assert.False(t, info.SupportsTools)
+	assert.Equal(t, 4096, info.ContextWindow)
+
+	info, ok = LookupModel("gpt-4o-2024-08-06")
+	require.True(t, ok)
+	assert.True(t, info.SupportsTools)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// CheckCapabilities 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+var testTools = []ToolSchema{{
+	Name:        "get_weather",
+	Description: "Get current weather",
+	InputSchema: map[string]any{"type": "object"},
+}}
+
+func TestCheckCapabilities_Strict(t *testing.T) {
+	opts := &Options{Tools: testTools, StrictCapabilities: true}
+
+	_, err := CheckCapabilities("o1-mini", nil, opts)
+
+	require.Error(t, err)
+	assert.True(t, IsRequestError(err))
+	assert.Contains(t, err.Error(), `"o1-mini"`)
+}
+
+func TestCheckCapabilities_Fallback(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *Options
+	}{
+		{"auto mode", &Options{Tools: testTools, ToolFallbackMode: ToolFallbackModeAuto}},
+		{"deprecated flag", &Options{Tools: testTools, ToolFallback: true}},
+		{"auto wins over strict", &Options{Tools: testTools, ToolFallbackMode: ToolFallbackModeAuto, StrictCapabilities: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckCapabilities("o1-mini", nil, tt.opts)
+
+			require.NoError(t, err)
+			// 降级统一交由 JSON 模式处理（core.ApplyToolFallback / core.ParseToolFallback）
+			assert.Equal(t, ToolFallbackModeJSON, got.ToolFallbackMode)
+			assert.False(t, got.ToolFallback)
+			assert.Len(t, got.Tools, 1)
+
+			// 调用方选项不被修改
+			assert.NotEqual(t, ToolFallbackModeJSON, tt.opts.ToolFallbackMode)
+		})
+	}
+}
+
+func TestCheckCapabilities_PassThrough(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		opts  *Options
+	}{
+		{"nil options", "o1-mini", nil},
+		{"no tools", "o1-mini", &Options{StrictCapabilities: true}},
+		{"not opted in", "o1-mini", &Options{Tools: testTools}},
+		{"tool model", "gpt-4o", &Options{Tools: testTools, StrictCapabilities: true}},
+		{"unknown model", "my-model", &Options{Tools: testTools, StrictCapabilities: true}},
+		{"auto on tool model", "gpt-4o", &Options{Tools: testTools, ToolFallbackMode: ToolFallbackModeAuto}},
+		// JSON 模式总是降级，严格检查不再报错
+		{"json mode", "o1-mini", &Options{Tools: testTools, ToolFallbackMode: ToolFallbackModeJSON, StrictCapabilities: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckCapabilities(tt.model, nil, tt.opts)
+			require.NoError(t, err)
+			assert.Same(t, tt.opts, got)
+		})
+	}
+}
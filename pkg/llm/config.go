@@ -43,8 +43,26 @@ type Config struct {
 	BaseURL string `koanf:"base-url"`
 
 	// 网络配置
-	Timeout    time.Duration `koanf:"timeout"`
-	MaxRetries int           `koanf:"max-retries"`
+	Timeout time.Duration `koanf:"timeout"`
+
+	// 可重试错误（429、5xx）的最大重试次数（不含首次请求，<= 0 不覆盖全局默认值，见 core.WithRetry）
+	MaxRetries int `koanf:"max-retries"`
+
+	// 跳过 TLS 证书校验（⚠️ 仅用于测试自签名证书的本地服务，切勿用于生产）
+	InsecureSkipVerify bool `koanf:"insecure-skip-verify"`
+
+	// 创建时探测模型元数据，认证失败立即返回 ConfigError（OpenAI 兼容、Anthropic、Gemini 有效）
+	ProbeOnInit bool `koanf:"probe-on-init"`
+
+	// 自定义端点路径（OpenAI 兼容与 Anthropic 有效，为空时使用默认端点）
+	CompletePath string `koanf:"complete-path"`
+	StreamPath   string `koanf:"stream-path"`
+
+	// 完成原因覆盖：在标准映射之后应用，归一化网关的非标准值（如 "complete" → "stop"）
+	FinishReasonOverrides map[string]string `koanf:"finish-reason-overrides"`
+
+	// 同时进行的 Complete / Stream 请求上限，超出时阻塞等待（<= 0 不限制，见 core.WithMaxConcurrentRequests）
+	MaxConcurrentRequests int `koanf:"max-concurrent-requests"`
 
 	// 扩展配置
 	Extra map[string]any `koanf:"extra"`
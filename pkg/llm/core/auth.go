@@ -0,0 +1,37 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 访问令牌认证
+// ═══════════════════════════════════════════════════════════════════════════
+
+// TokenSource 提供 Bearer 访问令牌（如 OAuth2 服务账户令牌）
+//
+// 每个请求发送前调用，实现负责缓存与过期前刷新，并保证并发安全。
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// WithTokenSource 在每个请求上设置 "Authorization: Bearer <token>"
+//
+// 覆盖 BuildHeaders 中的同名请求头，作用于 Complete、Stream（含重试）与 NewRequest
+// 创建的请求。获取令牌失败时请求不会发出，错误以 HTTPError 返回。
+// 克隆共享同一 TokenSource。
+func WithTokenSource(src TokenSource) ClientOption {
+	return func(c *BaseClient) {
+		c.resty.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+			token, err := src.Token(req.Context())
+			if err != nil {
+				return fmt.Errorf("fetch access token: %w", err)
+			}
+			req.SetAuthToken(token)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// Token 估算
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ImageTokenCost 单个图片块的估算 token 数
+//
+// 取 OpenAI 高精度模式下 1024x1024 图片的典型开销，仅用于预算估算。
+const ImageTokenCost int64 = 765
+
+// EstimateTokens 启发式估算文本 token 数
+//
+// 规则：ASCII 字符约 4 个 1 token，非 ASCII 字符（CJK、emoji 等）每个约 1 token。
+// 结果仅用于预算控制，与实际分词器存在偏差。
+func EstimateTokens(text string) int64 {
+	var ascii, other int64
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// EstimateBlockTokens 估算单个内容块的 token 数
+//
+// 文本类内容块按 EstimateTokens 估算；BlockType 为 "image" 的块按 ImageTokenCost 计。
+func EstimateBlockTokens(block llm.ContentBlock) int64 {
+	switch b := block.(type) {
+	case *llm.TextBlock:
+		return EstimateTokens(b.Text)
+	case *llm.ThinkingBlock:
+		return EstimateTokens(b.Thinking)
+	case *llm.ToolResultBlock:
+		return EstimateTokens(b.Content)
+	case *llm.CodeExecutionResultBlock:
+		return EstimateTokens(b.Code) + EstimateTokens(b.Stdout) + EstimateTokens(b.Stderr)
+	case *llm.ToolCall:
+		input, _ := json.Marshal(b.Input) //nolint:errchkjson // best effort
+		return EstimateTokens(b.Name) + EstimateTokens(string(input))
+	}
+	if block != nil && block.BlockType() == "image" {
+		return ImageTokenCost
+	}
+	return 0
+}
+
+// EstimateMessageTokens 估算单条消息的 token 数（Content 与全部内容块之和）
+func EstimateMessageTokens(msg *llm.Message) int64 {
+	total := EstimateTokens(msg.Content)
+	for _, block := range msg.ContentBlocks {
+		total += EstimateBlockTokens(block)
+	}
+	return total
+}
+
+// EstimatePromptTokens 估算提示词各段的 token 边界
+//
+// 按缓存前缀顺序依次为工具定义、Options.System（含 SystemParts）与各条消息，结果标记为 Estimated。
+// 用于 Options.ReturnPromptTokens 在 Provider 无法返回分词结果时的回退。
+func EstimatePromptTokens(messages []llm.Message, opts *llm.Options) *llm.PromptTokensDetail {
+	detail := &llm.PromptTokensDetail{Estimated: true}
+	add := func(kind string, index int, tokens int64) {
+		detail.Total += tokens
+		detail.Segments = append(detail.Segments, llm.PromptTokenSegment{
+			Kind:   kind,
+			Index:  index,
+			Tokens: tokens,
+			End:    detail.Total,
+		})
+	}
+
+	if opts != nil && len(opts.Tools) > 0 {
+		tools, _ := json.Marshal(opts.Tools) //nolint:errchkjson // best effort
+		add("tools", 0, EstimateTokens(string(tools)))
+	}
+	if opts != nil {
+		if parts := SystemParts(opts.System, opts); len(parts) > 0 {
+			add("system", 0, EstimateTokens(strings.Join(parts, "\n\n")))
+		}
+	}
+	for i := range messages {
+		add("message", i, EstimateMessageTokens(&messages[i]))
+	}
+	return detail
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 内容分块
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ChunkContent 按 token 预算将混合内容块切分为多组
+//
+// 适用于 map-reduce 式摘要：将超长文档拆成多次请求分别摘要，再合并结果。
+//
+// 规则：
+//   - 按顺序贪心装箱，每组估算 token 数不超过 maxTokensPerChunk
+//   - 图片块不会被拆分；单个图片超出预算时独占一组
+//   - 紧邻图片之前的文本块视为图片说明，尽量与图片放在同一组
+//   - 超出预算的文本块按行/字符边界拆分（不会截断多字节字符）
+//
+// maxTokensPerChunk <= 0 时返回包含全部内容的单组。
+func ChunkContent(blocks []llm.ContentBlock, maxTokensPerChunk int64) [][]llm.ContentBlock {
+	if len(blocks) == 0 {
+		return nil
+	}
+	if maxTokensPerChunk <= 0 {
+		return [][]llm.ContentBlock{blocks}
+	}
+
+	// 1. 拆分超出预算的文本块
+	var units []llm.ContentBlock
+	for _, block := range blocks {
+		if tb, ok := block.(*llm.TextBlock); ok && EstimateTokens(tb.Text) > maxTokensPerChunk {
+			for _, part := range splitText(tb.Text, maxTokensPerChunk) {
+				units = append(units, &llm.TextBlock{Text: part})
+			}
+			continue
+		}
+		units = append(units, block)
+	}
+
+	// 2. 分组：图片说明文本与图片绑定
+	var groups [][]llm.ContentBlock
+	for i := 0; i < len(units); i++ {
+		if _, ok := units[i].(*llm.TextBlock); ok && i+1 < len(units) && isImage(units[i+1]) {
+			pair := []llm.ContentBlock{units[i], units[i+1]}
+			if groupTokens(pair) <= maxTokensPerChunk {
+				groups = append(groups, pair)
+				i++
+				continue
+			}
+		}
+		groups = append(groups, []llm.ContentBlock{units[i]})
+	}
+
+	// 3. 贪心装箱
+	var (
+		chunks  [][]llm.ContentBlock
+		current []llm.ContentBlock
+		used    int64
+	)
+	for _, group := range groups {
+		cost := groupTokens(group)
+		if len(current) > 0 && used+cost > maxTokensPerChunk {
+			chunks = append(chunks, current)
+			current, used = nil, 0
+		}
+		current = append(current, group...)
+		used += cost
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// isImage 判断是否为图片块
+func isImage(block llm.ContentBlock) bool {
+	return block != nil && block.BlockType() == "image"
+}
+
+// groupTokens 估算一组内容块的 token 数
+func groupTokens(group []llm.ContentBlock) int64 {
+	var total int64
+	for _, block := range group {
+		total += EstimateBlockTokens(block)
+	}
+	return total
+}
+
+// splitText 将文本拆分为不超过 maxTokens 的片段
+//
+// 优先在换行处断开，单行仍超出时按字符边界硬切。
+func splitText(text string, maxTokens int64) []string {
+	var (
+		parts   []string
+		current strings.Builder
+	)
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if EstimateTokens(current.String()+line) <= maxTokens {
+			current.WriteString(line)
+			continue
+		}
+		flush()
+
+		// 单行超出预算：按字符硬切
+		for EstimateTokens(line) > maxTokens {
+			cut := cutIndex(line, maxTokens)
+			parts = append(parts, line[:cut])
+			line = line[cut:]
+		}
+		current.WriteString(line)
+	}
+	flush()
+
+	return parts
+}
+
+// cutIndex 返回 s 中估算 token 数不超过 maxTokens 的最长前缀的字节长度（至少一个字符）
+func cutIndex(s string, maxTokens int64) int {
+	cut := prefixIndex(s, maxTokens)
+	if cut == 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		cut = size
+	}
+	return cut
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// testImageBlock 测试用图片块
+type testImageBlock struct {
+	name string
+}
+
+func (b *testImageBlock) BlockType() string { return "image" }
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, int64(0), EstimateTokens(""))
+	assert.Equal(t, int64(1), EstimateTokens("abcd"))
+	assert.Equal(t, int64(2), EstimateTokens("abcde"))
+	assert.Equal(t, int64(2), EstimateTokens("你好"))
+}
+
+func TestEstimatePromptTokens(t *testing.T) {
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: strings.Repeat("a", 40)},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{&llm.TextBlock{Text: "你好"}}},
+	}
+	opts := &llm.Options{System: strings.Repeat("s", 8), Tools: []llm.ToolSchema{{Name: "t"}}}
+
+	detail := EstimatePromptTokens(messages, opts)
+	require.Len(t, detail.Segments, 4)
+	assert.True(t, detail.Estimated)
+	assert.Equal(t, "tools", detail.Segments[0].Kind)
+
+	tools := detail.Segments[0].Tokens
+	assert.Equal(t, llm.PromptTokenSegment{Kind: "system", Tokens: 2, End: tools + 2}, detail.Segments[1])
+	assert.Equal(t, llm.PromptTokenSegment{Kind: "message", Index: 0, Tokens: 10, End: tools + 12}, detail.Segments[2])
+	assert.Equal(t, llm.PromptTokenSegment{Kind: "message", Index: 1, Tokens: 2, End: tools + 14}, detail.Segments[3])
+	assert.Equal(t, tools+14, detail.Total)
+
+	// 无选项时仅包含消息
+	detail = EstimatePromptTokens(messages, nil)
+	require.Len(t, detail.Segments, 2)
+	assert.Equal(t, int64(12), detail.Total)
+}
+
+func TestChunkContent_MixedTextAndImages(t *testing.T) {
+	text := func(n int) *llm.TextBlock { return &llm.TextBlock{Text: strings.Repeat("a", n*4)} }
+	img1 := &testImageBlock{name: "img1"}
+	img2 := &testImageBlock{name: "img2"}
+	caption := text(50)
+
+	blocks := []llm.ContentBlock{
+		text(400), // 400 tokens
+		caption,   // 50 tokens，图片说明
+		img1,      // 765 tokens
+		text(100), // 100 tokens
+		img2,      // 765 tokens
+	}
+
+	chunks := ChunkContent(blocks, 1000)
+
+	// 每组不超过预算
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, groupTokens(chunk), int64(1000), "chunk %d over budget", i)
+	}
+
+	// 图片完整保留且数量不变
+	var images int
+	for _, chunk := range chunks {
+		for _, b := range chunk {
+			if isImage(b) {
+				images++
+			}
+		}
+	}
+	assert.Equal(t, 2, images)
+
+	// 图片说明与图片在同一组
+	require.Len(t, chunks, 3)
+	assert.Equal(t, []llm.ContentBlock{blocks[0]}, chunks[0])
+	assert.Equal(t, []llm.ContentBlock{caption, img1}, chunks[1])
+	assert.Equal(t, []llm.ContentBlock{blocks[3], img2}, chunks[2])
+}
+
+func TestChunkContent_OversizedImageAlone(t *testing.T) {
+	img := &testImageBlock{name: "big"}
+	blocks := []llm.ContentBlock{&llm.TextBlock{Text: "intro"}, img, &llm.TextBlock{Text: "outro"}}
+
+	chunks := ChunkContent(blocks, 100)
+
+	require.Len(t, chunks, 3)
+	assert.Equal(t, []llm.ContentBlock{img}, chunks[1])
+}
+
+func TestChunkContent_SplitsLongText(t *testing.T) {
+	long := strings.Repeat("第一行内容\n", 20) + strings.Repeat("长", 50)
+	blocks := []llm.ContentBlock{&llm.TextBlock{Text: long}}
+
+	chunks := ChunkContent(blocks, 30)
+
+	require.Greater(t, len(chunks), 1)
+	var joined strings.Builder
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, groupTokens(chunk), int64(30))
+		for _, b := range chunk {
+			tb, ok := b.(*llm.TextBlock)
+			require.True(t, ok)
+			assert.True(t, utf8.ValidString(tb.Text))
+			joined.WriteString(tb.Text)
+		}
+	}
+	assert.Equal(t, long, joined.String())
+}
+
+func TestChunkContent_NoBudget(t *testing.T) {
+	blocks := []llm.ContentBlock{&llm.TextBlock{Text: "a"}, &testImageBlock{}}
+
+	assert.Equal(t, [][]llm.ContentBlock{blocks}, ChunkContent(blocks, 0))
+	assert.Nil(t, ChunkContent(nil, 100))
+}
@@ -2,8 +2,15 @@ package core
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-resty/resty/v2"
 
@@ -46,6 +53,94 @@ type EndpointBuilder interface {
 	BuildStreamEndpoint() string
 }
 
+// ModelEndpointBuilder 模型相关的端点构建器（可选接口）
+//
+// 端点路径包含模型名称的 Provider（如 Gemini/Vertex）实现此接口，
+// 使 Options.Model 的单次覆盖同样作用于端点。
+type ModelEndpointBuilder interface {
+	// BuildModelEndpoint 构建指定模型的端点
+	BuildModelEndpoint(model string, stream bool) string
+}
+
+// APIKeyEndpointBuilder API Key 位于端点 URL 的端点构建器（可选接口）
+//
+// API Key 通过查询参数传递的 Provider（如 Gemini 的 key=）实现此接口，
+// 使 Options.APIKey 的单次覆盖同样作用于端点。model 为空时使用配置的模型。
+type APIKeyEndpointBuilder interface {
+	// BuildAPIKeyEndpoint 构建使用指定 API Key 的端点
+	BuildAPIKeyEndpoint(model, apiKey string, stream bool) string
+}
+
+// APIKeyHeaderBuilder 单次 API Key 认证头构建器（可选接口）
+//
+// 通过请求头认证的 ProviderConfig 实现此接口，返回使用指定 API Key 的认证头
+// （如 Authorization、X-Api-Key），用于 Options.APIKey 的单次覆盖。
+type APIKeyHeaderBuilder interface {
+	// BuildAPIKeyHeaders 构建指定 API Key 的认证头
+	BuildAPIKeyHeaders(apiKey string) map[string]string
+}
+
+// RequestHeaderBuilder 单次请求头构建器（可选接口）
+//
+// RequestBuilder 实现此接口以按请求选项附加协议要求的请求头（如 Anthropic 的
+// anthropic-beta），与 Options.Headers 合并后随本次请求发送，同名（不区分大小写）时覆盖。
+type RequestHeaderBuilder interface {
+	// BuildRequestHeaders 构建本次请求附加的请求头，无需附加时返回 nil
+	BuildRequestHeaders(opts *llm.Options) map[string]string
+}
+
+// PathEndpointBuilder 固定路径的端点构建器
+//
+// 适用于流式与非流式使用不同固定路径的网关（如 /chat/completions 与 /chat/stream）。
+type PathEndpointBuilder struct {
+	CompletePath string
+	StreamPath   string
+}
+
+// BuildCompleteEndpoint 返回 Complete 路径
+func (b *PathEndpointBuilder) BuildCompleteEndpoint() string {
+	return b.CompletePath
+}
+
+// BuildStreamEndpoint 返回 Stream 路径
+func (b *PathEndpointBuilder) BuildStreamEndpoint() string {
+	return b.StreamPath
+}
+
+// ValidateEndpointPath 校验自定义端点路径
+//
+// 空字符串表示使用默认端点；非空时必须以 "/" 开头。
+func ValidateEndpointPath(name, path string) error {
+	if path == "" {
+		return nil
+	}
+	if strings.TrimSpace(path) == "" || !strings.HasPrefix(path, "/") {
+		return llm.NewConfigError(fmt.Sprintf("invalid %s: %q must start with '/'", name, path), nil)
+	}
+	return nil
+}
+
+// ValidateTimeout 校验超时时间
+//
+// 0 表示使用默认值（见 GetDefaultTimeout）；负数返回 ConfigError。
+func ValidateTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return llm.NewConfigError(fmt.Sprintf("invalid timeout: %s must be positive", timeout), nil)
+	}
+	return nil
+}
+
+// WarnUnknownModel 模型未在注册表（llm.LookupModel）中时记录警告
+//
+// 未注册的模型仍可正常调用，但无法获取上下文窗口、输出上限等元数据，
+// 常见原因是模型名拼写错误。警告通过 slog 默认 Logger 输出。
+func WarnUnknownModel(provider, model string) {
+	if _, ok := llm.LookupModel(model); !ok {
+		slog.Warn("unknown model, capability checks and default max tokens are disabled",
+			"provider", provider, "model", model)
+	}
+}
+
 // RequestBuilder 请求构建器接口
 //
 // 每个 Provider 实现此接口来定义协议特定的请求体构建逻辑。
@@ -80,15 +175,36 @@ type BaseClient struct {
 	resty           *resty.Client
 	transformer     *Transformer
 	sseParser       *SSEParser
-	endpointBuilder EndpointBuilder // 可选，用于 Gemini 等动态端点的 Provider
+	endpointBuilder EndpointBuilder                                  // 可选，用于 Gemini 等动态端点的 Provider
+	streamHeaders   map[string]string                                // 仅流式请求附加的请求头
+	retry           RetryConfig                                      // 重试配置（见 WithRetry）
+	sleep           func(ctx context.Context, d time.Duration) error // 重试等待（测试可替换）
+
+	noDefaultMaxTokens bool            // 关闭 MaxTokens 自动填充（见 WithDefaultMaxTokens）
+	toolResultLimit    ToolResultLimit // 工具结果长度限制（见 WithToolResultLimit）
+	probed             *probedModel    // 模型元数据探测结果（见 ProbeModel）
+	limiter            *requestLimiter // 并发请求限制（见 WithMaxConcurrentRequests），克隆间共享
+	observer           Observer        // 请求/响应体观察（见 WithObserver）
+	idGenerator        IDGenerator     // 合成 ID 生成器（见 WithIDGenerator），nil 时使用默认
+}
+
+// TLSVerifySkipper 可选接口：ProviderConfig 实现此接口以跳过 TLS 证书校验
+//
+// ⚠️ 仅用于测试自签名证书的本地服务，生产环境必须保持校验。
+type TLSVerifySkipper interface {
+	SkipTLSVerify() bool
 }
 
+// DefaultStreamAccept 流式请求默认的 Accept 请求头
+const DefaultStreamAccept = "text/event-stream"
+
 // NewBaseClient 创建基础客户端
 //
 // 参数：
 //   - config: Provider 特定配置，实现 ProviderConfig 接口
 //   - adapter: 协议适配器，处理消息格式转换
 //   - eventHandler: SSE 事件处理器，处理流式响应
+//   - opts: 可选配置（如 WithIDGenerator）
 //
 // 返回：
 //   - BaseClient 实例
@@ -97,6 +213,7 @@ func NewBaseClient(
 	config ProviderConfig,
 	adapter ProtocolAdapter,
 	eventHandler EventHandler,
+	opts ...ClientOption,
 ) (*BaseClient, error) {
 	// 1. 验证配置
 	if err := config.Validate(); err != nil {
@@ -116,17 +233,41 @@ func NewBaseClient(
 	for k, v := range headers {
 		r.SetHeader(k, v)
 	}
+	if s, ok := config.(TLSVerifySkipper); ok && s.SkipTLSVerify() {
+		r.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true}) //nolint:gosec // 显式开启，仅用于测试
+	}
 
 	// 5. 创建协议适配器和转换器
 	transformer := NewTransformer(adapter)
 	sseParser := NewSSEParser(eventHandler)
 
-	return &BaseClient{
+	c := &BaseClient{
 		config:      config,
 		resty:       r,
 		transformer: transformer,
 		sseParser:   sseParser,
-	}, nil
+		streamHeaders: map[string]string{
+			"Accept": DefaultStreamAccept,
+		},
+		retry:   llm.DefaultRetry(),
+		sleep:   sleepContext,
+		limiter: &requestLimiter{},
+	}
+
+	// 6. 应用可选配置
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// SetStreamHeader 设置仅用于流式请求的请求头
+//
+// 默认包含 Accept: text/event-stream。后端需要其他值时由 Provider 覆盖；
+// 配置中（BuildHeaders）已存在的同名请求头优先，不会被覆盖。
+func (c *BaseClient) SetStreamHeader(key, value string) {
+	c.streamHeaders[key] = value
 }
 
 // SetEndpointBuilder 设置端点构建器
@@ -136,6 +277,54 @@ func (c *BaseClient) SetEndpointBuilder(builder EndpointBuilder) {
 	c.endpointBuilder = builder
 }
 
+// CloneWithConfig 使用新配置创建共享底层 HTTP 客户端的副本
+//
+// 不重新验证配置。副本与原客户端共享 resty 客户端（连接池、BaseURL、超时、
+// 认证请求头在创建时已固定），仅请求级配置（如默认模型）使用新配置。
+// 流式请求头为独立副本，端点构建器沿用原值，需要时由 Provider 重新设置。
+func (c *BaseClient) CloneWithConfig(config ProviderConfig) *BaseClient {
+	clone := *c
+	clone.config = config
+	clone.streamHeaders = maps.Clone(c.streamHeaders)
+	return &clone
+}
+
+// PreparedRequest 已校验并构建完成的请求（见 [BaseClient.PrepareRequest]）
+type PreparedRequest struct {
+	Body         map[string]any // 请求体
+	Options      *llm.Options   // 实际生效的选项（已按能力检查调整并合并附加请求头）
+	ToolFallback bool           // 是否应用了 JSON 模式工具调用降级（响应需经 ParseToolFallback 还原）
+}
+
+// PrepareRequest 执行 Complete / Stream 发送前的全部准备步骤
+//
+// 依次进行工具定义与图片校验、模型能力检查（含默认 MaxTokens 填充）、超长工具结果截断、
+// JSON 模式工具调用降级，再由 requestBuilder 构建请求体并合并其附加的请求头。
+// Provider 在 Complete / Stream 之外发送请求（如批处理）时使用，保证与 Complete 的校验一致。
+func (c *BaseClient) PrepareRequest(
+	ctx context.Context,
+	messages []llm.Message,
+	opts *llm.Options,
+	requestBuilder RequestBuilder,
+	stream bool,
+) (*PreparedRequest, error) {
+	opts, err := c.checkCapabilities(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	messages = LimitToolResults(ctx, messages, c.toolResultLimit)
+	messages, opts, toolFallback := ApplyToolFallback(messages, opts)
+	body, err := requestBuilder.BuildRequest(messages, opts, stream)
+	if err != nil {
+		return nil, llm.NewRequestError("build request", err)
+	}
+	return &PreparedRequest{
+		Body:         body,
+		Options:      withRequestHeaders(requestBuilder, opts),
+		ToolFallback: toolFallback,
+	}, nil
+}
+
 // Complete 同步完成（通用实现）
 //
 // 实现了 llm.Provider 接口的 Complete 方法。
@@ -144,10 +333,12 @@ func (c *BaseClient) SetEndpointBuilder(builder EndpointBuilder) {
 //  1. 构建 API 请求体（委托给 RequestBuilder）
 //  2. 序列化请求体
 //  3. 发送 HTTP POST 请求
-//  4. 检查 HTTP 状态码
+//  4. 检查 HTTP 状态码（配置 WithRetry 时，可重试错误按退避重新发送）
 //  5. 解析响应（使用 Transformer）
 //  6. 返回统一格式的 Response
 //
+// 重试期间保持并发名额；Response.Latency 为最后一次尝试的耗时。
+//
 // 参数：
 //   - ctx: 上下文，支持取消和超时
 //   - messages: 对话消息列表
@@ -164,60 +355,144 @@ func (c *BaseClient) Complete(
 	requestBuilder RequestBuilder,
 ) (*llm.Response, error) {
 	// 1. 构建请求体
-	body, err := requestBuilder.BuildRequest(messages, opts, false)
+	prepared, err := c.PrepareRequest(ctx, messages, opts, requestBuilder, false)
 	if err != nil {
-		return nil, llm.NewRequestError("build request", err)
+		return nil, err
 	}
+	body, opts, toolFallback := prepared.Body, prepared.Options, prepared.ToolFallback
 
-	bodyBytes, err := json.Marshal(body)
-	if err != nil {
-		return nil, llm.NewRequestError("marshal request", err)
+	if err := c.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.limiter.release()
+
+	// 2-4. 发送请求并解码响应（配置 WithRetry 时可重试错误按退避重新发送）
+	var (
+		apiResp map[string]any
+		status  int
+		latency time.Duration
+	)
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		apiResp, status, err = c.postJSON(ctx, c.getCompleteEndpoint(opts), body, opts)
+		latency = time.Since(start)
+		if err == nil {
+			break
+		}
+		if attempt >= c.retry.MaxAttempts || !llm.IsRetryableError(err) {
+			return nil, err
+		}
+		delay, ok := retryDelay(c.retry, err, attempt)
+		if !ok {
+			return nil, err
+		}
+		if sleepErr := c.sleep(ctx, delay); sleepErr != nil {
+			return nil, err
+		}
 	}
 
-	// 2. 确定端点
-	endpoint := c.getCompleteEndpoint()
+	// 5. 解析响应
+	msg, finishReason, usage, extras := c.transformer.ParseAPIResponse(apiResp)
+	if toolFallback {
+		msg, finishReason = ParseToolFallback(msg, finishReason, c.idGenerator)
+	}
 
-	// 3. 发送请求
-	var apiResp map[string]any
-	resp, err := c.resty.R().
-		SetContext(ctx).
-		SetBody(bodyBytes).
-		SetResult(&apiResp).
-		Post(endpoint)
-	if err != nil {
-		return nil, llm.NewHTTPError("request failed", err)
+	// 6. 提取模型（响应 > 单次覆盖 > 配置）；Gemini 以 modelVersion 报告解析后的版本
+	model := c.getModelFromConfig()
+	if opts != nil && opts.Model != "" {
+		model = opts.Model
+	}
+	if respModel, ok := apiResp["model"].(string); ok && respModel != "" {
+		model = respModel
+	}
+	resolvedModel := GetString(apiResp["model"])
+	if resolvedModel == "" {
+		resolvedModel = GetString(apiResp["modelVersion"])
 	}
 
-	// 4. 检查 HTTP 错误
-	if resp.StatusCode() >= 400 {
-		apiErr := llm.NewAPIError(resp.StatusCode(), resp.String())
+	response := &llm.Response{
+		Message:           msg,
+		FinishReason:      finishReason,
+		Model:             model,
+		ResolvedModel:     resolvedModel,
+		SystemFingerprint: GetString(apiResp["system_fingerprint"]),
+		StatusCode:        status,
+		Latency:           latency,
+		Reasoning:         msg.GetReasoning(),
+		Usage:             usage,
+	}
+	extras.Apply(response)
+	response.SetCacheStatus()
+	if opts != nil && opts.ReturnPromptTokens {
+		response.PromptTokensDetail = EstimatePromptTokens(messages, opts)
+	}
 
-		// 尝试提取请求 ID（从响应头）
-		if requestID := resp.Header().Get("X-Request-ID"); requestID != "" {
-			apiErr = apiErr.WithRequestID(requestID)
-		}
+	// 7. 工具调用数量限制与结构化输出校验（失败时仍返回响应）
+	if err := LimitToolCalls(response, opts); err != nil {
+		return response, err
+	}
+	return response, ValidateResponse(response, opts)
+}
+
+// CompleteRaw 发送原始请求体到 Complete 端点，返回解码后的响应
+//
+// 跳过 RequestBuilder 与响应解析，body 原样序列化发送；认证头、端点、客户端级
+// 请求头与 HTTP 错误处理与 [BaseClient.Complete] 一致。用于在库尚未建模的 API
+// 新特性上先行试验。响应体不是 JSON 对象时返回 [llm.ResponseError]。
+// 与 Complete 共享 [WithMaxConcurrentRequests] 的并发名额。
+func (c *BaseClient) CompleteRaw(ctx context.Context, body map[string]any) (map[string]any, error) {
+	if err := c.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.limiter.release()
 
-		// 设置 Provider 类型
-		apiErr = apiErr.WithProvider(c.config.ProviderName())
+	apiResp, _, err := c.postJSON(ctx, c.getCompleteEndpoint(nil), body, nil)
+	return apiResp, err
+}
 
-		return nil, apiErr
+// postJSON 发送 JSON 请求体并解码 JSON 对象响应，返回 HTTP 状态码
+//
+// opts 提供单次请求头、单次 API Key 与 Observer 的关联 ID，可为 nil。
+func (c *BaseClient) postJSON(ctx context.Context, endpoint string, body map[string]any, opts *llm.Options) (map[string]any, int, error) {
+	id := correlationID(opts)
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, 0, llm.NewRequestError("marshal request", err)
+	}
+	if c.observer != nil {
+		c.observer.OnRequest(id, bodyBytes)
 	}
 
-	// 5. 解析响应
-	msg, finishReason, usage := c.transformer.ParseAPIResponse(apiResp)
+	req := c.resty.R().
+		SetContext(ctx).
+		SetBody(bodyBytes)
+	resp, err := c.applyRequestHeaders(req, opts).Post(endpoint)
+	if err != nil {
+		err = llm.NewHTTPError("request failed", err)
+		if c.observer != nil {
+			c.observer.OnResponse(id, nil, err)
+		}
+		return nil, 0, err
+	}
 
-	// 6. 提取模型（如果响应中有）
-	model := c.getModelFromConfig()
-	if respModel, ok := apiResp["model"].(string); ok && respModel != "" {
-		model = respModel
+	// 检查 HTTP 错误
+	err = c.CheckResponse(resp)
+	if c.observer != nil {
+		c.observer.OnResponse(id, resp.Body(), err)
+	}
+	if err != nil {
+		return nil, resp.StatusCode(), err
 	}
 
-	return &llm.Response{
-		Message:      msg,
-		FinishReason: finishReason,
-		Model:        model,
-		Usage:        usage,
-	}, nil
+	// 显式解码响应体，避免代理返回的 HTML 等非 JSON 内容被静默解析为空响应
+	var apiResp map[string]any
+	if err := json.Unmarshal(resp.Body(), &apiResp); err != nil || apiResp == nil {
+		if err == nil {
+			err = errors.New("response body is not a JSON object")
+		}
+		return nil, resp.StatusCode(), llm.NewResponseError("body", err).WithBody(bodySnippet(resp.Body()))
+	}
+	return apiResp, resp.StatusCode(), nil
 }
 
 // Stream 流式完成（通用实现）
@@ -228,7 +503,7 @@ func (c *BaseClient) Complete(
 //  1. 构建 API 请求体（委托给 RequestBuilder）
 //  2. 序列化请求体
 //  3. 发送 HTTP POST 请求（不解析响应）
-//  4. 检查 HTTP 状态码
+//  4. 检查 HTTP 状态码（配置 WithRetry 时，可重试错误按退避重新发送）
 //  5. 启动 SSE 解析（在 goroutine 中）
 //  6. 返回事件 channel
 //
@@ -246,6 +521,10 @@ func (c *BaseClient) Complete(
 //   - 返回的 channel 缓冲区大小为 10
 //   - SSE 解析在 goroutine 中进行
 //   - 完成或出错后 channel 会自动关闭
+//   - Done 与 Error 事件携带 StreamMeta（初始响应状态码与首字节耗时）
+//   - 重试仅覆盖建立阶段；收到 2xx 后流中途的失败不会重试（避免重复输出）
+//   - ToolFallbackMode 为 json 时仅改写请求，流式输出为原始 JSON 文本，
+//     聚合后可通过 ParseToolFallback 还原工具调用
 func (c *BaseClient) Stream(
 	ctx context.Context,
 	messages []llm.Message,
@@ -253,50 +532,225 @@ func (c *BaseClient) Stream(
 	requestBuilder RequestBuilder,
 ) (<-chan *llm.Event, error) {
 	// 1. 构建请求体
-	body, err := requestBuilder.BuildRequest(messages, opts, true)
+	prepared, err := c.PrepareRequest(ctx, messages, opts, requestBuilder, true)
 	if err != nil {
-		return nil, llm.NewRequestError("build request", err)
+		return nil, err
 	}
+	opts = prepared.Options
 
-	bodyBytes, err := json.Marshal(body)
+	bodyBytes, err := json.Marshal(prepared.Body)
 	if err != nil {
 		return nil, llm.NewRequestError("marshal request", err)
 	}
 
 	// 2. 确定端点
-	endpoint := c.getStreamEndpoint()
+	endpoint := c.getStreamEndpoint(opts)
 
-	// 3. 发送请求（不解析响应）
-	resp, err := c.resty.R().
-		SetContext(ctx).
-		SetBody(bodyBytes).
-		SetDoNotParseResponse(true).
-		Post(endpoint)
+	// 3. 发送请求并检查 HTTP 错误（建立阶段，可重试）
+	if err := c.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	id := correlationID(opts)
+	if c.observer != nil {
+		c.observer.OnRequest(id, bodyBytes)
+	}
+	start := time.Now()
+	resp, err := c.openStream(ctx, endpoint, bodyBytes, opts)
 	if err != nil {
-		return nil, llm.NewHTTPError("request failed", err)
+		c.limiter.release()
+		if c.observer != nil {
+			c.observer.OnResponse(id, nil, err)
+		}
+		return nil, err
 	}
 
-	// 4. 检查 HTTP 错误
-	if resp.StatusCode() >= 400 {
-		apiErr := llm.NewAPIError(resp.StatusCode(), resp.String())
+	// 4. 启动 SSE 解析（此后不再重试，避免重复输出），流结束后释放并发名额
+	rawBody := resp.RawBody()
+	if c.observer != nil {
+		rawBody = &observedBody{ReadCloser: rawBody, id: id, obs: c.observer}
+	}
+	chunks := make(chan *llm.Event, 10)
+	go func() {
+		defer c.limiter.release()
+		c.sseParser.Parse(rawBody, chunks)
+	}()
+
+	meta := &llm.StreamMeta{StatusCode: resp.StatusCode(), TimeToFirstByte: time.Since(start)}
+	events := attachStreamMeta(chunks, meta)
+	if len(c.transformer.finishReasonOverrides) > 0 {
+		events = overrideFinishReasons(events, c.transformer)
+	}
+	if opts != nil && opts.AccumulateToolArgs {
+		return AccumulateToolArgs(events), nil
+	}
+	return events, nil
+}
 
-		// 尝试提取请求 ID
-		if requestID := resp.Header().Get("X-Request-ID"); requestID != "" {
-			apiErr = apiErr.WithRequestID(requestID)
+// openStream 发送流式请求，返回状态码正常、尚未读取的响应
+//
+// 配置了 [WithRetry] 时，建立阶段（收到 2xx 响应之前）遇到可重试错误会重试，
+// 等待时间优先取 APIError.RetryAfter（超过 MaxDelay 时不再重试），否则按指数退避；
+// 收到 2xx 响应后即交由 SSE 解析，流中途的失败以 Error 事件上报，不会重试。
+func (c *BaseClient) openStream(ctx context.Context, endpoint string, body []byte, opts *llm.Options) (*resty.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req := c.resty.R().
+			SetContext(ctx).
+			SetBody(body).
+			SetDoNotParseResponse(true)
+		for k, v := range c.streamHeaders {
+			if c.resty.Header.Get(k) == "" {
+				req.SetHeader(k, v)
+			}
 		}
 
-		// 设置 Provider 类型
-		apiErr = apiErr.WithProvider(c.config.ProviderName())
+		resp, err := c.applyRequestHeaders(req, opts).Post(endpoint)
+		if err != nil {
+			err = llm.NewHTTPError("request failed", err)
+		} else if err = c.CheckResponse(resp); err == nil {
+			return resp, nil
+		} else {
+			_ = resp.RawBody().Close()
+		}
 
-		_ = resp.RawBody().Close()
-		return nil, apiErr
+		if attempt >= c.retry.MaxAttempts || !llm.IsRetryableError(err) {
+			return nil, err
+		}
+		delay, ok := retryDelay(c.retry, err, attempt)
+		if !ok {
+			return nil, err
+		}
+		if sleepErr := c.sleep(ctx, delay); sleepErr != nil {
+			return nil, err
+		}
 	}
+}
 
-	// 5. 启动 SSE 解析
-	chunks := make(chan *llm.Event, 10)
-	go c.sseParser.Parse(resp.RawBody(), chunks)
+// NewRequest 创建原始 HTTP 请求
+//
+// 返回的请求已携带客户端的 Base URL、认证头和超时配置，
+// 供 Provider 调用 Complete/Stream 之外的 API（如批处理）使用。
+func (c *BaseClient) NewRequest(ctx context.Context) *resty.Request {
+	return c.resty.R().SetContext(ctx)
+}
+
+// ProtectedHeaders 受保护的认证请求头，不可被 Options.Headers 覆盖
+var ProtectedHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key"}
 
-	return chunks, nil
+// ApplyHeaders 将单次请求头合并到请求上
+//
+// 单次请求头优先于客户端级请求头，但 [ProtectedHeaders] 中的认证头会被忽略，
+// 避免单次调用意外替换凭据。供 Provider 在 Complete/Stream 之外的请求中复用。
+func ApplyHeaders(req *resty.Request, headers map[string]string) *resty.Request {
+	for k, v := range headers {
+		if isProtectedHeader(k) {
+			continue
+		}
+		req.SetHeader(k, v)
+	}
+	return req
+}
+
+// isProtectedHeader 判断是否为受保护的认证请求头（不区分大小写）
+func isProtectedHeader(key string) bool {
+	for _, h := range ProtectedHeaders {
+		if strings.EqualFold(key, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRequestHeaders 合并 RequestBuilder 附加的单次请求头（见 [RequestHeaderBuilder]）
+//
+// 无需附加时原样返回，否则返回 Headers 已合并的选项副本，不修改调用方对象。
+func withRequestHeaders(builder RequestBuilder, opts *llm.Options) *llm.Options {
+	b, ok := builder.(RequestHeaderBuilder)
+	if !ok {
+		return opts
+	}
+	headers := b.BuildRequestHeaders(opts)
+	if len(headers) == 0 {
+		return opts
+	}
+
+	var merged llm.Options
+	if opts != nil {
+		merged = *opts
+	}
+	src := merged.Headers
+	merged.Headers = make(map[string]string, len(src)+len(headers))
+	for k, v := range src {
+		if !hasHeader(headers, k) {
+			merged.Headers[k] = v
+		}
+	}
+	maps.Copy(merged.Headers, headers)
+	return &merged
+}
+
+// hasHeader 判断请求头中是否包含指定名称（不区分大小写）
+func hasHeader(headers map[string]string, key string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRequestHeaders 将单次请求头与单次 API Key 认证头应用到请求上
+//
+// Options.Headers 中的认证头被忽略（见 [ApplyHeaders]）；Options.APIKey 非空且
+// ProviderConfig 实现 [APIKeyHeaderBuilder] 时，以其认证头覆盖客户端级认证头，
+// 仅作用于本次请求。
+func (c *BaseClient) applyRequestHeaders(req *resty.Request, opts *llm.Options) *resty.Request {
+	if opts == nil {
+		return req
+	}
+	ApplyHeaders(req, opts.Headers)
+	if opts.APIKey != "" {
+		if b, ok := c.config.(APIKeyHeaderBuilder); ok {
+			req.SetHeaders(b.BuildAPIKeyHeaders(opts.APIKey))
+		}
+	}
+	return req
+}
+
+// CheckResponse 检查 HTTP 响应状态
+//
+// 状态码 >= 400 时返回携带请求 ID、错误代码和 Provider 名称的 APIError，否则返回 nil。
+// 响应体表明配额或账单耗尽时（见 [IsQuotaExhausted]）返回包装 APIError 的
+// [llm.QuotaError]，不可重试。
+func (c *BaseClient) CheckResponse(resp *resty.Response) error {
+	if resp.StatusCode() < 400 {
+		return nil
+	}
+
+	apiErr := llm.NewAPIError(resp.StatusCode(), resp.String())
+
+	// 尝试提取请求 ID（从响应头）
+	if requestID := resp.Header().Get("X-Request-ID"); requestID != "" {
+		apiErr = apiErr.WithRequestID(requestID)
+	}
+
+	// 配额或账单耗尽：重试无效，不附带重置时间
+	code, message := ParseErrorBody(resp.Body())
+	if code != "" {
+		apiErr = apiErr.WithErrorCode(code)
+	}
+	if IsQuotaExhausted(code, message) {
+		return llm.NewQuotaError(apiErr.WithProvider(c.config.ProviderName()))
+	}
+
+	// 可重试错误（429、5xx）附带限流重置时间（Retry-After 等响应头）
+	if apiErr.IsRetryable() {
+		if d := ParseRetryAfter(resp.Header(), time.Now()); d > 0 {
+			apiErr = apiErr.WithRetryAfter(d)
+		}
+	}
+
+	// 设置 Provider 类型
+	return apiErr.WithProvider(c.config.ProviderName())
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -304,7 +758,13 @@ func (c *BaseClient) Stream(
 // ═══════════════════════════════════════════════════════════════════════════
 
 // getCompleteEndpoint 获取 Complete 端点
-func (c *BaseClient) getCompleteEndpoint() string {
+func (c *BaseClient) getCompleteEndpoint(opts *llm.Options) string {
+	if b, ok := c.endpointBuilder.(APIKeyEndpointBuilder); ok && opts != nil && opts.APIKey != "" {
+		return b.BuildAPIKeyEndpoint(opts.Model, opts.APIKey, false)
+	}
+	if b, ok := c.endpointBuilder.(ModelEndpointBuilder); ok && opts != nil && opts.Model != "" {
+		return b.BuildModelEndpoint(opts.Model, false)
+	}
 	if c.endpointBuilder != nil {
 		return c.endpointBuilder.BuildCompleteEndpoint()
 	}
@@ -312,13 +772,55 @@ func (c *BaseClient) getCompleteEndpoint() string {
 }
 
 // getStreamEndpoint 获取 Stream 端点
-func (c *BaseClient) getStreamEndpoint() string {
+func (c *BaseClient) getStreamEndpoint(opts *llm.Options) string {
+	if b, ok := c.endpointBuilder.(APIKeyEndpointBuilder); ok && opts != nil && opts.APIKey != "" {
+		return b.BuildAPIKeyEndpoint(opts.Model, opts.APIKey, true)
+	}
+	if b, ok := c.endpointBuilder.(ModelEndpointBuilder); ok && opts != nil && opts.Model != "" {
+		return b.BuildModelEndpoint(opts.Model, true)
+	}
 	if c.endpointBuilder != nil {
 		return c.endpointBuilder.BuildStreamEndpoint()
 	}
 	return "/chat/completions" // 默认端点
 }
 
+// maxBodySnippet 错误信息中保留的响应体最大字节数
+const maxBodySnippet = 512
+
+// bodySnippet 截取响应体片段用于错误信息（保证 UTF-8 边界完整）
+func bodySnippet(body []byte) string {
+	if len(body) <= maxBodySnippet {
+		return string(body)
+	}
+	cut := maxBodySnippet
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return string(body[:cut]) + "..."
+}
+
+// checkCapabilities 按模型能力检查选项（工具不支持时报错或降级，并填充默认 MaxTokens）
+func (c *BaseClient) checkCapabilities(messages []llm.Message, opts *llm.Options) (*llm.Options, error) {
+	if opts != nil {
+		if err := ValidateTools(opts.Tools); err != nil {
+			return nil, err
+		}
+	}
+	if err := ValidateImages(messages); err != nil {
+		return nil, err
+	}
+	_, model, _ := c.config.GetDefaults()
+	if opts != nil && opts.Model != "" {
+		model = opts.Model
+	}
+	opts, err := llm.CheckCapabilities(model, messages, opts)
+	if err != nil || c.noDefaultMaxTokens {
+		return opts, err
+	}
+	return applyMaxTokens(c.modelInfo(model), opts), nil
+}
+
 // getModelFromConfig 从配置获取模型名称
 func (c *BaseClient) getModelFromConfig() string {
 	// 通过类型断言获取具体配置的模型字段
@@ -332,14 +834,20 @@ func (c *BaseClient) getModelFromConfig() string {
 // 辅助函数
 // ═══════════════════════════════════════════════════════════════════════════
 
+// FallbackTimeout 未设置超时且无全局默认值时使用的超时时间
+const FallbackTimeout = 120 * time.Second
+
 // GetDefaultTimeout 获取默认超时时间的辅助函数
 //
-// 如果 timeout 为 0，返回默认的 120 秒。
+// 如果 timeout 为 0，返回全局默认值（llm.SetDefaultTimeout），未设置时返回 FallbackTimeout。
 func GetDefaultTimeout(timeout time.Duration) time.Duration {
-	if timeout == 0 {
-		return 120 * time.Second
+	if timeout != 0 {
+		return timeout
+	}
+	if d := llm.DefaultTimeout(); d > 0 {
+		return d
 	}
-	return timeout
+	return FallbackTimeout
 }
 
 // NewInvalidConfigError 创建无效配置错误
@@ -1,9 +1,12 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -25,6 +28,8 @@ type mockConfig struct {
 	baseURL      string
 	model        string
 	providerName string
+	headers      map[string]string
+	insecure     bool
 }
 
 func (m *mockConfig) Validate() error {
@@ -48,10 +53,16 @@ func (m *mockConfig) GetDefaults() (string, string, time.Duration) {
 }
 
 func (m *mockConfig) BuildHeaders() map[string]string {
-	return map[string]string{
+	headers := map[string]string{
 		"Authorization": "Bearer " + m.apiKey,
 		"Content-Type":  "application/json",
 	}
+	maps.Copy(headers, m.headers)
+	return headers
+}
+
+func (m *mockConfig) SkipTLSVerify() bool {
+	return m.insecure
 }
 
 func (m *mockConfig) ProviderName() string {
@@ -97,11 +108,11 @@ func (m *mockAdapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 	return result
 }
 
-func (m *mockAdapter) ConvertFromAPI(apiResp map[string]any) (llm.Message, string) {
+func (m *mockAdapter) ConvertFromAPI(apiResp map[string]any) (llm.Message, string, ResponseExtras) {
 	return llm.Message{
 		Role:    llm.RoleAssistant,
 		Content: "Test response",
-	}, "stop"
+	}, "stop", ResponseExtras{}
 }
 
 func (m *mockAdapter) ConvertUsage(apiResp map[string]any) *llm.TokenUsage {
@@ -275,6 +286,157 @@ func TestBaseClient_Complete(t *testing.T) {
 		assert.Nil(t, resp)
 		assert.True(t, llm.IsHTTPError(err))
 	})
+
+	t.Run("200 但响应体非 JSON", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html><body>Gateway login</body></html>"))
+		}))
+		defer server.Close()
+
+		config := &mockConfig{apiKey: "test-key", baseURL: server.URL}
+		client, err := NewBaseClient(config, &mockAdapter{}, &mockEventHandler{})
+		require.NoError(t, err)
+
+		messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+		resp, err := client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+
+		require.Error(t, err)
+		assert.Nil(t, resp)
+		assert.True(t, llm.IsResponseError(err))
+
+		var respErr *llm.ResponseError
+		require.ErrorAs(t, err, &respErr)
+		assert.Contains(t, respErr.Body, "Gateway login")
+		assert.Contains(t, err.Error(), "Gateway login")
+	})
+}
+
+func TestBaseClient_Complete_ValidateResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "test-model"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+
+	// mockAdapter 返回非 JSON 文本
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}, &llm.Options{
+		ResponseFormat:   &llm.ResponseFormat{Type: "json_schema", Schema: map[string]any{"type": "object"}},
+		ValidateResponse: true,
+	}, &mockRequestBuilder{})
+	require.ErrorIs(t, err, llm.ErrSchemaViolation)
+	require.NotNil(t, resp)
+	assert.Equal(t, "Test response", resp.Message.Content)
+}
+
+func TestBaseClient_Complete_ReturnPromptTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "test-model"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello, world"}}
+
+	// 未开启时不填充
+	resp, err := client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+	require.NoError(t, err)
+	assert.Nil(t, resp.PromptTokensDetail)
+
+	// mockAdapter 不返回分词结果，回退为本地估算
+	resp, err = client.Complete(context.Background(), messages, &llm.Options{ReturnPromptTokens: true}, &mockRequestBuilder{})
+	require.NoError(t, err)
+	require.NotNil(t, resp.PromptTokensDetail)
+	assert.True(t, resp.PromptTokensDetail.Estimated)
+	assert.Equal(t, EstimateTokens("Hello, world"), resp.PromptTokensDetail.Total)
+	assert.Equal(t, []llm.PromptTokenSegment{{Kind: "message", Tokens: 3, End: 3}}, resp.PromptTokensDetail.Segments)
+}
+
+func TestBaseClient_CheckCapabilities(t *testing.T) {
+	tools := []llm.ToolSchema{{Name: "search", Description: "Search the web", InputSchema: map[string]any{"type": "object"}}}
+	config := &mockConfig{apiKey: "test-key", baseURL: "http://invalid-host-12345:9999", model: "o1-mini"}
+	client, err := NewBaseClient(config, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	t.Run("严格模式返回 RequestError", func(t *testing.T) {
+		opts := &llm.Options{Tools: tools, StrictCapabilities: true}
+
+		_, err := client.Complete(context.Background(), messages, opts, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.True(t, llm.IsRequestError(err))
+		assert.Contains(t, err.Error(), "o1-mini")
+
+		_, err = client.Stream(context.Background(), messages, opts, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.True(t, llm.IsRequestError(err))
+	})
+
+	t.Run("降级模式将工具嵌入系统提示", func(t *testing.T) {
+		builder := &capturingRequestBuilder{}
+		opts := &llm.Options{Tools: tools, ToolFallback: true}
+
+		// 请求本身会因网络失败，只验证传给 RequestBuilder 的选项
+		_, _ = client.Complete(context.Background(), messages, opts, builder)
+
+		require.NotNil(t, builder.opts)
+		assert.Empty(t, builder.opts.Tools)
+		assert.Contains(t, builder.opts.System, "search: Search the web")
+		assert.Equal(t, &llm.ResponseFormat{Type: "json_object"}, builder.opts.ResponseFormat)
+	})
+
+	t.Run("同时设置降级与 JSON 模式时仍降级", func(t *testing.T) {
+		builder := &capturingRequestBuilder{}
+		opts := &llm.Options{Tools: tools, ToolFallback: true, ToolFallbackMode: llm.ToolFallbackModeJSON, StrictCapabilities: true}
+
+		_, err := client.Complete(context.Background(), messages, opts, builder)
+		assert.False(t, llm.IsRequestError(err))
+
+		require.NotNil(t, builder.opts)
+		assert.Empty(t, builder.opts.Tools)
+		assert.Contains(t, builder.opts.System, "search: Search the web")
+	})
+}
+
+// capturingRequestBuilder 记录收到的消息与选项
+type capturingRequestBuilder struct {
+	messages []llm.Message
+	opts     *llm.Options
+}
+
+func (b *capturingRequestBuilder) BuildRequest(messages []llm.Message, opts *llm.Options, stream bool) (map[string]any, error) {
+	b.messages = messages
+	b.opts = opts
+	return map[string]any{"stream": stream}, nil
+}
+
+func TestBaseClient_InsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": []}`))
+	}))
+	defer server.Close()
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	// 默认校验证书：自签名证书请求失败
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+	_, err = client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+	require.Error(t, err)
+	assert.True(t, llm.IsHTTPError(err))
+
+	// 显式跳过校验：请求成功
+	client, err = NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL, insecure: true}, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+	_, err = client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+	require.NoError(t, err)
 }
 
 func TestBaseClient_Stream(t *testing.T) {
@@ -319,6 +481,44 @@ func TestBaseClient_Stream(t *testing.T) {
 		assert.Positive(t, eventCount)
 	})
 
+	t.Run("Accept 请求头", func(t *testing.T) {
+		var accept []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept = append(accept, r.Header.Get("Accept"))
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+		}))
+		defer server.Close()
+
+		stream := func(client *BaseClient) {
+			events, err := client.Stream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}, nil, &mockRequestBuilder{})
+			require.NoError(t, err)
+			for range events {
+			}
+		}
+
+		// 默认
+		client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{})
+		require.NoError(t, err)
+		stream(client)
+
+		// Provider 覆盖
+		client.SetStreamHeader("Accept", "application/x-ndjson")
+		stream(client)
+
+		// 配置请求头优先
+		client, err = NewBaseClient(&mockConfig{
+			apiKey:  "test-key",
+			baseURL: server.URL,
+			headers: map[string]string{"Accept": "*/*"},
+		}, &mockAdapter{}, &mockEventHandler{})
+		require.NoError(t, err)
+		client.SetStreamHeader("Accept", "application/x-ndjson")
+		stream(client)
+
+		assert.Equal(t, []string{"text/event-stream", "application/x-ndjson", "*/*"}, accept)
+	})
+
 	t.Run("Stream 返回错误", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusTooManyRequests)
@@ -350,6 +550,57 @@ func TestBaseClient_Stream(t *testing.T) {
 	})
 }
 
+func TestBaseClient_RequestHeaders(t *testing.T) {
+	var got []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "test-model"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewBaseClient(&mockConfig{
+		apiKey:  "test-key",
+		baseURL: server.URL,
+		headers: map[string]string{"X-Env": "prod"},
+	}, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+	opts := &llm.Options{Headers: map[string]string{
+		"X-Trace-Id":    "trace-1",
+		"X-Env":         "staging",
+		"authorization": "Bearer other-key",
+	}}
+
+	_, err = client.Complete(context.Background(), messages, opts, &mockRequestBuilder{})
+	require.NoError(t, err)
+	events, err := client.Stream(context.Background(), messages, opts, &mockRequestBuilder{})
+	require.NoError(t, err)
+	for range events {
+	}
+
+	// 未设置单次请求头
+	_, err = client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+	require.NoError(t, err)
+
+	require.Len(t, got, 3)
+	for _, h := range got[:2] {
+		assert.Equal(t, "trace-1", h.Get("X-Trace-Id"))
+		assert.Equal(t, "staging", h.Get("X-Env"))
+		assert.Equal(t, "Bearer test-key", h.Get("Authorization"))
+	}
+	assert.Empty(t, got[2].Get("X-Trace-Id"))
+	assert.Equal(t, "prod", got[2].Get("X-Env"))
+}
+
 func TestBaseClient_EndpointBuilder(t *testing.T) {
 	t.Run("使用自定义端点构建器", func(t *testing.T) {
 		mockBuilder := &mockEndpointBuilder{
@@ -363,8 +614,8 @@ func TestBaseClient_EndpointBuilder(t *testing.T) {
 
 		client.SetEndpointBuilder(mockBuilder)
 
-		assert.Equal(t, "/v1/chat", client.getCompleteEndpoint())
-		assert.Equal(t, "/v1/chat/stream", client.getStreamEndpoint())
+		assert.Equal(t, "/v1/chat", client.getCompleteEndpoint(nil))
+		assert.Equal(t, "/v1/chat/stream", client.getStreamEndpoint(nil))
 	})
 
 	t.Run("使用默认端点", func(t *testing.T) {
@@ -372,8 +623,8 @@ func TestBaseClient_EndpointBuilder(t *testing.T) {
 		client, err := NewBaseClient(config, &mockAdapter{}, &mockEventHandler{})
 		require.NoError(t, err)
 
-		assert.Equal(t, "/chat/completions", client.getCompleteEndpoint())
-		assert.Equal(t, "/chat/completions", client.getStreamEndpoint())
+		assert.Equal(t, "/chat/completions", client.getCompleteEndpoint(nil))
+		assert.Equal(t, "/chat/completions", client.getStreamEndpoint(nil))
 	})
 }
 
@@ -408,6 +659,37 @@ func TestGetDefaultTimeout(t *testing.T) {
 		timeout := GetDefaultTimeout(30 * time.Second)
 		assert.Equal(t, 30*time.Second, timeout)
 	})
+
+	t.Run("全局默认值", func(t *testing.T) {
+		llm.SetDefaultTimeout(45 * time.Second)
+		t.Cleanup(func() { llm.SetDefaultTimeout(0) })
+
+		assert.Equal(t, 45*time.Second, GetDefaultTimeout(0))
+		assert.Equal(t, 30*time.Second, GetDefaultTimeout(30*time.Second))
+	})
+}
+
+func TestValidateTimeout(t *testing.T) {
+	require.NoError(t, ValidateTimeout(0))
+	require.NoError(t, ValidateTimeout(time.Second))
+
+	err := ValidateTimeout(-time.Second)
+	assert.True(t, llm.IsConfigError(err))
+	assert.Contains(t, err.Error(), "invalid timeout")
+}
+
+func TestWarnUnknownModel(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	WarnUnknownModel("openai", "gpt-4o-mini")
+	assert.Empty(t, buf.String())
+
+	WarnUnknownModel("openai", "gtp-4o")
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), "model=gtp-4o")
 }
 
 func TestNewInvalidConfigError(t *testing.T) {
@@ -423,3 +705,23 @@ func TestNewMissingAPIKeyError(t *testing.T) {
 	assert.True(t, llm.IsConfigError(err))
 	assert.Contains(t, err.Error(), "API key")
 }
+
+func TestBaseClient_ToolResultLimit(t *testing.T) {
+	config := &mockConfig{apiKey: "test-key", baseURL: "http://invalid-host-12345:9999"}
+	client, err := NewBaseClient(config, &mockAdapter{}, &mockEventHandler{}, WithToolResultLimit(ToolResultLimit{MaxChars: 5}))
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+		&llm.ToolResultBlock{ToolUseID: "call_1", Content: "0123456789"},
+	}}}
+
+	for _, send := range []func(builder RequestBuilder){
+		func(builder RequestBuilder) { _, _ = client.Complete(context.Background(), messages, nil, builder) },
+		func(builder RequestBuilder) { _, _ = client.Stream(context.Background(), messages, nil, builder) },
+	} {
+		builder := &capturingRequestBuilder{}
+		send(builder)
+		require.Len(t, builder.messages, 1)
+		assert.Equal(t, "01234\n[truncated 5 chars]", builder.messages[0].GetToolResults()[0].Content)
+	}
+}
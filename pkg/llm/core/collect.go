@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 流式结果聚合
+// ═══════════════════════════════════════════════════════════════════════════
+
+// StreamStats 流式性能统计
+type StreamStats struct {
+	TimeToFirstToken time.Duration // 首个文本/推理事件到达耗时（无内容时为 0）
+	TotalDuration    time.Duration // 流开始到结束的总耗时
+	OutputTokens     int64         // 输出 token 数（按 EstimateTokens 估算文本与推理内容）
+}
+
+// TokensPerSecond 输出吞吐量（tokens/s）
+//
+// 按首 token 之后的生成时间计算，排除排队与首包延迟；
+// 首 token 后无耗时（如单事件流）时退化为按总耗时计算。
+func (s StreamStats) TokensPerSecond() float64 {
+	d := s.TotalDuration - s.TimeToFirstToken
+	if d <= 0 {
+		d = s.TotalDuration
+	}
+	if d <= 0 {
+		return 0
+	}
+	return float64(s.OutputTokens) / d.Seconds()
+}
+
+// StreamResult 流式聚合结果
+type StreamResult struct {
+	Response *llm.Response // 聚合后的响应（出错时为已收到的部分）
+	Stats    StreamStats   // 性能统计
+}
+
+// CollectStream 消费事件流并聚合为完整响应
+//
+// 聚合规则：
+//   - 文本增量拼接为 Message.Content，携带的 token 对数概率依次追加到 Response.Logprobs
+//   - 推理/思考增量合并为开头的 ThinkingBlock（同时填充 Response.Reasoning 与签名），
+//     加密的思考内容依次保留为其后的 RedactedThinkingBlock
+//   - 工具调用增量按 Index 合并为 ToolCall
+//   - 完成事件提供 FinishReason，携带用量（见 [llm.Event.Usage]）时同时填充 Response.Usage
+//
+// 流在完成事件之前结束（如 ctx 取消）时，按 EstimateTokens 估算已生成内容的输出
+// token 数填充 Response.Usage（Estimated 为 true），便于对部分生成计费。
+//
+// 计时从调用时开始；需要包含建连耗时请使用 [StreamAsComplete]。
+// 收到错误事件时继续消费至流结束，返回部分结果与 [llm.StreamError]。
+func CollectStream(events <-chan *llm.Event) (*StreamResult, error) {
+	return collectStream(time.Now(), events)
+}
+
+// StreamAsComplete 发起流式请求并聚合为完整响应
+//
+// 等价于 p.Stream + CollectStream，计时从发起请求前开始，
+// TimeToFirstToken 因此包含建连与首包延迟。流提前结束时估算用量同时包含
+// 输入 token（按 EstimatePromptTokens 估算）。
+//
+// 设置 Options.ValidateResponse 时按 [ValidateResponse] 校验聚合后的文本，
+// 不符合 Schema 时返回完整的 StreamResult 与 [llm.ResponseError]（流错误优先）。
+func StreamAsComplete(ctx context.Context, p llm.Provider, messages []llm.Message, opts *llm.Options) (*StreamResult, error) {
+	start := time.Now()
+	events, err := p.Stream(ctx, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := collectStream(start, events)
+	if usage := result.Response.Usage; usage != nil && usage.Estimated {
+		usage.InputTokens = EstimatePromptTokens(messages, opts).Total
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	if err != nil {
+		return result, err
+	}
+	return result, ValidateResponse(result.Response, opts)
+}
+
+// toolCallBuilder 工具调用增量累积
+type toolCallBuilder struct {
+	id   string
+	name string
+	args strings.Builder
+}
+
+// collectStream 聚合事件流，start 为计时起点
+func collectStream(start time.Time, events <-chan *llm.Event) (*StreamResult, error) {
+	var (
+		text      strings.Builder
+		reasoning strings.Builder
+		signature string
+		redacted  []llm.ContentBlock
+		calls     = make(map[int]*toolCallBuilder)
+		stats     StreamStats
+		streamErr error
+		done      bool
+	)
+	resp := &llm.Response{Message: llm.Message{Role: llm.RoleAssistant}}
+
+	markFirst := func() {
+		if stats.TimeToFirstToken == 0 {
+			stats.TimeToFirstToken = time.Since(start)
+		}
+	}
+
+	for event := range events {
+		switch event.Type {
+		case llm.EventTypeText:
+			markFirst()
+			text.WriteString(event.TextDelta)
+			resp.Logprobs = append(resp.Logprobs, event.Logprobs...)
+		case llm.EventTypeReasoning, llm.EventTypeThinking:
+			if event.Reasoning != nil {
+				markFirst()
+				reasoning.WriteString(event.Reasoning.ThoughtDelta)
+				signature += event.Reasoning.Signature
+				if event.Reasoning.RedactedData != "" {
+					redacted = append(redacted, &llm.RedactedThinkingBlock{Data: event.Reasoning.RedactedData})
+				}
+			}
+		case llm.EventTypeToolCall:
+			if tc := event.ToolCall; tc != nil {
+				b, ok := calls[tc.Index]
+				if !ok {
+					b = &toolCallBuilder{}
+					calls[tc.Index] = b
+				}
+				if tc.ID != "" {
+					b.id = tc.ID
+				}
+				if tc.Name != "" {
+					b.name = tc.Name
+				}
+				b.args.WriteString(tc.ArgumentsDelta)
+			}
+		case llm.EventTypeDone:
+			done = true
+			resp.FinishReason = event.FinishReason
+			if event.Usage != nil {
+				resp.Usage = event.Usage
+			}
+		case llm.EventTypeError:
+			if streamErr == nil {
+				streamErr = llm.NewStreamError(event.ErrorMessage, event.Error)
+			}
+		default:
+			// 忽略其他事件类型
+		}
+	}
+	stats.TotalDuration = time.Since(start)
+
+	// 组装消息
+	resp.Message.Content = text.String()
+	resp.Reasoning = reasoning.String()
+
+	var blocks []llm.ContentBlock
+	if resp.Reasoning != "" {
+		blocks = append(blocks, &llm.ThinkingBlock{Thinking: resp.Reasoning, Signature: signature})
+	}
+	blocks = append(blocks, redacted...)
+	if len(calls) > 0 {
+		if resp.Message.Content != "" {
+			blocks = append(blocks, &llm.TextBlock{Text: resp.Message.Content})
+		}
+		blocks = append(blocks, buildToolCalls(calls)...)
+	} else if len(blocks) > 0 && resp.Message.Content != "" {
+		blocks = append(blocks, &llm.TextBlock{Text: resp.Message.Content})
+	}
+	resp.Message.ContentBlocks = blocks
+
+	stats.OutputTokens = EstimateTokens(resp.Message.Content) + EstimateTokens(resp.Reasoning)
+
+	// 未完成的流收不到用量，按已生成内容估算
+	if !done && resp.Usage == nil {
+		output := stats.OutputTokens
+		for _, call := range resp.Message.GetToolCalls() {
+			output += EstimateBlockTokens(call)
+		}
+		resp.Usage = &llm.TokenUsage{OutputTokens: output, TotalTokens: output, Estimated: true}
+	}
+
+	return &StreamResult{Response: resp, Stats: stats}, streamErr
+}
+
+// buildToolCalls 按 Index 顺序组装工具调用
+func buildToolCalls(calls map[int]*toolCallBuilder) []llm.ContentBlock {
+	indexes := make([]int, 0, len(calls))
+	for i := range calls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	blocks := make([]llm.ContentBlock, 0, len(calls))
+	for _, i := range indexes {
+		b := calls[i]
+		blocks = append(blocks, &llm.ToolCall{ID: b.id, Name: b.name, Input: GetToolInput(b.args.String())})
+	}
+	return blocks
+}
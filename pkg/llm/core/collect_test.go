@@ -0,0 +1,221 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/mock"
+)
+
+func TestStreamAsComplete_Stats(t *testing.T) {
+	p := mock.New(mock.WithResponse("Hello, world!"), mock.WithDelay(30*time.Millisecond))
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+
+	result, err := core.StreamAsComplete(context.Background(), p, messages, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello, world!", result.Response.Message.Content)
+	assert.Equal(t, "stop", result.Response.FinishReason)
+
+	stats := result.Stats
+	assert.GreaterOrEqual(t, stats.TimeToFirstToken, 30*time.Millisecond)
+	assert.GreaterOrEqual(t, stats.TotalDuration, stats.TimeToFirstToken)
+	assert.Equal(t, core.EstimateTokens("Hello, world!"), stats.OutputTokens)
+	assert.Positive(t, stats.TokensPerSecond())
+}
+
+func TestCollectStream_Aggregate(t *testing.T) {
+	events := make(chan *llm.Event, 10)
+	events <- &llm.Event{Type: llm.EventTypeReasoning, Reasoning: &llm.ReasoningDelta{ThoughtDelta: "think"}}
+	events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "Checking"}
+	events <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 1, ID: "call_2", Name: "b"}}
+	events <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ID: "call_1", Name: "a"}}
+	events <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ArgumentsDelta: `{"x":`}}
+	events <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ArgumentsDelta: `1}`}}
+	events <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "tool_calls"}
+	close(events)
+
+	result, err := core.CollectStream(events)
+	require.NoError(t, err)
+
+	resp := result.Response
+	assert.Equal(t, "Checking", resp.Message.Content)
+	assert.Equal(t, "think", resp.Reasoning)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	require.Len(t, resp.Message.ContentBlocks, 4)
+
+	calls := resp.Message.GetToolCalls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_1", calls[0].ID)
+	assert.Equal(t, map[string]any{"x": float64(1)}, calls[0].Input)
+	assert.Equal(t, "call_2", calls[1].ID)
+	assert.Empty(t, calls[1].Input)
+}
+
+func TestCollectStream_ThinkingSignature(t *testing.T) {
+	events := make(chan *llm.Event, 4)
+	events <- &llm.Event{Type: llm.EventTypeReasoning, Reasoning: &llm.ReasoningDelta{ThoughtDelta: "think"}}
+	events <- &llm.Event{Type: llm.EventTypeReasoning, Reasoning: &llm.ReasoningDelta{Signature: "sig_abc"}}
+	events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "42"}
+	close(events)
+
+	result, err := core.CollectStream(events)
+	require.NoError(t, err)
+
+	thinking, ok := result.Response.Message.ContentBlocks[0].(*llm.ThinkingBlock)
+	require.True(t, ok)
+	assert.Equal(t, "think", thinking.Thinking)
+	assert.Equal(t, "sig_abc", thinking.Signature)
+}
+
+func TestCollectStream_RedactedThinking(t *testing.T) {
+	events := make(chan *llm.Event, 4)
+	events <- &llm.Event{Type: llm.EventTypeReasoning, Reasoning: &llm.ReasoningDelta{RedactedData: "enc_1"}}
+	events <- &llm.Event{Type: llm.EventTypeReasoning, Reasoning: &llm.ReasoningDelta{RedactedData: "enc_2"}}
+	events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "42"}
+	close(events)
+
+	result, err := core.CollectStream(events)
+	require.NoError(t, err)
+
+	assert.Equal(t, []llm.ContentBlock{
+		&llm.RedactedThinkingBlock{Data: "enc_1"},
+		&llm.RedactedThinkingBlock{Data: "enc_2"},
+		&llm.TextBlock{Text: "42"},
+	}, result.Response.Message.ContentBlocks)
+}
+
+func TestCollectStream_Error(t *testing.T) {
+	events := make(chan *llm.Event, 2)
+	events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "partial"}
+	events <- &llm.Event{Type: llm.EventTypeError, ErrorMessage: "overloaded"}
+	close(events)
+
+	result, err := core.CollectStream(events)
+
+	require.Error(t, err)
+	assert.True(t, llm.IsStreamError(err))
+	assert.Equal(t, "partial", result.Response.Message.Content)
+}
+
+func TestCollectStream_CancelledUsage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 模拟 Provider：ctx 取消后直接关闭流，不发送完成事件
+	events := make(chan *llm.Event)
+	go func() {
+		defer close(events)
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "word "}:
+				if i == 2 {
+					cancel()
+				}
+			}
+		}
+	}()
+
+	result, err := core.CollectStream(events)
+	require.NoError(t, err)
+
+	content := result.Response.Message.Content
+	require.NotEmpty(t, content)
+	usage := result.Response.Usage
+	require.NotNil(t, usage)
+	assert.True(t, usage.Estimated)
+	assert.Equal(t, core.EstimateTokens(content), usage.OutputTokens)
+	assert.Equal(t, usage.OutputTokens, usage.TotalTokens)
+
+	// 正常完成的流不估算
+	done := make(chan *llm.Event, 2)
+	done <- &llm.Event{Type: llm.EventTypeText, TextDelta: "ok"}
+	done <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "stop"}
+	close(done)
+	result, err = core.CollectStream(done)
+	require.NoError(t, err)
+	assert.Nil(t, result.Response.Usage)
+}
+
+func TestCollectStream_DoneUsage(t *testing.T) {
+	usage := &llm.TokenUsage{InputTokens: 10, OutputTokens: 2, TotalTokens: 12}
+	events := make(chan *llm.Event, 3)
+	events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "ok"}
+	events <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "stop", Usage: usage}
+	events <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "stop"} // 不携带用量的重复完成事件不覆盖
+	close(events)
+
+	result, err := core.CollectStream(events)
+	require.NoError(t, err)
+	assert.Equal(t, usage, result.Response.Usage)
+}
+
+func TestStreamAsComplete_CancelledUsage(t *testing.T) {
+	p := mock.New(mock.WithResponse("Hello, world!"), mock.WithDelay(time.Second))
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Tell me a long story"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	result, err := core.StreamAsComplete(ctx, p, messages, nil)
+	require.NoError(t, err)
+
+	usage := result.Response.Usage
+	require.NotNil(t, usage)
+	assert.True(t, usage.Estimated)
+	assert.Equal(t, core.EstimateTokens("Tell me a long story"), usage.InputTokens)
+	assert.Zero(t, usage.OutputTokens)
+	assert.Equal(t, usage.InputTokens, usage.TotalTokens)
+}
+
+func TestStreamStats_TokensPerSecond(t *testing.T) {
+	stats := core.StreamStats{
+		TimeToFirstToken: 500 * time.Millisecond,
+		TotalDuration:    2500 * time.Millisecond,
+		OutputTokens:     100,
+	}
+	assert.InDelta(t, 50.0, stats.TokensPerSecond(), 0.001)
+
+	assert.Zero(t, core.StreamStats{}.TokensPerSecond())
+}
+
+func TestStreamAsComplete_ValidateResponse(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"age": map[string]any{"type": "integer"}},
+		"required":   []any{"name", "age"},
+	}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	opts := &llm.Options{
+		ResponseFormat:   &llm.ResponseFormat{Type: "json_schema", Name: "person", Schema: schema},
+		ValidateResponse: true,
+	}
+
+	// 不符合 Schema：返回完整结果与 ResponseError
+	p := mock.New(mock.WithResponse(`{"name": "Ann", "age": "ten"}`))
+	result, err := core.StreamAsComplete(context.Background(), p, messages, opts)
+	require.Error(t, err)
+	require.ErrorIs(t, err, llm.ErrSchemaViolation)
+	assert.True(t, llm.IsResponseError(err))
+	assert.Contains(t, err.Error(), "content")
+	require.NotNil(t, result)
+	assert.JSONEq(t, `{"name": "Ann", "age": "ten"}`, result.Response.Message.Content)
+
+	// 符合 Schema
+	p = mock.New(mock.WithResponse(`{"name": "Ann", "age": 10}`))
+	_, err = core.StreamAsComplete(context.Background(), p, messages, opts)
+	require.NoError(t, err)
+
+	// 未开启校验
+	p = mock.New(mock.WithResponse(`not json`))
+	_, err = core.StreamAsComplete(context.Background(), p, messages, &llm.Options{ResponseFormat: opts.ResponseFormat})
+	require.NoError(t, err)
+}
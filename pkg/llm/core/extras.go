@@ -0,0 +1,40 @@
+package core
+
+import "github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 响应附加数据
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ResponseExtras 协议适配器解析出的 Message 之外的 Response 级数据
+//
+// 由 [ProtocolAdapter.ConvertFromAPI] 与 [Transformer.ParseAPIResponse] 随消息一同返回，
+// 不经过 Message.Meta（该字段归应用层所有，库不读写）。
+type ResponseExtras struct {
+	Citations []llm.Citation     // 引用来源，写入 Response.Citations
+	Logprobs  []llm.TokenLogprob // 输出 token 的对数概率，写入 Response.Logprobs
+	Metadata  map[string]any     // 协议特有的响应标记（如 Gemini 思考耗尽、Responses API 响应 ID），写入 Response.Metadata
+}
+
+// SetMetadata 记录协议特有的响应标记
+func (e *ResponseExtras) SetMetadata(key string, value any) {
+	if e.Metadata == nil {
+		e.Metadata = map[string]any{}
+	}
+	e.Metadata[key] = value
+}
+
+// Apply 将附加数据写入响应
+//
+// [BaseClient.Complete] 与不经过 Complete 的响应解析（如 Anthropic 批处理结果）共用。
+// Metadata 合并到 resp.Metadata，已有键被覆盖。
+func (e ResponseExtras) Apply(resp *llm.Response) {
+	resp.Citations = e.Citations
+	resp.Logprobs = e.Logprobs
+	for key, value := range e.Metadata {
+		if resp.Metadata == nil {
+			resp.Metadata = map[string]any{}
+		}
+		resp.Metadata[key] = value
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"maps"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 完成原因覆盖
+// ═══════════════════════════════════════════════════════════════════════════
+
+// WithFinishReasonOverrides 设置完成原因的覆盖映射
+//
+// 在适配器的标准映射之后应用，用于归一化网关返回的非标准完成原因，
+// 如 {"complete": "stop", "eos": "stop"}。Complete 的响应与 Stream 的 Done 事件
+// 均生效；未命中映射的完成原因保持不变。
+//
+// 示例：
+//
+//	client, _ := openai.New(config, core.WithFinishReasonOverrides(map[string]string{"complete": "stop"}))
+func WithFinishReasonOverrides(overrides map[string]string) ClientOption {
+	return func(c *BaseClient) {
+		c.transformer.SetFinishReasonOverrides(overrides)
+	}
+}
+
+// overrideFinishReasons 按覆盖映射改写流中 Done 事件的完成原因
+//
+// 返回新的 channel，其余事件原样透传；输入 channel 关闭后输出随之关闭。
+func overrideFinishReasons(in <-chan *llm.Event, t *Transformer) <-chan *llm.Event {
+	out := make(chan *llm.Event, 10)
+	go func() {
+		defer close(out)
+		for event := range in {
+			if event != nil && event.Type == llm.EventTypeDone {
+				event.FinishReason = t.MapFinishReason(event.FinishReason)
+			}
+			out <- event
+		}
+	}()
+	return out
+}
+
+// SetFinishReasonOverrides 设置完成原因的覆盖映射（见 [WithFinishReasonOverrides]）
+//
+// 保存映射的副本，传入 nil 或空映射时清除覆盖。
+func (t *Transformer) SetFinishReasonOverrides(overrides map[string]string) {
+	if len(overrides) == 0 {
+		t.finishReasonOverrides = nil
+		return
+	}
+	t.finishReasonOverrides = maps.Clone(overrides)
+}
+
+// MapFinishReason 对适配器标准化后的完成原因应用覆盖映射
+func (t *Transformer) MapFinishReason(reason string) string {
+	if mapped, ok := t.finishReasonOverrides[reason]; ok {
+		return mapped
+	}
+	return reason
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestOverrideFinishReasons(t *testing.T) {
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key"}, &mockAdapter{}, &mockEventHandler{},
+		WithFinishReasonOverrides(map[string]string{"eos": "stop"}))
+	require.NoError(t, err)
+
+	in := make(chan *llm.Event, 3)
+	in <- &llm.Event{Type: llm.EventTypeText, TextDelta: "Hi"}
+	in <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "eos"}
+	close(in)
+
+	var events []*llm.Event
+	for e := range overrideFinishReasons(in, client.transformer) {
+		events = append(events, e)
+	}
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "Hi", events[0].TextDelta)
+	assert.Equal(t, "stop", events[1].FinishReason)
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 请求哈希
+// ═══════════════════════════════════════════════════════════════════════════
+
+// RequestHash 计算请求的稳定哈希（SHA-256 十六进制），用于缓存键与请求去重
+//
+// 参与哈希的内容：
+//   - 模型：opts.Model 非空时优先（与 Provider 的单次覆盖一致），否则为 model
+//   - 消息：Role、Content、ToolCallID 与全部内容块（含块类型）
+//   - 选项：影响模型输出的全部字段（System、Temperature 等采样参数、Tools、
+//     ToolChoice、ResponseFormat、推理参数、缓存引用等）
+//
+// 不参与哈希的内容（不影响模型输出）：
+//   - Message.Meta 与 Message.CacheBreakpoint
+//   - Options.Headers（幂等键、追踪 ID 等）、Options.Metadata（关联 ID 等）、
+//     Options.EndUserID 与 Options.ReturnPromptTokens
+//
+// Options.APIKey 以不可逆指纹（见 llm.APIKeyFingerprint）参与哈希：不同租户的
+// 单次 API Key 互不命中，凭据本身不进入哈希输入。
+//
+// 序列化时 map 键按字典序排列，工具 Schema 与工具参数的键顺序不影响结果。
+// 内容块无法序列化时（如自定义块包含 channel）退化为仅按块类型计算。
+func RequestHash(model string, messages []llm.Message, opts *llm.Options) string {
+	type block struct {
+		Type string           `json:"type"`
+		Data llm.ContentBlock `json:"data"`
+	}
+	type message struct {
+		Role       llm.Role `json:"role"`
+		Content    string   `json:"content,omitempty"`
+		ToolCallID string   `json:"tool_call_id,omitempty"`
+		Blocks     []block  `json:"blocks,omitempty"`
+	}
+
+	msgs := make([]message, 0, len(messages))
+	for _, m := range messages {
+		msg := message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, b := range m.ContentBlocks {
+			if b == nil {
+				continue
+			}
+			// ContentBlock 为接口，显式记录块类型以区分结构相同的不同块
+			if _, err := json.Marshal(b); err != nil {
+				msg.Blocks = append(msg.Blocks, block{Type: b.BlockType()})
+				continue
+			}
+			msg.Blocks = append(msg.Blocks, block{Type: b.BlockType(), Data: b})
+		}
+		msgs = append(msgs, msg)
+	}
+
+	var (
+		o   *llm.Options
+		key string
+	)
+	if opts != nil {
+		key = llm.APIKeyFingerprint(opts.APIKey)
+		cp := *opts
+		if cp.Model != "" {
+			model = cp.Model
+		}
+		cp.Model = ""
+		cp.Headers = nil
+		cp.Metadata = nil
+		cp.EndUserID = ""
+		cp.ReturnPromptTokens = false
+		o = &cp
+	}
+
+	data, err := json.Marshal(struct {
+		Model    string       `json:"model"`
+		Messages []message    `json:"messages"`
+		Options  *llm.Options `json:"options,omitempty"`
+		APIKey   string       `json:"api_key,omitempty"`
+	}{model, msgs, o, key})
+	if err != nil {
+		// 选项中的 Metadata 等已移除，剩余字段均可序列化；兜底仅按模型、消息与 API Key 指纹计算
+		data, _ = json.Marshal(struct { //nolint:errchkjson // 消息块已逐个校验
+			Model    string    `json:"model"`
+			Messages []message `json:"messages"`
+			APIKey   string    `json:"api_key,omitempty"`
+		}{model, msgs, key})
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
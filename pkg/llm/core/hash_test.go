@@ -0,0 +1,105 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestRequestHash(t *testing.T) {
+	// 每次构造新的 map，验证键顺序无关
+	messages := func() []llm.Message {
+		return []llm.Message{
+			{Role: llm.RoleUser, Content: "Weather in Paris?"},
+			{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+				&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris", "unit": "c", "days": 3}},
+			}},
+			{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+				&llm.ToolResultBlock{ToolUseID: "call_1", Content: "22°C"},
+			}},
+		}
+	}
+	opts := func() *llm.Options {
+		return &llm.Options{
+			Temperature: 0.2,
+			Tools: []llm.ToolSchema{{Name: "get_weather", InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}, "unit": map[string]any{"type": "string"}},
+				"required":   []string{"city"},
+			}}},
+			ResponseFormat: &llm.ResponseFormat{Type: "json_object"},
+		}
+	}
+
+	base := RequestHash("gpt-4o", messages(), opts())
+	assert.Len(t, base, 64)
+	assert.Equal(t, base, RequestHash("gpt-4o", messages(), opts()))
+
+	t.Run("不影响输出的字段", func(t *testing.T) {
+		o := opts()
+		o.Headers = map[string]string{"Idempotency-Key": "abc"}
+		o.Metadata = map[string]any{"correlation_id": "req-1"}
+		o.EndUserID = "user-1"
+		o.ReturnPromptTokens = true
+
+		msgs := messages()
+		msgs[0].Meta = map[string]any{"source": "web"}
+		msgs[0].CacheBreakpoint = true
+
+		assert.Equal(t, base, RequestHash("gpt-4o", msgs, o))
+	})
+
+	t.Run("Options.Model 与 model 等价", func(t *testing.T) {
+		o := opts()
+		o.Model = "gpt-4o"
+		assert.Equal(t, base, RequestHash("other", messages(), o))
+	})
+
+	t.Run("影响输出的字段", func(t *testing.T) {
+		changed := map[string]string{}
+
+		o := opts()
+		o.Temperature = 0.7
+		changed["temperature"] = RequestHash("gpt-4o", messages(), o)
+
+		o = opts()
+		o.Tools[0].Description = "Get weather"
+		changed["tools"] = RequestHash("gpt-4o", messages(), o)
+
+		o = opts()
+		o.ResponseFormat = &llm.ResponseFormat{Type: "text"}
+		changed["response_format"] = RequestHash("gpt-4o", messages(), o)
+
+		changed["model"] = RequestHash("gpt-4o-mini", messages(), opts())
+		changed["no_options"] = RequestHash("gpt-4o", messages(), nil)
+
+		msgs := messages()
+		msgs[2].ContentBlocks[0].(*llm.ToolResultBlock).Content = "25°C"
+		changed["content"] = RequestHash("gpt-4o", msgs, opts())
+
+		o = opts()
+		o.APIKey = "tenant-a-key"
+		changed["api_key"] = RequestHash("gpt-4o", messages(), o)
+
+		// 结构相同但类型不同的内容块
+		text := []llm.Message{{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{&llm.TextBlock{Text: "a"}}}}
+		thinking := []llm.Message{{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{&llm.ThinkingBlock{Thinking: "a"}}}}
+		assert.NotEqual(t, RequestHash("m", text, nil), RequestHash("m", thinking, nil))
+
+		for name, h := range changed {
+			assert.NotEqual(t, base, h, name)
+		}
+	})
+}
+
+func TestRequestHash_APIKey(t *testing.T) {
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	a := RequestHash("gpt-4o", messages, &llm.Options{APIKey: "tenant-a-key"})
+	b := RequestHash("gpt-4o", messages, &llm.Options{APIKey: "tenant-b-key"})
+
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, RequestHash("gpt-4o", messages, &llm.Options{APIKey: "tenant-a-key"}))
+	assert.NotEqual(t, a, RequestHash("gpt-4o", messages, &llm.Options{}))
+}
@@ -1,5 +1,11 @@
 package core
 
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 类型转换辅助函数
 // ═══════════════════════════════════════════════════════════════════════════
@@ -10,8 +16,10 @@ package core
 //   - float64: JSON 数字的默认类型
 //   - int: Go 原生整数
 //   - int64: Go 64位整数
+//   - string / json.Number: 数字字符串（部分 OpenAI 兼容网关以字符串返回 token 数），
+//     支持整数与小数（截断）形式
 //
-// 其他类型返回 0（零值）。
+// 其他类型或无法解析的字符串返回 0（零值）。
 //
 // 使用场景：
 //   - 解析 API 响应中的 token 数量
@@ -20,7 +28,7 @@ package core
 // 示例：
 //
 //	usage := apiResp["usage"].(map[string]any)
-//	inputTokens := GetInt64(usage["input_tokens"])  // 处理 float64
+//	inputTokens := GetInt64(usage["input_tokens"])  // 处理 float64 与 "12"
 func GetInt64(val any) int64 {
 	switch v := val.(type) {
 	case float64:
@@ -29,11 +37,27 @@ func GetInt64(val any) int64 {
 		return int64(v)
 	case int64:
 		return v
+	case json.Number:
+		return parseInt64(string(v))
+	case string:
+		return parseInt64(v)
 	default:
 		return 0
 	}
 }
 
+// parseInt64 解析数字字符串，失败时返回 0
+func parseInt64(s string) int64 {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return int64(f)
+	}
+	return 0
+}
+
 // GetFloat64 将 any 类型安全转换为 float64
 //
 // 支持的输入类型：
@@ -84,3 +108,31 @@ func GetString(val any) string {
 	}
 	return ""
 }
+
+// GetToolInput 将工具调用参数转换为非 nil 的 map
+//
+// 支持的输入类型：
+//   - map[string]any: 直接对象（Anthropic、Gemini）
+//   - string: JSON 字符串（OpenAI），空串或 "{}" 视为无参数
+//
+// 无参数或无法解析时返回空 map（非 nil），工具处理函数可直接取值。
+//
+// 示例：
+//
+//	input := GetToolInput(fn["arguments"])
+//	city, _ := input["city"].(string)
+func GetToolInput(val any) map[string]any {
+	var input map[string]any
+	switch v := val.(type) {
+	case map[string]any:
+		input = v
+	case string:
+		if strings.TrimSpace(v) != "" {
+			_ = json.Unmarshal([]byte(v), &input)
+		}
+	}
+	if input == nil {
+		input = map[string]any{}
+	}
+	return input
+}
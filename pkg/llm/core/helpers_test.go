@@ -1,6 +1,7 @@
 package core
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -35,8 +36,23 @@ func TestGetInt64(t *testing.T) {
 			want: 0,
 		},
 		{
-			name: "string 返回 0",
+			name: "数字字符串",
 			val:  "123",
+			want: 123,
+		},
+		{
+			name: "小数字符串截断",
+			val:  "12.9",
+			want: 12,
+		},
+		{
+			name: "json.Number",
+			val:  json.Number("42"),
+			want: 42,
+		},
+		{
+			name: "非数字字符串返回 0",
+			val:  "abc",
 			want: 0,
 		},
 		{
@@ -149,3 +165,38 @@ func TestGetString(t *testing.T) {
 		})
 	}
 }
+
+// ═══════════════════════════════════════════════════════════════════════════
+// GetToolInput 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestGetToolInput(t *testing.T) {
+	tests := []struct {
+		name string
+		val  any
+		want int
+	}{
+		{name: "nil", val: nil, want: 0},
+		{name: "nil map", val: map[string]any(nil), want: 0},
+		{name: "空 map", val: map[string]any{}, want: 0},
+		{name: "map", val: map[string]any{"a": 1}, want: 1},
+		{name: "空字符串", val: "", want: 0},
+		{name: "空白字符串", val: "  ", want: 0},
+		{name: "空对象字符串", val: "{}", want: 0},
+		{name: "JSON 字符串", val: `{"a":1,"b":2}`, want: 2},
+		{name: "无效 JSON", val: "not json", want: 0},
+		{name: "JSON null", val: "null", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetToolInput(tt.val)
+			if got == nil {
+				t.Fatal("GetToolInput() returned nil")
+			}
+			if len(got) != tt.want {
+				t.Errorf("GetToolInput() len = %d, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ID 生成器
+// ═══════════════════════════════════════════════════════════════════════════
+
+// IDGenerator 合成 ID 生成器接口
+//
+// 上游 API 不返回 ID 时（如 Gemini 工具调用），适配器通过此接口生成 ID。
+// 实现必须并发安全。
+type IDGenerator interface {
+	// NewID 生成一个新 ID（不含协议前缀，前缀由调用方添加）
+	NewID() string
+}
+
+// IDGeneratorSetter 可选接口：需要合成 ID 的适配器或事件处理器实现此接口
+//
+// [WithIDGenerator] 通过此接口将生成器注入 ProtocolAdapter 与 EventHandler。
+type IDGeneratorSetter interface {
+	SetIDGenerator(gen IDGenerator)
+}
+
+// UUIDGenerator 基于随机 UUID (v4) 的 ID 生成器，默认实现
+type UUIDGenerator struct{}
+
+// NewID 生成 UUID v4 字符串
+func (UUIDGenerator) NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// DefaultIDGenerator 默认 ID 生成器
+var DefaultIDGenerator IDGenerator = UUIDGenerator{}
+
+// newID 使用 gen 生成 ID，gen 为 nil 时使用 [DefaultIDGenerator]
+func newID(gen IDGenerator) string {
+	if gen == nil {
+		gen = DefaultIDGenerator
+	}
+	return gen.NewID()
+}
+
+// SequentialIDGenerator 顺序递增的确定性 ID 生成器
+//
+// 生成 "1", "2", "3", ...，用于测试中保持请求/响应 JSON 稳定（golden 文件）。
+type SequentialIDGenerator struct {
+	mu   sync.Mutex
+	next int
+}
+
+// NewSequentialIDGenerator 创建从 1 开始的顺序 ID 生成器
+func NewSequentialIDGenerator() *SequentialIDGenerator {
+	return &SequentialIDGenerator{}
+}
+
+// NewID 返回下一个序号
+func (g *SequentialIDGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	return strconv.Itoa(g.next)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 客户端选项
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ClientOption BaseClient 可选配置
+type ClientOption func(*BaseClient)
+
+// WithIDGenerator 设置合成 ID 的生成器
+//
+// 生成器会注入实现了 [IDGeneratorSetter] 的协议适配器与事件处理器，
+// 并用于 JSON 模式工具调用降级还原的工具调用 ID（见 [ParseToolFallback]）。
+//
+// 示例：
+//
+//	client, _ := gemini.New(config, core.WithIDGenerator(core.NewSequentialIDGenerator()))
+func WithIDGenerator(gen IDGenerator) ClientOption {
+	return func(c *BaseClient) {
+		if gen == nil {
+			return
+		}
+		c.idGenerator = gen
+		if s, ok := c.transformer.adapter.(IDGeneratorSetter); ok {
+			s.SetIDGenerator(gen)
+		}
+		if s, ok := c.sseParser.handler.(IDGeneratorSetter); ok {
+			s.SetIDGenerator(gen)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package core_test
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+func TestUUIDGenerator(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	a := core.UUIDGenerator{}.NewID()
+	b := core.UUIDGenerator{}.NewID()
+
+	assert.Regexp(t, pattern, a)
+	assert.NotEqual(t, a, b)
+}
+
+func TestSequentialIDGenerator(t *testing.T) {
+	gen := core.NewSequentialIDGenerator()
+
+	assert.Equal(t, "1", gen.NewID())
+	assert.Equal(t, "2", gen.NewID())
+	assert.Equal(t, "3", gen.NewID())
+}
+
+func TestSequentialIDGenerator_Concurrent(t *testing.T) {
+	gen := core.NewSequentialIDGenerator()
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+		wg   sync.WaitGroup
+	)
+	for range 50 {
+		wg.Go(func() {
+			id := gen.NewID()
+			mu.Lock()
+			seen[id] = true
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 50)
+}
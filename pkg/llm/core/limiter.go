@@ -0,0 +1,55 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 并发请求限制
+// ═══════════════════════════════════════════════════════════════════════════
+
+// WithMaxConcurrentRequests 限制客户端同时进行的 Complete / Stream 请求数
+//
+// 超出上限的调用阻塞等待空位，等待期间 ctx 取消时返回 ctx.Err()。
+// Complete 在返回前占用名额（包括重试等待）；Stream 占用至事件 channel 关闭。
+// n <= 0 表示不限制（默认）。克隆的客户端共享同一上限。
+func WithMaxConcurrentRequests(n int) ClientOption {
+	return func(c *BaseClient) {
+		if n > 0 {
+			c.limiter.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// requestLimiter 并发请求计数与限制
+type requestLimiter struct {
+	sem      chan struct{} // nil 表示不限制
+	inFlight atomic.Int64
+}
+
+// acquire 占用一个名额，ctx 取消时返回错误
+func (l *requestLimiter) acquire(ctx context.Context) error {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	l.inFlight.Add(1)
+	return nil
+}
+
+// release 释放 acquire 占用的名额
+func (l *requestLimiter) release() {
+	l.inFlight.Add(-1)
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+// InFlight 返回当前进行中的 Complete / Stream 请求数（含未读完的流），用于监控
+func (c *BaseClient) InFlight() int {
+	return int(c.limiter.inFlight.Load())
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestBaseClient_MaxConcurrentRequests(t *testing.T) {
+	const limit = 3
+
+	var active, peak atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer active.Add(-1)
+		storeMax(&peak, active.Add(1))
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{},
+		WithMaxConcurrentRequests(limit))
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+	var wg sync.WaitGroup
+	var maxInFlight atomic.Int64
+	for range 30 {
+		wg.Go(func() {
+			_, err := client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+			assert.NoError(t, err)
+			storeMax(&maxInFlight, int64(client.InFlight()))
+		})
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak.Load(), int64(limit))
+	assert.Equal(t, int64(limit), peak.Load(), "应达到上限")
+	assert.LessOrEqual(t, maxInFlight.Load(), int64(limit))
+	assert.Zero(t, client.InFlight())
+}
+
+// storeMax 将 v 更新为 v 与 n 中的较大值
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		cur := v.Load()
+		if n <= cur || v.CompareAndSwap(cur, n) {
+			return
+		}
+	}
+}
+
+func TestBaseClient_MaxConcurrentRequests_Stream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: {\"content\": \"Hello\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{},
+		WithMaxConcurrentRequests(1))
+	require.NoError(t, err)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	// 流未结束时占用名额
+	events, err := client.Stream(context.Background(), messages, nil, &mockRequestBuilder{})
+	require.NoError(t, err)
+	<-events
+	assert.Equal(t, 1, client.InFlight())
+
+	// 等待名额时 ctx 取消
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.Complete(ctx, messages, nil, &mockRequestBuilder{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 流结束后释放
+	release <- struct{}{}
+	for range events {
+	}
+	assert.Eventually(t, func() bool { return client.InFlight() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestBaseClient_MaxConcurrentRequests_CompleteRaw(t *testing.T) {
+	var active, peak atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer active.Add(-1)
+		storeMax(&peak, active.Add(1))
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{},
+		WithMaxConcurrentRequests(1))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			_, err := client.CompleteRaw(context.Background(), map[string]any{"model": "test"})
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), peak.Load())
+	assert.Zero(t, client.InFlight())
+
+	// 等待名额时 ctx 取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, client.limiter.acquire(context.Background()))
+	defer client.limiter.release()
+	_, err = client.CompleteRaw(ctx, map[string]any{"model": "test"})
+	require.ErrorIs(t, err, context.Canceled)
+}
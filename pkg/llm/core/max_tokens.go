@@ -0,0 +1,41 @@
+package core
+
+import "github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 默认最大输出 tokens
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ApplyDefaultMaxTokens 未设置 MaxTokens 时按模型能力表填充最大输出 tokens
+//
+// 取 llm.LookupModel(model) 的 MaxOutputTokens；模型未注册或上限未知时原样返回，
+// 由 Provider 使用自身的回退值（如 Anthropic 必填的 max_tokens）。
+// 返回的 Options 为副本，不修改调用方对象。
+func ApplyDefaultMaxTokens(model string, opts *llm.Options) *llm.Options {
+	info, _ := llm.LookupModel(model)
+	return applyMaxTokens(info, opts)
+}
+
+// applyMaxTokens 未设置 MaxTokens 时使用 info.MaxOutputTokens 填充
+func applyMaxTokens(info llm.ModelInfo, opts *llm.Options) *llm.Options {
+	if (opts != nil && opts.MaxTokens > 0) || info.MaxOutputTokens <= 0 {
+		return opts
+	}
+
+	var filled llm.Options
+	if opts != nil {
+		filled = *opts
+	}
+	filled.MaxTokens = info.MaxOutputTokens
+	return &filled
+}
+
+// WithDefaultMaxTokens 设置是否自动填充 MaxTokens（默认开启）
+//
+// 开启时未设置 Options.MaxTokens 的请求按 [ApplyDefaultMaxTokens] 使用模型的最大输出上限；
+// 关闭后保持 Provider 原有行为（Anthropic 8192、Gemini DefaultMaxTokens、OpenAI 不发送）。
+func WithDefaultMaxTokens(enabled bool) ClientOption {
+	return func(c *BaseClient) {
+		c.noDefaultMaxTokens = !enabled
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestApplyDefaultMaxTokens(t *testing.T) {
+	t.Run("按模型上限填充", func(t *testing.T) {
+		opts := &llm.Options{Temperature: 0.5}
+		got := ApplyDefaultMaxTokens("claude-sonnet-4-20250514", opts)
+		assert.Equal(t, 64000, got.MaxTokens)
+		assert.InDelta(t, 0.5, got.Temperature, 0.001)
+		assert.Zero(t, opts.MaxTokens, "不修改调用方对象")
+	})
+
+	t.Run("nil 选项", func(t *testing.T) {
+		got := ApplyDefaultMaxTokens("gemini-2.5-flash", nil)
+		require.NotNil(t, got)
+		assert.Equal(t, 65536, got.MaxTokens)
+	})
+
+	t.Run("显式设置优先", func(t *testing.T) {
+		opts := &llm.Options{MaxTokens: 100}
+		assert.Same(t, opts, ApplyDefaultMaxTokens("gpt-4o", opts))
+	})
+
+	t.Run("未注册或上限未知", func(t *testing.T) {
+		assert.Nil(t, ApplyDefaultMaxTokens("my-local-model", nil))
+		assert.Nil(t, ApplyDefaultMaxTokens("gemma-3", nil))
+	})
+}
+
+func TestBaseClient_DefaultMaxTokens(t *testing.T) {
+	config := &mockConfig{apiKey: "test-key", baseURL: "http://invalid-host-12345:9999", model: "gpt-4o"}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	client, err := NewBaseClient(config, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+	builder := &capturingRequestBuilder{}
+	_, _ = client.Complete(context.Background(), messages, nil, builder)
+	require.NotNil(t, builder.opts)
+	assert.Equal(t, 16384, builder.opts.MaxTokens)
+
+	// 关闭自动填充
+	client, err = NewBaseClient(config, &mockAdapter{}, &mockEventHandler{}, WithDefaultMaxTokens(false))
+	require.NoError(t, err)
+	builder = &capturingRequestBuilder{}
+	_, _ = client.Complete(context.Background(), messages, nil, builder)
+	assert.Nil(t, builder.opts)
+}
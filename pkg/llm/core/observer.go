@@ -0,0 +1,125 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 请求观察
+// ═══════════════════════════════════════════════════════════════════════════
+
+// MetadataCorrelationID Options.Metadata 中的关联 ID 键
+//
+// 值为字符串时作为 [Observer] 回调的关联 ID，便于与应用日志对照；
+// 未设置时每次调用生成随机 ID。
+const MetadataCorrelationID = "correlation_id"
+
+// Observer 观察发送给 API 的原始请求体与收到的响应体（调试用）
+//
+// 同一次调用的 OnRequest 与 OnResponse 使用相同的关联 ID：
+//   - Complete: body 为完整的 JSON 响应体
+//   - Stream: body 为流结束时收到的全部原始 SSE 数据；建立阶段失败时为 nil
+//   - err 为 HTTP 或 API 错误（流中途的错误以 Error 事件上报，不在此传递）
+//
+// 回调在请求路径上同步执行，实现应尽快返回并保证并发安全。
+// body 仅在回调期间有效，需要保留时应复制。
+type Observer interface {
+	OnRequest(id string, body []byte)
+	OnResponse(id string, body []byte, err error)
+}
+
+// WithObserver 设置观察全部 Complete / Stream 请求的 [Observer]
+//
+// 请求体与响应体可能包含敏感内容，高流量服务应使用 [WithSampledObserver]。
+func WithObserver(obs Observer) ClientOption {
+	return func(c *BaseClient) {
+		c.observer = obs
+	}
+}
+
+// WithSampledObserver 设置按比例采样的 [Observer]
+//
+// rate 为采样比例（0 ~ 1），<= 0 不采样，>= 1 全部采样。是否采样由关联 ID 的哈希决定：
+// 同一次调用的请求与响应要么都被观察，要么都被跳过；通过 [MetadataCorrelationID]
+// 指定相同关联 ID 的调用采样结果一致。
+//
+// 示例：
+//
+//	client, _ := openai.New(config, core.WithSampledObserver(debugLog, 0.01))
+func WithSampledObserver(obs Observer, rate float64) ClientOption {
+	return WithObserver(&sampledObserver{obs: obs, rate: rate})
+}
+
+// sampledObserver 按关联 ID 采样的 Observer
+type sampledObserver struct {
+	obs  Observer
+	rate float64
+}
+
+// OnRequest 实现 Observer 接口
+func (s *sampledObserver) OnRequest(id string, body []byte) {
+	if sampled(id, s.rate) {
+		s.obs.OnRequest(id, body)
+	}
+}
+
+// OnResponse 实现 Observer 接口
+func (s *sampledObserver) OnResponse(id string, body []byte, err error) {
+	if sampled(id, s.rate) {
+		s.obs.OnResponse(id, body, err)
+	}
+}
+
+// sampled 根据 id 的哈希判断是否落在采样比例内
+//
+// 使用 SHA-256 而非 FNV 等快速哈希：顺序 ID（如 "req-1"、"req-2"）的 FNV 高位分布不均，
+// 采样比例偏差明显。
+func sampled(id string, rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	sum := sha256.Sum256([]byte(id))
+	return float64(binary.BigEndian.Uint64(sum[:8]))/math.MaxUint64 < rate
+}
+
+// correlationID 返回调用的关联 ID（Options.Metadata 优先，否则随机生成）
+func correlationID(opts *llm.Options) string {
+	if opts != nil {
+		if id := GetString(opts.Metadata[MetadataCorrelationID]); id != "" {
+			return id
+		}
+	}
+	return DefaultIDGenerator.NewID()
+}
+
+// observedBody 包装流式响应体，关闭时将读到的全部数据交给 Observer
+type observedBody struct {
+	io.ReadCloser
+
+	buf bytes.Buffer
+	id  string
+	obs Observer
+}
+
+// Read 读取并记录数据
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+// Close 关闭响应体并回调 OnResponse
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.obs.OnResponse(b.id, b.buf.Bytes(), nil)
+	return err
+}
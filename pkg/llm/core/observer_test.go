@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// recordingObserver 记录每个关联 ID 的请求体与响应体
+type recordingObserver struct {
+	mu        sync.Mutex
+	requests  map[string]string
+	responses map[string]string
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{requests: make(map[string]string), responses: make(map[string]string)}
+}
+
+func (o *recordingObserver) OnRequest(id string, body []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.requests[id] = string(body)
+}
+
+func (o *recordingObserver) OnResponse(id string, body []byte, _ error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.responses[id] = string(body)
+}
+
+func TestSampled(t *testing.T) {
+	const n = 10000
+	for _, rate := range []float64{0.01, 0.1, 0.5} {
+		count := 0
+		for i := range n {
+			if sampled("req-"+strconv.Itoa(i), rate) {
+				count++
+			}
+		}
+		assert.InDelta(t, rate, float64(count)/n, 0.02, "rate %v", rate)
+	}
+
+	assert.False(t, sampled("any", 0))
+	assert.True(t, sampled("any", 1))
+	assert.Equal(t, sampled("req-42", 0.3), sampled("req-42", 0.3), "同一 ID 结果一致")
+}
+
+func TestBaseClient_SampledObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "resp"}`))
+	}))
+	defer server.Close()
+
+	obs := newRecordingObserver()
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{},
+		WithSampledObserver(obs, 0.25))
+	require.NoError(t, err)
+
+	const n = 400
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+	for i := range n {
+		opts := &llm.Options{Metadata: map[string]any{MetadataCorrelationID: fmt.Sprintf("req-%d", i)}}
+		_, err := client.Complete(context.Background(), messages, opts, &mockRequestBuilder{})
+		require.NoError(t, err)
+	}
+
+	assert.InDelta(t, 0.25, float64(len(obs.requests))/n, 0.07)
+	require.Len(t, obs.responses, len(obs.requests), "请求与响应同时采样")
+	for id, req := range obs.requests {
+		assert.Contains(t, req, `"model":"test-model"`, id)
+		assert.JSONEq(t, `{"id": "resp"}`, obs.responses[id], id)
+	}
+}
+
+func TestBaseClient_Observer_Stream(t *testing.T) {
+	const sse = "data: {\"content\": \"Hello\"}\n\ndata: [DONE]\n\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, sse)
+	}))
+	defer server.Close()
+
+	obs := newRecordingObserver()
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{},
+		WithObserver(obs))
+	require.NoError(t, err)
+
+	opts := &llm.Options{Metadata: map[string]any{MetadataCorrelationID: "stream-1"}}
+	events, err := client.Stream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}, opts, &mockRequestBuilder{})
+	require.NoError(t, err)
+	for range events {
+	}
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	assert.Contains(t, obs.requests["stream-1"], `"model":"test-model"`)
+	assert.Equal(t, sse, obs.responses["stream-1"])
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 模型元数据探测
+// ═══════════════════════════════════════════════════════════════════════════
+
+// probedModel 探测得到的模型信息
+type probedModel struct {
+	model string
+	info  llm.ModelInfo
+}
+
+// ProbeModel 请求模型元数据接口并缓存模型信息
+//
+// path 为 Provider 的模型元数据端点（如 OpenAI "/models/{model}"）。探测结果与静态
+// 注册表（llm.LookupModel）合并：接口返回的上下文窗口与输出上限优先，SupportsTools
+// 仍以注册表为准。缓存的输出上限同时用于默认 MaxTokens（见 WithDefaultMaxTokens）。识别的字段：
+//   - 上下文窗口：context_length、context_window、max_input_tokens、inputTokenLimit
+//   - 输出上限：max_output_tokens、max_completion_tokens、outputTokenLimit、top_provider.max_completion_tokens
+//
+// 认证失败（401/403）或模型不存在（404）时返回 [llm.ConfigError]，其他错误原样返回。
+func (c *BaseClient) ProbeModel(ctx context.Context, path string) (llm.ModelInfo, error) {
+	model := c.model()
+
+	resp, err := c.NewRequest(ctx).Get(path)
+	if err != nil {
+		return llm.ModelInfo{}, llm.NewHTTPError("probe model", err)
+	}
+	if err := c.CheckResponse(resp); err != nil {
+		switch llm.GetStatusCode(err) {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return llm.ModelInfo{}, llm.NewConfigError("authentication failed", err)
+		case http.StatusNotFound:
+			return llm.ModelInfo{}, llm.NewConfigError("model not found: "+model, err)
+		}
+		return llm.ModelInfo{}, err
+	}
+
+	var meta map[string]any
+	_ = json.Unmarshal(resp.Body(), &meta) // 元数据字段可选，无法解析时仅使用注册表
+
+	info, _ := llm.LookupModel(model)
+	if n := firstInt(meta, "context_length", "context_window", "max_input_tokens", "inputTokenLimit"); n > 0 {
+		info.ContextWindow = n
+	}
+	if n := firstInt(meta, "max_output_tokens", "max_completion_tokens", "outputTokenLimit"); n > 0 {
+		info.MaxOutputTokens = n
+	} else if top, ok := meta["top_provider"].(map[string]any); ok {
+		if n := firstInt(top, "max_completion_tokens"); n > 0 {
+			info.MaxOutputTokens = n
+		}
+	}
+
+	c.probed = &probedModel{model: model, info: info}
+	return info, nil
+}
+
+// ModelInfo 返回当前默认模型的能力信息
+//
+// 优先返回 [BaseClient.ProbeModel] 缓存的结果（模型一致时），否则查询静态注册表。
+func (c *BaseClient) ModelInfo() (llm.ModelInfo, bool) {
+	model := c.model()
+	if c.probed != nil && c.probed.model == model {
+		return c.probed.info, true
+	}
+	return llm.LookupModel(model)
+}
+
+// modelInfo 返回指定模型的能力信息（探测结果优先，未知时为零值）
+func (c *BaseClient) modelInfo(model string) llm.ModelInfo {
+	if c.probed != nil && c.probed.model == model {
+		return c.probed.info
+	}
+	info, _ := llm.LookupModel(model)
+	return info
+}
+
+// model 返回配置的默认模型
+func (c *BaseClient) model() string {
+	_, model, _ := c.config.GetDefaults()
+	return model
+}
+
+// firstInt 返回 m 中第一个为正数的字段值
+func firstInt(m map[string]any, keys ...string) int {
+	for _, k := range keys {
+		if n := GetInt64(m[k]); n > 0 {
+			return int(n)
+		}
+	}
+	return 0
+}
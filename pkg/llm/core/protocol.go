@@ -49,14 +49,16 @@ type ProtocolAdapter interface {
 	//   - 提取文本内容
 	//   - 提取工具调用（反序列化 JSON 字符串 → 对象）
 	//   - 映射完成原因
+	//   - 提取引用来源、对数概率等 Response 级数据（见 ResponseExtras）
 	//
 	// 参数：
 	//   - apiResp: API 返回的原始响应 map
 	//
 	// 返回：
-	//   - msg: 统一格式的 Message
+	//   - msg: 统一格式的 Message（不写入 Meta）
 	//   - finishReason: 标准化的完成原因
-	ConvertFromAPI(apiResp map[string]any) (msg llm.Message, finishReason string)
+	//   - extras: Message 之外的 Response 级数据
+	ConvertFromAPI(apiResp map[string]any) (msg llm.Message, finishReason string, extras ResponseExtras)
 
 	// ConvertUsage 解析 Token 使用量
 	//
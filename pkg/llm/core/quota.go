@@ -0,0 +1,71 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 配额耗尽识别
+// ═══════════════════════════════════════════════════════════════════════════
+
+// quotaErrorCodes 表示配额或账单耗尽的错误代码
+var quotaErrorCodes = map[string]bool{
+	"insufficient_quota":         true, // OpenAI
+	"billing_hard_limit_reached": true, // OpenAI
+	"billing_not_active":         true, // OpenAI
+	"insufficient_balance":       true, // DeepSeek 等兼容服务
+}
+
+// quotaErrorMessages 表示配额或账单耗尽的错误信息片段（小写）
+var quotaErrorMessages = []string{
+	"credit balance is too low", // Anthropic
+	"insufficient balance",
+}
+
+// ParseErrorBody 从错误响应体中提取错误代码与信息
+//
+// 支持 {"error": {"code": "...", "type": "...", "message": "..."}} 形式（OpenAI、
+// Anthropic、Gemini 及兼容服务），code 非字符串时使用 type 或 status。
+// 响应体不是 JSON 或缺少 error 对象时返回空字符串。
+func ParseErrorBody(body []byte) (code, message string) {
+	var payload struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) != nil || len(payload.Error) == 0 {
+		return "", ""
+	}
+
+	var errObj map[string]any
+	if json.Unmarshal(payload.Error, &errObj) != nil {
+		// 部分服务的 error 字段为字符串
+		var msg string
+		_ = json.Unmarshal(payload.Error, &msg)
+		return "", msg
+	}
+
+	message = GetString(errObj["message"])
+	for _, key := range []string{"code", "type", "status"} {
+		if code = GetString(errObj[key]); code != "" {
+			return code, message
+		}
+	}
+	return "", message
+}
+
+// IsQuotaExhausted 根据错误代码与信息判断是否为配额或账单耗尽
+//
+// 代码命中 insufficient_quota、billing_hard_limit_reached 等，或信息包含
+// "credit balance is too low" 等片段时返回 true。
+func IsQuotaExhausted(code, message string) bool {
+	if quotaErrorCodes[code] {
+		return true
+	}
+	lower := strings.ToLower(message)
+	for _, s := range quotaErrorMessages {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
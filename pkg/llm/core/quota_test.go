@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// insufficientQuotaBody OpenAI 配额耗尽的响应体
+const insufficientQuotaBody = `{"error": {"message": "You exceeded your current quota, please check your plan and billing details.", "type": "insufficient_quota", "param": null, "code": "insufficient_quota"}}`
+
+func TestParseErrorBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    string
+		wantMessage string
+	}{
+		{"OpenAI", insufficientQuotaBody, "insufficient_quota", "You exceeded your current quota, please check your plan and billing details."},
+		{"Anthropic", `{"type": "error", "error": {"type": "invalid_request_error", "message": "Your credit balance is too low"}}`, "invalid_request_error", "Your credit balance is too low"},
+		{"Gemini 数字代码", `{"error": {"code": 429, "message": "Resource exhausted", "status": "RESOURCE_EXHAUSTED"}}`, "RESOURCE_EXHAUSTED", "Resource exhausted"},
+		{"字符串 error", `{"error": "cold start"}`, "", "cold start"},
+		{"非 JSON", `Too Many Requests`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, message := ParseErrorBody([]byte(tt.body))
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+func TestIsQuotaExhausted(t *testing.T) {
+	assert.True(t, IsQuotaExhausted("insufficient_quota", ""))
+	assert.True(t, IsQuotaExhausted("billing_hard_limit_reached", ""))
+	assert.True(t, IsQuotaExhausted("invalid_request_error", "Your credit balance is too low to access the API"))
+	assert.False(t, IsQuotaExhausted("rate_limit_exceeded", "Rate limit reached for requests"))
+	assert.False(t, IsQuotaExhausted("RESOURCE_EXHAUSTED", "Resource exhausted"))
+	assert.False(t, IsQuotaExhausted("", ""))
+}
+
+func TestBaseClient_CheckResponse_Quota(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "7")
+		if r.URL.Path == "/billing" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error": {"code": "billing_hard_limit_reached", "message": "Billing hard limit has been reached"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(insufficientQuotaBody))
+	}))
+	defer server.Close()
+
+	retry := WithRetry(RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{}, retry)
+	require.NoError(t, err)
+
+	// 429 insufficient_quota：不可重试，不附带重置时间
+	resp, err := client.NewRequest(context.Background()).Post("/chat")
+	require.NoError(t, err)
+	err = client.CheckResponse(resp)
+	require.True(t, llm.IsQuotaError(err))
+	assert.False(t, llm.IsRetryableError(err))
+	assert.Zero(t, llm.GetRetryAfter(err))
+	apiErr, ok := llm.GetAPIError(err)
+	require.True(t, ok)
+	assert.Equal(t, "insufficient_quota", apiErr.ErrorCode)
+
+	// 403 billing_hard_limit_reached
+	resp, err = client.NewRequest(context.Background()).Post("/billing")
+	require.NoError(t, err)
+	assert.True(t, llm.IsQuotaError(client.CheckResponse(resp)))
+
+	// Complete 不重试配额错误
+	attempts.Store(0)
+	_, err = client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}, nil, &mockRequestBuilder{})
+	require.True(t, llm.IsQuotaError(err))
+	assert.Equal(t, int32(1), attempts.Load())
+}
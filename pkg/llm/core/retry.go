@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 重试配置
+// ═══════════════════════════════════════════════════════════════════════════
+
+// 默认重试退避参数（见 llm.RetryConfig）
+const (
+	DefaultRetryBaseDelay = llm.DefaultRetryBaseDelay
+	DefaultRetryMaxDelay  = llm.DefaultRetryMaxDelay
+)
+
+// RetryConfig 重试配置，定义于 llm 包以支持全局默认值（llm.SetDefaultRetry）
+type RetryConfig = llm.RetryConfig
+
+// retryDelay 返回第 attempt 次重试前的等待时间
+//
+// 错误携带服务端建议的 RetryAfter 时优先使用（不加抖动），否则按指数退避。
+// RetryAfter 超过 MaxDelay 时返回 false：服务端要求的等待超出单次等待上限，
+// 放弃重试并返回原错误，由调用方按 APIError.RetryAfter 自行调度。
+func retryDelay(cfg RetryConfig, err error, attempt int) (time.Duration, bool) {
+	if d := llm.GetRetryAfter(err); d > 0 {
+		maxDelay := cfg.MaxDelay
+		if maxDelay <= 0 {
+			maxDelay = DefaultRetryMaxDelay
+		}
+		return d, d <= maxDelay
+	}
+	return cfg.JitteredBackoff(attempt, rand.Float64()), true //nolint:gosec // 退避抖动无需密码学随机数
+}
+
+// WithRetry 设置重试配置
+//
+// 对 llm.IsRetryableError 判定为可重试的错误（429、5xx），Complete 重新发送整个请求，
+// Stream 仅在建立阶段（收到 2xx 之前）重试，详见 [BaseClient.Complete] 与 [BaseClient.Stream]。
+// 等待时间优先取响应头中的 Retry-After（APIError.RetryAfter），否则按指数退避；
+// Retry-After 超过 MaxDelay 时不再重试，直接返回错误。默认不重试，覆盖全局默认值（llm.SetDefaultRetry）。
+//
+// 示例：
+//
+//	client, _ := openai.New(config, core.WithRetry(core.RetryConfig{MaxAttempts: 3, Jitter: 0.2}))
+func WithRetry(cfg RetryConfig) ClientOption {
+	return func(c *BaseClient) {
+		c.retry = cfg
+	}
+}
+
+// sleepContext 等待 d 或直到 ctx 取消
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 限流重置时间
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ParseRetryAfter 从响应头解析建议的重试等待时间
+//
+// 优先级：
+//  1. retry-after-ms：毫秒数（OpenAI）
+//  2. Retry-After：秒数或 HTTP-date（RFC 9110）
+//  3. *ratelimit*-reset* 系列：取最大值，支持 Go 时长（"6m0s"、"20ms"，OpenAI）、
+//     RFC 3339 时间戳（Anthropic）与秒数
+//
+// now 用于将绝对时间换算为等待时长。无法解析或已过期时返回 0。
+func ParseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if v := header.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	if v := header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return max(time.Duration(secs)*time.Second, 0)
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(t.Sub(now), 0)
+		}
+	}
+
+	var longest time.Duration
+	for key, values := range header {
+		lower := strings.ToLower(key)
+		if !strings.Contains(lower, "ratelimit") || !strings.Contains(lower, "reset") || len(values) == 0 {
+			continue
+		}
+		longest = max(longest, parseResetValue(values[0], now))
+	}
+	return longest
+}
+
+// parseResetValue 解析单个限流重置响应头的值
+func parseResetValue(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if d, err := time.ParseDuration(v); err == nil {
+		return max(d, 0)
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return 0
+}
@@ -0,0 +1,319 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestRetryConfig_Backoff(t *testing.T) {
+	cfg := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: 350 * time.Millisecond}
+	assert.Equal(t, 100*time.Millisecond, cfg.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, cfg.Backoff(2))
+	assert.Equal(t, 350*time.Millisecond, cfg.Backoff(3))
+	assert.Equal(t, 350*time.Millisecond, cfg.Backoff(10))
+
+	assert.Equal(t, DefaultRetryBaseDelay, RetryConfig{}.Backoff(1))
+}
+
+func TestRetryConfig_JitteredBackoff(t *testing.T) {
+	cfg := RetryConfig{BaseDelay: 100 * time.Millisecond}
+	assert.Equal(t, 100*time.Millisecond, cfg.JitteredBackoff(1, 0.9), "no jitter by default")
+
+	cfg.Jitter = 0.5
+	assert.Equal(t, 100*time.Millisecond, cfg.JitteredBackoff(1, 0))
+	assert.Equal(t, 75*time.Millisecond, cfg.JitteredBackoff(1, 0.5))
+	assert.Equal(t, 150*time.Millisecond, cfg.JitteredBackoff(2, 0.5))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   time.Duration
+	}{
+		{"无响应头", nil, 0},
+		{"Retry-After 秒数", map[string]string{"Retry-After": "30"}, 30 * time.Second},
+		{"Retry-After HTTP-date", map[string]string{"Retry-After": "Wed, 01 Jan 2025 12:00:45 GMT"}, 45 * time.Second},
+		{"Retry-After 已过期", map[string]string{"Retry-After": "Wed, 01 Jan 2025 11:00:00 GMT"}, 0},
+		{"retry-after-ms 优先", map[string]string{"Retry-After-Ms": "1500", "Retry-After": "30"}, 1500 * time.Millisecond},
+		{"OpenAI 重置时长取最大", map[string]string{
+			"X-Ratelimit-Reset-Requests": "20ms",
+			"X-Ratelimit-Reset-Tokens":   "6m0s",
+		}, 6 * time.Minute},
+		{"Anthropic RFC 3339", map[string]string{"Anthropic-Ratelimit-Requests-Reset": "2025-01-01T12:00:10Z"}, 10 * time.Second},
+		{"重置秒数", map[string]string{"X-Ratelimit-Reset": "2.5"}, 2500 * time.Millisecond},
+		{"无法解析", map[string]string{"Retry-After": "soon"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, ParseRetryAfter(header, now))
+		})
+	}
+}
+
+func TestBaseClient_CheckResponse_RetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Reset-Requests", "3s")
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+
+	resp, err := client.NewRequest(context.Background()).Post("/chat")
+	require.NoError(t, err)
+	err = client.CheckResponse(resp)
+	assert.Equal(t, 7*time.Second, llm.GetRetryAfter(err))
+
+	// 不可重试的错误不附带重置时间
+	resp, err = client.NewRequest(context.Background()).Post("/bad")
+	require.NoError(t, err)
+	err = client.CheckResponse(resp)
+	assert.Zero(t, llm.GetRetryAfter(err))
+}
+
+func TestBaseClient_Stream_RetryOnStart(t *testing.T) {
+	retry := WithRetry(RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	t.Run("503 两次后成功", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error": "cold start"}`))
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "data: {\"content\": \"Hello\"}\n\n")
+			_, _ = fmt.Fprint(w, "data: {\"content\": \" World\"}\n\n")
+			_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+		}))
+		defer server.Close()
+
+		client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{}, retry)
+		require.NoError(t, err)
+
+		events, err := client.Stream(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.NoError(t, err)
+
+		var text string
+		for e := range events {
+			require.NotEqual(t, llm.EventTypeError, e.Type)
+			text += e.TextDelta
+		}
+
+		assert.Equal(t, int32(3), attempts.Load())
+		assert.Equal(t, "testtest", text)
+	})
+
+	t.Run("超过最大次数返回最后的错误", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{}, retry)
+		require.NoError(t, err)
+
+		_, err = client.Stream(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, llm.GetStatusCode(err))
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("不可重试错误不重试", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{}, retry)
+		require.NoError(t, err)
+
+		_, err = client.Stream(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("未配置时不重试", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{})
+		require.NoError(t, err)
+
+		_, err = client.Stream(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+}
+
+func TestBaseClient_Complete_Retry(t *testing.T) {
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	// newClient 创建记录重试等待时间（不实际等待）的客户端
+	newClient := func(t *testing.T, url string, cfg RetryConfig) (*BaseClient, *[]time.Duration) {
+		t.Helper()
+		client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: url}, &mockAdapter{}, &mockEventHandler{}, WithRetry(cfg))
+		require.NoError(t, err)
+		var sleeps []time.Duration
+		client.sleep = func(_ context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return nil
+		}
+		return client, &sleeps
+	}
+
+	t.Run("429 两次后成功，等待时间递增", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) <= 2 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"content": "ok"}`))
+		}))
+		defer server.Close()
+
+		client, sleeps := newClient(t, server.URL, RetryConfig{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond})
+		resp, err := client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(3), attempts.Load())
+		assert.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, *sleeps)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("优先按 Retry-After 等待", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) == 1 {
+				w.Header().Set("Retry-After", "3")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"content": "ok"}`))
+		}))
+		defer server.Close()
+
+		client, sleeps := newClient(t, server.URL, RetryConfig{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond})
+		_, err := client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.NoError(t, err)
+		assert.Equal(t, []time.Duration{3 * time.Second}, *sleeps)
+	})
+
+	t.Run("Retry-After 超过 MaxDelay 时不再重试", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client, sleeps := newClient(t, server.URL, RetryConfig{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Minute})
+		_, err := client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.Equal(t, time.Hour, llm.GetRetryAfter(err))
+		assert.Equal(t, int32(1), attempts.Load())
+		assert.Empty(t, *sleeps)
+
+		// 流式建立阶段同样不等待
+		attempts.Store(0)
+		_, err = client.Stream(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+		assert.Empty(t, *sleeps)
+	})
+
+	t.Run("超过最大次数或不可重试时返回错误", func(t *testing.T) {
+		var attempts atomic.Int32
+		status := http.StatusTooManyRequests
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		client, _ := newClient(t, server.URL, RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+		_, err := client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.Equal(t, int32(3), attempts.Load())
+
+		attempts.Store(0)
+		status = http.StatusBadRequest
+		_, err = client.Complete(context.Background(), messages, nil, &mockRequestBuilder{})
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+}
+
+func TestBaseClient_DefaultRetry(t *testing.T) {
+	llm.SetDefaultRetry(RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	t.Cleanup(func() { llm.SetDefaultRetry(RetryConfig{}) })
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	config := &mockConfig{apiKey: "test-key", baseURL: server.URL}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	// 继承全局默认值
+	client, err := NewBaseClient(config, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+	events, err := client.Stream(context.Background(), messages, nil, &mockRequestBuilder{})
+	require.NoError(t, err)
+	for range events {
+	}
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// 显式配置优先
+	attempts.Store(0)
+	client, err = NewBaseClient(config, &mockAdapter{}, &mockEventHandler{}, WithRetry(RetryConfig{MaxAttempts: 1}))
+	require.NoError(t, err)
+	_, err = client.Stream(context.Background(), messages, nil, &mockRequestBuilder{})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), attempts.Load())
+}
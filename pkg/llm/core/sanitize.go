@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 跨 Provider 历史清洗
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ToolCallIDFormatter 工具调用 ID 格式化器（可选接口）
+//
+// 协议适配器实现此接口以指定历史清洗时生成的工具调用 ID 格式；
+// 未实现时使用 "call_<seq>"。
+type ToolCallIDFormatter interface {
+	// FormatToolCallID 根据会话内序号（从 1 开始）生成工具调用 ID
+	FormatToolCallID(seq int) string
+}
+
+// SanitizeHistoryForProvider 清洗对话历史，使其可在另一个 Provider 上重放
+//
+// 中途切换 Provider 时（如 Gemini 开始、Claude 接续），源 Provider 产生的
+// 协议产物对目标 Provider 无效，直接发送会导致 400。本函数：
+//   - 移除所有 ThinkingBlock 与 RedactedThinkingBlock（推理内容与签名绑定源 Provider，无法迁移）
+//   - 按目标协议格式重新编号全部工具调用 ID，并同步改写对应 ToolResultBlock
+//     与 RoleTool 消息的 ToolCallID
+//   - 找不到对应调用的工具结果降级为文本块（保留内容，丢失结构；RoleTool 消息降级为 user 文本消息）
+//   - 服务端代码执行结果降级为文本块（代码与输出，目标 Provider 无法回放）
+//   - 移除清洗后为空的消息
+//
+// 有损部分：推理/思考过程全部丢弃，目标 Provider 无法看到源模型的思考；
+// 原始工具调用 ID 不保留。仅在切换 Provider 时调用，同一 Provider 内续聊无需清洗。
+//
+// 工具结果按出现顺序匹配同 ID 的调用，因此 Gemini 生成的重复 ID 也能正确配对。
+// 返回新的消息切片，不修改输入。
+func SanitizeHistoryForProvider(messages []llm.Message, target ProtocolAdapter) []llm.Message {
+	format := func(seq int) string { return fmt.Sprintf("call_%d", seq) }
+	if f, ok := target.(ToolCallIDFormatter); ok {
+		format = f.FormatToolCallID
+	}
+
+	var (
+		seq     int
+		pending = make(map[string][]string) // 原 ID → 待匹配的新 ID（按调用顺序）
+		result  = make([]llm.Message, 0, len(messages))
+	)
+
+	for _, msg := range messages {
+		// OpenAI 风格的 RoleTool 消息：与 ToolResultBlock 相同，按调用顺序改写 ToolCallID
+		if msg.Role == llm.RoleTool {
+			ids := pending[msg.ToolCallID]
+			if len(ids) == 0 {
+				result = append(result, llm.Message{Role: llm.RoleUser, Content: "[tool result] " + msg.GetContent(), Meta: msg.Meta})
+				continue
+			}
+			pending[msg.ToolCallID] = ids[1:]
+			msg.ToolCallID = ids[0]
+		}
+
+		if len(msg.ContentBlocks) == 0 {
+			result = append(result, msg)
+			continue
+		}
+
+		blocks := make([]llm.ContentBlock, 0, len(msg.ContentBlocks))
+		for _, block := range msg.ContentBlocks {
+			switch b := block.(type) {
+			case *llm.ThinkingBlock, *llm.RedactedThinkingBlock:
+				// 推理内容无法跨 Provider 迁移
+				continue
+
+			case *llm.ToolCall:
+				seq++
+				id := format(seq)
+				pending[b.ID] = append(pending[b.ID], id)
+				blocks = append(blocks, &llm.ToolCall{ID: id, Name: b.Name, Input: b.Input})
+
+			case *llm.ToolResultBlock:
+				ids := pending[b.ToolUseID]
+				if len(ids) == 0 {
+					blocks = append(blocks, &llm.TextBlock{Text: "[tool result] " + b.Content})
+					continue
+				}
+				pending[b.ToolUseID] = ids[1:]
+				blocks = append(blocks, &llm.ToolResultBlock{ToolUseID: ids[0], Name: b.Name, Content: b.Content, IsError: b.IsError})
+
+			case *llm.CodeExecutionResultBlock:
+				blocks = append(blocks, &llm.TextBlock{Text: "[code execution] " + b.Code + "\n" + b.Stdout + b.Stderr})
+
+			default:
+				blocks = append(blocks, block)
+			}
+		}
+
+		if len(blocks) == 0 {
+			if msg.Content == "" {
+				continue
+			}
+			blocks = nil
+		}
+		msg.ContentBlocks = blocks
+		result = append(result, msg)
+	}
+
+	return result
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestSanitizeHistoryForProvider(t *testing.T) {
+	history := []llm.Message{
+		{Role: llm.RoleSystem, Content: "Be brief"},
+		{Role: llm.RoleUser, Content: "Hi"},
+		// 仅包含思考内容的消息在清洗后被移除
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{&llm.ThinkingBlock{Thinking: "hmm"}}},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+			&llm.TextBlock{Text: "Checking"},
+			&llm.ToolCall{ID: "fc-abc", Name: "search", Input: map[string]any{"q": "go"}},
+		}},
+		{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolResultBlock{ToolUseID: "fc-abc", Content: "found", IsError: true},
+			// 找不到对应调用的结果降级为文本
+			&llm.ToolResultBlock{ToolUseID: "missing", Content: "orphan"},
+		}},
+	}
+
+	result := SanitizeHistoryForProvider(history, &mockAdapter{})
+
+	require.Len(t, result, 4)
+	assert.Equal(t, "Be brief", result[0].Content)
+
+	call := result[2].GetToolCalls()[0]
+	assert.Equal(t, "call_1", call.ID)
+	assert.Equal(t, "search", call.Name)
+
+	tr := result[3].GetToolResults()
+	require.Len(t, tr, 1)
+	assert.Equal(t, "call_1", tr[0].ToolUseID)
+	assert.True(t, tr[0].IsError)
+	assert.Equal(t, &llm.TextBlock{Text: "[tool result] orphan"}, result[3].ContentBlocks[1])
+
+	// 输入未被修改
+	assert.Equal(t, "fc-abc", history[3].GetToolCalls()[0].ID)
+}
+
+func TestSanitizeHistoryForProvider_RoleTool(t *testing.T) {
+	history := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather?"},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolCall{ID: "fc-1", Name: "weather", Input: map[string]any{"city": "Paris"}},
+			&llm.ToolCall{ID: "fc-2", Name: "weather", Input: map[string]any{"city": "Rome"}},
+		}},
+		{Role: llm.RoleTool, ToolCallID: "fc-1", Content: "sunny"},
+		{Role: llm.RoleTool, ToolCallID: "fc-2", Content: "rainy"},
+		// 找不到对应调用的 RoleTool 消息降级为 user 文本消息
+		{Role: llm.RoleTool, ToolCallID: "missing", Content: "orphan"},
+	}
+
+	result := SanitizeHistoryForProvider(history, &mockAdapter{})
+
+	require.Len(t, result, 5)
+	assert.Equal(t, llm.RoleTool, result[2].Role)
+	assert.Equal(t, "call_1", result[2].ToolCallID)
+	assert.Equal(t, "call_2", result[3].ToolCallID)
+	assert.Equal(t, llm.Message{Role: llm.RoleUser, Content: "[tool result] orphan"}, result[4])
+
+	// 输入未被修改
+	assert.Equal(t, "fc-1", history[2].ToolCallID)
+}
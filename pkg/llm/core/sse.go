@@ -113,12 +113,15 @@ func NewSSEParser(handler EventHandler) *SSEParser {
 	return &SSEParser{handler: handler}
 }
 
+// maxSSELineSize 单行 SSE 数据的最大长度（大型工具参数、内联图片等）
+const maxSSELineSize = 8 << 20
+
 // Parse 解析 SSE 流
 //
 // 通用流程：
-//  1. 逐行扫描流
+//  1. 逐行扫描流（跨读取边界的行由 bufio.Scanner 拼接）
 //  2. 解析 "event:" 行（Anthropic）
-//  3. 解析 "data:" 行
+//  3. 累积 "data:" 行，数据构成完整 JSON（或终止信号）、遇到空行或流结束时分发
 //  4. 检查终止信号（OpenAI [DONE]）
 //  5. JSON 解析数据
 //  6. 委托 handler 处理事件
@@ -131,8 +134,13 @@ func NewSSEParser(handler EventHandler) *SSEParser {
 // 行为：
 //   - 自动关闭 body
 //   - 自动关闭 events channel
-//   - JSON 解析失败静默忽略（继续处理下一行）
+//   - JSON 解析失败静默忽略（继续处理下一帧）
 //   - 遇到终止信号或 handler 返回 stop 时退出
+//   - 流在最后一帧之后没有换行或空行（连接被提前关闭）时，未分发的数据在 EOF 时补发，
+//     避免丢失最后的完成事件
+//   - 同一帧的多个 data: 行按 SSE 规范以换行拼接；字段冒号后的空格可省略
+//   - data: 与同一帧内最近的 event: 关联，空行（帧边界）重置事件类型；
+//     缺少 event: 的帧以空事件类型交给 handler（代理拆分或丢失 event: 行时不会误用上一帧的类型）
 //
 // 注意：
 //   - 此方法应在 goroutine 中调用
@@ -153,37 +161,31 @@ func (p *SSEParser) Parse(body io.ReadCloser, events chan<- *llm.Event) {
 	defer close(events)
 
 	scanner := bufio.NewScanner(body)
-	var currentEvent string
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
 
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// 解析事件类型（Anthropic 使用）
-		// 格式: event: message_start
-		if after, ok := strings.CutPrefix(line, "event: "); ok {
-			currentEvent = after
-			continue
-		}
+	var (
+		currentEvent string
+		pending      []string // 当前帧尚未分发的 data 行
+	)
 
-		// 解析数据行
-		// 格式: data: {"key": "value"}
-		if !strings.HasPrefix(line, "data: ") {
-			continue
+	// dispatch 分发累积的数据，返回是否应停止解析
+	dispatch := func() bool {
+		if len(pending) == 0 {
+			return false
 		}
-
-		data := strings.TrimPrefix(line, "data: ")
+		data := strings.Join(pending, "\n")
+		pending = pending[:0]
 
 		// 检查终止信号（OpenAI [DONE]）
 		if p.handler.ShouldStopOnData(data) {
 			events <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "stop"}
-			return
+			return true
 		}
 
-		// 解析 JSON 数据
+		// 解析 JSON 数据，失败时静默忽略
 		var payload map[string]any
 		if err := json.Unmarshal([]byte(data), &payload); err != nil {
-			// JSON 解析失败，静默忽略
-			continue
+			return false
 		}
 
 		// 委托 handler 处理事件
@@ -191,10 +193,65 @@ func (p *SSEParser) Parse(body io.ReadCloser, events chan<- *llm.Event) {
 		for _, event := range parsedEvents {
 			events <- event
 		}
+		return shouldStop
+	}
 
-		// 检查是否应该停止
-		if shouldStop {
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// 空行：帧结束，事件类型只作用于所在帧
+		if line == "" {
+			if dispatch() {
+				return
+			}
+			currentEvent = ""
+			continue
+		}
+
+		// 解析事件类型（Anthropic 使用）
+		// 格式: event: message_start
+		if value, ok := sseField(line, "event"); ok {
+			// 上一帧缺少结束空行时先分发
+			if dispatch() {
+				return
+			}
+			currentEvent = value
+			continue
+		}
+
+		// 解析数据行
+		// 格式: data: {"key": "value"}
+		value, ok := sseField(line, "data")
+		if !ok {
+			continue
+		}
+
+		// 新行自身已完整时，丢弃无法解析的残留数据（与逐行忽略无效 JSON 的行为一致）
+		if len(pending) > 0 && p.completeData(value) {
+			pending = pending[:0]
+		}
+		pending = append(pending, value)
+
+		// 数据已完整时立即分发，兼容帧之间没有空行的实现
+		if p.completeData(strings.Join(pending, "\n")) && dispatch() {
 			return
 		}
 	}
+
+	// EOF：补发未以空行结束的最后一帧
+	dispatch()
+}
+
+// completeData 判断数据是否构成可分发的完整帧（终止信号或合法 JSON）
+func (p *SSEParser) completeData(data string) bool {
+	return p.handler.ShouldStopOnData(data) || json.Valid([]byte(data))
+}
+
+// sseField 解析 "name: value" 格式的 SSE 字段，冒号后的单个空格可省略
+func sseField(line, name string) (string, bool) {
+	value, ok := strings.CutPrefix(line, name+":")
+	if !ok {
+		return "", false
+	}
+	return strings.TrimPrefix(value, " "), true
 }
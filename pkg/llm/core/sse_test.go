@@ -4,6 +4,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
@@ -122,6 +123,32 @@ data: {"delta": {"type": "text_delta", "text": "World"}}
 	assert.Equal(t, "content_block_delta", handler.calls[0].eventType)
 }
 
+func TestSSEParser_Parse_EventTypeResetsAtFrameBoundary(t *testing.T) {
+	handler := newMockEventHandler()
+	parser := core.NewSSEParser(handler)
+
+	// 第二帧缺少 event: 行，第三帧的 event: 与 data: 之间多出一个无关字段
+	sseData := "event: content_block_delta\n" +
+		"data: {\"n\": 1}\n\n" +
+		"data: {\"n\": 2}\n\n" +
+		"event: message_delta\n" +
+		"id: 3\n" +
+		"data: {\"n\": 3}\n\n" +
+		"event: ping\n\n" +
+		"data: {\"n\": 4}\n"
+	events := make(chan *llm.Event, 10)
+
+	go parser.Parse(io.NopCloser(strings.NewReader(sseData)), events)
+	for range events {
+	}
+
+	require.Len(t, handler.calls, 4)
+	assert.Equal(t, "content_block_delta", handler.calls[0].eventType)
+	assert.Empty(t, handler.calls[1].eventType, "stray data must not inherit the previous frame's event")
+	assert.Equal(t, "message_delta", handler.calls[2].eventType)
+	assert.Empty(t, handler.calls[3].eventType, "event without data must not leak into the next frame")
+}
+
 func TestSSEParser_Parse_InvalidJSON(t *testing.T) {
 	handler := newMockEventHandler()
 	parser := core.NewSSEParser(handler)
@@ -264,6 +291,49 @@ data: {"valid": true}
 	assert.Equal(t, true, handler.calls[0].data["valid"])
 }
 
+func TestSSEParser_Parse_UnterminatedFinalFrame(t *testing.T) {
+	handler := newMockEventHandler()
+	parser := core.NewSSEParser(handler)
+
+	// 最后一帧没有换行，且冒号后省略空格
+	reader := io.NopCloser(strings.NewReader("data: {\"first\": true}\n\ndata:{\"last\": true}"))
+	events := make(chan *llm.Event, 10)
+
+	go parser.Parse(reader, events)
+	for range events {
+	}
+
+	require.Len(t, handler.calls, 2)
+	assert.Equal(t, true, handler.calls[1].data["last"])
+}
+
+func TestSSEParser_Parse_TruncatedFinishFrame(t *testing.T) {
+	parser := core.NewSSEParser(openai.NewEventHandler())
+
+	// 完成帧的 JSON 被拆成两个 data 行，流在没有结束空行与 [DONE] 的情况下关闭；
+	// 逐字节读取模拟帧跨越多次 TCP 读取
+	sseData := "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{},\n" +
+		"data: \"finish_reason\":\"stop\"}]}"
+	reader := io.NopCloser(iotest.OneByteReader(strings.NewReader(sseData)))
+	events := make(chan *llm.Event, 10)
+
+	go parser.Parse(reader, events)
+
+	var collected []*llm.Event //nolint:prealloc // channel 收集数量未知
+	for e := range events {
+		collected = append(collected, e)
+	}
+
+	textEvents := filterEventsByType(collected, llm.EventTypeText)
+	require.Len(t, textEvents, 1)
+	assert.Equal(t, "Hi", textEvents[0].TextDelta)
+
+	doneEvents := filterEventsByType(collected, llm.EventTypeDone)
+	require.Len(t, doneEvents, 1, "最后一帧的完成事件不应丢失")
+	assert.Equal(t, "stop", doneEvents[0].FinishReason)
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 联合测试 - SSEParser + 真实 EventHandler
 // ═══════════════════════════════════════════════════════════════════════════
@@ -397,6 +467,40 @@ data: {"type":"message_stop"}
 	assert.NotEmpty(t, doneEvents, "Expected done events")
 }
 
+func TestSSEParser_Integration_Anthropic_MispairedLines(t *testing.T) {
+	parser := core.NewSSEParser(anthropic.NewEventHandler())
+
+	// 代理丢失了部分 event: 行：data 按自身的 type 处理，不沿用上一帧的事件类型
+	sseData := `event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" World"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+	events := make(chan *llm.Event, 10)
+	go parser.Parse(io.NopCloser(strings.NewReader(sseData)), events)
+
+	var collected []*llm.Event //nolint:prealloc // channel 收集数量未知
+	for e := range events {
+		collected = append(collected, e)
+	}
+
+	textEvents := filterEventsByType(collected, llm.EventTypeText)
+	require.Len(t, textEvents, 2)
+	assert.Equal(t, " World", textEvents[1].TextDelta)
+
+	doneEvents := filterEventsByType(collected, llm.EventTypeDone)
+	require.NotEmpty(t, doneEvents)
+	assert.Equal(t, "stop", doneEvents[0].FinishReason)
+}
+
 func TestSSEParser_Integration_Anthropic_ToolCall(t *testing.T) {
 	handler := anthropic.NewEventHandler()
 	parser := core.NewSSEParser(handler)
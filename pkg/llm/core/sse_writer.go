@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// SSE 转发
+// ═══════════════════════════════════════════════════════════════════════════
+
+// SSEDone 流结束时写出的终止数据（data: [DONE]）
+const SSEDone = "[DONE]"
+
+// WriteEventsAsSSE 将事件流以 Server-Sent Events 写入 HTTP 响应，用于向浏览器转发
+//
+// 输出格式：
+//   - 每个事件一帧 "data: <JSON>\n\n"，JSON 为 llm.Event 的序列化结果（type 字段区分
+//     事件类型，可直接用 EventSource 的 onmessage 接收），写入后立即刷新
+//   - 错误事件的 error 字段为错误信息（Event.Error 不参与序列化）
+//   - 事件流结束后写出 "data: [DONE]\n\n"
+//
+// 首次写入前设置 Content-Type: text/event-stream 等响应头。ctx 通常为请求的
+// r.Context()：客户端断开后停止写入并返回 ctx.Err()，剩余事件在后台丢弃，
+// 不阻塞上游 Provider。写入失败时同样丢弃剩余事件并返回错误；
+// 流中出现错误事件时写完 [DONE] 后返回 [llm.StreamError]。
+//
+// 使用示例：
+//
+//	http.HandleFunc("/chat", func(w http.ResponseWriter, r *http.Request) {
+//	    events, err := p.Stream(r.Context(), messages, opts)
+//	    if err != nil {
+//	        http.Error(w, err.Error(), http.StatusBadGateway)
+//	        return
+//	    }
+//	    if err := core.WriteEventsAsSSE(r.Context(), events, w); err != nil {
+//	        slog.Warn("sse stream ended", "error", err)
+//	    }
+//	})
+func WriteEventsAsSSE(ctx context.Context, events <-chan *llm.Event, w http.ResponseWriter) error {
+	h := w.Header()
+	h.Set("Content-Type", DefaultStreamAccept)
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // 关闭 Nginx 等反向代理的缓冲
+
+	rc := http.NewResponseController(w)
+	write := func(data []byte) error {
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+	// 提前返回时继续消费事件，避免上游阻塞
+	drain := func() {
+		go func() {
+			for range events {
+				// 丢弃剩余事件
+			}
+		}()
+	}
+
+	var streamErr error
+	for {
+		select {
+		case <-ctx.Done():
+			drain()
+			return ctx.Err()
+
+		case event, ok := <-events:
+			if !ok {
+				if err := write([]byte(SSEDone)); err != nil {
+					return err
+				}
+				return streamErr
+			}
+			if event == nil {
+				continue
+			}
+
+			if event.Type == llm.EventTypeError {
+				if event.ErrorMessage == "" && event.Error != nil {
+					e := *event
+					e.ErrorMessage = event.Error.Error()
+					event = &e
+				}
+				if streamErr == nil {
+					streamErr = llm.NewStreamError(event.ErrorMessage, event.Error)
+				}
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				drain()
+				return err
+			}
+			if err := write(data); err != nil {
+				drain()
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestWriteEventsAsSSE(t *testing.T) {
+	events := make(chan *llm.Event, 4)
+	events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "Hello"}
+	events <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{ID: "call_1", Name: "search", ArgumentsDelta: `{"q":`}}
+	events <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "stop"}
+	close(events)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, WriteEventsAsSSE(context.Background(), events, rec))
+
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, `data: {"type":"text","text_delta":"Hello"}`+"\n\n"+
+		`data: {"type":"tool_call","tool_call":{"index":0,"id":"call_1","name":"search","arguments_delta":"{\"q\":"}}`+"\n\n"+
+		`data: {"type":"done","finish_reason":"stop"}`+"\n\n"+
+		"data: [DONE]\n\n", rec.Body.String())
+}
+
+func TestWriteEventsAsSSE_ErrorEvent(t *testing.T) {
+	events := make(chan *llm.Event, 2)
+	events <- &llm.Event{Type: llm.EventTypeError, Error: errors.New("upstream reset")}
+	close(events)
+
+	rec := httptest.NewRecorder()
+	err := WriteEventsAsSSE(context.Background(), events, rec)
+
+	var streamErr *llm.StreamError
+	require.ErrorAs(t, err, &streamErr)
+	assert.Equal(t, `data: {"type":"error","error":"upstream reset"}`+"\n\n"+"data: [DONE]\n\n", rec.Body.String())
+}
+
+func TestWriteEventsAsSSE_ClientDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan *llm.Event)
+
+	done := make(chan error, 1)
+	rec := httptest.NewRecorder()
+	go func() { done <- WriteEventsAsSSE(ctx, events, rec) }()
+
+	events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "Hi"}
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// 断开后剩余事件被后台消费，上游不会阻塞
+	events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "ignored"}
+	close(events)
+	assert.NotContains(t, rec.Body.String(), "[DONE]")
+	assert.NotContains(t, rec.Body.String(), "ignored")
+}
@@ -0,0 +1,24 @@
+package core
+
+import "github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 流式响应信息
+// ═══════════════════════════════════════════════════════════════════════════
+
+// attachStreamMeta 为流中的 Done 与 Error 事件附加初始响应信息
+//
+// 返回新的 channel，其余事件原样透传；输入 channel 关闭后输出随之关闭。
+func attachStreamMeta(in <-chan *llm.Event, meta *llm.StreamMeta) <-chan *llm.Event {
+	out := make(chan *llm.Event, 10)
+	go func() {
+		defer close(out)
+		for event := range in {
+			if event != nil && (event.Type == llm.EventTypeDone || event.Type == llm.EventTypeError) {
+				event.StreamMeta = meta
+			}
+			out <- event
+		}
+	}()
+	return out
+}
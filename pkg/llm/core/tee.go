@@ -0,0 +1,63 @@
+package core
+
+import "github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 事件流分发
+// ═══════════════════════════════════════════════════════════════════════════
+
+// teeBuffer Tee 输出 channel 的缓冲大小
+const teeBuffer = 10
+
+// Tee 将一个事件流复制分发给 n 个独立的 channel，用于同时显示与记录同一流
+//
+// 每个事件以浅拷贝发送给全部消费者，一个消费者修改事件字段不影响其他消费者
+// （ToolCall 等指针字段仍共享，不应修改其内容）。输入关闭后全部输出随之关闭。
+// n <= 0 时返回 nil，不读取输入。
+//
+// 慢消费者策略：阻塞。每个输出有 10 个事件的缓冲，缓冲满时等待该消费者读取，
+// 因此不会丢失事件，但最慢的消费者决定整体速度。每个输出都必须读取到关闭，
+// 否则分发 goroutine 与上游 Provider 会被阻塞。
+//
+// 使用示例：
+//
+//	outs := core.Tee(events, 2)
+//	go func() {
+//	    for event := range outs[1] {
+//	        logger.Info("event", "type", event.Type)
+//	    }
+//	}()
+//	for event := range outs[0] {
+//	    fmt.Print(event.TextDelta)
+//	}
+func Tee(events <-chan *llm.Event, n int) []<-chan *llm.Event {
+	if n <= 0 {
+		return nil
+	}
+
+	outs := make([]chan *llm.Event, n)
+	result := make([]<-chan *llm.Event, n)
+	for i := range outs {
+		outs[i] = make(chan *llm.Event, teeBuffer)
+		result[i] = outs[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for event := range events {
+			for _, out := range outs {
+				if event == nil {
+					out <- nil
+					continue
+				}
+				e := *event
+				out <- &e
+			}
+		}
+	}()
+	return result
+}
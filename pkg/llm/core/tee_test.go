@@ -0,0 +1,53 @@
+package core
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestTee(t *testing.T) {
+	in := make(chan *llm.Event)
+	go func() {
+		defer close(in)
+		for _, text := range []string{"Hello", ", ", "world"} {
+			in <- &llm.Event{Type: llm.EventTypeText, TextDelta: text}
+		}
+		in <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "stop"}
+	}()
+
+	outs := Tee(in, 2)
+	require.Len(t, outs, 2)
+
+	// 两个消费者并发读取，均收到完整的事件序列
+	received := make([][]*llm.Event, 2)
+	var wg sync.WaitGroup
+	for i, out := range outs {
+		wg.Go(func() {
+			for event := range out {
+				received[i] = append(received[i], event)
+			}
+		})
+	}
+	wg.Wait()
+
+	for i := range outs {
+		require.Len(t, received[i], 4, "consumer %d", i)
+		assert.Equal(t, "Hello", received[i][0].TextDelta)
+		assert.Equal(t, "world", received[i][2].TextDelta)
+		assert.Equal(t, "stop", received[i][3].FinishReason)
+	}
+
+	// 每个消费者拿到独立的事件副本
+	assert.NotSame(t, received[0][0], received[1][0])
+	received[0][0].TextDelta = "changed"
+	assert.Equal(t, "Hello", received[1][0].TextDelta)
+}
+
+func TestTee_ZeroConsumers(t *testing.T) {
+	assert.Nil(t, Tee(make(chan *llm.Event), 0))
+}
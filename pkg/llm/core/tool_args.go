@@ -0,0 +1,278 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 工具参数累积
+// ═══════════════════════════════════════════════════════════════════════════
+
+// AccumulateToolArgs 为流式工具调用事件补充累积参数
+//
+// 按 ToolCallDelta.Index 累积 ArgumentsDelta，并在每个工具调用事件上填充：
+//   - ArgumentsSoFar: 截至当前的参数字符串
+//   - PartialArguments: 尽力解析的部分参数（自动补齐未闭合的字符串与括号）
+//
+// 每段增量只扫描一次；参数超过 4 KiB 后按增长比例节流解析（累积长度较上次解析增长
+// 1/4 以上才重新解析），未重新解析的事件 PartialArguments 为 nil，使用方沿用上一次
+// 结果即可，总开销与参数长度成线性。流结束后需要最终参数时解析 ArgumentsSoFar。
+//
+// 原始 ArgumentsDelta 保持不变，其他事件原样透传。
+// 适用于 UI 实时展示 "calling search with query: lon..." 之类的场景。
+func AccumulateToolArgs(events <-chan *llm.Event) <-chan *llm.Event {
+	out := make(chan *llm.Event, 10)
+
+	go func() {
+		defer close(out)
+
+		args := make(map[int]*partialJSON)
+		for event := range events {
+			if event.Type == llm.EventTypeToolCall && event.ToolCall != nil {
+				tc := event.ToolCall
+				p, ok := args[tc.Index]
+				if !ok {
+					p = &partialJSON{}
+					args[tc.Index] = p
+				}
+				p.feed(tc.ArgumentsDelta)
+
+				tc.ArgumentsSoFar = p.buf.String()
+				if p.due() {
+					tc.PartialArguments = p.parse()
+				}
+			}
+			out <- event
+		}
+	}()
+
+	return out
+}
+
+// PartialToolArgs 单个工具调用参数的渐进解析结果
+type PartialToolArgs[T any] struct {
+	Value T      // 由截至当前的参数解码，尚未到达的字段为零值，字符串字段可能只有前缀
+	Raw   string // 截至当前的参数字符串
+	Done  bool   // 流已结束，Value 为最终参数（最后一个结果）
+}
+
+// StreamToolArgs 渐进解析指定工具调用的参数
+//
+// 基于 [AccumulateToolArgs] 与 [ParsePartialJSON]：Index 为 index 的工具调用每收到
+// 一段参数增量、且截至当前的参数可尽力解析时，解码为 T 并发送；流结束时发送
+// Done 为 true 的最终结果（该工具调用未出现时不发送）。适用于大参数的单个工具调用，
+// 如 write_file 工具边生成边写入文件内容，无需等待完整参数。
+//
+// 该函数消费整个事件流，其他事件被丢弃；需要同时处理文本等事件时，
+// 自行转发事件后再传入。参数较长时部分解析按增长比例节流（见 [AccumulateToolArgs]），
+// 发送频率随之降低，最终结果不受影响。
+//
+// 使用示例：
+//
+//	type writeFile struct {
+//	    Path    string `json:"path"`
+//	    Content string `json:"content"`
+//	}
+//
+//	written := 0
+//	for p := range core.StreamToolArgs[writeFile](events, 0) {
+//	    if len(p.Value.Content) > written { // 停在转义序列中间时可能暂时回退
+//	        f.WriteString(p.Value.Content[written:])
+//	        written = len(p.Value.Content)
+//	    }
+//	}
+func StreamToolArgs[T any](events <-chan *llm.Event, index int) <-chan PartialToolArgs[T] {
+	out := make(chan PartialToolArgs[T], 10)
+
+	go func() {
+		defer close(out)
+
+		var (
+			last PartialToolArgs[T]
+			seen bool
+		)
+		for event := range AccumulateToolArgs(events) {
+			tc := event.ToolCall
+			if event.Type != llm.EventTypeToolCall || tc == nil || tc.Index != index {
+				continue
+			}
+			seen = true
+			last.Raw = tc.ArgumentsSoFar
+			if tc.ArgumentsDelta == "" || tc.PartialArguments == nil {
+				continue
+			}
+			if value, ok := decodeAs[T](tc.PartialArguments); ok {
+				last.Value = value
+				out <- last
+			}
+		}
+		if !seen {
+			return
+		}
+
+		// 完整参数直接解码；不完整（如流被截断）时沿用最后一次部分解析的结果
+		var value T
+		if err := json.Unmarshal([]byte(last.Raw), &value); err == nil {
+			last.Value = value
+		}
+		last.Done = true
+		out <- last
+	}()
+
+	return out
+}
+
+// decodeAs 将部分参数解码为 T
+func decodeAs[T any](args map[string]any) (T, bool) {
+	var value T
+	data, err := json.Marshal(args)
+	if err != nil {
+		return value, false
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, false
+	}
+	return value, true
+}
+
+// ParsePartialJSON 尽力解析不完整的 JSON 对象
+//
+// 补齐未闭合的字符串、对象和数组后解码；仍无法解码时（如停在键名或
+// 字面量中间）回退到最近一个完整成员处截断。非对象或完全无法解析时返回 nil。
+func ParsePartialJSON(s string) map[string]any {
+	var p partialJSON
+	p.feed(s)
+	return p.parse()
+}
+
+// partialParseThreshold 参数长度超过该值后按增长比例节流解析
+const partialParseThreshold = 4 << 10
+
+// partialJSON 增量扫描不完整的 JSON 对象
+//
+// 逐段喂入并保留扫描状态（待闭合括号、字符串与转义、最近可截断位置），
+// 每段增量只扫描一次，解析时按当前状态补齐后解码。
+type partialJSON struct {
+	buf      strings.Builder
+	started  bool   // 是否已遇到开头的 '{'
+	invalid  bool   // 首个非空白字符不是 '{'
+	stack    []byte // 待闭合的括号
+	inString bool
+	escaped  bool
+
+	safePos   int    // 最近一个可截断位置
+	safeStack []byte // 截断处待闭合的括号
+
+	parsedLen int // 上次解析时的累积长度
+}
+
+// feed 喂入新的参数片段
+func (p *partialJSON) feed(chunk string) {
+	base := p.buf.Len()
+	p.buf.WriteString(chunk)
+	if p.invalid {
+		return
+	}
+
+	for i := 0; i < len(chunk); i++ {
+		ch := chunk[i]
+		if !p.started {
+			switch ch {
+			case ' ', '\t', '\n', '\r':
+				continue
+			case '{':
+				p.started = true
+			default:
+				p.invalid = true
+				return
+			}
+		}
+
+		if p.inString {
+			switch {
+			case p.escaped:
+				p.escaped = false
+			case ch == '\\':
+				p.escaped = true
+			case ch == '"':
+				p.inString = false
+			}
+			continue
+		}
+
+		switch ch {
+		case '"':
+			p.inString = true
+		case '{':
+			p.stack = append(p.stack, '}')
+			p.markSafe(base + i + 1)
+		case '[':
+			p.stack = append(p.stack, ']')
+			p.markSafe(base + i + 1)
+		case '}', ']':
+			if len(p.stack) > 0 {
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+			p.markSafe(base + i + 1)
+		case ',':
+			p.markSafe(base + i)
+		}
+	}
+}
+
+// markSafe 记录可截断位置及其待闭合的括号
+func (p *partialJSON) markSafe(pos int) {
+	p.safePos = pos
+	p.safeStack = append(p.safeStack[:0], p.stack...)
+}
+
+// due 是否应重新解析：较短时每次解析，超过阈值后累积长度较上次增长 1/4 以上才解析
+func (p *partialJSON) due() bool {
+	n := p.buf.Len()
+	return n <= partialParseThreshold || n-p.parsedLen >= p.parsedLen/4
+}
+
+// parse 按当前扫描状态补齐并解码
+func (p *partialJSON) parse() map[string]any {
+	s := p.buf.String()
+	p.parsedLen = len(s)
+	if !p.started || p.invalid {
+		return nil
+	}
+
+	// 1. 直接补齐
+	candidate := s
+	if p.inString {
+		if p.escaped {
+			candidate = candidate[:len(candidate)-1]
+		}
+		candidate += `"`
+	}
+	if result := decodeObject(candidate + closers(p.stack)); result != nil {
+		return result
+	}
+
+	// 2. 回退到最近的完整成员
+	return decodeObject(s[:p.safePos] + closers(p.safeStack))
+}
+
+// closers 按栈逆序生成闭合括号
+func closers(stack []byte) string {
+	b := make([]byte, len(stack))
+	for i, ch := range stack {
+		b[len(stack)-1-i] = ch
+	}
+	return string(b)
+}
+
+// decodeObject 解码 JSON 对象，失败返回 nil
+func decodeObject(s string) map[string]any {
+	var result map[string]any
+	if err := json.Unmarshal([]byte(s), &result); err != nil {
+		return nil
+	}
+	return result
+}
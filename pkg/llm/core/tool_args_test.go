@@ -0,0 +1,186 @@
+package core
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestAccumulateToolArgs(t *testing.T) {
+	in := make(chan *llm.Event, 10)
+	deltas := []string{`{"que`, `ry":"lon`, `don","limit`, `":5}`}
+
+	in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ID: "call_1", Name: "search"}}
+	for _, d := range deltas {
+		in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ArgumentsDelta: d}}
+	}
+	in <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "tool_calls"}
+	close(in)
+
+	var events []*llm.Event
+	for e := range AccumulateToolArgs(in) {
+		events = append(events, e)
+	}
+	require.Len(t, events, 6)
+
+	// 累积字符串逐步增长，原始增量保持不变
+	want := []string{"", `{"que`, `{"query":"lon`, `{"query":"london","limit`, `{"query":"london","limit":5}`}
+	for i, w := range want {
+		assert.Equal(t, w, events[i].ToolCall.ArgumentsSoFar, "event %d", i)
+	}
+	assert.Equal(t, `ry":"lon`, events[2].ToolCall.ArgumentsDelta)
+
+	// 部分解析
+	assert.Equal(t, map[string]any{"query": "lon"}, events[2].ToolCall.PartialArguments)
+	assert.Equal(t, map[string]any{"query": "london"}, events[3].ToolCall.PartialArguments)
+	assert.Equal(t, map[string]any{"query": "london", "limit": float64(5)}, events[4].ToolCall.PartialArguments)
+
+	assert.Equal(t, llm.EventTypeDone, events[5].Type)
+}
+
+func TestAccumulateToolArgs_MultipleCalls(t *testing.T) {
+	in := make(chan *llm.Event, 10)
+	in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ArgumentsDelta: `{"a":`}}
+	in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 1, ArgumentsDelta: `{"b":`}}
+	in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ArgumentsDelta: `1}`}}
+	close(in)
+
+	var last []string
+	for e := range AccumulateToolArgs(in) {
+		last = append(last, e.ToolCall.ArgumentsSoFar)
+	}
+
+	assert.Equal(t, []string{`{"a":`, `{"b":`, `{"a":1}`}, last)
+}
+
+func TestStreamToolArgs(t *testing.T) {
+	type writeFile struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+
+	// 大参数按 64 字节切分为增量，另有一个并行的工具调用与文本事件
+	content := strings.Repeat("line of generated file content\n", 200)
+	raw, err := json.Marshal(writeFile{Path: "out.txt", Content: content})
+	require.NoError(t, err)
+
+	in := make(chan *llm.Event, 10)
+	go func() {
+		defer close(in)
+		in <- &llm.Event{Type: llm.EventTypeText, TextDelta: "Writing the file."}
+		in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ID: "call_1", Name: "write_file"}}
+		in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 1, ArgumentsDelta: `{"other":1}`}}
+		for chunk := range slices.Chunk(raw, 64) {
+			in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ArgumentsDelta: string(chunk)}}
+		}
+		in <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "tool_calls"}
+	}()
+
+	var results []PartialToolArgs[writeFile]
+	for p := range StreamToolArgs[writeFile](in, 0) {
+		results = append(results, p)
+	}
+	require.Greater(t, len(results), 10, "参数逐段解析")
+
+	// 内容逐步增长且始终为最终内容的前缀，可直接追加写入（节流时剩余部分由最终结果补齐）
+	var written strings.Builder
+	for i, p := range results {
+		assert.Equal(t, i == len(results)-1, p.Done)
+		assert.True(t, strings.HasPrefix(content, p.Value.Content))
+		if n := written.Len(); len(p.Value.Content) > n {
+			written.WriteString(p.Value.Content[n:])
+		}
+	}
+	assert.Less(t, len(results[len(results)/2].Value.Content), len(content), "中途只有部分内容")
+
+	final := results[len(results)-1]
+	assert.True(t, final.Done)
+	assert.Equal(t, writeFile{Path: "out.txt", Content: content}, final.Value)
+	assert.Equal(t, string(raw), final.Raw)
+	assert.Equal(t, content, written.String())
+}
+
+func TestAccumulateToolArgs_Throttle(t *testing.T) {
+	content := strings.Repeat("x", 256<<10)
+	raw, err := json.Marshal(map[string]any{"content": content})
+	require.NoError(t, err)
+
+	in := make(chan *llm.Event)
+	go func() {
+		defer close(in)
+		for chunk := range slices.Chunk(raw, 16) {
+			in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{ArgumentsDelta: string(chunk)}}
+		}
+	}()
+
+	var events, parsed int
+	var last map[string]any
+	for e := range AccumulateToolArgs(in) {
+		events++
+		if e.ToolCall.PartialArguments != nil {
+			parsed++
+			last = e.ToolCall.PartialArguments
+		}
+	}
+
+	// 超过阈值后按增长比例解析：解析次数远少于事件数
+	assert.Greater(t, events, 16000)
+	assert.Less(t, parsed, partialParseThreshold/16+100)
+	require.NotNil(t, last)
+	assert.True(t, strings.HasPrefix(content, last["content"].(string)))
+}
+
+func TestPartialJSON_Incremental(t *testing.T) {
+	raw := `  {"a":[1,{"b":"c\\\"d"}],"e":"f`
+	for size := 1; size <= len(raw); size++ {
+		var p partialJSON
+		for chunk := range slices.Chunk([]byte(raw), size) {
+			p.feed(string(chunk))
+		}
+		assert.Equal(t, ParsePartialJSON(raw), p.parse(), "chunk size %d", size)
+	}
+	assert.Equal(t, map[string]any{"a": []any{float64(1), map[string]any{"b": `c\"d`}}, "e": "f"}, ParsePartialJSON(raw))
+}
+
+func TestStreamToolArgs_NoMatchingCall(t *testing.T) {
+	in := make(chan *llm.Event, 2)
+	in <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ArgumentsDelta: `{"a":1}`}}
+	close(in)
+
+	var count int
+	for range StreamToolArgs[map[string]any](in, 1) {
+		count++
+	}
+	assert.Zero(t, count)
+}
+
+func TestParsePartialJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{"空", "", nil},
+		{"非对象", `[1,2`, nil},
+		{"仅左括号", `{`, map[string]any{}},
+		{"停在冒号", `{"a":`, map[string]any{}},
+		{"未闭合字符串值", `{"a":"he`, map[string]any{"a": "he"}},
+		{"未闭合转义", `{"a":"x\`, map[string]any{"a": "x"}},
+		{"停在键名", `{"a":1,"b`, map[string]any{"a": float64(1)}},
+		{"嵌套数组", `{"a":[1,{"b":"c`, map[string]any{"a": []any{float64(1), map[string]any{"b": "c"}}}},
+		{"不完整字面量", `{"a":1,"b":tr`, map[string]any{"a": float64(1)}},
+		{"完整", `{"a":true}`, map[string]any{"a": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParsePartialJSON(tt.input))
+		})
+	}
+}
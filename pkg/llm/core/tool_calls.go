@@ -0,0 +1,49 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 工具调用数量限制
+// ═══════════════════════════════════════════════════════════════════════════
+
+// LimitToolCalls 按 Options.MaxToolCalls 限制响应中的工具调用数量
+//
+// 超出上限时：
+//   - 默认只保留前 MaxToolCalls 个 ToolCall 块（其他内容块不变），并设置 Response.ToolCallsTruncated
+//   - StrictMaxToolCalls 为 true 时不修改响应，返回 [llm.ResponseError]（字段 "tool_calls"，
+//     包装 [llm.ErrTooManyToolCalls]）
+//
+// [BaseClient.Complete] 自动调用，返回错误时仍返回响应。
+func LimitToolCalls(resp *llm.Response, opts *llm.Options) error {
+	if resp == nil || opts == nil || opts.MaxToolCalls <= 0 {
+		return nil
+	}
+
+	count := len(resp.Message.GetToolCalls())
+	if count <= opts.MaxToolCalls {
+		return nil
+	}
+	if opts.StrictMaxToolCalls {
+		return llm.NewResponseError("tool_calls",
+			fmt.Errorf("%w: got %d, max %d", llm.ErrTooManyToolCalls, count, opts.MaxToolCalls))
+	}
+
+	blocks := make([]llm.ContentBlock, 0, len(resp.Message.ContentBlocks))
+	kept := 0
+	for _, block := range resp.Message.ContentBlocks {
+		if _, ok := block.(*llm.ToolCall); ok {
+			if kept == opts.MaxToolCalls {
+				continue
+			}
+			kept++
+		}
+		blocks = append(blocks, block)
+	}
+	resp.Message.ContentBlocks = blocks
+	resp.ToolCallsTruncated = true
+	return nil
+}
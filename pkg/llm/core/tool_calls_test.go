@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// toolCallResponse 构造包含 n 个工具调用的响应
+func toolCallResponse(n int) *llm.Response {
+	blocks := []llm.ContentBlock{&llm.TextBlock{Text: "Calling tools"}}
+	for i := range n {
+		blocks = append(blocks, &llm.ToolCall{ID: string(rune('a' + i)), Name: "search"})
+	}
+	return &llm.Response{Message: llm.Message{Role: llm.RoleAssistant, ContentBlocks: blocks}, FinishReason: "tool_calls"}
+}
+
+func TestLimitToolCalls(t *testing.T) {
+	t.Run("截断到前 N 个", func(t *testing.T) {
+		resp := toolCallResponse(5)
+		require.NoError(t, LimitToolCalls(resp, &llm.Options{MaxToolCalls: 2}))
+
+		calls := resp.Message.GetToolCalls()
+		require.Len(t, calls, 2)
+		assert.Equal(t, "a", calls[0].ID)
+		assert.Equal(t, "b", calls[1].ID)
+		assert.Equal(t, "Calling tools", resp.Message.GetContent())
+		assert.True(t, resp.ToolCallsTruncated)
+	})
+
+	t.Run("严格模式返回错误", func(t *testing.T) {
+		resp := toolCallResponse(5)
+		err := LimitToolCalls(resp, &llm.Options{MaxToolCalls: 2, StrictMaxToolCalls: true})
+
+		require.ErrorIs(t, err, llm.ErrTooManyToolCalls)
+		assert.True(t, llm.IsResponseError(err))
+		assert.Contains(t, err.Error(), "got 5, max 2")
+		assert.Len(t, resp.Message.GetToolCalls(), 5)
+		assert.False(t, resp.ToolCallsTruncated)
+	})
+
+	t.Run("未超限或未设置", func(t *testing.T) {
+		resp := toolCallResponse(2)
+		require.NoError(t, LimitToolCalls(resp, &llm.Options{MaxToolCalls: 2, StrictMaxToolCalls: true}))
+		require.NoError(t, LimitToolCalls(resp, &llm.Options{}))
+		require.NoError(t, LimitToolCalls(resp, nil))
+		assert.Len(t, resp.Message.GetToolCalls(), 2)
+		assert.False(t, resp.ToolCallsTruncated)
+	})
+}
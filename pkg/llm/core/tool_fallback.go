@@ -0,0 +1,164 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// JSON 模式工具调用降级
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ApplyToolFallback 对 Options.ToolFallbackMode 为 json 的请求应用工具调用降级
+//
+// ToolFallbackMode 为 auto（或 Options.ToolFallback）时，由 llm.CheckCapabilities
+// 在模型不支持工具时先改为 json，再经此处降级。
+//
+// 适用于不支持原生工具调用、但能遵循指令输出 JSON 的模型（如本地 Llama）：
+//   - 移除 Tools，将工具定义与输出格式说明追加到系统提示
+//   - 未指定 ResponseFormat 时开启 json_object 模式
+//   - 历史中的 ToolCall / ToolResultBlock 改写为纯文本，避免发送 tool_calls 字段；
+//     OpenAI 风格的 RoleTool 消息先转换为 ToolResultBlock（见 normalizeToolMessages）再改写
+//
+// 模型按约定输出 {"tool": "<name>", "arguments": {...}} 或 {"answer": "<text>"}，
+// 由 [ParseToolFallback] 还原。未开启或没有工具时原样返回，applied 为 false。
+// 返回的消息与 Options 均为副本，不修改调用方对象。
+func ApplyToolFallback(messages []llm.Message, opts *llm.Options) ([]llm.Message, *llm.Options, bool) {
+	if opts == nil || opts.ToolFallbackMode != llm.ToolFallbackModeJSON || len(opts.Tools) == 0 {
+		return messages, opts, false
+	}
+
+	// 系统提示（Options.System 优先，其次为系统消息）
+	system := opts.System
+	if system == "" {
+		for _, msg := range messages {
+			if msg.Role == llm.RoleSystem {
+				system = msg.Content
+				break
+			}
+		}
+	}
+	if system != "" {
+		system += "\n\n"
+	}
+
+	downgraded := *opts
+	downgraded.System = system + jsonToolFallbackPrompt(opts.Tools)
+	downgraded.Tools = nil
+	if downgraded.ResponseFormat == nil {
+		downgraded.ResponseFormat = &llm.ResponseFormat{Type: "json_object"}
+	}
+
+	return toolFallbackMessages(normalizeToolMessages(messages)), &downgraded, true
+}
+
+// ParseToolFallback 将降级模式下模型输出的 JSON 还原为工具调用
+//
+// 输出为 {"tool": ..., "arguments": ...} 时转换为 ToolCall 块（ID 由 gen 生成，
+// nil 时使用 [DefaultIDGenerator]），finishReason 改为 "tool_calls"；为 {"answer": ...}
+// 时 Content 替换为答案文本。无法识别的输出原样返回。
+//
+// [BaseClient.Complete] 传入 [WithIDGenerator] 设置的生成器。
+func ParseToolFallback(msg llm.Message, finishReason string, gen IDGenerator) (llm.Message, string) {
+	text := strings.TrimSpace(msg.GetContent())
+
+	var out struct {
+		Tool      string  `json:"tool"`
+		Arguments any     `json:"arguments"`
+		Answer    *string `json:"answer"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return msg, finishReason
+	}
+
+	switch {
+	case out.Tool != "":
+		msg.Content = ""
+		msg.ContentBlocks = append(thinkingBlocks(msg.ContentBlocks), &llm.ToolCall{
+			ID:    "call_" + newID(gen),
+			Name:  out.Tool,
+			Input: GetToolInput(out.Arguments),
+		})
+		return msg, "tool_calls"
+	case out.Answer != nil:
+		msg.Content = *out.Answer
+		msg.ContentBlocks = thinkingBlocks(msg.ContentBlocks)
+	}
+	return msg, finishReason
+}
+
+// thinkingBlocks 保留内容块中的思考块
+func thinkingBlocks(blocks []llm.ContentBlock) []llm.ContentBlock {
+	var result []llm.ContentBlock
+	for _, block := range blocks {
+		switch block.(type) {
+		case *llm.ThinkingBlock, *llm.RedactedThinkingBlock:
+			result = append(result, block)
+		}
+	}
+	return result
+}
+
+// jsonToolFallbackPrompt 渲染降级模式的系统提示
+func jsonToolFallbackPrompt(tools []llm.ToolSchema) string {
+	var b strings.Builder
+	b.WriteString("You have access to the following tools. Always reply with a single JSON object and nothing else.\n")
+	b.WriteString(`To call a tool, reply with {"tool": "<name>", "arguments": {...}}.` + "\n")
+	b.WriteString(`To answer the user directly, reply with {"answer": "<text>"}.` + "\n")
+	b.WriteString("Tool results will be provided in the following user message.\n")
+	for _, tool := range tools {
+		fmt.Fprintf(&b, "\n- %s: %s", tool.Name, tool.Description)
+		if len(tool.InputSchema) > 0 {
+			schema, _ := json.Marshal(tool.InputSchema) //nolint:errchkjson // best effort
+			fmt.Fprintf(&b, "\n  parameters: %s", schema)
+		}
+	}
+	return b.String()
+}
+
+// toolFallbackMessages 将历史中的工具调用与结果改写为纯文本消息
+//
+// 与工具结果同在一条消息中的图片块保留在改写后的文本之后。
+func toolFallbackMessages(messages []llm.Message) []llm.Message {
+	result := make([]llm.Message, 0, len(messages))
+	for _, msg := range messages {
+		if !msg.HasToolCalls() && !msg.HasToolResults() {
+			result = append(result, msg)
+			continue
+		}
+
+		var (
+			parts  []string
+			images []llm.ContentBlock
+		)
+		if msg.Content != "" {
+			parts = append(parts, msg.Content)
+		}
+		for _, block := range msg.ContentBlocks {
+			switch b := block.(type) {
+			case *llm.TextBlock:
+				parts = append(parts, b.Text)
+			case *llm.ToolCall:
+				call, _ := json.Marshal(map[string]any{"tool": b.Name, "arguments": b.Input}) //nolint:errchkjson // best effort
+				parts = append(parts, string(call))
+			case *llm.ToolResultBlock:
+				label := "Tool result"
+				if b.IsError {
+					label = "Tool error"
+				}
+				parts = append(parts, fmt.Sprintf("%s (%s):\n%s", label, b.ToolUseID, b.Content))
+			case *llm.ImageBlock:
+				images = append(images, b)
+			}
+		}
+		rewritten := llm.Message{Role: msg.Role, Content: strings.Join(parts, "\n\n"), CacheBreakpoint: msg.CacheBreakpoint, Meta: msg.Meta}
+		if len(images) > 0 {
+			rewritten.ContentBlocks = append([]llm.ContentBlock{&llm.TextBlock{Text: rewritten.Content}}, images...)
+		}
+		result = append(result, rewritten)
+	}
+	return result
+}
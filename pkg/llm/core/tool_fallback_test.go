@@ -0,0 +1,139 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestApplyToolFallback(t *testing.T) {
+	tools := []llm.ToolSchema{{
+		Name:        "get_weather",
+		Description: "Get weather",
+		InputSchema: map[string]any{"type": "object"},
+	}}
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "Be brief."},
+		{Role: llm.RoleUser, Content: "Weather in Paris?"},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+		}},
+		{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolResultBlock{ToolUseID: "call_1", Content: "Sunny"},
+		}},
+	}
+	opts := &llm.Options{Tools: tools, ToolFallbackMode: llm.ToolFallbackModeJSON}
+
+	gotMessages, gotOpts, applied := ApplyToolFallback(messages, opts)
+	require.True(t, applied)
+
+	// 工具定义注入系统提示，保留原系统提示
+	assert.Nil(t, gotOpts.Tools)
+	assert.Contains(t, gotOpts.System, "Be brief.\n\n")
+	assert.Contains(t, gotOpts.System, `{"tool": "<name>", "arguments": {...}}`)
+	assert.Contains(t, gotOpts.System, "- get_weather: Get weather\n  parameters: {\"type\":\"object\"}")
+	require.NotNil(t, gotOpts.ResponseFormat)
+	assert.Equal(t, "json_object", gotOpts.ResponseFormat.Type)
+
+	// 历史中的工具调用改写为文本
+	require.Len(t, gotMessages, 4)
+	assert.Equal(t, `{"arguments":{"city":"Paris"},"tool":"get_weather"}`, gotMessages[2].Content)
+	assert.Empty(t, gotMessages[2].ContentBlocks)
+	assert.Equal(t, "Tool result (call_1):\nSunny", gotMessages[3].Content)
+
+	// 不修改调用方对象
+	assert.Len(t, opts.Tools, 1)
+	assert.Nil(t, opts.ResponseFormat)
+	assert.True(t, messages[2].HasToolCalls())
+
+	// 与工具结果同在一条消息中的图片保留
+	image := &llm.ImageBlock{URL: "https://example.com/chart.png"}
+	withImage := append(messages[:3:3], llm.Message{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+		&llm.ToolResultBlock{ToolUseID: "call_1", Content: "See chart"},
+		image,
+	}})
+	gotMessages, _, _ = ApplyToolFallback(withImage, opts)
+	require.Len(t, gotMessages, 4)
+	assert.Equal(t, []llm.ContentBlock{&llm.TextBlock{Text: "Tool result (call_1):\nSee chart"}, image}, gotMessages[3].ContentBlocks)
+	assert.False(t, gotMessages[3].HasToolResults())
+
+	// 未开启时不处理
+	_, same, applied := ApplyToolFallback(messages, &llm.Options{Tools: tools})
+	assert.False(t, applied)
+	assert.Len(t, same.Tools, 1)
+}
+
+func TestApplyToolFallback_RoleTool(t *testing.T) {
+	opts := &llm.Options{
+		Tools:            []llm.ToolSchema{{Name: "get_weather", InputSchema: map[string]any{"type": "object"}}},
+		ToolFallbackMode: llm.ToolFallbackModeJSON,
+	}
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather in Paris and Rome?"},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+			&llm.ToolCall{ID: "call_2", Name: "get_weather", Input: map[string]any{"city": "Rome"}},
+		}},
+		{Role: llm.RoleTool, ToolCallID: "call_1", Content: "Sunny"},
+		{Role: llm.RoleTool, ToolCallID: "call_2", Content: "Rainy"},
+	}
+
+	got, _, applied := ApplyToolFallback(messages, opts)
+	require.True(t, applied)
+
+	// 连续的 RoleTool 消息合并为一条纯文本 user 消息，不再残留 tool 角色
+	require.Len(t, got, 3)
+	for _, msg := range got {
+		assert.NotEqual(t, llm.RoleTool, msg.Role)
+		assert.False(t, msg.HasToolCalls())
+		assert.False(t, msg.HasToolResults())
+	}
+	assert.Equal(t, llm.RoleUser, got[2].Role)
+	assert.Equal(t, "Tool result (call_1):\nSunny\n\nTool result (call_2):\nRainy", got[2].Content)
+	assert.Empty(t, got[2].ToolCallID)
+
+	// 不修改调用方对象
+	assert.Equal(t, llm.RoleTool, messages[2].Role)
+}
+
+func TestParseToolFallback(t *testing.T) {
+	t.Run("工具调用", func(t *testing.T) {
+		msg := llm.Message{Role: llm.RoleAssistant, Content: ` {"tool": "get_weather", "arguments": {"city": "Paris"}} `}
+
+		got, finishReason := ParseToolFallback(msg, "stop", nil)
+
+		assert.Equal(t, "tool_calls", finishReason)
+		assert.Empty(t, got.Content)
+		calls := got.GetToolCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "get_weather", calls[0].Name)
+		assert.Equal(t, map[string]any{"city": "Paris"}, calls[0].Input)
+		assert.Contains(t, calls[0].ID, "call_")
+
+		// 使用传入的生成器
+		got, _ = ParseToolFallback(msg, "stop", NewSequentialIDGenerator())
+		assert.Equal(t, "call_1", got.GetToolCalls()[0].ID)
+	})
+
+	t.Run("直接回答", func(t *testing.T) {
+		msg := llm.Message{Role: llm.RoleAssistant, Content: `{"answer": "It is sunny."}`}
+
+		got, finishReason := ParseToolFallback(msg, "stop", nil)
+
+		assert.Equal(t, "stop", finishReason)
+		assert.Equal(t, "It is sunny.", got.Content)
+		assert.False(t, got.HasToolCalls())
+	})
+
+	t.Run("无法识别", func(t *testing.T) {
+		msg := llm.Message{Role: llm.RoleAssistant, Content: "plain text"}
+
+		got, finishReason := ParseToolFallback(msg, "stop", nil)
+
+		assert.Equal(t, "stop", finishReason)
+		assert.Equal(t, "plain text", got.Content)
+	})
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 工具结果长度限制
+// ═══════════════════════════════════════════════════════════════════════════
+
+// DefaultSummaryPrompt 摘要超长工具结果时使用的默认提示
+const DefaultSummaryPrompt = "Summarize the following tool output. Keep every fact, identifier and number that may be needed to answer the user. Reply with the summary only.\n\n"
+
+// ToolResultLimit 工具结果长度限制
+//
+// MaxChars 与 MaxTokens 同时设置时取更严格者；两者均 <= 0 时不做限制。
+type ToolResultLimit struct {
+	MaxChars  int   // 单个工具结果的最大字符数（rune），<= 0 表示不限
+	MaxTokens int64 // 单个工具结果的最大估算 token 数（见 EstimateTokens），<= 0 表示不限
+	KeepTail  int   // 截断时保留结尾的字符数（计入上限），0 表示只保留开头
+
+	// 可选：超限内容先交给该 Provider 摘要，摘要结果仍受上述上限约束；
+	// 摘要失败时回退为截断
+	Summarizer    llm.Provider
+	SummaryPrompt string // 摘要提示（内容追加在其后），空值为 DefaultSummaryPrompt
+}
+
+// enabled 是否设置了上限
+func (l ToolResultLimit) enabled() bool {
+	return l.MaxChars > 0 || l.MaxTokens > 0
+}
+
+// allowedRunes 返回 content 可保留的字符数，未超限时返回 -1
+func (l ToolResultLimit) allowedRunes(content string) int {
+	total := utf8.RuneCountInString(content)
+	allowed := total
+	if l.MaxChars > 0 {
+		allowed = min(allowed, l.MaxChars)
+	}
+	if l.MaxTokens > 0 && EstimateTokens(content) > l.MaxTokens {
+		// 与 TruncateText 相同的切点：字符边界，优先单词边界
+		allowed = min(allowed, utf8.RuneCountInString(content[:wordCutIndex(content, l.MaxTokens)]))
+	}
+	if allowed >= total {
+		return -1
+	}
+	return allowed
+}
+
+// TruncateToolResult 按上限截断工具结果
+//
+// 保留开头（以及 KeepTail 个结尾字符），在截断处插入 "[truncated N chars]" 标记，
+// N 为被移除的字符数。标记本身不计入上限。按 MaxTokens 截断时切点与 [TruncateText]
+// 相同（字符边界，优先单词边界）。结果只取决于输入，相同输入总是得到相同输出。
+func TruncateToolResult(content string, limit ToolResultLimit) string {
+	allowed := limit.allowedRunes(content)
+	if allowed < 0 {
+		return content
+	}
+
+	runes := []rune(content)
+	tail := min(max(limit.KeepTail, 0), allowed)
+	head := allowed - tail
+	marker := fmt.Sprintf("[truncated %d chars]", len(runes)-allowed)
+
+	if tail == 0 {
+		return string(runes[:head]) + "\n" + marker
+	}
+	return string(runes[:head]) + "\n" + marker + "\n" + string(runes[len(runes)-tail:])
+}
+
+// LimitToolResults 对消息中超限的工具结果应用限制
+//
+// 处理 ToolResultBlock 与 RoleTool 消息的 Content。设置了 Summarizer 时先尝试摘要。
+// 只复制发生变化的消息与内容块，不修改调用方对象。
+func LimitToolResults(ctx context.Context, messages []llm.Message, limit ToolResultLimit) []llm.Message {
+	if !limit.enabled() {
+		return messages
+	}
+
+	var result []llm.Message
+	replace := func(i int, msg llm.Message) {
+		if result == nil {
+			result = append([]llm.Message(nil), messages...)
+		}
+		result[i] = msg
+	}
+
+	for i, msg := range messages {
+		if msg.Role == llm.RoleTool {
+			if content, changed := limit.apply(ctx, msg.Content); changed {
+				msg.Content = content
+				replace(i, msg)
+			}
+			continue
+		}
+
+		var blocks []llm.ContentBlock
+		for j, block := range msg.ContentBlocks {
+			tr, ok := block.(*llm.ToolResultBlock)
+			if !ok {
+				continue
+			}
+			content, changed := limit.apply(ctx, tr.Content)
+			if !changed {
+				continue
+			}
+			if blocks == nil {
+				blocks = append([]llm.ContentBlock(nil), msg.ContentBlocks...)
+			}
+			limited := *tr
+			limited.Content = content
+			blocks[j] = &limited
+		}
+		if blocks != nil {
+			msg.ContentBlocks = blocks
+			replace(i, msg)
+		}
+	}
+
+	if result == nil {
+		return messages
+	}
+	return result
+}
+
+// apply 对单个工具结果应用限制，返回新内容与是否变化
+func (l ToolResultLimit) apply(ctx context.Context, content string) (string, bool) {
+	if l.allowedRunes(content) < 0 {
+		return content, false
+	}
+	if summary, ok := l.summarize(ctx, content); ok {
+		return TruncateToolResult(summary, l), true
+	}
+	return TruncateToolResult(content, l), true
+}
+
+// summarize 调用 Summarizer 摘要内容
+func (l ToolResultLimit) summarize(ctx context.Context, content string) (string, bool) {
+	if l.Summarizer == nil {
+		return "", false
+	}
+
+	prompt := l.SummaryPrompt
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+	resp, err := l.Summarizer.Complete(ctx, []llm.Message{{Role: llm.RoleUser, Content: prompt + content}}, nil)
+	if err != nil || resp.Message.GetContent() == "" {
+		return "", false
+	}
+	return fmt.Sprintf("[summarized from %d chars]\n%s", utf8.RuneCountInString(content), resp.Message.GetContent()), true
+}
+
+// WithToolResultLimit 设置工具结果长度限制
+//
+// 发送前对超限的工具结果截断或摘要（见 [LimitToolResults]），避免文件读取、
+// 网页抓取等工具的大段输出超出上下文导致 400 错误。
+func WithToolResultLimit(limit ToolResultLimit) ClientOption {
+	return func(c *BaseClient) {
+		c.toolResultLimit = limit
+	}
+}
@@ -0,0 +1,104 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/mock"
+)
+
+func TestTruncateToolResult(t *testing.T) {
+	content := strings.Repeat("a", 50) + strings.Repeat("z", 50)
+
+	t.Run("未超限原样返回", func(t *testing.T) {
+		assert.Equal(t, content, core.TruncateToolResult(content, core.ToolResultLimit{MaxChars: 100}))
+		assert.Equal(t, content, core.TruncateToolResult(content, core.ToolResultLimit{}))
+	})
+
+	t.Run("保留开头", func(t *testing.T) {
+		got := core.TruncateToolResult(content, core.ToolResultLimit{MaxChars: 10})
+		assert.Equal(t, strings.Repeat("a", 10)+"\n[truncated 90 chars]", got)
+	})
+
+	t.Run("保留开头与结尾", func(t *testing.T) {
+		got := core.TruncateToolResult(content, core.ToolResultLimit{MaxChars: 10, KeepTail: 4})
+		assert.Equal(t, strings.Repeat("a", 6)+"\n[truncated 90 chars]\n"+strings.Repeat("z", 4), got)
+	})
+
+	t.Run("按估算 token 截断", func(t *testing.T) {
+		// 100 个 ASCII 字符约 25 tokens，上限 5 tokens 保留 20 个字符
+		got := core.TruncateToolResult(content, core.ToolResultLimit{MaxTokens: 5})
+		assert.Equal(t, strings.Repeat("a", 20)+"\n[truncated 80 chars]", got)
+
+		// 非 ASCII 字符每个约 1 token
+		got = core.TruncateToolResult("你好世界你好世界", core.ToolResultLimit{MaxTokens: 3, MaxChars: 100})
+		assert.Equal(t, "你好世\n[truncated 5 chars]", got)
+	})
+
+	t.Run("按估算 token 截断优先单词边界", func(t *testing.T) {
+		got := core.TruncateToolResult("alpha beta gamma delta epsilon", core.ToolResultLimit{MaxTokens: 5})
+		assert.Equal(t, "alpha beta gamma\n[truncated 14 chars]", got)
+	})
+
+	t.Run("结果确定", func(t *testing.T) {
+		limit := core.ToolResultLimit{MaxChars: 33, KeepTail: 7}
+		assert.Equal(t, core.TruncateToolResult(content, limit), core.TruncateToolResult(content, limit))
+	})
+}
+
+func TestLimitToolResults(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Read the file"},
+		{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolResultBlock{ToolUseID: "call_1", Content: long},
+			&llm.ToolResultBlock{ToolUseID: "call_2", Content: "short"},
+		}},
+		{Role: llm.RoleTool, ToolCallID: "call_3", Content: long},
+	}
+	limit := core.ToolResultLimit{MaxChars: 20}
+
+	got := core.LimitToolResults(context.Background(), messages, limit)
+
+	require.Len(t, got, 3)
+	assert.Equal(t, messages[0], got[0])
+	results := got[1].GetToolResults()
+	assert.Equal(t, strings.Repeat("x", 20)+"\n[truncated 180 chars]", results[0].Content)
+	assert.Equal(t, "call_1", results[0].ToolUseID)
+	assert.Equal(t, "short", results[1].Content)
+	assert.Equal(t, strings.Repeat("x", 20)+"\n[truncated 180 chars]", got[2].Content)
+
+	// 不修改调用方对象
+	assert.Equal(t, long, messages[1].GetToolResults()[0].Content)
+	assert.Equal(t, long, messages[2].Content)
+}
+
+func TestLimitToolResults_Summarizer(t *testing.T) {
+	long := strings.Repeat("log line\n", 100)
+	messages := []llm.Message{{Role: llm.RoleTool, ToolCallID: "call_1", Content: long}}
+
+	t.Run("使用摘要", func(t *testing.T) {
+		summarizer := mock.New(mock.WithResponse("3 errors"))
+		got := core.LimitToolResults(context.Background(), messages, core.ToolResultLimit{MaxChars: 100, Summarizer: summarizer})
+
+		assert.Equal(t, "[summarized from 900 chars]\n3 errors", got[0].Content)
+		require.Equal(t, 1, summarizer.CallCount())
+		prompt := summarizer.LastCall().Messages[0].Content
+		assert.True(t, strings.HasPrefix(prompt, core.DefaultSummaryPrompt))
+		assert.True(t, strings.HasSuffix(prompt, long))
+	})
+
+	t.Run("摘要失败回退为截断", func(t *testing.T) {
+		summarizer := mock.New(mock.WithError(errors.New("unavailable")))
+		got := core.LimitToolResults(context.Background(), messages, core.ToolResultLimit{MaxChars: 9, Summarizer: summarizer})
+
+		assert.Equal(t, "log line\n\n[truncated 891 chars]", got[0].Content)
+	})
+}
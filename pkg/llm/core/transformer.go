@@ -1,6 +1,8 @@
 package core
 
 import (
+	"slices"
+
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
 )
 
@@ -25,10 +27,17 @@ import (
 //	// 构建 API 请求消息
 //	apiMsgs := transformer.BuildAPIMessages(messages, systemPrompt)
 //
+//	// 构建 API 请求消息并解析系统提示（SystemSeparate 协议）
+//	apiMsgs, system := transformer.BuildRequest(messages, opts.System)
+//
 //	// 解析 API 响应
-//	msg, reason, usage := transformer.ParseAPIResponse(apiResp)
+//	msg, reason, usage, extras := transformer.ParseAPIResponse(apiResp)
 type Transformer struct {
 	adapter ProtocolAdapter
+
+	finishReasonOverrides map[string]string // 完成原因覆盖（见 SetFinishReasonOverrides）
+	keepEmptyUserMessages bool              // 保留空的用户消息（见 SetKeepEmptyUserMessages）
+	preserveInlineSystem  bool              // 保留对话中间的系统消息（见 SetPreserveInlineSystem）
 }
 
 // NewTransformer 创建消息转换器
@@ -42,13 +51,37 @@ func NewTransformer(adapter ProtocolAdapter) *Transformer {
 	return &Transformer{adapter: adapter}
 }
 
+// SetKeepEmptyUserMessages 设置是否保留空的用户消息（默认丢弃）
+//
+// 空的用户消息指 Content 与 ContentBlocks 均为空的 user 消息。部分流程以空的用户轮次
+// 作为 "继续" 信号，开启后这类消息交由 adapter 以协议允许的最小形式发送：
+//   - OpenAI Chat / Responses: {"role": "user", "content": ""}（官方 API 接受空字符串）
+//   - Gemini: {"role": "user", "parts": [{"text": ""}]}（parts 不能为空数组）
+//   - Anthropic: 始终丢弃，API 要求 content 非空；续写请使用 assistant 预填充
+//
+// 默认丢弃，避免发送缺少 content 的消息被 API 拒绝。
+func (t *Transformer) SetKeepEmptyUserMessages(keep bool) {
+	t.keepEmptyUserMessages = keep
+}
+
+// SetPreserveInlineSystem 设置是否在原位置保留对话中间的系统消息（默认过滤）
+//
+// 默认所有系统消息从消息数组中过滤，仅由解析后的系统提示代替。开启后，对于
+// SystemInline 协议，出现在首条非系统消息之后的系统消息以 {"role": "system"}
+// 保留在原位置，用于支持多条内联系统消息的模型或调试；开头的系统消息仍由
+// 系统提示代替。SystemSeparate 协议不受影响。
+func (t *Transformer) SetPreserveInlineSystem(preserve bool) {
+	t.preserveInlineSystem = preserve
+}
+
 // BuildAPIMessages 构建 API 请求消息数组
 //
 // 通用流程：
-//  1. 检查消息有效性
-//  2. 过滤系统消息（根据协议策略处理）
-//  3. 委托 adapter 转换每条消息
-//  4. 根据协议策略处理系统提示
+//  1. 检查消息有效性（空的用户消息默认丢弃，见 SetKeepEmptyUserMessages）
+//  2. 过滤系统消息（根据协议策略处理，见 SetPreserveInlineSystem）
+//  3. RoleTool 消息统一转换为 ToolResultBlock（见 normalizeToolMessages）
+//  4. 委托 adapter 转换每条消息
+//  5. 根据协议策略处理系统提示
 //
 // 参数：
 //   - messages: 统一格式的内部消息
@@ -61,20 +94,44 @@ func NewTransformer(adapter ProtocolAdapter) *Transformer {
 //   - 系统消息的处理方式由 adapter.GetSystemMessageHandling() 决定
 //   - SystemInline: 系统提示插入消息数组开头
 //   - SystemSeparate: 系统提示不处理（由调用方作为独立参数传递）
+//   - 消息中的系统消息默认被过滤，SystemInline 协议可通过 SetPreserveInlineSystem 保留
 func (t *Transformer) BuildAPIMessages(
 	messages []llm.Message,
 	systemPrompt string,
 ) []map[string]any {
-	// 预处理：过滤系统消息（系统消息由独立参数处理）
-	var userMessages []llm.Message
+	preserve := t.preserveInlineSystem && t.adapter.GetSystemMessageHandling() == SystemInline
+	messages = normalizeToolMessages(messages)
+
+	// 预处理：过滤系统消息（系统消息由独立参数处理），委托 adapter 转换消息
+	var (
+		apiMsgs       []map[string]any
+		userMessages  []llm.Message
+		seenNonSystem bool
+	)
 	for _, msg := range messages {
-		if msg.Role != llm.RoleSystem {
-			userMessages = append(userMessages, msg)
+		if msg.Role == llm.RoleSystem {
+			if preserve && seenNonSystem {
+				// 对话中间的系统消息：先转换之前的消息，再在原位置插入
+				apiMsgs = append(apiMsgs, t.adapter.ConvertToAPI(userMessages)...)
+				apiMsgs = append(apiMsgs, map[string]any{
+					"role":    "system",
+					"content": msg.GetContent(),
+				})
+				userMessages = nil
+			}
+			continue
 		}
+		seenNonSystem = true
+		if !t.keepEmptyUserMessages && isEmptyUserMessage(&msg) {
+			continue
+		}
+		userMessages = append(userMessages, msg)
+	}
+	if apiMsgs == nil {
+		apiMsgs = t.adapter.ConvertToAPI(userMessages)
+	} else {
+		apiMsgs = append(apiMsgs, t.adapter.ConvertToAPI(userMessages)...)
 	}
-
-	// 委托 adapter 转换消息
-	apiMsgs := t.adapter.ConvertToAPI(userMessages)
 
 	// 处理系统提示（根据协议策略）
 	if systemPrompt != "" {
@@ -96,35 +153,129 @@ func (t *Transformer) BuildAPIMessages(
 	return apiMsgs
 }
 
+// normalizeToolMessages 将 OpenAI 风格的 RoleTool 消息转换为包含 ToolResultBlock 的 user 消息
+//
+// 连续的 RoleTool 消息（并行工具调用的结果）合并为一条 user 消息，使各协议
+// 适配器只需处理 ToolResultBlock 一种表示。RoleTool 消息携带的 ImageBlock
+// （如截图工具的输出）保留在该 user 消息中、全部工具结果之后。
+// 不含 RoleTool 消息时原样返回。
+func normalizeToolMessages(messages []llm.Message) []llm.Message {
+	if !slices.ContainsFunc(messages, func(m llm.Message) bool { return m.Role == llm.RoleTool }) {
+		return messages
+	}
+
+	result := make([]llm.Message, 0, len(messages))
+	for i := 0; i < len(messages); i++ {
+		if messages[i].Role != llm.RoleTool {
+			result = append(result, messages[i])
+			continue
+		}
+
+		var results, images []llm.ContentBlock
+		meta := messages[i].Meta
+		for ; i < len(messages) && messages[i].Role == llm.RoleTool; i++ {
+			msg := messages[i]
+			results = append(results, &llm.ToolResultBlock{ToolUseID: msg.ToolCallID, Content: msg.GetContent()})
+			for _, block := range msg.ContentBlocks {
+				if img, ok := block.(*llm.ImageBlock); ok {
+					images = append(images, img)
+				}
+			}
+		}
+		i--
+		result = append(result, llm.Message{Role: llm.RoleUser, ContentBlocks: append(results, images...), Meta: meta})
+	}
+	return result
+}
+
+// isEmptyUserMessage 判断是否为没有任何内容的用户消息
+func isEmptyUserMessage(msg *llm.Message) bool {
+	return msg.Role == llm.RoleUser && msg.Content == "" && len(msg.ContentBlocks) == 0
+}
+
+// BuildRequest 构建 API 请求消息数组，并返回解析后的系统提示
+//
+// 系统提示解析规则：optsSystem 非空时优先使用，否则取第一条系统消息的内容。
+// 随后按 BuildAPIMessages 处理消息，保证返回的消息与系统提示处理一致：
+//   - SystemInline: 系统提示已插入消息数组开头，返回值仅供参考
+//   - SystemSeparate: 调用方应将返回的系统提示放入请求的独立字段
+//
+// 参数：
+//   - messages: 统一格式的内部消息
+//   - optsSystem: 选项中的系统提示（通常为 Options.System）
+//
+// 返回：
+//   - apiMessages: API 特定格式的消息数组（不含系统消息）
+//   - systemPrompt: 解析后的系统提示（可能为空）
+func (t *Transformer) BuildRequest(
+	messages []llm.Message,
+	optsSystem string,
+) (apiMessages []map[string]any, systemPrompt string) {
+	systemPrompt = optsSystem
+	if systemPrompt == "" {
+		for _, msg := range messages {
+			if msg.Role == llm.RoleSystem {
+				systemPrompt = msg.Content
+				break
+			}
+		}
+	}
+
+	return t.BuildAPIMessages(messages, systemPrompt), systemPrompt
+}
+
+// SystemParts 合并系统提示与 Options.SystemParts，跳过空段
+//
+// system 为已解析的系统提示（见 [Transformer.BuildRequest]），位于首段。
+// 支持多段系统提示的 Provider（Gemini、Anthropic）逐段发送，其余 Provider
+// 以空行拼接。
+func SystemParts(system string, opts *llm.Options) []string {
+	var parts []string
+	if system != "" {
+		parts = append(parts, system)
+	}
+	if opts != nil {
+		for _, part := range opts.SystemParts {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
+
 // ParseAPIResponse 解析 API 响应
 //
 // 通用流程：
 //  1. 委托 adapter 转换响应为统一 Message
-//  2. 委托 adapter 解析 Token 使用量
-//  3. 返回统一格式的结果
+//  2. 应用完成原因覆盖映射（见 SetFinishReasonOverrides）
+//  3. 委托 adapter 解析 Token 使用量
+//  4. 返回统一格式的结果
 //
 // 参数：
 //   - apiResp: API 返回的原始响应 map
 //
 // 返回：
-//   - msg: 统一格式的 Message
+//   - msg: 统一格式的 Message（推理内容以 ThinkingBlock 形式包含在 ContentBlocks 中）
 //   - finishReason: 标准化的完成原因
 //   - usage: Token 使用量统计（可能为 nil）
+//   - extras: 引用来源、对数概率等 Response 级数据（见 ResponseExtras.Apply）
 //
 // 示例：
 //
 //	var apiResp map[string]any
 //	// ... HTTP 请求获取响应 ...
 //
-//	msg, reason, usage := transformer.ParseAPIResponse(apiResp)
+//	msg, reason, usage, _ := transformer.ParseAPIResponse(apiResp)
 //	fmt.Println("完成原因:", reason)
 //	fmt.Println("使用 tokens:", usage.TotalTokens)
-func (t *Transformer) ParseAPIResponse(apiResp map[string]any) (llm.Message, string, *llm.TokenUsage) {
+func (t *Transformer) ParseAPIResponse(apiResp map[string]any) (llm.Message, string, *llm.TokenUsage, ResponseExtras) {
 	// 委托 adapter 转换消息
-	msg, finishReason := t.adapter.ConvertFromAPI(apiResp)
+	msg, finishReason, extras := t.adapter.ConvertFromAPI(apiResp)
+	finishReason = t.MapFinishReason(finishReason)
 
 	// 委托 adapter 解析使用量
 	usage := t.adapter.ConvertUsage(apiResp)
 
-	return msg, finishReason, usage
+	return msg, finishReason, usage, extras
 }
@@ -1,11 +1,13 @@
 package core_test
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/anthropic"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/gemini"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -83,6 +85,45 @@ func TestTransformer_BuildAPIMessages_FilterSystemMessages(t *testing.T) {
 	assert.Equal(t, "assistant", result[2]["role"], "Third should be assistant")
 }
 
+func TestTransformer_BuildAPIMessages_PreserveInlineSystem(t *testing.T) {
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "Be concise."},
+		{Role: llm.RoleUser, Content: "Hello!"},
+		{Role: llm.RoleAssistant, Content: "Hi there!"},
+		{Role: llm.RoleSystem, Content: "Now answer in French."},
+		{Role: llm.RoleUser, Content: "How are you?"},
+	}
+
+	t.Run("默认过滤", func(t *testing.T) {
+		result := core.NewTransformer(openai.NewAdapter()).BuildAPIMessages(messages, "Be concise.")
+		require.Len(t, result, 4)
+		assert.Equal(t, "Be concise.", result[0]["content"])
+		assert.Equal(t, "How are you?", result[3]["content"])
+	})
+
+	t.Run("SystemInline 保留原位置", func(t *testing.T) {
+		transformer := core.NewTransformer(openai.NewAdapter())
+		transformer.SetPreserveInlineSystem(true)
+
+		result := transformer.BuildAPIMessages(messages, "Be concise.")
+		require.Len(t, result, 5)
+		roles := make([]any, len(result))
+		for i, msg := range result {
+			roles[i] = msg["role"]
+		}
+		assert.Equal(t, []any{"system", "user", "assistant", "system", "user"}, roles)
+		assert.Equal(t, "Be concise.", result[0]["content"])
+		assert.Equal(t, "Now answer in French.", result[3]["content"])
+	})
+
+	t.Run("SystemSeparate 不受影响", func(t *testing.T) {
+		transformer := core.NewTransformer(anthropic.NewAdapter())
+		transformer.SetPreserveInlineSystem(true)
+
+		assert.Len(t, transformer.BuildAPIMessages(messages, "Be concise."), 3)
+	})
+}
+
 func TestTransformer_BuildAPIMessages_EmptySystemPrompt(t *testing.T) {
 	adapter := openai.NewAdapter()
 	transformer := core.NewTransformer(adapter)
@@ -99,6 +140,46 @@ func TestTransformer_BuildAPIMessages_EmptySystemPrompt(t *testing.T) {
 	assert.Equal(t, "user", result[0]["role"])
 }
 
+func TestTransformer_BuildAPIMessages_EmptyUserMessages(t *testing.T) {
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Write a story."},
+		{Role: llm.RoleAssistant, Content: "Once upon a time"},
+		{Role: llm.RoleUser}, // 空用户轮次作为 "继续" 信号
+	}
+
+	t.Run("默认丢弃", func(t *testing.T) {
+		for _, adapter := range []core.ProtocolAdapter{openai.NewAdapter(), gemini.NewAdapter(), anthropic.NewAdapter()} {
+			result := core.NewTransformer(adapter).BuildAPIMessages(messages, "")
+			assert.Len(t, result, 2)
+		}
+	})
+
+	t.Run("OpenAI 保留为空字符串", func(t *testing.T) {
+		transformer := core.NewTransformer(openai.NewAdapter())
+		transformer.SetKeepEmptyUserMessages(true)
+
+		result := transformer.BuildAPIMessages(messages, "")
+		require.Len(t, result, 3)
+		assert.Equal(t, map[string]any{"role": "user", "content": ""}, result[2])
+	})
+
+	t.Run("Gemini 保留为空文本 part", func(t *testing.T) {
+		transformer := core.NewTransformer(gemini.NewAdapter())
+		transformer.SetKeepEmptyUserMessages(true)
+
+		result := transformer.BuildAPIMessages(messages, "")
+		require.Len(t, result, 3)
+		assert.Equal(t, []map[string]any{{"text": ""}}, result[2]["parts"])
+	})
+
+	t.Run("Anthropic 要求非空内容始终丢弃", func(t *testing.T) {
+		transformer := core.NewTransformer(anthropic.NewAdapter())
+		transformer.SetKeepEmptyUserMessages(true)
+
+		assert.Len(t, transformer.BuildAPIMessages(messages, ""), 2)
+	})
+}
+
 func TestTransformer_BuildAPIMessages_EmptyMessages(t *testing.T) {
 	adapter := openai.NewAdapter()
 	transformer := core.NewTransformer(adapter)
@@ -147,6 +228,130 @@ func TestTransformer_BuildAPIMessages_WithToolCall(t *testing.T) {
 	assert.Equal(t, "call_123", toolCalls[0]["id"])
 }
 
+func TestTransformer_BuildAPIMessages_RoleTool(t *testing.T) {
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather?"},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolCall{ID: "call_1", Name: "weather", Input: map[string]any{"city": "Paris"}},
+			&llm.ToolCall{ID: "call_2", Name: "weather", Input: map[string]any{"city": "Rome"}},
+		}},
+		{Role: llm.RoleTool, ToolCallID: "call_1", Content: "sunny"},
+		{Role: llm.RoleTool, ToolCallID: "call_2", Content: "rainy"},
+	}
+
+	t.Run("openai", func(t *testing.T) {
+		result := core.NewTransformer(openai.NewAdapter()).BuildAPIMessages(messages, "")
+		require.Len(t, result, 4)
+		assert.Equal(t, map[string]any{"role": "tool", "tool_call_id": "call_1", "content": "sunny"}, result[2])
+		assert.Equal(t, map[string]any{"role": "tool", "tool_call_id": "call_2", "content": "rainy"}, result[3])
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		result := core.NewTransformer(anthropic.NewAdapter()).BuildAPIMessages(messages, "")
+		// 连续的 RoleTool 消息合并为一条包含两个 tool_result 的 user 消息
+		require.Len(t, result, 3)
+		assert.Equal(t, "user", result[2]["role"])
+		content, ok := result[2]["content"].([]map[string]any)
+		require.True(t, ok)
+		require.Len(t, content, 2)
+		assert.Equal(t, "tool_result", content[0]["type"])
+		assert.Equal(t, "call_1", content[0]["tool_use_id"])
+		assert.Equal(t, "call_2", content[1]["tool_use_id"])
+	})
+
+	t.Run("gemini", func(t *testing.T) {
+		result := core.NewTransformer(gemini.NewAdapter()).BuildAPIMessages(messages, "")
+		require.Len(t, result, 3)
+		assert.Equal(t, "user", result[2]["role"])
+		parts, ok := result[2]["parts"].([]map[string]any)
+		require.True(t, ok)
+		require.Len(t, parts, 2)
+		resp, ok := parts[0]["functionResponse"].(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, "weather", resp["name"])
+	})
+
+	// 输入未被修改
+	assert.Equal(t, llm.RoleTool, messages[2].Role)
+
+	// RoleTool 消息中的图片保留在全部工具结果之后
+	image := &llm.ImageBlock{URL: "https://example.com/screen.png"}
+	withImage := slices.Clone(messages)
+	withImage[2].ContentBlocks = []llm.ContentBlock{&llm.TextBlock{Text: "sunny"}, image}
+
+	t.Run("openai image", func(t *testing.T) {
+		result := core.NewTransformer(openai.NewAdapter()).BuildAPIMessages(withImage, "")
+		require.Len(t, result, 5)
+		assert.Equal(t, "tool", result[2]["role"])
+		assert.Equal(t, "sunny", result[2]["content"])
+		assert.Equal(t, "tool", result[3]["role"])
+		assert.Equal(t, "user", result[4]["role"])
+		parts, ok := result[4]["content"].([]map[string]any)
+		require.True(t, ok)
+		require.Len(t, parts, 1)
+		assert.Equal(t, "image_url", parts[0]["type"])
+	})
+
+	t.Run("anthropic image", func(t *testing.T) {
+		result := core.NewTransformer(anthropic.NewAdapter()).BuildAPIMessages(withImage, "")
+		require.Len(t, result, 3)
+		content, ok := result[2]["content"].([]map[string]any)
+		require.True(t, ok)
+		require.Len(t, content, 3)
+		assert.Equal(t, "tool_result", content[0]["type"])
+		assert.Equal(t, "tool_result", content[1]["type"])
+		assert.Equal(t, "image", content[2]["type"])
+	})
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// BuildRequest 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestTransformer_BuildRequest_SystemInline(t *testing.T) {
+	transformer := core.NewTransformer(openai.NewAdapter())
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "From message"},
+		{Role: llm.RoleUser, Content: "Hello!"},
+	}
+
+	// 系统消息作为来源
+	result, system := transformer.BuildRequest(messages, "")
+	assert.Equal(t, "From message", system)
+	require.Len(t, result, 2)
+	assert.Equal(t, "system", result[0]["role"])
+	assert.Equal(t, "From message", result[0]["content"])
+
+	// 选项优先
+	result, system = transformer.BuildRequest(messages, "From options")
+	assert.Equal(t, "From options", system)
+	require.Len(t, result, 2)
+	assert.Equal(t, "From options", result[0]["content"])
+}
+
+func TestTransformer_BuildRequest_SystemSeparate(t *testing.T) {
+	transformer := core.NewTransformer(anthropic.NewAdapter())
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "From message"},
+		{Role: llm.RoleUser, Content: "Hello!"},
+	}
+
+	result, system := transformer.BuildRequest(messages, "")
+	assert.Equal(t, "From message", system)
+	require.Len(t, result, 1)
+	assert.Equal(t, "user", result[0]["role"])
+
+	result, system = transformer.BuildRequest(messages, "From options")
+	assert.Equal(t, "From options", system)
+	require.Len(t, result, 1)
+
+	// 无系统提示
+	_, system = transformer.BuildRequest(messages[1:], "")
+	assert.Empty(t, system)
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // ParseAPIResponse 测试
 // ═══════════════════════════════════════════════════════════════════════════
@@ -172,7 +377,7 @@ func TestTransformer_ParseAPIResponse_OpenAI(t *testing.T) {
 		},
 	}
 
-	msg, finishReason, usage := transformer.ParseAPIResponse(apiResp)
+	msg, finishReason, usage, _ := transformer.ParseAPIResponse(apiResp)
 
 	// 验证消息
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
@@ -207,7 +412,7 @@ func TestTransformer_ParseAPIResponse_Anthropic(t *testing.T) {
 		},
 	}
 
-	msg, finishReason, usage := transformer.ParseAPIResponse(apiResp)
+	msg, finishReason, usage, _ := transformer.ParseAPIResponse(apiResp)
 
 	// 验证消息
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
@@ -249,7 +454,7 @@ func TestTransformer_ParseAPIResponse_WithToolCall_OpenAI(t *testing.T) {
 		},
 	}
 
-	msg, finishReason, _ := transformer.ParseAPIResponse(apiResp)
+	msg, finishReason, _, _ := transformer.ParseAPIResponse(apiResp)
 
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
 	assert.Equal(t, "tool_calls", finishReason)
@@ -290,7 +495,7 @@ func TestTransformer_ParseAPIResponse_WithToolCall_Anthropic(t *testing.T) {
 		"stop_reason": "tool_use",
 	}
 
-	msg, finishReason, _ := transformer.ParseAPIResponse(apiResp)
+	msg, finishReason, _, _ := transformer.ParseAPIResponse(apiResp)
 
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
 	assert.Equal(t, "tool_calls", finishReason) // tool_use -> tool_calls
@@ -320,11 +525,37 @@ func TestTransformer_ParseAPIResponse_NoUsage(t *testing.T) {
 		// 没有 usage 字段
 	}
 
-	_, _, usage := transformer.ParseAPIResponse(apiResp)
+	_, _, usage, _ := transformer.ParseAPIResponse(apiResp)
 
 	assert.Nil(t, usage, "Expected nil usage when not present")
 }
 
+func TestTransformer_ParseAPIResponse_FinishReasonOverrides(t *testing.T) {
+	transformer := core.NewTransformer(openai.NewAdapter())
+	transformer.SetFinishReasonOverrides(map[string]string{"complete": "stop"})
+
+	apiResp := func(reason string) map[string]any {
+		return map[string]any{
+			"choices": []any{
+				map[string]any{
+					"message":       map[string]any{"content": "Hello"},
+					"finish_reason": reason,
+				},
+			},
+		}
+	}
+
+	_, finishReason, _, _ := transformer.ParseAPIResponse(apiResp("complete"))
+	assert.Equal(t, "stop", finishReason, "非标准完成原因按覆盖映射归一化")
+
+	_, finishReason, _, _ = transformer.ParseAPIResponse(apiResp("length"))
+	assert.Equal(t, "length", finishReason, "未命中映射时保持不变")
+
+	transformer.SetFinishReasonOverrides(nil)
+	_, finishReason, _, _ = transformer.ParseAPIResponse(apiResp("complete"))
+	assert.Equal(t, "complete", finishReason)
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 联合测试：完整消息往返
 // ═══════════════════════════════════════════════════════════════════════════
@@ -358,7 +589,7 @@ func TestTransformer_Integration_MessageRoundTrip_OpenAI(t *testing.T) {
 		},
 	}
 
-	msg, reason, _ := transformer.ParseAPIResponse(apiResp)
+	msg, reason, _, _ := transformer.ParseAPIResponse(apiResp)
 
 	// 验证往返完整性
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
@@ -393,7 +624,7 @@ func TestTransformer_Integration_MessageRoundTrip_Anthropic(t *testing.T) {
 		"stop_reason": "end_turn",
 	}
 
-	msg, reason, _ := transformer.ParseAPIResponse(apiResp)
+	msg, reason, _, _ := transformer.ParseAPIResponse(apiResp)
 
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
 	assert.Equal(t, "Why did the chicken cross the road?", msg.Content)
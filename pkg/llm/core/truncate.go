@@ -0,0 +1,73 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 文本截断
+// ═══════════════════════════════════════════════════════════════════════════
+
+// TruncationEllipsis TruncateText 追加的省略标记（估算为 1 token）
+const TruncationEllipsis = "…"
+
+// TruncateText 按估算 token 数截断文本
+//
+// 在字符边界（优先单词边界）处切断并追加 [TruncationEllipsis]，不会产生无效的 UTF-8；
+// 结果（含省略标记）的 EstimateTokens 不超过 maxTokens。未超出时原样返回，
+// maxTokens <= 0 时返回空字符串。
+func TruncateText(s string, maxTokens int64) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	if EstimateTokens(s) <= maxTokens {
+		return s
+	}
+
+	budget := maxTokens - EstimateTokens(TruncationEllipsis)
+	if budget <= 0 {
+		return TruncationEllipsis
+	}
+	return strings.TrimRightFunc(s[:wordCutIndex(s, budget)], unicode.IsSpace) + TruncationEllipsis
+}
+
+// wordCutIndex 返回估算 token 数不超过 maxTokens 的前缀的字节长度，优先在单词边界处切断
+//
+// 切点落在单词中间时回退到前面最近的空白处；回退会丢弃超过一半的内容（如 CJK 等
+// 无空格文本）时仍按字符边界切断。
+func wordCutIndex(s string, maxTokens int64) int {
+	cut := prefixIndex(s, maxTokens)
+	if cut == 0 || cut >= len(s) {
+		return cut
+	}
+
+	next, _ := utf8.DecodeRuneInString(s[cut:])
+	prev, _ := utf8.DecodeLastRuneInString(s[:cut])
+	if unicode.IsSpace(next) || unicode.IsSpace(prev) {
+		return cut
+	}
+	if i := strings.LastIndexFunc(s[:cut], unicode.IsSpace); i > cut/2 {
+		return i
+	}
+	return cut
+}
+
+// prefixIndex 返回 s 中估算 token 数不超过 maxTokens 的最长前缀的字节长度（可能为 0）
+func prefixIndex(s string, maxTokens int64) int {
+	var ascii, other int64
+	cut := 0
+	for i, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+		if (ascii+3)/4+other > maxTokens {
+			break
+		}
+		cut = i + utf8.RuneLen(r)
+	}
+	return cut
+}
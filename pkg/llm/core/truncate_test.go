@@ -0,0 +1,51 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		maxTokens int64
+		want      string
+	}{
+		{"未超出原样返回", "Hello world", 10, "Hello world"},
+		{"单词边界", "The quick brown fox jumps over the lazy dog", 5, "The quick brown…"},
+		{"无空格时按字符切断", strings.Repeat("a", 40), 3, strings.Repeat("a", 8) + "…"},
+		{"CJK", "你好世界，今天天气不错", 4, "你好世…"},
+		{"emoji", "🎉🎊🎈🎁🎂", 3, "🎉🎊…"},
+		{"混合", "Go 语言 🚀 rocks", 4, "Go 语言…"},
+		{"预算仅够省略号", "你好世界", 1, "…"},
+		{"非正预算", "Hello", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateText(tt.input, tt.maxTokens)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, utf8.ValidString(got), "输出包含无效字符: %q", got)
+			assert.LessOrEqual(t, EstimateTokens(got), max(tt.maxTokens, 0))
+		})
+	}
+}
+
+func TestTruncateText_NeverSplitsRunes(t *testing.T) {
+	inputs := []string{
+		strings.Repeat("中文字符", 50),
+		strings.Repeat("👨‍👩‍👧 family ", 30),
+		strings.Repeat("ümlaut ñ é ", 40),
+	}
+	for _, input := range inputs {
+		for budget := int64(1); budget <= 40; budget++ {
+			got := TruncateText(input, budget)
+			assert.True(t, utf8.ValidString(got), "budget %d: %q", budget, got)
+			assert.LessOrEqual(t, EstimateTokens(got), budget)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 结构化输出校验
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ValidateResponse 按 Options.ResponseFormat 校验响应文本
+//
+// 仅在 opts.ValidateResponse 为 true 且 ResponseFormat 为带 Schema 的 json_schema 时生效。
+// 文本不是合法 JSON 或不符合 Schema 时返回 [llm.ResponseError]（字段 "content"，
+// 包装 [llm.ErrSchemaViolation]），其余情况返回 nil。
+//
+// [BaseClient.Complete] 与 [StreamAsComplete] 自动调用，校验失败时仍返回响应。
+func ValidateResponse(resp *llm.Response, opts *llm.Options) error {
+	if resp == nil || opts == nil || !opts.ValidateResponse {
+		return nil
+	}
+	format := opts.ResponseFormat
+	if format == nil || format.Type != "json_schema" || format.Schema == nil {
+		return nil
+	}
+
+	text := resp.Message.GetContent()
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return llm.NewResponseError("content", fmt.Errorf("%w: %w", llm.ErrSchemaViolation, err)).
+			WithBody(bodySnippet([]byte(text)))
+	}
+	if err := llm.ValidateJSONSchema(value, format.Schema); err != nil {
+		return llm.NewResponseError("content", fmt.Errorf("%w: %w", llm.ErrSchemaViolation, err)).
+			WithBody(bodySnippet([]byte(text)))
+	}
+	return nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 工具定义校验
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ValidateTools 在发送请求前校验工具定义
+//
+// 以下情况返回 [llm.RequestError]（Stage 为 "validate"，包装 [llm.ErrInvalidTool]），
+// 错误信息包含出错的工具名：
+//   - 名称为空或与其他工具重复
+//   - InputSchema 为 nil（无参数的工具应使用 {"type": "object"}）
+//   - InputSchema 的 type 不是 "object"（各家 API 均要求参数为对象）
+//
+// 否则 API 会以含义模糊的 400 拒绝请求。[BaseClient.Complete] 与 [BaseClient.Stream]
+// 在能力检查之前自动调用。
+func ValidateTools(tools []llm.ToolSchema) error {
+	seen := make(map[string]bool, len(tools))
+	for i, tool := range tools {
+		var err error
+		switch {
+		case tool.Name == "":
+			err = fmt.Errorf("%w: tool at index %d has no name", llm.ErrInvalidTool, i)
+		case seen[tool.Name]:
+			err = fmt.Errorf("%w: duplicate tool name %q", llm.ErrInvalidTool, tool.Name)
+		case tool.InputSchema == nil:
+			err = fmt.Errorf("%w: tool %q has no input schema", llm.ErrInvalidTool, tool.Name)
+		case tool.InputSchema["type"] != "object":
+			err = fmt.Errorf("%w: tool %q input schema type must be \"object\", got %v",
+				llm.ErrInvalidTool, tool.Name, tool.InputSchema["type"])
+		}
+		if err != nil {
+			return llm.NewRequestError("validate", err)
+		}
+		seen[tool.Name] = true
+	}
+	return nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 图片块校验
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ValidateImages 在发送请求前校验消息中的图片块
+//
+// 以下情况返回 [llm.RequestError]（Stage 为 "validate"，包装 [llm.ErrInvalidImage]）：
+//   - URL 与 Data 均为空，或设置了 Data 但缺少 MediaType
+//   - Detail 不是 low、high、auto 或空值
+//
+// [BaseClient.Complete] 与 [BaseClient.Stream] 在能力检查之前自动调用。
+func ValidateImages(messages []llm.Message) error {
+	for i, msg := range messages {
+		for _, block := range msg.ContentBlocks {
+			img, ok := block.(*llm.ImageBlock)
+			if !ok {
+				continue
+			}
+			var err error
+			switch {
+			case img.URL == "" && img.Data == "":
+				err = fmt.Errorf("%w: image in message %d has neither url nor data", llm.ErrInvalidImage, i)
+			case img.URL == "" && img.MediaType == "":
+				err = fmt.Errorf("%w: image data in message %d has no media type", llm.ErrInvalidImage, i)
+			case img.Detail != "" && img.Detail != llm.ImageDetailAuto &&
+				img.Detail != llm.ImageDetailLow && img.Detail != llm.ImageDetailHigh:
+				err = fmt.Errorf("%w: unsupported detail %q in message %d (want low, high or auto)",
+					llm.ErrInvalidImage, img.Detail, i)
+			}
+			if err != nil {
+				return llm.NewRequestError("validate", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestValidateTools(t *testing.T) {
+	object := map[string]any{"type": "object", "properties": map[string]any{"q": map[string]any{"type": "string"}}}
+
+	tests := []struct {
+		name    string
+		tools   []llm.ToolSchema
+		wantErr string
+	}{
+		{name: "无工具", tools: nil},
+		{
+			name:  "合法工具",
+			tools: []llm.ToolSchema{{Name: "search", InputSchema: object}, {Name: "now", InputSchema: map[string]any{"type": "object"}}},
+		},
+		{
+			name:    "名称重复",
+			tools:   []llm.ToolSchema{{Name: "search", InputSchema: object}, {Name: "search", InputSchema: object}},
+			wantErr: `duplicate tool name "search"`,
+		},
+		{
+			name:    "缺少名称",
+			tools:   []llm.ToolSchema{{InputSchema: object}},
+			wantErr: "tool at index 0 has no name",
+		},
+		{
+			name:    "缺少 Schema",
+			tools:   []llm.ToolSchema{{Name: "search", InputSchema: object}, {Name: "get_weather"}},
+			wantErr: `tool "get_weather" has no input schema`,
+		},
+		{
+			name:    "Schema 不是对象",
+			tools:   []llm.ToolSchema{{Name: "echo", InputSchema: map[string]any{"type": "string"}}},
+			wantErr: `tool "echo" input schema type must be "object", got string`,
+		},
+		{
+			name:    "Schema 缺少 type",
+			tools:   []llm.ToolSchema{{Name: "echo", InputSchema: map[string]any{"properties": map[string]any{}}}},
+			wantErr: `tool "echo" input schema type must be "object", got <nil>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTools(tt.tools)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var reqErr *llm.RequestError
+			require.ErrorAs(t, err, &reqErr)
+			assert.Equal(t, "validate", reqErr.Stage)
+			require.ErrorIs(t, err, llm.ErrInvalidTool)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestBaseClient_ValidateToolsBeforeRequest(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	client, err := NewBaseClient(&mockConfig{apiKey: "test-key", baseURL: server.URL}, &mockAdapter{}, &mockEventHandler{})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	opts := &llm.Options{Tools: []llm.ToolSchema{{Name: "search"}}}
+
+	_, err = client.Complete(context.Background(), messages, opts, &mockRequestBuilder{})
+	require.ErrorIs(t, err, llm.ErrInvalidTool)
+
+	_, err = client.Stream(context.Background(), messages, opts, &mockRequestBuilder{})
+	require.ErrorIs(t, err, llm.ErrInvalidTool)
+
+	assert.Zero(t, hits, "校验失败时不发送请求")
+}
+
+func TestValidateImages(t *testing.T) {
+	user := func(blocks ...llm.ContentBlock) []llm.Message {
+		return []llm.Message{{Role: llm.RoleUser, ContentBlocks: blocks}}
+	}
+
+	tests := []struct {
+		name     string
+		messages []llm.Message
+		wantErr  string
+	}{
+		{name: "无图片", messages: user(&llm.TextBlock{Text: "hi"})},
+		{name: "URL 默认 detail", messages: user(&llm.ImageBlock{URL: "https://example.com/a.png"})},
+		{
+			name: "合法 detail",
+			messages: user(
+				&llm.ImageBlock{URL: "https://example.com/a.png", Detail: llm.ImageDetailLow},
+				&llm.ImageBlock{Data: "aGVsbG8=", MediaType: "image/png", Detail: llm.ImageDetailHigh},
+				&llm.ImageBlock{URL: "https://example.com/b.png", Detail: llm.ImageDetailAuto},
+			),
+		},
+		{
+			name:     "不支持的 detail",
+			messages: user(&llm.ImageBlock{URL: "https://example.com/a.png", Detail: "ultra"}),
+			wantErr:  `unsupported detail "ultra"`,
+		},
+		{
+			name:     "缺少数据",
+			messages: user(&llm.ImageBlock{Detail: llm.ImageDetailLow}),
+			wantErr:  "neither url nor data",
+		},
+		{
+			name:     "数据缺少 MediaType",
+			messages: user(&llm.ImageBlock{Data: "aGVsbG8="}),
+			wantErr:  "no media type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateImages(tt.messages)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.ErrorIs(t, err, llm.ErrInvalidImage)
+			assert.True(t, llm.IsRequestError(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
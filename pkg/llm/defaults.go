@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"sync"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 全局默认值
+// ═══════════════════════════════════════════════════════════════════════════
+
+// 进程级默认超时与重试配置
+var (
+	defaultsMu     sync.RWMutex
+	defaultTimeout time.Duration
+	defaultRetry   RetryConfig
+)
+
+// SetDefaultTimeout 设置全局默认请求超时
+//
+// 新建的 Provider 在自身配置未设置 Timeout（为 0）时采用此值；d <= 0 清除全局默认值，
+// 恢复各 Provider 内置默认（120 秒）。仅在创建客户端时读取，不影响已创建的客户端。
+// 客户端显式配置始终优先于全局默认值。
+func SetDefaultTimeout(d time.Duration) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaultTimeout = max(d, 0)
+}
+
+// DefaultTimeout 返回全局默认请求超时（未设置时为 0）
+func DefaultTimeout() time.Duration {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	return defaultTimeout
+}
+
+// SetDefaultRetry 设置全局默认重试配置
+//
+// 新建的客户端在未通过 core.WithRetry 显式配置时采用此值；传入零值清除全局默认值
+// （不重试）。仅在创建客户端时读取，不影响已创建的客户端。
+func SetDefaultRetry(cfg RetryConfig) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaultRetry = cfg
+}
+
+// DefaultRetry 返回全局默认重试配置（未设置时为零值）
+func DefaultRetry() RetryConfig {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	return defaultRetry
+}
@@ -0,0 +1,27 @@
+package llm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaults(t *testing.T) {
+	t.Cleanup(func() {
+		SetDefaultTimeout(0)
+		SetDefaultRetry(RetryConfig{})
+	})
+
+	assert.Zero(t, DefaultTimeout())
+	assert.Zero(t, DefaultRetry())
+
+	SetDefaultTimeout(30 * time.Second)
+	SetDefaultRetry(RetryConfig{MaxAttempts: 3})
+	assert.Equal(t, 30*time.Second, DefaultTimeout())
+	assert.Equal(t, RetryConfig{MaxAttempts: 3}, DefaultRetry())
+
+	// 负值清除
+	SetDefaultTimeout(-time.Second)
+	assert.Zero(t, DefaultTimeout())
+}
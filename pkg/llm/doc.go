@@ -20,6 +20,12 @@
 // [Provider] 接口定义了 LLM 服务的调用契约，支持同步和流式两种模式。
 //
 // [Message] 表示对话中的单条消息，支持多种内容块（文本、工具调用、工具结果）。
+// 由 Provider 托管执行的代码（Anthropic code execution、Gemini codeExecution）
+// 解析为 [CodeExecutionResultBlock]，不作为需要调用方执行的工具调用返回。
+//
+// 用户消息可携带 [ImageBlock] 图片输入（OpenAI、Anthropic、Gemini），Detail 为分辨率提示
+// （[ImageDetailLow] / [ImageDetailHigh] / [ImageDetailAuto]，默认 auto），
+// 在 token 成本与识别质量之间取舍；取值无效时请求在发送前被拒绝。
 //
 // [Event] 用于流式响应，包含文本增量、工具调用、完成、错误等事件类型。
 //
@@ -29,6 +35,40 @@
 //
 // [ToolResult] 表示工具执行结果。
 //
+// Options.ToolChoice 控制模型是否调用工具：[ToolChoiceNone] 保留工具定义但禁止调用，
+// 适用于 Agent 中需要纯文本回答的步骤（如总结），无需从 Options 中移除 Tools。
+//
+// 向不支持工具调用的模型（按 [LookupModel] 注册表判断）传入 Options.Tools 时：
+//   - Options.StrictCapabilities: 发送前返回指明模型的 [RequestError]
+//   - Options.ToolFallbackMode 为 [ToolFallbackModeAuto]: 按 JSON 模式降级（见下文）
+//
+// 两者均未设置时请求原样发送。未注册的模型可通过 [RegisterModel] 补充。
+//
+// 未注册的本地模型可设置 Options.ToolFallbackMode 为 [ToolFallbackModeJSON]，无论
+// 模型能力如何都降级：工具定义嵌入系统提示并开启 JSON 模式，模型输出的 JSON 由
+// 客户端还原为 [ToolCall]。
+//
+// 文件读取、网页抓取等工具的输出可能超出上下文，可通过 core.WithToolResultLimit
+// 在发送前按字符数或估算 token 数截断（或交给摘要 Provider 压缩）超长的工具结果。
+//
+// # 最大输出 tokens
+//
+// 未设置 Options.MaxTokens 时，内置 Provider 按 [LookupModel] 的 MaxOutputTokens
+// 填充（Anthropic 要求必填 max_tokens）；模型未注册时使用 Provider 自身的回退值。
+// 可通过 core.WithDefaultMaxTokens(false) 关闭。
+//
+// [Config].ProbeOnInit 为 true 时，创建客户端即请求模型元数据接口：认证失败立即返回
+// [ConfigError]，接口返回的上下文窗口与输出上限缓存在客户端上并优先于注册表。
+//
+// # 提示词 token 边界
+//
+// Options.ReturnPromptTokens 用于排查 Prompt Caching 未命中（断点未对齐）：
+// Response.PromptTokensDetail 给出工具定义、系统提示与各条消息结束处的累计 token 偏移，
+// 可与 Usage.CachedTokens 对照。各 Provider 支持情况：
+//   - OpenAI / Anthropic / Gemini 对话接口：不返回提示词分词，使用本地估算（Estimated 为 true）
+//   - OpenAI 兼容 FIM 补全（openai.Client.CompleteFIM）：通过 echo + logprobs 返回实际分词
+//   - 流式调用：不支持
+//
 // # Provider 类型
 //
 // [ProviderType] 枚举支持的 Provider 类型，并提供元数据查询：
@@ -49,11 +89,27 @@
 //   - [pkg/llm/provider/gemini]: Gemini 协议实现
 //   - [pkg/llm/provider/mock]: 本地 Mock 实现（用于测试）
 //
+// 工具调用循环（执行工具并回传结果直到模型给出最终回答）见 [pkg/llm/agent]。
+//
 // # 包文件组织
 //
 //   - types.go: Provider 接口、Options、Response
 //   - message.go: Message、ContentBlock、ToolCall
 //   - event.go: Event、EventType
-//   - provider_type.go: ProviderType 枚举与元数据
+//   - provider_type.go: ProviderType 枚举、元数据与别名解析
 //   - config.go: Config 配置与 DefaultConfig
+//   - stream_json.go: StreamJSON 流式解析 JSON 数组输出
+//   - capability.go: 模型能力注册表与 CheckCapabilities
+//   - transform.go: WithTransform 请求/响应转换装饰器
+//   - cache.go: CachedProvider 响应缓存装饰器与 LRUCache
+//   - recorder.go: NewRecorder 请求/响应 JSONL 记录装饰器（评测数据集）
+//   - tool_registry.go: ToolRegistry 工具定义与处理函数注册表
+//   - format.go: FormatConversation 对话可读文本渲染（日志、测试输出）
+//   - schema.go: ValidateJSONSchema 结构化输出的 JSON Schema 子集校验
+//   - batch.go: CompleteBatch / CompleteBatchStream 并发批量调用
+//   - multi_result.go: MultiResult 部分失败的聚合结果
+//   - few_shot.go: FewShot / FewShotWithTools 少样本示例消息构建
+//   - session.go: Session 多轮会话与 MergeOptions 选项合并
+//   - retry.go: RetryConfig 重试配置与指数退避
+//   - defaults.go: SetDefaultTimeout / SetDefaultRetry 全局默认值
 package llm
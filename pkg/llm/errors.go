@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -131,6 +132,9 @@ type APIError struct {
 	Provider   string
 	RequestID  string
 	ErrorCode  string // Provider 特定的错误代码
+
+	// RetryAfter 服务端建议的重试等待时间（来自 Retry-After 或限流重置响应头），0 表示未提供
+	RetryAfter time.Duration
 }
 
 // NewAPIError 创建 API 错误
@@ -157,6 +161,12 @@ func (e *APIError) WithRequestID(requestID string) *APIError {
 	return e
 }
 
+// WithRetryAfter 设置建议的重试等待时间
+func (e *APIError) WithRetryAfter(d time.Duration) *APIError {
+	e.RetryAfter = d
+	return e
+}
+
 // WithErrorCode 设置错误代码
 func (e *APIError) WithErrorCode(code string) *APIError {
 	e.ErrorCode = code
@@ -178,6 +188,37 @@ func (e *APIError) IsRetryable() bool {
 		e.StatusCode >= 500 && e.StatusCode <= 504
 }
 
+// ═══════════════════════════════════════════════════════════════════════════
+// 配额错误
+// ═══════════════════════════════════════════════════════════════════════════
+
+// QuotaError 配额或账单耗尽错误（如 OpenAI insufficient_quota）
+//
+// 与限流不同，等待后重试不会成功（需充值或提升配额），即使状态码为 429 也不可重试。
+// 包装原始 [APIError]，[IsAPIError] 与 [GetAPIError] 仍然适用。
+type QuotaError struct {
+	*APIError
+}
+
+// NewQuotaError 将 API 错误标记为配额耗尽
+func NewQuotaError(apiErr *APIError) *QuotaError {
+	return &QuotaError{APIError: apiErr}
+}
+
+func (e *QuotaError) Error() string {
+	return "quota exhausted: " + e.APIError.Error()
+}
+
+// Unwrap 返回原始 API 错误
+func (e *QuotaError) Unwrap() error {
+	return e.APIError
+}
+
+// IsRetryable 配额耗尽不可重试
+func (e *QuotaError) IsRetryable() bool {
+	return false
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 响应解析错误
 // ═══════════════════════════════════════════════════════════════════════════
@@ -187,6 +228,7 @@ type ResponseError struct {
 	*BaseError
 
 	Field string // 出错的字段
+	Body  string // 原始响应体片段（用于排查非 JSON 响应）
 }
 
 // NewResponseError 创建响应错误
@@ -201,6 +243,20 @@ func NewResponseError(field string, err error) *ResponseError {
 	}
 }
 
+// WithBody 设置原始响应体片段
+func (e *ResponseError) WithBody(body string) *ResponseError {
+	e.Body = body
+	return e
+}
+
+func (e *ResponseError) Error() string {
+	base := e.BaseError.Error()
+	if e.Body != "" {
+		return fmt.Sprintf("%s (body: %s)", base, e.Body)
+	}
+	return base
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 流式错误
 // ═══════════════════════════════════════════════════════════════════════════
@@ -261,8 +317,17 @@ func IsStreamError(err error) bool {
 	return errors.As(err, &e)
 }
 
-// IsRetryableError 检查错误是否可重试
+// IsQuotaError 检查是否为配额或账单耗尽错误
+func IsQuotaError(err error) bool {
+	var e *QuotaError
+	return errors.As(err, &e)
+}
+
+// IsRetryableError 检查错误是否可重试（配额耗尽的 429 不可重试）
 func IsRetryableError(err error) bool {
+	if IsQuotaError(err) {
+		return false
+	}
 	var e *APIError
 	if errors.As(err, &e) {
 		return e.IsRetryable()
@@ -279,6 +344,14 @@ func GetAPIError(err error) (*APIError, bool) {
 	return nil, false
 }
 
+// GetRetryAfter 提取服务端建议的重试等待时间（非 API 错误或未提供时返回 0）
+func GetRetryAfter(err error) time.Duration {
+	if e, ok := GetAPIError(err); ok {
+		return e.RetryAfter
+	}
+	return 0
+}
+
 // GetStatusCode 提取 HTTP 状态码（如果是 API 错误）
 func GetStatusCode(err error) int {
 	if e, ok := GetAPIError(err); ok {
@@ -162,6 +162,28 @@ func TestAPIError(t *testing.T) {
 	})
 }
 
+// ═══════════════════════════════════════════════════════════════════════════
+// QuotaError 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestQuotaError(t *testing.T) {
+	apiErr := NewAPIError(http.StatusTooManyRequests, "quota").WithErrorCode("insufficient_quota")
+	err := NewQuotaError(apiErr)
+
+	assert.True(t, IsQuotaError(err))
+	assert.False(t, IsRetryableError(err), "配额耗尽的 429 不可重试")
+	assert.True(t, IsRetryableError(apiErr), "普通 429 可重试")
+	assert.False(t, IsQuotaError(apiErr))
+
+	// 仍可作为 APIError 处理
+	assert.True(t, IsAPIError(err))
+	assert.Equal(t, http.StatusTooManyRequests, GetStatusCode(err))
+	extracted, ok := GetAPIError(err)
+	require.True(t, ok)
+	assert.Equal(t, "insufficient_quota", extracted.ErrorCode)
+	assert.Contains(t, err.Error(), "quota exhausted")
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // ResponseError 测试
 // ═══════════════════════════════════════════════════════════════════════════
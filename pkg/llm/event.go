@@ -10,13 +10,15 @@ import "time"
 type EventType string
 
 const (
-	EventTypeText       EventType = "text"        // 文本增量
-	EventTypeToolCall   EventType = "tool_call"   // 工具调用
-	EventTypeToolResult EventType = "tool_result" // 工具执行结果 (Agent 层填充)
-	EventTypeReasoning  EventType = "reasoning"   // 推理过程 (DeepSeek R1 等)
-	EventTypeThinking   EventType = "thinking"    // 思考过程 (Anthropic extended thinking)
-	EventTypeDone       EventType = "done"        // 完成
-	EventTypeError      EventType = "error"       // 错误
+	EventTypeText         EventType = "text"          // 文本增量
+	EventTypeToolCall     EventType = "tool_call"     // 工具调用
+	EventTypeToolResult   EventType = "tool_result"   // 工具执行结果 (Agent 层填充)
+	EventTypeStepBoundary EventType = "step_boundary" // 步骤边界 (Agent 层填充，Index 为已完成的步数)
+	EventTypeReasoning    EventType = "reasoning"     // 推理过程 (DeepSeek R1 等)
+	EventTypeThinking     EventType = "thinking"      // 思考过程 (Anthropic extended thinking)
+	EventTypeBlockStart   EventType = "block_start"   // 内容块开始 (Anthropic，BlockType 为块类型，Index 为块下标)
+	EventTypeDone         EventType = "done"          // 完成
+	EventTypeError        EventType = "error"         // 错误
 )
 
 // Event 统一事件结构
@@ -43,6 +45,9 @@ type Event struct {
 	// Text event - 文本增量
 	TextDelta string `json:"text_delta,omitempty"`
 
+	// Text event - 本次增量中输出 token 的对数概率（Options.Logprobs）
+	Logprobs []TokenLogprob `json:"logprobs,omitempty"`
+
 	// ToolCall event - 工具调用增量
 	ToolCall *ToolCallDelta `json:"tool_call,omitempty"`
 
@@ -52,9 +57,18 @@ type Event struct {
 	// Reasoning/Thinking event - 推理过程增量
 	Reasoning *ReasoningDelta `json:"reasoning,omitempty"`
 
+	// BlockStart event - 新内容块的类型（text、thinking、tool_use 等），仅用于界面区分块边界，可忽略
+	BlockType string `json:"block_type,omitempty"`
+
 	// Done event - 完成原因
 	FinishReason string `json:"finish_reason,omitempty"`
 
+	// Done event - Token 用量（协议在完成事件所在的数据块中提供时填充，否则为 nil）
+	Usage *TokenUsage `json:"usage,omitempty"`
+
+	// Done/Error event - 流的初始响应信息（由 core.BaseClient 填充）
+	StreamMeta *StreamMeta `json:"stream_meta,omitempty"`
+
 	// Error event - 错误信息
 	Error        error  `json:"-"`               // 错误对象 (不序列化)
 	ErrorMessage string `json:"error,omitempty"` // 错误消息 (序列化用)
@@ -84,14 +98,28 @@ type ToolResult struct {
 }
 
 // ToolCallDelta 工具调用增量
+//
+// ArgumentsSoFar / PartialArguments 仅在启用 Options.AccumulateToolArgs 时填充。
 type ToolCallDelta struct {
 	Index          int    `json:"index"`
 	ID             string `json:"id,omitempty"`
 	Name           string `json:"name,omitempty"`
 	ArgumentsDelta string `json:"arguments_delta,omitempty"`
+
+	// 累积模式
+	ArgumentsSoFar   string         `json:"arguments_so_far,omitempty"`  // 截至当前的完整参数片段
+	PartialArguments map[string]any `json:"partial_arguments,omitempty"` // 尽力解析的部分参数（无法解析或本次节流未解析时为 nil）
+}
+
+// StreamMeta 流式响应的初始响应信息，用于 SLA 监控
+type StreamMeta struct {
+	StatusCode      int           `json:"status_code"`        // 初始响应的 HTTP 状态码
+	TimeToFirstByte time.Duration `json:"time_to_first_byte"` // 发送请求到收到响应头的耗时（含建立阶段的重试）
 }
 
 // ReasoningDelta 推理内容增量
 type ReasoningDelta struct {
 	ThoughtDelta string `json:"thought_delta,omitempty"`
+	Signature    string `json:"signature,omitempty"`     // 思考签名（Anthropic signature_delta）
+	RedactedData string `json:"redacted_data,omitempty"` // 加密的思考内容（Anthropic redacted_thinking 块）
 }
@@ -0,0 +1,90 @@
+package llm
+
+import "fmt"
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 少样本示例
+// ═══════════════════════════════════════════════════════════════════════════
+
+// Example 少样本示例：一组输入与期望输出
+type Example struct {
+	Input  string
+	Output string
+}
+
+// ToolExample 包含工具调用的少样本示例
+//
+// 依次展开为：用户输入 → 模型发起 ToolCalls → 工具结果 → 模型最终回答 Output。
+// Results 与 ToolCalls 按下标一一对应。
+type ToolExample struct {
+	Input     string
+	ToolCalls []*ToolCall
+	Results   []string
+	Output    string
+}
+
+// FewShot 将示例展开为交替的 RoleUser / RoleAssistant 消息，置于真实对话之前
+//
+// note 非空时作为说明文字加在第一个示例的输入之前（如 "以下为示例对话"）。
+// 说明不使用系统消息：Transformer 只保留第一条系统消息作为系统提示，
+// 插在对话中的系统消息会被丢弃。
+//
+// 使用示例：
+//
+//	messages := append(llm.FewShot([]llm.Example{
+//	    {Input: "I love it", Output: "positive"},
+//	    {Input: "Terrible", Output: "negative"},
+//	}, ""), llm.Message{Role: llm.RoleUser, Content: "Not bad"})
+func FewShot(examples []Example, note string) []Message {
+	messages := make([]Message, 0, len(examples)*2)
+	for i, ex := range examples {
+		messages = append(messages,
+			Message{Role: RoleUser, Content: withNote(note, ex.Input, i)},
+			Message{Role: RoleAssistant, Content: ex.Output},
+		)
+	}
+	return messages
+}
+
+// FewShotWithTools 将包含工具调用的示例展开为消息序列
+//
+// 每个示例展开为四条消息：用户输入、携带 ToolCall 的 assistant 消息、携带
+// ToolResultBlock 的 user 消息、最终回答。ToolCall 未设置 ID 时生成
+// "example_<示例下标>_<调用下标>"；传入的 ToolCall 不被修改。note 同 [FewShot]。
+func FewShotWithTools(examples []ToolExample, note string) []Message {
+	messages := make([]Message, 0, len(examples)*4)
+	for i, ex := range examples {
+		calls := make([]ContentBlock, 0, len(ex.ToolCalls))
+		results := make([]ContentBlock, 0, len(ex.ToolCalls))
+		for j, tc := range ex.ToolCalls {
+			call := *tc
+			if call.ID == "" {
+				call.ID = fmt.Sprintf("example_%d_%d", i, j)
+			}
+			var content string
+			if j < len(ex.Results) {
+				content = ex.Results[j]
+			}
+			calls = append(calls, &call)
+			results = append(results, &ToolResultBlock{ToolUseID: call.ID, Name: call.Name, Content: content})
+		}
+
+		messages = append(messages, Message{Role: RoleUser, Content: withNote(note, ex.Input, i)})
+		if len(calls) > 0 {
+			messages = append(messages,
+				Message{Role: RoleAssistant, ContentBlocks: calls},
+				Message{Role: RoleUser, ContentBlocks: results},
+			)
+		}
+		messages = append(messages, Message{Role: RoleAssistant, Content: ex.Output})
+	}
+	return messages
+}
+
+// withNote 在第一个示例的输入前加上说明
+func withNote(note, input string, index int) string {
+	if note == "" || index > 0 {
+		return input
+	}
+	return note + "\n\n" + input
+}
@@ -0,0 +1,79 @@
+package llm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestFewShot(t *testing.T) {
+	messages := llm.FewShot([]llm.Example{
+		{Input: "I love it", Output: "positive"},
+		{Input: "Terrible", Output: "negative"},
+	}, "")
+
+	require.Len(t, messages, 4)
+	for i, msg := range messages {
+		want := llm.RoleUser
+		if i%2 == 1 {
+			want = llm.RoleAssistant
+		}
+		assert.Equal(t, want, msg.Role, "message %d", i)
+	}
+	assert.Equal(t, "I love it", messages[0].Content)
+	assert.Equal(t, "positive", messages[1].Content)
+	assert.Equal(t, "Terrible", messages[2].Content)
+	assert.Equal(t, "negative", messages[3].Content)
+
+	assert.Empty(t, llm.FewShot(nil, "note"))
+}
+
+func TestFewShot_Note(t *testing.T) {
+	messages := llm.FewShot([]llm.Example{
+		{Input: "I love it", Output: "positive"},
+		{Input: "Terrible", Output: "negative"},
+	}, "Examples:")
+
+	// 说明仅加在第一个示例之前，不产生系统消息
+	require.Len(t, messages, 4)
+	assert.Equal(t, "Examples:\n\nI love it", messages[0].Content)
+	assert.Equal(t, "Terrible", messages[2].Content)
+	for _, msg := range messages {
+		assert.NotEqual(t, llm.RoleSystem, msg.Role)
+	}
+}
+
+func TestFewShotWithTools(t *testing.T) {
+	call := &llm.ToolCall{Name: "get_weather", Input: map[string]any{"city": "Paris"}}
+	messages := llm.FewShotWithTools([]llm.ToolExample{
+		{Input: "Weather in Paris?", ToolCalls: []*llm.ToolCall{call}, Results: []string{"18°C"}, Output: "It's 18°C."},
+		{Input: "Hi", Output: "Hello!"},
+	}, "")
+
+	roles := make([]llm.Role, 0, len(messages))
+	for _, msg := range messages {
+		roles = append(roles, msg.Role)
+	}
+	assert.Equal(t, []llm.Role{
+		llm.RoleUser, llm.RoleAssistant, llm.RoleUser, llm.RoleAssistant, // 带工具调用的示例
+		llm.RoleUser, llm.RoleAssistant, // 无工具调用的示例
+	}, roles)
+
+	calls := messages[1].GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "example_0_0", calls[0].ID)
+	assert.Equal(t, "get_weather", calls[0].Name)
+	assert.Empty(t, call.ID, "不修改传入的 ToolCall")
+
+	results := messages[2].GetToolResults()
+	require.Len(t, results, 1)
+	assert.Equal(t, "example_0_0", results[0].ToolUseID)
+	assert.Equal(t, "get_weather", results[0].Name)
+	assert.Equal(t, "18°C", results[0].Content)
+
+	assert.Equal(t, "It's 18°C.", messages[3].Content)
+	assert.Equal(t, "Hello!", messages[5].Content)
+}
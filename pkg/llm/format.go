@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 对话格式化
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ConversationFormat 对话输出格式
+type ConversationFormat string
+
+const (
+	FormatPlain    ConversationFormat = "plain"    // 纯文本（默认）
+	FormatMarkdown ConversationFormat = "markdown" // Markdown（标题 + 代码块）
+)
+
+// FormatOptions 对话格式化选项
+type FormatOptions struct {
+	Format           ConversationFormat // 输出格式，空值为 FormatPlain
+	HideThinking     bool               // 不输出 ThinkingBlock
+	MaxContentLength int                // 单段内容的最大字符数（rune），超出截断；<= 0 表示不截断
+}
+
+// FormatConversation 将对话渲染为可读文本
+//
+// 用于日志与测试失败输出，包含角色、文本、思考内容、工具调用（名称、ID、参数）
+// 与工具结果（ID、错误标记）。仅读取消息，不做修改。
+//
+// 纯文本示例：
+//
+//	[user]
+//	Weather in Paris?
+//
+//	[assistant]
+//	(thinking) Need the weather tool.
+//	(tool_call call_1) get_weather {"city":"Paris"}
+func FormatConversation(messages []Message, opts FormatOptions) string {
+	f := conversationFormatter{opts: opts}
+	parts := make([]string, 0, len(messages))
+	for i := range messages {
+		parts = append(parts, f.message(&messages[i]))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// conversationFormatter 对话格式化器
+type conversationFormatter struct {
+	opts FormatOptions
+}
+
+// message 渲染单条消息
+func (f conversationFormatter) message(msg *Message) string {
+	lines := []string{f.header(msg.Role)}
+
+	for _, block := range msg.ContentBlocks {
+		if s := f.block(block); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	// Content 与 TextBlock 可能同时存在（如思考 + 回答），避免重复输出
+	if msg.Content != "" && !slices.ContainsFunc(msg.ContentBlocks, isTextBlock) {
+		lines = append(lines, f.truncate(msg.Content))
+	}
+
+	if f.opts.Format == FormatMarkdown {
+		return strings.Join(lines, "\n\n")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isTextBlock 判断是否为文本块
+func isTextBlock(block ContentBlock) bool {
+	_, ok := block.(*TextBlock)
+	return ok
+}
+
+// header 渲染角色标题
+func (f conversationFormatter) header(role Role) string {
+	if f.opts.Format == FormatMarkdown {
+		return "### " + string(role)
+	}
+	return "[" + string(role) + "]"
+}
+
+// block 渲染内容块，返回空字符串表示跳过
+func (f conversationFormatter) block(block ContentBlock) string {
+	markdown := f.opts.Format == FormatMarkdown
+
+	switch b := block.(type) {
+	case *TextBlock:
+		return f.truncate(b.Text)
+
+	case *ThinkingBlock:
+		if f.opts.HideThinking {
+			return ""
+		}
+		if markdown {
+			return "> *thinking*\n>\n> " + strings.ReplaceAll(f.truncate(b.Thinking), "\n", "\n> ")
+		}
+		return "(thinking) " + f.truncate(b.Thinking)
+
+	case *ToolCall:
+		args, _ := json.Marshal(b.Input) //nolint:errchkjson // best effort
+		if markdown {
+			return fmt.Sprintf("**tool_call** `%s` (id: `%s`)\n```json\n%s\n```", b.Name, b.ID, f.truncate(string(args)))
+		}
+		return fmt.Sprintf("(tool_call %s) %s %s", b.ID, b.Name, f.truncate(string(args)))
+
+	case *ToolResultBlock:
+		status := ""
+		if b.IsError {
+			status = " error"
+		}
+		if markdown {
+			return fmt.Sprintf("**tool_result%s** (id: `%s`)\n```\n%s\n```", status, b.ToolUseID, f.truncate(b.Content))
+		}
+		return fmt.Sprintf("(tool_result %s%s) %s", b.ToolUseID, status, f.truncate(b.Content))
+
+	case *CodeExecutionResultBlock:
+		output := b.Stdout + b.Stderr
+		if markdown {
+			return fmt.Sprintf("**code_execution** (exit: %d)\n```%s\n%s\n```\n```\n%s\n```", b.ReturnCode, b.Language, f.truncate(b.Code), f.truncate(output))
+		}
+		return fmt.Sprintf("(code_execution exit=%d) %s\n%s", b.ReturnCode, f.truncate(b.Code), f.truncate(output))
+
+	case nil:
+		return ""
+
+	default:
+		return "(" + block.BlockType() + ")"
+	}
+}
+
+// truncate 按 MaxContentLength 截断内容
+func (f conversationFormatter) truncate(s string) string {
+	limit := f.opts.MaxContentLength
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+
+	runes := []rune(s)
+	return fmt.Sprintf("%s… (%d more chars)", string(runes[:limit]), len(runes)-limit)
+}
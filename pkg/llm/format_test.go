@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// formatConversationFixture 含思考、工具调用与工具结果的对话
+func formatConversationFixture() []Message {
+	return []Message{
+		{Role: RoleUser, Content: "Weather in Paris and Rome?"},
+		{Role: RoleAssistant, ContentBlocks: []ContentBlock{
+			&ThinkingBlock{Thinking: "Need the weather tool."},
+			&ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+			&ToolCall{ID: "call_2", Name: "get_weather", Input: map[string]any{"city": "Rome"}},
+		}},
+		{Role: RoleUser, ContentBlocks: []ContentBlock{
+			&ToolResultBlock{ToolUseID: "call_1", Content: "Sunny"},
+			&ToolResultBlock{ToolUseID: "call_2", Content: "timeout", IsError: true},
+		}},
+	}
+}
+
+func TestFormatConversation_Plain(t *testing.T) {
+	got := FormatConversation(formatConversationFixture(), FormatOptions{})
+
+	want := `[user]
+Weather in Paris and Rome?
+
+[assistant]
+(thinking) Need the weather tool.
+(tool_call call_1) get_weather {"city":"Paris"}
+(tool_call call_2) get_weather {"city":"Rome"}
+
+[user]
+(tool_result call_1) Sunny
+(tool_result call_2 error) timeout`
+	assert.Equal(t, want, got)
+}
+
+func TestFormatConversation_HideThinking(t *testing.T) {
+	got := FormatConversation(formatConversationFixture(), FormatOptions{HideThinking: true})
+
+	assert.NotContains(t, got, "thinking")
+	assert.Contains(t, got, "(tool_call call_1) get_weather")
+}
+
+func TestFormatConversation_Markdown(t *testing.T) {
+	got := FormatConversation(formatConversationFixture(), FormatOptions{Format: FormatMarkdown})
+
+	assert.Contains(t, got, "### assistant")
+	assert.Contains(t, got, "> *thinking*\n>\n> Need the weather tool.")
+	assert.Contains(t, got, "**tool_call** `get_weather` (id: `call_1`)\n```json\n{\"city\":\"Paris\"}\n```")
+	assert.Contains(t, got, "**tool_result error** (id: `call_2`)\n```\ntimeout\n```")
+}
+
+func TestFormatConversation_Truncate(t *testing.T) {
+	messages := []Message{{Role: RoleAssistant, Content: "你好世界，很长的内容"}}
+
+	got := FormatConversation(messages, FormatOptions{MaxContentLength: 4})
+	assert.Equal(t, "[assistant]\n你好世界… (6 more chars)", got)
+
+	got = FormatConversation(messages, FormatOptions{})
+	assert.Equal(t, "[assistant]\n你好世界，很长的内容", got)
+}
+
+func TestFormatConversation_ContentWithTextBlock(t *testing.T) {
+	messages := []Message{{Role: RoleAssistant, Content: "42", ContentBlocks: []ContentBlock{
+		&ThinkingBlock{Thinking: "hmm"},
+		&TextBlock{Text: "42"},
+	}}}
+
+	got := FormatConversation(messages, FormatOptions{})
+	assert.Equal(t, "[assistant]\n(thinking) hmm\n42", got)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/anthropic"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/gemini"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/openai"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider"
 	"github.com/stretchr/testify/assert"
@@ -95,7 +96,7 @@ func TestIntegration_MessageRoundTrip_OpenAI(t *testing.T) {
 	}
 
 	// 解析响应
-	msg, finishReason, usage := transformer.ParseAPIResponse(apiResponse)
+	msg, finishReason, usage, _ := transformer.ParseAPIResponse(apiResponse)
 
 	// 验证解析结果
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
@@ -174,7 +175,7 @@ func TestIntegration_MessageRoundTrip_Anthropic(t *testing.T) {
 	}
 
 	// 解析响应
-	msg, finishReason, usage := transformer.ParseAPIResponse(apiResponse)
+	msg, finishReason, usage, _ := transformer.ParseAPIResponse(apiResponse)
 
 	// 验证解析结果
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
@@ -184,6 +185,47 @@ func TestIntegration_MessageRoundTrip_Anthropic(t *testing.T) {
 	assert.Equal(t, int64(100), usage.OutputTokens)
 }
 
+// TestIntegration_MessageMeta_NotSentToAPI 验证 Message.Meta 不会泄漏到请求中
+//
+// 验证点：所有适配器忽略 Meta，原始消息切片中的 Meta 保持不变
+func TestIntegration_MessageMeta_NotSentToAPI(t *testing.T) {
+	adapters := map[string]core.ProtocolAdapter{
+		"openai":    openai.NewAdapter(),
+		"anthropic": anthropic.NewAdapter(),
+		"gemini":    gemini.NewAdapter(),
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			messages := []llm.Message{
+				{
+					Role:    llm.RoleUser,
+					Content: "Hello",
+					Meta:    map[string]any{"source": "web-ui", "message_id": "msg-secret-42"},
+				},
+				{
+					Role:          llm.RoleAssistant,
+					ContentBlocks: []llm.ContentBlock{&llm.TextBlock{Text: "Hi!"}},
+					Meta:          map[string]any{"source": "model"},
+				},
+			}
+
+			apiMessages := core.NewTransformer(adapter).BuildAPIMessages(messages, "")
+			data, err := json.Marshal(apiMessages)
+			require.NoError(t, err)
+
+			assert.NotContains(t, string(data), "meta")
+			assert.NotContains(t, string(data), "msg-secret-42")
+			assert.NotContains(t, string(data), "web-ui")
+
+			// 本地消息中的 Meta 保持不变
+			assert.Equal(t, "web-ui", messages[0].Meta["source"])
+			assert.Equal(t, "msg-secret-42", messages[0].Meta["message_id"])
+			assert.Equal(t, "model", messages[1].Meta["source"])
+		})
+	}
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // SSE 完整流测试 - 验证流式解析的完整性
 // ═══════════════════════════════════════════════════════════════════════════
@@ -1,5 +1,10 @@
 package llm
 
+import (
+	"errors"
+	"strings"
+)
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 角色定义
 // ═══════════════════════════════════════════════════════════════════════════
@@ -19,23 +24,61 @@ const (
 // ═══════════════════════════════════════════════════════════════════════════
 
 // Message 对话消息
+//
+// Meta 字段供应用层附加自定义元数据（来源、时间戳、消息 ID 等），
+// 仅保留在本地消息切片中，所有协议适配器都会忽略它，不会发送给 API。
+//
+// 工具结果有两种等价表示：RoleUser 消息中的 ToolResultBlock（推荐），
+// 或 OpenAI 风格的 RoleTool 消息（Content 为结果，ToolCallID 为对应调用 ID）。
+// RoleTool 消息在发送前由 core.Transformer 统一转换为 ToolResultBlock
+// （连续的 RoleTool 消息合并为一条，携带的 ImageBlock 保留在工具结果之后），
+// 因此所有 Provider 均支持。
+//
+// CacheBreakpoint 在该消息的最后一个内容块设置 Anthropic 缓存断点（cache_control），
+// 用于增量缓存不断增长的对话前缀；单次请求最多 4 个断点。其他 Provider 忽略此字段。
+//
+// 响应消息使用 ContentBlocks 时 Content 可能为空（如包含工具调用或多个文本块），
+// 读取文本应使用 [Message.GetContent]（或 [Message.GetText]），不要直接读取 Content。
 type Message struct {
-	Role          Role           `json:"role"`
-	Content       string         `json:"content,omitempty"`
-	ContentBlocks []ContentBlock `json:"content_blocks,omitempty"`
+	Role            Role           `json:"role"`
+	Content         string         `json:"content,omitempty"`
+	ContentBlocks   []ContentBlock `json:"content_blocks,omitempty"`
+	ToolCallID      string         `json:"tool_call_id,omitempty"`     // RoleTool 消息对应的工具调用 ID
+	CacheBreakpoint bool           `json:"cache_breakpoint,omitempty"` // 缓存断点（仅 Anthropic）
+	Meta            map[string]any `json:"meta,omitempty"`             // 应用层元数据（不发送给 API）
 }
 
 // GetContent 获取消息文本内容
+//
+// Content 非空时直接返回，否则拼接全部 TextBlock 的文本；思考、工具调用等
+// 非文本块被忽略。各 Provider 的响应无论以字符串还是内容块数组返回，结果一致。
 func (m *Message) GetContent() string {
 	if m.Content != "" {
 		return m.Content
 	}
+	var b strings.Builder
 	for _, block := range m.ContentBlocks {
 		if tb, ok := block.(*TextBlock); ok {
-			return tb.Text
+			b.WriteString(tb.Text)
+		}
+	}
+	return b.String()
+}
+
+// GetText 获取消息文本内容，[Message.GetContent] 的别名（名称表明只包含文本块）
+func (m *Message) GetText() string {
+	return m.GetContent()
+}
+
+// GetReasoning 获取消息中的推理/思考内容（拼接所有 ThinkingBlock）
+func (m *Message) GetReasoning() string {
+	var reasoning string
+	for _, block := range m.ContentBlocks {
+		if tb, ok := block.(*ThinkingBlock); ok {
+			reasoning += tb.Thinking
 		}
 	}
-	return ""
+	return reasoning
 }
 
 // GetToolCalls 获取消息中的工具调用
@@ -97,9 +140,48 @@ type TextBlock struct {
 // BlockType 实现 ContentBlock 接口
 func (b *TextBlock) BlockType() string { return "text" }
 
+// 图片分辨率提示（ImageBlock.Detail）
+const (
+	ImageDetailAuto = "auto" // 由服务端决定（默认）
+	ImageDetailLow  = "low"  // 低分辨率，消耗更少 token
+	ImageDetailHigh = "high" // 高分辨率，识别更精细
+)
+
+// ErrInvalidImage 图片块无效（缺少数据或 Detail 取值不支持，见 core.ValidateImages）
+var ErrInvalidImage = errors.New("invalid image block")
+
+// ImageBlock 图片输入块（用户消息）
+//
+// URL 与 Data 二选一：URL 为图片地址，Data 为 Base64 编码的图片数据（需设置 MediaType）。
+// Detail 为分辨率提示，在成本与识别质量之间取舍，空值等同于 [ImageDetailAuto]：
+//   - OpenAI: image_url.detail
+//   - Gemini: generationConfig.mediaResolution（作用于整个请求，取各图片中最高的提示）
+//   - Anthropic: 不支持，忽略
+type ImageBlock struct {
+	URL       string `json:"url,omitempty"`
+	Data      string `json:"data,omitempty"`
+	MediaType string `json:"media_type,omitempty"` // 如 "image/png"
+	Detail    string `json:"detail,omitempty"`     // low / high / auto
+}
+
+// BlockType 实现 ContentBlock 接口
+func (b *ImageBlock) BlockType() string { return "image" }
+
+// ResolvedDetail 返回分辨率提示，未设置时为 [ImageDetailAuto]
+func (b *ImageBlock) ResolvedDetail() string {
+	if b.Detail == "" {
+		return ImageDetailAuto
+	}
+	return b.Detail
+}
+
 // ToolResultBlock 工具结果块
+//
+// Name 为对应工具调用的工具名（可选）。Gemini 按函数名而非 ID 匹配 functionResponse，
+// 未设置时由适配器按 ToolUseID 在历史中查找对应 ToolCall 的名称。
 type ToolResultBlock struct {
 	ToolUseID string `json:"tool_use_id"`
+	Name      string `json:"name,omitempty"`
 	Content   string `json:"content"`
 	IsError   bool   `json:"is_error,omitempty"`
 }
@@ -127,9 +209,49 @@ func (tc *ToolCall) BlockType() string { return "tool_use" }
 //   - Gemini 2.5 系列的 thinking
 //   - Anthropic Claude 的 extended thinking
 //   - DeepSeek R1 的 reasoning
+//
+// Signature 为 Anthropic 返回的思考签名。带签名的思考块可原样回放给
+// Anthropic（如多轮工具调用或 few-shot 示例）；无签名的思考块在发送时被丢弃。
 type ThinkingBlock struct {
-	Thinking string `json:"thinking"`
+	Thinking  string `json:"thinking"`
+	Signature string `json:"signature,omitempty"`
 }
 
 // BlockType 实现 ContentBlock 接口
 func (b *ThinkingBlock) BlockType() string { return "thinking" }
+
+// RedactedThinkingBlock 加密的思考内容块（Anthropic redacted_thinking）
+//
+// 思考内容被安全系统加密，调用方无法读取，但模型在后续轮次需要它：
+// 继续对话（尤其是工具调用循环）时必须将 Data 原样回放给 Anthropic，否则 API 报错。
+// 其他 Provider 忽略此块。
+type RedactedThinkingBlock struct {
+	Data string `json:"data"`
+}
+
+// BlockType 实现 ContentBlock 接口
+func (b *RedactedThinkingBlock) BlockType() string { return "redacted_thinking" }
+
+// CodeExecutionResultBlock 服务端代码执行结果块
+//
+// 由 Provider 托管执行的代码及其输出，不需要调用方执行工具：
+//   - Anthropic code execution 工具（server_tool_use + *_code_execution_tool_result）
+//   - Gemini codeExecution 工具（executableCode + codeExecutionResult）
+//
+// ToolName 与 ToolUseID 为 Anthropic 的服务端工具名（如 "code_execution"、
+// "bash_code_execution"）与调用 ID，回放时据此还原原始块；Gemini 不返回 ID。
+// ErrorCode 为执行失败的错误码（Anthropic error_code，或 Gemini 非 OUTCOME_OK 的 outcome）。
+type CodeExecutionResultBlock struct {
+	ToolUseID  string   `json:"tool_use_id,omitempty"`
+	ToolName   string   `json:"tool_name,omitempty"`
+	Language   string   `json:"language,omitempty"`
+	Code       string   `json:"code,omitempty"`
+	Stdout     string   `json:"stdout,omitempty"`
+	Stderr     string   `json:"stderr,omitempty"`
+	ReturnCode int      `json:"return_code"`
+	Files      []string `json:"files,omitempty"` // 输出文件 ID
+	ErrorCode  string   `json:"error_code,omitempty"`
+}
+
+// BlockType 实现 ContentBlock 接口
+func (b *CodeExecutionResultBlock) BlockType() string { return "code_execution_result" }
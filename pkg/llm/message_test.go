@@ -33,8 +33,8 @@ func TestMessage_GetContent_FromContentBlocks(t *testing.T) {
 
 	result := msg.GetContent()
 
-	// 应该返回第一个 TextBlock 的内容
-	assert.Equal(t, "First text block", result)
+	// 拼接全部 TextBlock 的内容
+	assert.Equal(t, "First text blockSecond text block", result)
 }
 
 func TestMessage_GetContent_ContentPriority(t *testing.T) {
@@ -76,6 +76,38 @@ func TestMessage_GetContent_MixedBlocks(t *testing.T) {
 	assert.Empty(t, result, "Should return empty when no TextBlock")
 }
 
+func TestMessage_GetText(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"单个文本", Message{Role: RoleAssistant, Content: "Hello"}, "Hello"},
+		{
+			"多个内容块",
+			Message{Role: RoleAssistant, ContentBlocks: []ContentBlock{
+				&ThinkingBlock{Thinking: "hmm"},
+				&TextBlock{Text: "Part 1. "},
+				&ToolCall{ID: "call_1", Name: "search"},
+				&TextBlock{Text: "Part 2."},
+			}},
+			"Part 1. Part 2.",
+		},
+		{
+			"仅工具调用",
+			Message{Role: RoleAssistant, ContentBlocks: []ContentBlock{&ToolCall{ID: "call_1", Name: "search"}}},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.msg.GetText())
+			assert.Equal(t, tt.msg.GetContent(), tt.msg.GetText())
+		})
+	}
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // GetToolCalls 测试
 // ═══════════════════════════════════════════════════════════════════════════
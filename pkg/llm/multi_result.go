@@ -0,0 +1,60 @@
+package llm
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 部分失败结果
+// ═══════════════════════════════════════════════════════════════════════════
+
+// MultiResult 多个独立操作的聚合结果，统一批量、多 Provider 等场景的部分失败语义
+//
+// Results 与 Errors 等长，下标 i 对应第 i 个输入：成功时 Errors[i] 为 nil，
+// 失败时 Results[i] 为零值。单个操作失败不影响其他操作。
+type MultiResult[T any] struct {
+	Results []T     // 按输入顺序的结果，失败位置为零值
+	Errors  []error // 按输入顺序的错误，成功位置为 nil
+	OKCount int     // 成功的操作数
+}
+
+// newMultiResult 创建容纳 n 个结果的 MultiResult
+func newMultiResult[T any](n int) MultiResult[T] {
+	return MultiResult[T]{Results: make([]T, n), Errors: make([]error, n)}
+}
+
+// set 记录第 i 个操作的结果
+func (m *MultiResult[T]) set(i int, v T, err error) {
+	if err != nil {
+		m.Errors[i] = err
+		return
+	}
+	m.Results[i] = v
+	m.OKCount++
+}
+
+// FirstError 返回按输入顺序的第一个错误，全部成功时返回 nil
+func (m MultiResult[T]) FirstError() error {
+	for _, err := range m.Errors {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllOK 报告是否全部成功（无输入时为 true）
+func (m MultiResult[T]) AllOK() bool {
+	return m.OKCount == len(m.Results)
+}
+
+// Partition 拆分成功与失败：ok 为按输入顺序的成功结果，failed 为失败操作的下标
+//
+// 失败原因通过 Errors[i] 获取，下标可用于重试对应的输入。
+func (m MultiResult[T]) Partition() (ok []T, failed []int) {
+	ok = make([]T, 0, m.OKCount)
+	for i, err := range m.Errors {
+		if err != nil {
+			failed = append(failed, i)
+			continue
+		}
+		ok = append(ok, m.Results[i])
+	}
+	return ok, failed
+}
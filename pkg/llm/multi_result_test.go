@@ -0,0 +1,72 @@
+package llm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestMultiResult(t *testing.T) {
+	errA := errors.New("a")
+	errB := errors.New("b")
+
+	tests := []struct {
+		name       string
+		result     llm.MultiResult[string]
+		firstError error
+		allOK      bool
+		ok         []string
+		failed     []int
+	}{
+		{
+			name:   "empty",
+			result: llm.MultiResult[string]{},
+			allOK:  true,
+			ok:     []string{},
+		},
+		{
+			name: "all ok",
+			result: llm.MultiResult[string]{
+				Results: []string{"x", "y"},
+				Errors:  []error{nil, nil},
+				OKCount: 2,
+			},
+			allOK: true,
+			ok:    []string{"x", "y"},
+		},
+		{
+			name: "partial",
+			result: llm.MultiResult[string]{
+				Results: []string{"", "y", "", "w"},
+				Errors:  []error{errA, nil, errB, nil},
+				OKCount: 2,
+			},
+			firstError: errA,
+			ok:         []string{"y", "w"},
+			failed:     []int{0, 2},
+		},
+		{
+			name: "all failed",
+			result: llm.MultiResult[string]{
+				Results: []string{"", ""},
+				Errors:  []error{errB, errA},
+			},
+			firstError: errB,
+			ok:         []string{},
+			failed:     []int{0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.firstError, tt.result.FirstError())
+			assert.Equal(t, tt.allOK, tt.result.AllOK())
+			ok, failed := tt.result.Partition()
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.failed, failed)
+		})
+	}
+}
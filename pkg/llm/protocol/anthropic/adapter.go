@@ -1,6 +1,9 @@
 package anthropic
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 )
@@ -37,6 +40,23 @@ func NewAdapter() *Adapter {
 //   - 工具参数直接传递对象（无需序列化为 JSON 字符串）
 //   - ToolResult 内联在 content 数组中（不展开为独立消息）
 //   - content 数组必须非空
+//
+// ThinkingBlock 回放规则：
+//   - 带 Signature 的思考块原样发送（thinking + signature），用于多轮回放或 few-shot
+//   - 无 Signature 的思考块被丢弃（API 拒绝未签名的思考内容）
+//   - RedactedThinkingBlock 原样发送为 redacted_thinking（API 要求回放）
+//   - API 要求思考块位于 assistant 消息开头，调用方需保证顺序
+//
+// Message.CacheBreakpoint 为 true 时，在该消息最后一个内容块设置
+// cache_control: {"type": "ephemeral"}（TTL 与断点数量上限由 Provider 处理）。
+//
+// CodeExecutionResultBlock 还原为 server_tool_use + *_tool_result 两个块；
+// 没有 ToolUseID 的块（如来自 Gemini）无法配对，降级为文本块。
+//
+// ImageBlock 转换为 image 块（URL 使用 url 来源，Base64 数据使用 base64 来源），
+// Anthropic 不支持分辨率提示，Detail 被忽略；消息没有 TextBlock 时 Content 作为开头的文本。
+// API 只接受用户消息中的图片，
+// 其他位置的图片由 Provider 在构建请求时拒绝（见 [ValidateImagePlacement]）。
 func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 	result := make([]map[string]any, 0, len(messages))
 
@@ -53,6 +73,10 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 
 		// 优先处理 ContentBlocks
 		if len(msg.ContentBlocks) > 0 {
+			// 与 OpenAI 一致：图片消息没有 TextBlock 时，Content 作为开头的文本
+			if msg.Content != "" && hasImageWithoutText(msg.ContentBlocks) {
+				content = append(content, map[string]any{"type": "text", "text": msg.Content})
+			}
 			for _, block := range msg.ContentBlocks {
 				switch b := block.(type) {
 				case *llm.TextBlock:
@@ -61,6 +85,26 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 						"text": b.Text,
 					})
 
+				case *llm.ImageBlock:
+					content = append(content, imageBlock(b))
+
+				case *llm.ThinkingBlock:
+					// 仅回放带签名的思考块
+					if b.Signature == "" {
+						continue
+					}
+					content = append(content, map[string]any{
+						"type":      "thinking",
+						"thinking":  b.Thinking,
+						"signature": b.Signature,
+					})
+
+				case *llm.RedactedThinkingBlock:
+					content = append(content, map[string]any{
+						"type": "redacted_thinking",
+						"data": b.Data,
+					})
+
 				case *llm.ToolCall:
 					// ⚠️ 关键差异：参数直接是对象，不是 JSON 字符串
 					content = append(content, map[string]any{
@@ -77,6 +121,9 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 						"tool_use_id": b.ToolUseID,
 						"content":     b.Content,
 					})
+
+				case *llm.CodeExecutionResultBlock:
+					content = append(content, codeExecutionBlocks(b)...)
 				}
 			}
 		} else if msg.Content != "" {
@@ -89,6 +136,9 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 
 		// Anthropic 要求 content 必须非空
 		if len(content) > 0 {
+			if msg.CacheBreakpoint {
+				content[len(content)-1]["cache_control"] = map[string]any{"type": "ephemeral"}
+			}
 			m["content"] = content
 			result = append(result, m)
 		}
@@ -97,6 +147,49 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 	return result
 }
 
+// imageBlock 构建 Anthropic image 内容块
+func imageBlock(b *llm.ImageBlock) map[string]any {
+	source := map[string]any{"type": "url", "url": b.URL}
+	if b.URL == "" {
+		source = map[string]any{"type": "base64", "media_type": b.MediaType, "data": b.Data}
+	}
+	return map[string]any{"type": "image", "source": source}
+}
+
+// hasImageWithoutText 内容块是否包含图片但不含文本块
+func hasImageWithoutText(blocks []llm.ContentBlock) bool {
+	hasImage := false
+	for _, block := range blocks {
+		switch block.(type) {
+		case *llm.TextBlock:
+			return false
+		case *llm.ImageBlock:
+			hasImage = true
+		}
+	}
+	return hasImage
+}
+
+// ValidateImagePlacement 校验图片块的位置
+//
+// Anthropic 只接受用户消息中的图片（RoleTool 消息中的图片由 core.Transformer
+// 移到工具结果之后的用户内容中），助手消息等其他位置的图片无法表示。此时返回包装
+// [llm.ErrInvalidImage] 的错误，避免图片被静默丢弃。
+func ValidateImagePlacement(messages []llm.Message) error {
+	for i, msg := range messages {
+		if msg.Role == llm.RoleUser || msg.Role == llm.RoleTool {
+			continue
+		}
+		for _, block := range msg.ContentBlocks {
+			if _, ok := block.(*llm.ImageBlock); ok {
+				return fmt.Errorf("%w: image in %s message %d is not supported (anthropic accepts images only in user messages)",
+					llm.ErrInvalidImage, msg.Role, i)
+			}
+		}
+	}
+	return nil
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // ConvertFromAPI - 解析 Anthropic 响应
 // ═══════════════════════════════════════════════════════════════════════════
@@ -107,18 +200,27 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 //
 //	{
 //	  "content": [
+//	    {"type": "redacted_thinking", "data": "..."},
 //	    {"type": "text", "text": "..."},
 //	    {"type": "tool_use", "id": "...", "name": "...", "input": {...}}
 //	  ],
 //	  "stop_reason": "end_turn"
 //	}
-func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
+//
+// 服务端代码执行（server_tool_use + code_execution_tool_result /
+// bash_code_execution_tool_result）合并为 CodeExecutionResultBlock，不作为工具调用返回。
+// 文本块的 citations 解析为 extras.Citations。
+func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string, core.ResponseExtras) {
 	msg := llm.Message{Role: llm.RoleAssistant}
 
 	// 提取 content 数组
 	contentArray, _ := resp["content"].([]any)
 	var blocks []llm.ContentBlock
 	var textContent string
+	var thinkingCount int
+	var citations []llm.Citation
+	var textOffset int                             // 当前文本块在可见文本中的字节偏移
+	serverCalls := make(map[string]map[string]any) // server_tool_use ID → 调用块
 
 	for _, item := range contentArray {
 		block, ok := item.(map[string]any)
@@ -133,25 +235,47 @@ func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
 			text, _ := block["text"].(string)
 			textContent = text
 			blocks = append(blocks, &llm.TextBlock{Text: text})
+			citations = append(citations, parseCitations(block, textOffset, textOffset+len(text))...)
+			textOffset += len(text)
+
+		case "thinking":
+			// Extended thinking 内容
+			thinking, _ := block["thinking"].(string)
+			thinkingCount++
+			blocks = append(blocks, &llm.ThinkingBlock{
+				Thinking:  thinking,
+				Signature: core.GetString(block["signature"]),
+			})
+
+		case "redacted_thinking":
+			// 加密的思考内容，需原样回放
+			thinkingCount++
+			blocks = append(blocks, &llm.RedactedThinkingBlock{Data: core.GetString(block["data"])})
 
 		case "tool_use":
 			id, _ := block["id"].(string)
 			name, _ := block["name"].(string)
 			// ⚠️ 关键差异：参数直接是对象（无需反序列化）
-			input, _ := block["input"].(map[string]any)
 			blocks = append(blocks, &llm.ToolCall{
 				ID:    id,
 				Name:  name,
-				Input: input, // ← 直接对象
+				Input: core.GetToolInput(block["input"]), // ← 直接对象
 			})
+
+		case "server_tool_use":
+			// 服务端工具调用，代码随结果块一并保存
+			serverCalls[core.GetString(block["id"])] = block
+
+		case "code_execution_tool_result", "bash_code_execution_tool_result":
+			blocks = append(blocks, parseCodeExecutionResult(blockType, block, serverCalls))
 		}
 	}
 
 	// 设置 ContentBlocks 或 Content
 	if len(blocks) > 0 {
 		msg.ContentBlocks = blocks
-		// 如果只有单个文本块，同时设置 Content
-		if len(blocks) == 1 && textContent != "" {
+		// 如果只有单个文本块（不计 thinking），同时设置 Content
+		if len(blocks)-thinkingCount == 1 && textContent != "" {
 			msg.Content = textContent
 		} else {
 			msg.Content = "" // 清空，使用 ContentBlocks
@@ -162,7 +286,146 @@ func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
 	stopReason, _ := resp["stop_reason"].(string)
 	finishReason := convertStopReason(stopReason)
 
-	return msg, finishReason
+	return msg, finishReason, core.ResponseExtras{Citations: citations}
+}
+
+// parseCitations 解析文本块的 citations
+//
+// 引用的回答片段即该文本块，[start, end) 为其在可见文本中的字节区间。
+// 来源 URI/标题取 url/title（web_search_result_location），文档引用取 document_title。
+func parseCitations(block map[string]any, start, end int) []llm.Citation {
+	list, _ := block["citations"].([]any)
+	citations := make([]llm.Citation, 0, len(list))
+	for _, item := range list {
+		c, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		title := core.GetString(c["title"])
+		if title == "" {
+			title = core.GetString(c["document_title"])
+		}
+		citations = append(citations, llm.Citation{
+			SourceURI:  core.GetString(c["url"]),
+			Title:      title,
+			StartIndex: start,
+			EndIndex:   end,
+			Text:       core.GetString(c["cited_text"]),
+		})
+	}
+	return citations
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 服务端代码执行
+// ═══════════════════════════════════════════════════════════════════════════
+
+// codeExecutionInputKey 返回服务端代码执行工具的代码参数名
+func codeExecutionInputKey(toolName string) string {
+	if toolName == "bash_code_execution" {
+		return "command"
+	}
+	return "code"
+}
+
+// parseCodeExecutionResult 解析代码执行结果块
+//
+// 块格式：
+//
+//	{
+//	  "type": "code_execution_tool_result",
+//	  "tool_use_id": "srvtoolu_...",
+//	  "content": {
+//	    "type": "code_execution_result",
+//	    "stdout": "...", "stderr": "...", "return_code": 0,
+//	    "content": [{"type": "code_execution_output", "file_id": "..."}]
+//	  }
+//	}
+//
+// 执行失败时 content 为 {"type": "..._tool_result_error", "error_code": "..."}。
+// 代码取自同 ID 的 server_tool_use 块。
+func parseCodeExecutionResult(blockType string, block map[string]any, calls map[string]map[string]any) *llm.CodeExecutionResultBlock {
+	name := strings.TrimSuffix(blockType, "_tool_result")
+	result := &llm.CodeExecutionResultBlock{
+		ToolUseID: core.GetString(block["tool_use_id"]),
+		ToolName:  name,
+		Language:  "python",
+	}
+	if name == "bash_code_execution" {
+		result.Language = "bash"
+	}
+
+	if call, ok := calls[result.ToolUseID]; ok {
+		input, _ := call["input"].(map[string]any)
+		result.Code = core.GetString(input[codeExecutionInputKey(name)])
+	}
+
+	content, _ := block["content"].(map[string]any)
+	if code := core.GetString(content["error_code"]); code != "" {
+		result.ErrorCode = code
+		return result
+	}
+
+	result.Stdout = core.GetString(content["stdout"])
+	result.Stderr = core.GetString(content["stderr"])
+	result.ReturnCode = int(core.GetInt64(content["return_code"]))
+	outputs, _ := content["content"].([]any)
+	for _, item := range outputs {
+		output, _ := item.(map[string]any)
+		if id := core.GetString(output["file_id"]); id != "" {
+			result.Files = append(result.Files, id)
+		}
+	}
+	return result
+}
+
+// codeExecutionBlocks 将代码执行结果还原为 server_tool_use 与结果块
+func codeExecutionBlocks(b *llm.CodeExecutionResultBlock) []map[string]any {
+	if b.ToolUseID == "" {
+		return []map[string]any{{
+			"type": "text",
+			"text": b.Code + "\n" + b.Stdout + b.Stderr,
+		}}
+	}
+
+	name := b.ToolName
+	if name == "" {
+		name = "code_execution"
+	}
+
+	var result map[string]any
+	if b.ErrorCode != "" {
+		result = map[string]any{
+			"type":       name + "_tool_result_error",
+			"error_code": b.ErrorCode,
+		}
+	} else {
+		files := make([]map[string]any, 0, len(b.Files))
+		for _, id := range b.Files {
+			files = append(files, map[string]any{"type": name + "_output", "file_id": id})
+		}
+		result = map[string]any{
+			"type":        name + "_result",
+			"stdout":      b.Stdout,
+			"stderr":      b.Stderr,
+			"return_code": b.ReturnCode,
+			"content":     files,
+		}
+	}
+
+	return []map[string]any{
+		{
+			"type":  "server_tool_use",
+			"id":    b.ToolUseID,
+			"name":  name,
+			"input": map[string]any{codeExecutionInputKey(name): b.Code},
+		},
+		{
+			"type":        name + "_tool_result",
+			"tool_use_id": b.ToolUseID,
+			"content":     result,
+		},
+	}
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -173,7 +436,7 @@ func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
 //
 // Anthropic 字段名：
 //   - input_tokens, output_tokens（无 total_tokens）
-//   - cache_read_input_tokens（Prompt Caching）
+//   - cache_read_input_tokens / cache_creation_input_tokens（Prompt Caching）
 func (a *Adapter) ConvertUsage(resp map[string]any) *llm.TokenUsage {
 	usage, ok := resp["usage"].(map[string]any)
 	if !ok {
@@ -192,6 +455,7 @@ func (a *Adapter) ConvertUsage(resp map[string]any) *llm.TokenUsage {
 	if cacheRead := core.GetInt64(usage["cache_read_input_tokens"]); cacheRead > 0 {
 		result.CachedTokens = cacheRead
 	}
+	result.CacheWriteTokens = core.GetInt64(usage["cache_creation_input_tokens"])
 
 	return result
 }
@@ -207,6 +471,13 @@ func (a *Adapter) GetSystemMessageHandling() core.SystemMessageStrategy {
 	return core.SystemSeparate
 }
 
+// FormatToolCallID 实现 core.ToolCallIDFormatter 接口
+//
+// 生成与原生 tool_use ID 风格一致的 "toolu_<seq>"（满足 ^[a-zA-Z0-9_-]+$ 约束）。
+func (a *Adapter) FormatToolCallID(seq int) string {
+	return fmt.Sprintf("toolu_%d", seq)
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 辅助函数
 // ═══════════════════════════════════════════════════════════════════════════
@@ -1,10 +1,14 @@
 package anthropic
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/gemini"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -252,6 +256,154 @@ func TestAdapter_ConvertToAPI_EmptyContent(t *testing.T) {
 	}
 }
 
+func TestAdapter_ConvertToAPI_Image(t *testing.T) {
+	adapter := NewAdapter()
+	messages := []llm.Message{
+		{
+			Role:    llm.RoleUser,
+			Content: "What is in these images?",
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ImageBlock{URL: "https://example.com/cat.png", Detail: llm.ImageDetailHigh},
+				&llm.ImageBlock{Data: "iVBORw0KGgo=", MediaType: "image/png"},
+			},
+		},
+	}
+
+	result := adapter.ConvertToAPI(messages)
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(result))
+	}
+	content, ok := result[0]["content"].([]map[string]any)
+	if !ok || len(content) != 3 {
+		t.Fatalf("Expected 3 content blocks (text + 2 images), got %v", result[0]["content"])
+	}
+
+	if content[0]["type"] != "text" || content[0]["text"] != "What is in these images?" {
+		t.Errorf("Expected leading text block from Content, got %v", content[0])
+	}
+
+	wantURL := map[string]any{"type": "url", "url": "https://example.com/cat.png"}
+	if content[1]["type"] != "image" || !reflect.DeepEqual(wantURL, content[1]["source"]) {
+		t.Errorf("Unexpected url image block: %v", content[1])
+	}
+
+	wantData := map[string]any{"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}
+	if content[2]["type"] != "image" || !reflect.DeepEqual(wantData, content[2]["source"]) {
+		t.Errorf("Unexpected base64 image block: %v", content[2])
+	}
+}
+
+func TestValidateImagePlacement(t *testing.T) {
+	image := &llm.ImageBlock{URL: "https://example.com/cat.png"}
+
+	tests := []struct {
+		name    string
+		msg     llm.Message
+		wantErr bool
+	}{
+		{"用户消息", llm.Message{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{image}}, false},
+		{"助手消息", llm.Message{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{image}}, true},
+		{"工具结果消息", llm.Message{Role: llm.RoleTool, ToolCallID: "call_1", ContentBlocks: []llm.ContentBlock{image}}, false},
+		{"无图片", llm.Message{Role: llm.RoleAssistant, Content: "hi"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateImagePlacement([]llm.Message{tt.msg})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateImagePlacement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, llm.ErrInvalidImage) {
+				t.Errorf("Expected error to wrap ErrInvalidImage, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAdapter_ConvertToAPI_ThinkingReplay(t *testing.T) {
+	adapter := NewAdapter()
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "What is 6*7?"},
+		{
+			Role: llm.RoleAssistant,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ThinkingBlock{Thinking: "6*7=42", Signature: "sig_abc"},
+				&llm.TextBlock{Text: "42"},
+			},
+		},
+		{
+			Role: llm.RoleAssistant,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ThinkingBlock{Thinking: "unsigned reasoning"},
+				&llm.TextBlock{Text: "ok"},
+			},
+		},
+	}
+
+	result := adapter.ConvertToAPI(messages)
+	require.Len(t, result, 3)
+
+	// 带签名：原样回放
+	signed, ok := result[1]["content"].([]map[string]any)
+	require.True(t, ok)
+	require.Len(t, signed, 2)
+	assert.Equal(t, map[string]any{"type": "thinking", "thinking": "6*7=42", "signature": "sig_abc"}, signed[0])
+	assert.Equal(t, "text", signed[1]["type"])
+
+	// 无签名：丢弃
+	unsigned, ok := result[2]["content"].([]map[string]any)
+	require.True(t, ok)
+	require.Len(t, unsigned, 1)
+	assert.Equal(t, "text", unsigned[0]["type"])
+}
+
+func TestAdapter_ThinkingSignatureRoundTrip(t *testing.T) {
+	adapter := NewAdapter()
+	msg, _, _ := adapter.ConvertFromAPI(map[string]any{
+		"content": []any{
+			map[string]any{"type": "thinking", "thinking": "Let me think...", "signature": "sig_xyz"},
+			map[string]any{"type": "text", "text": "42"},
+		},
+		"stop_reason": "end_turn",
+	})
+
+	thinking, ok := msg.ContentBlocks[0].(*llm.ThinkingBlock)
+	require.True(t, ok)
+	assert.Equal(t, "sig_xyz", thinking.Signature)
+
+	result := adapter.ConvertToAPI([]llm.Message{msg})
+	require.Len(t, result, 1)
+	content, ok := result[0]["content"].([]map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "sig_xyz", content[0]["signature"])
+}
+
+func TestAdapter_RedactedThinkingRoundTrip(t *testing.T) {
+	adapter := NewAdapter()
+	original := []any{
+		map[string]any{"type": "thinking", "thinking": "Let me think...", "signature": "sig_xyz"},
+		map[string]any{"type": "redacted_thinking", "data": "EmwKAhgBEgy3va3pzix/LafPsn4a"},
+		map[string]any{"type": "tool_use", "id": "toolu_1", "name": "search", "input": map[string]any{"q": "go"}},
+	}
+	msg, _, _ := adapter.ConvertFromAPI(map[string]any{"content": original, "stop_reason": "tool_use"})
+
+	require.Len(t, msg.ContentBlocks, 3)
+	redacted, ok := msg.ContentBlocks[1].(*llm.RedactedThinkingBlock)
+	require.True(t, ok)
+	assert.Equal(t, "EmwKAhgBEgy3va3pzix/LafPsn4a", redacted.Data)
+
+	// 回放时与原始响应一致
+	result := adapter.ConvertToAPI([]llm.Message{msg})
+	require.Len(t, result, 1)
+	content, ok := result[0]["content"].([]map[string]any)
+	require.True(t, ok)
+	require.Len(t, content, 3)
+	for i, block := range original {
+		assert.Equal(t, block, map[string]any(content[i]), "block %d", i)
+	}
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // ConvertFromAPI 测试
 // ═══════════════════════════════════════════════════════════════════════════
@@ -268,7 +420,7 @@ func TestAdapter_ConvertFromAPI_TextResponse(t *testing.T) {
 		"stop_reason": "end_turn",
 	}
 
-	msg, finishReason := adapter.ConvertFromAPI(apiResp)
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 	if msg.Role != llm.RoleAssistant {
 		t.Errorf("Expected role assistant, got %v", msg.Role)
@@ -283,6 +435,26 @@ func TestAdapter_ConvertFromAPI_TextResponse(t *testing.T) {
 	}
 }
 
+func TestAdapter_ConvertFromAPI_ThinkingResponse(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"content": []any{
+			map[string]any{"type": "thinking", "thinking": "Let me think..."},
+			map[string]any{"type": "text", "text": "42"},
+		},
+		"stop_reason": "end_turn",
+	}
+
+	msg, _, _ := adapter.ConvertFromAPI(apiResp)
+
+	if msg.Content != "42" {
+		t.Errorf("Expected content '42', got %v", msg.Content)
+	}
+	if msg.GetReasoning() != "Let me think..." {
+		t.Errorf("Expected reasoning, got %v", msg.GetReasoning())
+	}
+}
+
 func TestAdapter_ConvertFromAPI_ToolUseResponse(t *testing.T) {
 	adapter := NewAdapter()
 	apiResp := map[string]any{
@@ -305,7 +477,7 @@ func TestAdapter_ConvertFromAPI_ToolUseResponse(t *testing.T) {
 		"stop_reason": "tool_use",
 	}
 
-	msg, finishReason := adapter.ConvertFromAPI(apiResp)
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 	if msg.Role != llm.RoleAssistant {
 		t.Errorf("Expected role assistant, got %v", msg.Role)
@@ -358,6 +530,144 @@ func TestAdapter_ConvertFromAPI_ToolUseResponse(t *testing.T) {
 	}
 }
 
+func TestAdapter_ConvertFromAPI_Citations(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"content": []any{
+			map[string]any{"type": "text", "text": "According to the document, "},
+			map[string]any{
+				"type": "text",
+				"text": "the grass is green",
+				"citations": []any{
+					map[string]any{
+						"type":             "char_location",
+						"cited_text":       "The grass is green.",
+						"document_index":   float64(0),
+						"document_title":   "Example Document",
+						"start_char_index": float64(0),
+						"end_char_index":   float64(20),
+					},
+				},
+			},
+			map[string]any{
+				"type": "text",
+				"text": " and the sky is blue.",
+				"citations": []any{
+					map[string]any{
+						"type":       "web_search_result_location",
+						"url":        "https://example.com/sky",
+						"title":      "Why is the sky blue?",
+						"cited_text": "The sky is blue.",
+					},
+				},
+			},
+		},
+		"stop_reason": "end_turn",
+	}
+
+	msg, _, extras := adapter.ConvertFromAPI(apiResp)
+
+	assert.Nil(t, msg.Meta)
+	assert.Equal(t, []llm.Citation{
+		{Title: "Example Document", StartIndex: 27, EndIndex: 45, Text: "The grass is green."},
+		{SourceURI: "https://example.com/sky", Title: "Why is the sky blue?", StartIndex: 45, EndIndex: 66, Text: "The sky is blue."},
+	}, extras.Citations)
+}
+
+func TestAdapter_ConvertFromAPI_CodeExecution(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"content": []any{
+			map[string]any{"type": "text", "text": "Let me calculate that."},
+			map[string]any{
+				"type":  "server_tool_use",
+				"id":    "srvtoolu_01",
+				"name":  "code_execution",
+				"input": map[string]any{"code": "print(sum(range(10)))"},
+			},
+			map[string]any{
+				"type":        "code_execution_tool_result",
+				"tool_use_id": "srvtoolu_01",
+				"content": map[string]any{
+					"type":        "code_execution_result",
+					"stdout":      "45\n",
+					"stderr":      "",
+					"return_code": float64(0),
+					"content": []any{
+						map[string]any{"type": "code_execution_output", "file_id": "file_abc"},
+					},
+				},
+			},
+			map[string]any{
+				"type":  "server_tool_use",
+				"id":    "srvtoolu_02",
+				"name":  "bash_code_execution",
+				"input": map[string]any{"command": "sleep 999"},
+			},
+			map[string]any{
+				"type":        "bash_code_execution_tool_result",
+				"tool_use_id": "srvtoolu_02",
+				"content": map[string]any{
+					"type":       "bash_code_execution_tool_result_error",
+					"error_code": "execution_time_exceeded",
+				},
+			},
+			map[string]any{"type": "text", "text": "The sum is 45."},
+		},
+		"stop_reason": "end_turn",
+	}
+
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
+
+	assert.Equal(t, "stop", finishReason)
+	assert.False(t, msg.HasToolCalls(), "服务端工具不应作为工具调用返回")
+	require.Len(t, msg.ContentBlocks, 4)
+	assert.Equal(t, &llm.CodeExecutionResultBlock{
+		ToolUseID: "srvtoolu_01",
+		ToolName:  "code_execution",
+		Language:  "python",
+		Code:      "print(sum(range(10)))",
+		Stdout:    "45\n",
+		Files:     []string{"file_abc"},
+	}, msg.ContentBlocks[1])
+	assert.Equal(t, &llm.CodeExecutionResultBlock{
+		ToolUseID: "srvtoolu_02",
+		ToolName:  "bash_code_execution",
+		Language:  "bash",
+		Code:      "sleep 999",
+		ErrorCode: "execution_time_exceeded",
+	}, msg.ContentBlocks[2])
+
+	// 回放时还原为 server_tool_use + 结果块
+	result := adapter.ConvertToAPI([]llm.Message{msg})
+	require.Len(t, result, 1)
+	content, ok := result[0]["content"].([]map[string]any)
+	require.True(t, ok)
+	require.Len(t, content, 6)
+	assert.Equal(t, map[string]any{
+		"type":  "server_tool_use",
+		"id":    "srvtoolu_01",
+		"name":  "code_execution",
+		"input": map[string]any{"code": "print(sum(range(10)))"},
+	}, content[1])
+	assert.Equal(t, map[string]any{
+		"type":        "code_execution_tool_result",
+		"tool_use_id": "srvtoolu_01",
+		"content": map[string]any{
+			"type":        "code_execution_result",
+			"stdout":      "45\n",
+			"stderr":      "",
+			"return_code": 0,
+			"content":     []map[string]any{{"type": "code_execution_output", "file_id": "file_abc"}},
+		},
+	}, content[2])
+	assert.Equal(t, map[string]any{"command": "sleep 999"}, content[3]["input"])
+	assert.Equal(t, map[string]any{
+		"type":       "bash_code_execution_tool_result_error",
+		"error_code": "execution_time_exceeded",
+	}, content[4]["content"])
+}
+
 func TestAdapter_ConvertFromAPI_StopReasonMapping(t *testing.T) {
 	adapter := NewAdapter()
 
@@ -383,7 +693,7 @@ func TestAdapter_ConvertFromAPI_StopReasonMapping(t *testing.T) {
 			"stop_reason": tc.stopReason,
 		}
 
-		_, finishReason := adapter.ConvertFromAPI(apiResp)
+		_, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 		if finishReason != tc.expectedFinish {
 			t.Errorf("Expected stop_reason %q to map to %q, got %q",
@@ -467,6 +777,65 @@ func TestAdapter_GetSystemMessageHandling(t *testing.T) {
 	}
 }
 
+// ═══════════════════════════════════════════════════════════════════════════
+// 跨 Provider 历史重放测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestSanitizeHistory_GeminiToAnthropic(t *testing.T) {
+	geminiAdapter := gemini.NewAdapter()
+
+	// Gemini 产生的两轮工具调用（含思考内容）
+	turn := func(city string) llm.Message {
+		msg, _, _ := geminiAdapter.ConvertFromAPI(map[string]any{
+			"candidates": []any{map[string]any{
+				"content": map[string]any{"parts": []any{
+					map[string]any{"text": "need weather", "thought": true},
+					map[string]any{"functionCall": map[string]any{"name": "get_weather", "args": map[string]any{"city": city}}},
+				}},
+				"finishReason": "STOP",
+			}},
+		})
+		// Gemini 生成的 ID 按计数器取模，模拟两轮 ID 冲突
+		msg.GetToolCalls()[0].ID = "call_1"
+		return msg
+	}
+	result := func(content string) llm.Message {
+		return llm.Message{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolResultBlock{ToolUseID: "call_1", Content: content},
+		}}
+	}
+
+	history := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather in Paris and Rome?"},
+		turn("Paris"), result("Sunny"),
+		turn("Rome"), result("Rainy"),
+	}
+
+	adapter := NewAdapter()
+	sanitized := core.SanitizeHistoryForProvider(history, adapter)
+	require.Len(t, sanitized, 5)
+
+	// 思考内容被移除，工具调用 ID 重新编号
+	require.Len(t, sanitized[1].ContentBlocks, 1)
+	assert.Equal(t, "toolu_1", sanitized[1].GetToolCalls()[0].ID)
+	assert.Equal(t, "toolu_1", sanitized[2].GetToolResults()[0].ToolUseID)
+	assert.Equal(t, "toolu_2", sanitized[3].GetToolCalls()[0].ID)
+	assert.Equal(t, "toolu_2", sanitized[4].GetToolResults()[0].ToolUseID)
+	assert.Equal(t, "Rainy", sanitized[4].GetToolResults()[0].Content)
+
+	// 原始历史未被修改
+	assert.Len(t, history[1].ContentBlocks, 2)
+	assert.Equal(t, "call_1", history[3].GetToolCalls()[0].ID)
+
+	// 转换为 Anthropic 格式：tool_use 与 tool_result 一一对应
+	apiMsgs := adapter.ConvertToAPI(sanitized)
+	require.Len(t, apiMsgs, 5)
+	content := apiMsgs[3]["content"].([]map[string]any)
+	require.Len(t, content, 1)
+	assert.Equal(t, "tool_use", content[0]["type"])
+	assert.Equal(t, "toolu_2", content[0]["id"])
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 接口实现验证
 // ═══════════════════════════════════════════════════════════════════════════
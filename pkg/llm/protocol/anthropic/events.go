@@ -20,10 +20,10 @@ import (
 //
 // 事件类型：
 //   - message_start:        消息开始
-//   - content_block_start:  内容块开始（包含工具调用初始化）
+//   - content_block_start:  内容块开始（发出 block_start 事件，包含工具调用初始化）
 //   - content_block_delta:  内容块增量（文本、工具参数、推理）
 //   - content_block_stop:   内容块结束
-//   - message_delta:        消息元数据增量（包含 stop_reason）
+//   - message_delta:        消息元数据增量（包含 stop_reason 与累计用量）
 //   - message_stop:         消息结束
 //   - ping:                 心跳
 type EventHandler struct{}
@@ -43,14 +43,29 @@ func NewEventHandler() *EventHandler {
 //   - eventType 驱动不同的处理逻辑
 //   - content_block_delta 包含多种 delta 类型（text_delta, input_json_delta, thinking_delta）
 //   - 使用 index 字段关联工具调用
+//   - eventType 为空（帧中缺少 event: 行）时使用数据中的 type 字段
 func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*llm.Event, bool) {
 	var result []*llm.Event
 
+	// 缺少 event: 行的帧（代理改写等）：按数据中的 type 字段处理，其值与事件类型一致
+	if eventType == "" {
+		eventType = core.GetString(data["type"])
+	}
+
 	switch eventType {
 	case "content_block_start":
-		// 工具调用开始
 		if block, ok := data["content_block"].(map[string]any); ok {
-			if blockType, _ := block["type"].(string); blockType == "tool_use" {
+			blockType := core.GetString(block["type"])
+			// 块边界：界面可据此切换显示（如 "思考中..." → 回答），不关心的消费者可忽略
+			result = append(result, &llm.Event{
+				Type:      llm.EventTypeBlockStart,
+				Index:     int(core.GetFloat64(data["index"])),
+				BlockType: blockType,
+			})
+
+			switch blockType {
+			case "tool_use":
+				// 工具调用开始
 				result = append(result, &llm.Event{
 					Type: "tool_call",
 					ToolCall: &llm.ToolCallDelta{
@@ -59,6 +74,14 @@ func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*ll
 						Name:  core.GetString(block["name"]),
 					},
 				})
+			case "redacted_thinking":
+				// 加密的思考内容在块开始时完整给出，没有后续增量
+				if data := core.GetString(block["data"]); data != "" {
+					result = append(result, &llm.Event{
+						Type:      "reasoning",
+						Reasoning: &llm.ReasoningDelta{RedactedData: data},
+					})
+				}
 			}
 		}
 
@@ -106,6 +129,18 @@ func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*ll
 					},
 				})
 			}
+
+		case "signature_delta":
+			// 思考签名（在 thinking 块结束前发送）
+			signature, _ := delta["signature"].(string)
+			if signature != "" {
+				result = append(result, &llm.Event{
+					Type: "reasoning",
+					Reasoning: &llm.ReasoningDelta{
+						Signature: signature,
+					},
+				})
+			}
 		}
 
 	case "message_delta":
@@ -115,6 +150,7 @@ func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*ll
 				result = append(result, &llm.Event{
 					Type:         "done",
 					FinishReason: convertStopReason(stopReason),
+					Usage:        NewAdapter().ConvertUsage(data),
 				})
 			}
 		}
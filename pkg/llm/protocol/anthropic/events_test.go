@@ -3,6 +3,7 @@ package anthropic
 import (
 	"testing"
 
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 )
 
@@ -27,11 +28,12 @@ func TestEventHandler_HandleEvent_ContentBlockStart_ToolUse(t *testing.T) {
 		t.Error("Expected stop=false for content_block_start")
 	}
 
-	if len(chunks) != 1 {
-		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	// block_start + tool_call
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d", len(chunks))
 	}
 
-	chunk := chunks[0]
+	chunk := chunks[1]
 
 	if chunk.Type != "tool_call" {
 		t.Errorf("Expected type 'tool_call', got %v", chunk.Type)
@@ -157,6 +159,48 @@ func TestEventHandler_HandleEvent_ContentBlockDelta_ThinkingDelta(t *testing.T)
 	}
 }
 
+func TestEventHandler_HandleEvent_ContentBlockDelta_SignatureDelta(t *testing.T) {
+	handler := NewEventHandler()
+	data := map[string]any{
+		"delta": map[string]any{
+			"type":      "signature_delta",
+			"signature": "sig_abc",
+		},
+	}
+
+	chunks, _ := handler.HandleEvent("content_block_delta", data)
+
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if chunks[0].Reasoning == nil || chunks[0].Reasoning.Signature != "sig_abc" {
+		t.Errorf("Expected signature 'sig_abc', got %+v", chunks[0].Reasoning)
+	}
+	if chunks[0].Reasoning.ThoughtDelta != "" {
+		t.Errorf("Expected empty ThoughtDelta, got %q", chunks[0].Reasoning.ThoughtDelta)
+	}
+}
+
+func TestEventHandler_HandleEvent_ContentBlockStart_RedactedThinking(t *testing.T) {
+	handler := NewEventHandler()
+	data := map[string]any{
+		"index": float64(0),
+		"content_block": map[string]any{
+			"type": "redacted_thinking",
+			"data": "EmwKAhgBEgy3va3pzix",
+		},
+	}
+
+	chunks, _ := handler.HandleEvent("content_block_start", data)
+
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d", len(chunks))
+	}
+	if chunks[1].Type != "reasoning" || chunks[1].Reasoning == nil || chunks[1].Reasoning.RedactedData != "EmwKAhgBEgy3va3pzix" {
+		t.Errorf("Expected redacted reasoning chunk, got %+v", chunks[1])
+	}
+}
+
 func TestEventHandler_HandleEvent_MessageDelta(t *testing.T) {
 	handler := NewEventHandler()
 	data := map[string]any{
@@ -187,6 +231,30 @@ func TestEventHandler_HandleEvent_MessageDelta(t *testing.T) {
 	}
 }
 
+func TestEventHandler_HandleEvent_MessageDeltaUsage(t *testing.T) {
+	handler := NewEventHandler()
+	data := map[string]any{
+		"delta": map[string]any{"stop_reason": "end_turn"},
+		"usage": map[string]any{
+			"input_tokens":  float64(12),
+			"output_tokens": float64(7),
+		},
+	}
+
+	chunks, _ := handler.HandleEvent("message_delta", data)
+
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	usage := chunks[0].Usage
+	if usage == nil {
+		t.Fatal("Expected Usage to be non-nil")
+	}
+	if usage.InputTokens != 12 || usage.OutputTokens != 7 || usage.TotalTokens != 19 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+}
+
 func TestEventHandler_HandleEvent_MessageStop(t *testing.T) {
 	handler := NewEventHandler()
 	data := map[string]any{}
@@ -251,6 +319,31 @@ func TestEventHandler_HandleEvent_UnknownEvent(t *testing.T) {
 	}
 }
 
+func TestEventHandler_HandleEvent_MissingEventType(t *testing.T) {
+	handler := NewEventHandler()
+	data := map[string]any{
+		"type":  "content_block_delta",
+		"index": float64(0),
+		"delta": map[string]any{"type": "text_delta", "text": "Hello"},
+	}
+
+	// 帧中缺少 event: 行时按数据中的 type 处理
+	chunks, _ := handler.HandleEvent("", data)
+
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if chunks[0].Type != llm.EventTypeText || chunks[0].TextDelta != "Hello" {
+		t.Errorf("Expected text chunk 'Hello', got %v %q", chunks[0].Type, chunks[0].TextDelta)
+	}
+
+	// 没有 type 字段的数据被忽略
+	chunks, _ = handler.HandleEvent("", map[string]any{"delta": map[string]any{"type": "text_delta", "text": "x"}})
+	if len(chunks) != 0 {
+		t.Errorf("Expected 0 chunks without type, got %d", len(chunks))
+	}
+}
+
 func TestEventHandler_HandleEvent_EmptyDelta(t *testing.T) {
 	handler := NewEventHandler()
 	data := map[string]any{
@@ -352,25 +445,37 @@ func TestEventHandler_HandleEvent_MessageDeltaNoStopReason(t *testing.T) {
 	}
 }
 
-func TestEventHandler_HandleEvent_ContentBlockStartNonToolUse(t *testing.T) {
+func TestEventHandler_HandleEvent_ContentBlockStart_BlockStart(t *testing.T) {
 	handler := NewEventHandler()
-	data := map[string]any{
-		"index": float64(0),
-		"content_block": map[string]any{
-			"type": "text",
-			"text": "Some text",
-		},
+
+	testCases := []struct {
+		index     float64
+		blockType string
+		chunks    int
+	}{
+		{0, "thinking", 1},
+		{1, "text", 1},
+		{2, "tool_use", 2}, // 另有 tool_call 事件
 	}
 
-	chunks, stop := handler.HandleEvent("content_block_start", data)
+	for _, tc := range testCases {
+		data := map[string]any{
+			"index":         tc.index,
+			"content_block": map[string]any{"type": tc.blockType, "text": "", "thinking": "", "id": "toolu_1", "name": "search"},
+		}
 
-	if stop {
-		t.Error("Expected stop=false")
-	}
+		chunks, stop := handler.HandleEvent("content_block_start", data)
 
-	// 非 tool_use 的 content_block_start 应该被忽略
-	if len(chunks) != 0 {
-		t.Errorf("Expected 0 chunks for non-tool_use content_block_start, got %d", len(chunks))
+		if stop {
+			t.Errorf("%s: expected stop=false", tc.blockType)
+		}
+		if len(chunks) != tc.chunks {
+			t.Fatalf("%s: expected %d chunks, got %d", tc.blockType, tc.chunks, len(chunks))
+		}
+		start := chunks[0]
+		if start.Type != llm.EventTypeBlockStart || start.BlockType != tc.blockType || start.Index != int(tc.index) {
+			t.Errorf("%s: expected block_start at index %v, got %+v", tc.blockType, tc.index, start)
+		}
 	}
 }
 
@@ -389,12 +494,12 @@ func TestEventHandler_HandleEvent_MultipleIndexes(t *testing.T) {
 
 	chunks1, _ := handler.HandleEvent("content_block_start", data1)
 
-	if len(chunks1) != 1 {
-		t.Fatalf("Expected 1 chunk for first tool call, got %d", len(chunks1))
+	if len(chunks1) != 2 {
+		t.Fatalf("Expected 2 chunks for first tool call, got %d", len(chunks1))
 	}
 
-	if chunks1[0].ToolCall.Index != 0 {
-		t.Errorf("Expected Index 0 for first tool call, got %d", chunks1[0].ToolCall.Index)
+	if chunks1[1].ToolCall.Index != 0 {
+		t.Errorf("Expected Index 0 for first tool call, got %d", chunks1[1].ToolCall.Index)
 	}
 
 	// 第二个工具调用
@@ -409,12 +514,12 @@ func TestEventHandler_HandleEvent_MultipleIndexes(t *testing.T) {
 
 	chunks2, _ := handler.HandleEvent("content_block_start", data2)
 
-	if len(chunks2) != 1 {
-		t.Fatalf("Expected 1 chunk for second tool call, got %d", len(chunks2))
+	if len(chunks2) != 2 {
+		t.Fatalf("Expected 2 chunks for second tool call, got %d", len(chunks2))
 	}
 
-	if chunks2[0].ToolCall.Index != 1 {
-		t.Errorf("Expected Index 1 for second tool call, got %d", chunks2[0].ToolCall.Index)
+	if chunks2[1].ToolCall.Index != 1 {
+		t.Errorf("Expected Index 1 for second tool call, got %d", chunks2[1].ToolCall.Index)
 	}
 }
 
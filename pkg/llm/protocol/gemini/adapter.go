@@ -1,10 +1,26 @@
 package gemini
 
 import (
+	"strings"
+
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 )
 
+// MetaThinkingExhausted Response.Metadata 中标记思考耗尽输出预算的键
+//
+// 候选结果达到 MAX_TOKENS 却没有任何 parts 时（思考阶段耗尽了全部预算），
+// 适配器在 ResponseExtras.Metadata 中将该键置为 true。思考消耗的 token 数见
+// TokenUsage.ReasoningTokens（来自 thoughtsTokenCount）。
+const MetaThinkingExhausted = "thinking_exhausted"
+
+// MetaBlockedCategories Response.Metadata 中记录安全拦截类别的键
+//
+// 候选结果的 finishReason 为 SAFETY，或提示词被拦截（promptFeedback.blockReason
+// 为 SAFETY）时，适配器将 safetyRatings 中被拦截的类别（如 HARM_CATEGORY_HARASSMENT）
+// 以 []string 写入该键，完成原因为 "content_filter"。
+const MetaBlockedCategories = "blocked_categories"
+
 // ═══════════════════════════════════════════════════════════════════════════
 // Gemini 协议适配器
 // ═══════════════════════════════════════════════════════════════════════════
@@ -20,13 +36,22 @@ import (
 //  4. 工具结果：作为 functionResponse Part
 //  5. 系统消息：独立的 systemInstruction 字段
 //  6. Token 字段名：promptTokenCount, candidatesTokenCount
-type Adapter struct{}
+type Adapter struct {
+	idGenerator core.IDGenerator // 工具调用 ID 生成器（nil 时使用默认）
+}
 
 // NewAdapter 创建 Gemini 协议适配器
 func NewAdapter() *Adapter {
 	return &Adapter{}
 }
 
+// SetIDGenerator 设置工具调用 ID 生成器
+//
+// 实现 core.IDGeneratorSetter 接口。
+func (a *Adapter) SetIDGenerator(gen core.IDGenerator) {
+	a.idGenerator = gen
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // ConvertToAPI - 消息转换为 Gemini 格式
 // ═══════════════════════════════════════════════════════════════════════════
@@ -38,8 +63,13 @@ func NewAdapter() *Adapter {
 //   - 角色映射：user→user, assistant→model, tool→function
 //   - ToolResult 作为 functionResponse Part
 //   - 工具调用参数直接是对象（不序列化为 JSON 字符串）
+//
+// ⚠️ Gemini 按函数名匹配 functionCall 与 functionResponse（API 没有调用 ID）：
+// functionResponse.name 取 ToolResultBlock.Name，未设置时按 ToolUseID 查找历史中
+// 对应 ToolCall 的名称，均找不到时退回 ToolUseID。生成的调用 ID 仅在本地用于配对。
 func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 	result := make([]map[string]any, 0, len(messages))
+	names := toolCallNames(messages)
 
 	for _, msg := range messages {
 		// 跳过系统消息（由 Transformer 统一处理，传递到 systemInstruction）
@@ -53,7 +83,11 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 		}
 
 		// 构建 Parts 数组
-		parts := buildParts(msg)
+		parts := buildParts(msg, names)
+		if len(parts) == 0 && msg.Role == llm.RoleUser {
+			// 空的用户消息由 Transformer 决定是否保留；Gemini 要求 parts 非空
+			parts = []map[string]any{{"text": ""}}
+		}
 		if len(parts) > 0 {
 			content["parts"] = parts
 		}
@@ -78,8 +112,30 @@ func mapRole(role llm.Role) string {
 	}
 }
 
+// toolCallNames 收集历史中工具调用 ID 到工具名的映射
+func toolCallNames(messages []llm.Message) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.GetToolCalls() {
+			names[call.ID] = call.Name
+		}
+	}
+	return names
+}
+
+// functionResponseName 返回工具结果对应的函数名
+func functionResponseName(b *llm.ToolResultBlock, names map[string]string) string {
+	if b.Name != "" {
+		return b.Name
+	}
+	if name, ok := names[b.ToolUseID]; ok {
+		return name
+	}
+	return b.ToolUseID
+}
+
 // buildParts 构建 Gemini Parts 数组
-func buildParts(msg llm.Message) []map[string]any {
+func buildParts(msg llm.Message, names map[string]string) []map[string]any {
 	var parts []map[string]any
 
 	// 如果有 ContentBlocks，优先使用
@@ -91,6 +147,20 @@ func buildParts(msg llm.Message) []map[string]any {
 					"text": b.Text,
 				})
 
+			case *llm.ImageBlock:
+				// Base64 数据为 inlineData，URL 为 fileData（分辨率提示由 Provider 设置到 generationConfig）
+				if b.Data != "" {
+					parts = append(parts, map[string]any{
+						"inlineData": map[string]any{"mimeType": b.MediaType, "data": b.Data},
+					})
+				} else {
+					fileData := map[string]any{"fileUri": b.URL}
+					if b.MediaType != "" {
+						fileData["mimeType"] = b.MediaType
+					}
+					parts = append(parts, map[string]any{"fileData": fileData})
+				}
+
 			case *llm.ToolCall:
 				// Gemini 使用 functionCall 格式
 				parts = append(parts, map[string]any{
@@ -104,7 +174,7 @@ func buildParts(msg llm.Message) []map[string]any {
 				// Gemini 使用 functionResponse 格式
 				parts = append(parts, map[string]any{
 					"functionResponse": map[string]any{
-						"name": b.ToolUseID, // 使用 ToolUseID 作为函数名
+						"name": functionResponseName(b, names), // Gemini 按函数名匹配
 						"response": map[string]any{
 							"content": b.Content,
 							"error":   b.IsError,
@@ -118,6 +188,10 @@ func buildParts(msg llm.Message) []map[string]any {
 					"text":    b.Thinking,
 					"thought": true,
 				})
+
+			case *llm.CodeExecutionResultBlock:
+				// 代码执行还原为 executableCode + codeExecutionResult
+				parts = append(parts, codeExecutionParts(b)...)
 			}
 		}
 	}
@@ -147,37 +221,62 @@ func buildParts(msg llm.Message) []map[string]any {
 //	      "parts": [
 //	        {"text": "..."},
 //	        {"functionCall": {"name": "...", "args": {...}}},
-//	        {"text": "...", "thought": true}
+//	        {"text": "...", "thought": true},
+//	        {"executableCode": {"language": "PYTHON", "code": "..."}},
+//	        {"codeExecutionResult": {"outcome": "OUTCOME_OK", "output": "..."}}
 //	      ]
 //	    },
 //	    "finishReason": "STOP"
 //	  }],
 //	  "usageMetadata": {...}
 //	}
-func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
+//
+// msg.Content 为全部非思考文本 part 的拼接（与块数量无关），便于直接读取可见回答；
+// 思考内容（thought: true）仅以 ThinkingBlock 保留在 ContentBlocks 中。
+// executableCode 与其后的 codeExecutionResult 合并为一个 CodeExecutionResultBlock。
+//
+// finishReason 为 MAX_TOKENS 且没有 parts 时返回空消息与 "length"，
+// 并在 extras.Metadata[MetaThinkingExhausted] 中标记思考耗尽预算。
+// 安全拦截时在 extras.Metadata[MetaBlockedCategories] 中记录被拦截的类别。
+// 接地与引用元数据解析为 extras.Citations。
+func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string, core.ResponseExtras) {
 	msg := llm.Message{Role: llm.RoleAssistant}
+	var extras core.ResponseExtras
 
 	// 提取 candidates[0]
 	candidates, _ := resp["candidates"].([]any)
 	if len(candidates) == 0 {
-		return msg, ""
+		// 提示词被安全策略拦截时不返回候选结果
+		if feedback, ok := resp["promptFeedback"].(map[string]any); ok && core.GetString(feedback["blockReason"]) == "SAFETY" {
+			extras.SetMetadata(MetaBlockedCategories, blockedCategories(feedback))
+			return msg, "content_filter", extras
+		}
+		return msg, "", extras
 	}
 
 	candidate, ok := candidates[0].(map[string]any)
 	if !ok {
-		return msg, ""
+		return msg, "", extras
 	}
 	content, _ := candidate["content"].(map[string]any)
 	finishReason := mapFinishReason(core.GetString(candidate["finishReason"]))
+	if core.GetString(candidate["finishReason"]) == "SAFETY" {
+		extras.SetMetadata(MetaBlockedCategories, blockedCategories(candidate))
+	}
 
 	// 解析 parts
 	parts, _ := content["parts"].([]any)
 	if len(parts) == 0 {
-		return msg, finishReason
+		// 思考耗尽预算：无输出但非错误
+		if finishReason == "length" {
+			extras.SetMetadata(MetaThinkingExhausted, true)
+		}
+		return msg, finishReason, extras
 	}
 
 	var blocks []llm.ContentBlock
-	var textContent string
+	var textContent strings.Builder
+	var pendingCode *llm.CodeExecutionResultBlock // 等待执行结果的 executableCode
 
 	for _, part := range parts {
 		partMap, ok := part.(map[string]any)
@@ -196,39 +295,188 @@ func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
 					Thinking: text,
 				})
 			} else {
-				// 普通文本
-				if len(blocks) == 0 {
-					textContent = text
-				}
+				// 普通文本（可见回答）
+				textContent.WriteString(text)
 				blocks = append(blocks, &llm.TextBlock{Text: text})
 			}
 		}
 
 		// 函数调用
 		if fc, ok := partMap["functionCall"].(map[string]any); ok {
-			args, _ := fc["args"].(map[string]any)
 			blocks = append(blocks, &llm.ToolCall{
-				ID:    generateToolCallID(), // Gemini 不返回 ID，需要生成
+				ID:    newToolCallID(a.idGenerator), // Gemini 不返回 ID，需要生成
 				Name:  core.GetString(fc["name"]),
-				Input: args,
+				Input: core.GetToolInput(fc["args"]),
 			})
 		}
+
+		// 服务端代码执行
+		if code, ok := partMap["executableCode"].(map[string]any); ok {
+			pendingCode = &llm.CodeExecutionResultBlock{
+				Language: strings.ToLower(core.GetString(code["language"])),
+				Code:     core.GetString(code["code"]),
+			}
+			blocks = append(blocks, pendingCode)
+		}
+		if result, ok := partMap["codeExecutionResult"].(map[string]any); ok {
+			if pendingCode == nil {
+				pendingCode = &llm.CodeExecutionResultBlock{}
+				blocks = append(blocks, pendingCode)
+			}
+			applyCodeExecutionResult(pendingCode, result)
+			pendingCode = nil
+		}
+	}
+
+	// 设置消息内容：Content 始终为全部非思考文本的拼接，思考内容仅保留在 ContentBlocks
+	msg.ContentBlocks = blocks
+	msg.Content = textContent.String()
+	extras.Citations = parseCitations(candidate)
+
+	return msg, finishReason, extras
+}
+
+// blockedCategories 提取 safetyRatings 中被拦截的类别
+//
+// 优先取 blocked 为 true 的评级；均未标记时取概率为 MEDIUM 或 HIGH 的类别。
+func blockedCategories(src map[string]any) []string {
+	ratings, _ := src["safetyRatings"].([]any)
+	var blocked, likely []string
+	for _, r := range ratings {
+		rating, _ := r.(map[string]any)
+		category := core.GetString(rating["category"])
+		if category == "" {
+			continue
+		}
+		if b, _ := rating["blocked"].(bool); b {
+			blocked = append(blocked, category)
+		}
+		switch core.GetString(rating["probability"]) {
+		case "MEDIUM", "HIGH":
+			likely = append(likely, category)
+		}
+	}
+	if len(blocked) > 0 {
+		return blocked
 	}
+	return likely
+}
+
+// parseCitations 解析候选结果中的引用来源
+//
+//   - groundingMetadata（Google Search 接地）：每个 groundingSupports 片段与其引用的
+//     groundingChunks 各生成一条；没有 supports 时每个 chunk 生成一条（仅来源）
+//   - citationMetadata（背诵检测）：citationSources（或 citations）各生成一条
+func parseCitations(candidate map[string]any) []llm.Citation {
+	var citations []llm.Citation
+
+	if grounding, ok := candidate["groundingMetadata"].(map[string]any); ok {
+		chunks, _ := grounding["groundingChunks"].([]any)
+		sources := make([]llm.Citation, len(chunks))
+		for i, chunk := range chunks {
+			c, _ := chunk.(map[string]any)
+			// web（Google Search）或 retrievedContext（Vertex AI Search 等）
+			src, ok := c["web"].(map[string]any)
+			if !ok {
+				src, _ = c["retrievedContext"].(map[string]any)
+			}
+			sources[i] = llm.Citation{SourceURI: core.GetString(src["uri"]), Title: core.GetString(src["title"])}
+		}
 
-	// 设置消息内容
-	if len(blocks) > 0 {
-		msg.ContentBlocks = blocks
-		// 如果只有一个文本块，也设置 Content 字段以保持兼容
-		if len(blocks) == 1 {
-			if tb, ok := blocks[0].(*llm.TextBlock); ok {
-				msg.Content = tb.Text
+		supports, _ := grounding["groundingSupports"].([]any)
+		for _, support := range supports {
+			sp, _ := support.(map[string]any)
+			segment, _ := sp["segment"].(map[string]any)
+			indices, _ := sp["groundingChunkIndices"].([]any)
+			for _, idx := range indices {
+				i := int(core.GetInt64(idx))
+				if i < 0 || i >= len(sources) {
+					continue
+				}
+				citation := sources[i]
+				citation.StartIndex = int(core.GetInt64(segment["startIndex"]))
+				citation.EndIndex = int(core.GetInt64(segment["endIndex"]))
+				citation.Text = core.GetString(segment["text"])
+				citations = append(citations, citation)
 			}
 		}
-	} else if textContent != "" {
-		msg.Content = textContent
+		if len(supports) == 0 {
+			citations = append(citations, sources...)
+		}
+	}
+
+	if meta, ok := candidate["citationMetadata"].(map[string]any); ok {
+		list, ok := meta["citationSources"].([]any)
+		if !ok {
+			list, _ = meta["citations"].([]any)
+		}
+		for _, item := range list {
+			src, _ := item.(map[string]any)
+			citations = append(citations, llm.Citation{
+				SourceURI:  core.GetString(src["uri"]),
+				Title:      core.GetString(src["title"]),
+				StartIndex: int(core.GetInt64(src["startIndex"])),
+				EndIndex:   int(core.GetInt64(src["endIndex"])),
+			})
+		}
+	}
+
+	return citations
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 服务端代码执行
+// ═══════════════════════════════════════════════════════════════════════════
+
+// outcomeOK 代码执行成功的 outcome
+const outcomeOK = "OUTCOME_OK"
+
+// applyCodeExecutionResult 将 codeExecutionResult 写入代码执行块
+//
+// Gemini 不区分 stdout/stderr：OUTCOME_OK 时 output 记为 Stdout、ReturnCode 为 0；
+// 其他 outcome（OUTCOME_FAILED、OUTCOME_DEADLINE_EXCEEDED）记为 Stderr、ReturnCode 为 1，
+// 并将 outcome 保存在 ErrorCode 中。
+func applyCodeExecutionResult(block *llm.CodeExecutionResultBlock, result map[string]any) {
+	outcome := core.GetString(result["outcome"])
+	output := core.GetString(result["output"])
+	if outcome == outcomeOK {
+		block.Stdout = output
+		return
+	}
+	block.Stderr = output
+	block.ReturnCode = 1
+	block.ErrorCode = outcome
+}
+
+// codeExecutionParts 将代码执行块还原为 executableCode 与 codeExecutionResult Part
+func codeExecutionParts(b *llm.CodeExecutionResultBlock) []map[string]any {
+	var parts []map[string]any
+	if b.Code != "" {
+		language := b.Language
+		if language == "" {
+			language = "python"
+		}
+		parts = append(parts, map[string]any{
+			"executableCode": map[string]any{
+				"language": strings.ToUpper(language),
+				"code":     b.Code,
+			},
+		})
 	}
 
-	return msg, finishReason
+	outcome := outcomeOK
+	switch {
+	case strings.HasPrefix(b.ErrorCode, "OUTCOME_"):
+		outcome = b.ErrorCode
+	case b.ErrorCode != "" || b.ReturnCode != 0:
+		outcome = "OUTCOME_FAILED"
+	}
+	return append(parts, map[string]any{
+		"codeExecutionResult": map[string]any{
+			"outcome": outcome,
+			"output":  b.Stdout + b.Stderr,
+		},
+	})
 }
 
 // mapFinishReason 将 Gemini 完成原因映射到标准格式
@@ -249,15 +497,17 @@ func mapFinishReason(reason string) string {
 	}
 }
 
-// generateToolCallID 生成工具调用 ID
-//
-// Gemini API 不返回工具调用 ID，需要自行生成。
-// 使用简单的计数器格式，因为 Gemini 的工具调用是顺序的。
-var toolCallCounter int
+// ToolCallIDPrefix 合成工具调用 ID 的前缀
+const ToolCallIDPrefix = "call_"
 
-func generateToolCallID() string {
-	toolCallCounter++
-	return "call_" + string(rune('0'+toolCallCounter%10))
+// newToolCallID 使用生成器合成工具调用 ID
+//
+// Gemini API 不返回工具调用 ID，需要自行生成。未设置生成器时使用 core.DefaultIDGenerator。
+func newToolCallID(gen core.IDGenerator) string {
+	if gen == nil {
+		gen = core.DefaultIDGenerator
+	}
+	return ToolCallIDPrefix + gen.NewID()
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -292,6 +542,11 @@ func (a *Adapter) ConvertUsage(resp map[string]any) *llm.TokenUsage {
 		result.CachedTokens = cachedTokens
 	}
 
+	// 缺少 totalTokenCount 时按输入 + 输出计算（candidatesTokenCount 不含思考 tokens）
+	if result.TotalTokens == 0 {
+		result.TotalTokens = result.InputTokens + result.OutputTokens + result.ReasoningTokens
+	}
+
 	return result
 }
 
@@ -1,6 +1,7 @@
 package gemini
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
@@ -57,6 +58,31 @@ func TestAdapter_ConvertToAPI_RoleMapping(t *testing.T) {
 	}
 }
 
+func TestAdapter_ConvertToAPI_TextAndImages(t *testing.T) {
+	adapter := NewAdapter()
+	messages := []llm.Message{
+		{
+			Role: llm.RoleUser,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.TextBlock{Text: "Compare these two charts."},
+				&llm.ImageBlock{Data: "aGVsbG8=", MediaType: "image/png"},
+				&llm.ImageBlock{Data: "d29ybGQ=", MediaType: "image/jpeg"},
+				&llm.ImageBlock{URL: "gs://bucket/c.webp", MediaType: "image/webp"},
+			},
+		},
+	}
+
+	result := adapter.ConvertToAPI(messages)
+
+	require.Len(t, result, 1)
+	assert.Equal(t, []map[string]any{
+		{"text": "Compare these two charts."},
+		{"inlineData": map[string]any{"mimeType": "image/png", "data": "aGVsbG8="}},
+		{"inlineData": map[string]any{"mimeType": "image/jpeg", "data": "d29ybGQ="}},
+		{"fileData": map[string]any{"fileUri": "gs://bucket/c.webp", "mimeType": "image/webp"}},
+	}, result[0]["parts"])
+}
+
 func TestAdapter_ConvertToAPI_ToolCall(t *testing.T) {
 	adapter := NewAdapter()
 	messages := []llm.Message{
@@ -127,13 +153,43 @@ func TestAdapter_ConvertToAPI_ToolResult(t *testing.T) {
 	fr, ok := parts[0]["functionResponse"].(map[string]any)
 	require.True(t, ok, "Expected functionResponse part")
 
-	assert.Equal(t, "get_weather", fr["name"]) // ToolUseID 作为函数名
+	assert.Equal(t, "get_weather", fr["name"]) // 找不到对应调用时退回 ToolUseID
 
 	response, ok := fr["response"].(map[string]any)
 	require.True(t, ok)
 	assert.Equal(t, "Temperature: 25°C, Sunny", response["content"])
 }
 
+func TestAdapter_ConvertToAPI_ToolResultName(t *testing.T) {
+	adapter := NewAdapter()
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather and time in Paris?"},
+		{
+			Role: llm.RoleAssistant,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+				&llm.ToolCall{ID: "call_2", Name: "get_time", Input: map[string]any{"city": "Paris"}},
+			},
+		},
+		{
+			Role: llm.RoleUser,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ToolResultBlock{ToolUseID: "call_1", Content: "Sunny"},                   // 按 ID 查找调用名
+				&llm.ToolResultBlock{ToolUseID: "call_2", Name: "get_time", Content: "12:00"}, // 显式名称
+			},
+		},
+	}
+
+	result := adapter.ConvertToAPI(messages)
+	require.Len(t, result, 3)
+
+	parts, ok := result[2]["parts"].([]map[string]any)
+	require.True(t, ok)
+	require.Len(t, parts, 2)
+	assert.Equal(t, "get_weather", parts[0]["functionResponse"].(map[string]any)["name"])
+	assert.Equal(t, "get_time", parts[1]["functionResponse"].(map[string]any)["name"])
+}
+
 func TestAdapter_ConvertToAPI_ThinkingBlock(t *testing.T) {
 	adapter := NewAdapter()
 	messages := []llm.Message{
@@ -190,9 +246,9 @@ func TestAdapter_ConvertToAPI_EmptyContent(t *testing.T) {
 	result := adapter.ConvertToAPI(messages)
 
 	require.Len(t, result, 1)
-	// 空内容消息应该没有 parts 或 parts 为空
-	parts, _ := result[0]["parts"].([]map[string]any)
-	assert.Empty(t, parts, "Empty content should have no parts")
+	// Gemini 要求 parts 非空：保留下来的空用户消息以单个空文本 part 发送
+	// （是否保留由 Transformer 决定，见 SetKeepEmptyUserMessages）
+	assert.Equal(t, []map[string]any{{"text": ""}}, result[0]["parts"])
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -217,7 +273,7 @@ func TestAdapter_ConvertFromAPI_TextResponse(t *testing.T) {
 		},
 	}
 
-	msg, finishReason := adapter.ConvertFromAPI(apiResp)
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
 	assert.Equal(t, "Hello! How can I help you today?", msg.Content)
@@ -250,7 +306,7 @@ func TestAdapter_ConvertFromAPI_ToolCallResponse(t *testing.T) {
 		},
 	}
 
-	msg, _ := adapter.ConvertFromAPI(apiResp)
+	msg, _, _ := adapter.ConvertFromAPI(apiResp)
 
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
 	require.Len(t, msg.ContentBlocks, 2, "Expected text + tool_call")
@@ -267,6 +323,47 @@ func TestAdapter_ConvertFromAPI_ToolCallResponse(t *testing.T) {
 	assert.Equal(t, "London", toolCall.Input["city"])
 	// Gemini 不返回 ID，应该是生成的
 	assert.NotEmpty(t, toolCall.ID)
+
+	assert.Equal(t, "I'll check the weather for you.", msg.Content)
+}
+
+func TestAdapter_ConvertFromAPI_ToolCallIDsUnique(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"candidates": []any{
+			map[string]any{
+				"content": map[string]any{
+					"role": "model",
+					"parts": []any{
+						map[string]any{"functionCall": map[string]any{"name": "search"}},
+						map[string]any{"functionCall": map[string]any{"name": "search"}},
+					},
+				},
+			},
+		},
+	}
+
+	// 500 个 goroutine 并发转换，每次 2 个工具调用，共 1000 个 ID
+	const goroutines = 500
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+		wg   sync.WaitGroup
+	)
+	for range goroutines {
+		wg.Go(func() {
+			msg, _, _ := adapter.ConvertFromAPI(apiResp)
+			calls := msg.GetToolCalls()
+			mu.Lock()
+			defer mu.Unlock()
+			for _, call := range calls {
+				seen[call.ID] = true
+			}
+		})
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, goroutines*2, "tool call IDs must not repeat")
 }
 
 func TestAdapter_ConvertFromAPI_ThinkingResponse(t *testing.T) {
@@ -291,7 +388,7 @@ func TestAdapter_ConvertFromAPI_ThinkingResponse(t *testing.T) {
 		},
 	}
 
-	msg, _ := adapter.ConvertFromAPI(apiResp)
+	msg, _, _ := adapter.ConvertFromAPI(apiResp)
 
 	require.Len(t, msg.ContentBlocks, 2)
 
@@ -304,6 +401,126 @@ func TestAdapter_ConvertFromAPI_ThinkingResponse(t *testing.T) {
 	textBlock, ok := msg.ContentBlocks[1].(*llm.TextBlock)
 	require.True(t, ok)
 	assert.Equal(t, "The answer is 42.", textBlock.Text)
+
+	// Content 为可见回答，不含思考内容
+	assert.Equal(t, "The answer is 42.", msg.Content)
+}
+
+func TestAdapter_ConvertFromAPI_ContentJoinsVisibleText(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"candidates": []any{
+			map[string]any{
+				"content": map[string]any{
+					"role": "model",
+					"parts": []any{
+						map[string]any{"text": "Hmm...", "thought": true},
+						map[string]any{"text": "The answer "},
+						map[string]any{"text": "is 42."},
+						map[string]any{"functionCall": map[string]any{"name": "log", "args": map[string]any{}}},
+					},
+				},
+				"finishReason": "STOP",
+			},
+		},
+	}
+
+	msg, _, _ := adapter.ConvertFromAPI(apiResp)
+
+	require.Len(t, msg.ContentBlocks, 4)
+	assert.Equal(t, "The answer is 42.", msg.Content)
+	assert.Equal(t, "Hmm...", msg.GetReasoning())
+}
+
+func TestAdapter_ConvertFromAPI_CodeExecution(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"candidates": []any{
+			map[string]any{
+				"content": map[string]any{
+					"role": "model",
+					"parts": []any{
+						map[string]any{"text": "Computing."},
+						map[string]any{"executableCode": map[string]any{"language": "PYTHON", "code": "print(2 ** 10)"}},
+						map[string]any{"codeExecutionResult": map[string]any{"outcome": "OUTCOME_OK", "output": "1024\n"}},
+						map[string]any{"executableCode": map[string]any{"language": "PYTHON", "code": "1 / 0"}},
+						map[string]any{"codeExecutionResult": map[string]any{"outcome": "OUTCOME_FAILED", "output": "ZeroDivisionError"}},
+						map[string]any{"text": "2^10 = 1024."},
+					},
+				},
+				"finishReason": "STOP",
+			},
+		},
+	}
+
+	msg, _, _ := adapter.ConvertFromAPI(apiResp)
+
+	assert.Equal(t, "Computing.2^10 = 1024.", msg.Content)
+	require.Len(t, msg.ContentBlocks, 4)
+	assert.Equal(t, &llm.CodeExecutionResultBlock{
+		Language: "python",
+		Code:     "print(2 ** 10)",
+		Stdout:   "1024\n",
+	}, msg.ContentBlocks[1])
+	assert.Equal(t, &llm.CodeExecutionResultBlock{
+		Language:   "python",
+		Code:       "1 / 0",
+		Stderr:     "ZeroDivisionError",
+		ReturnCode: 1,
+		ErrorCode:  "OUTCOME_FAILED",
+	}, msg.ContentBlocks[2])
+
+	// 回放时还原为 executableCode + codeExecutionResult
+	result := adapter.ConvertToAPI([]llm.Message{msg})
+	require.Len(t, result, 1)
+	parts, ok := result[0]["parts"].([]map[string]any)
+	require.True(t, ok)
+	require.Len(t, parts, 6)
+	assert.Equal(t, map[string]any{"language": "PYTHON", "code": "print(2 ** 10)"}, parts[1]["executableCode"])
+	assert.Equal(t, map[string]any{"outcome": "OUTCOME_OK", "output": "1024\n"}, parts[2]["codeExecutionResult"])
+	assert.Equal(t, map[string]any{"outcome": "OUTCOME_FAILED", "output": "ZeroDivisionError"}, parts[4]["codeExecutionResult"])
+}
+
+func TestAdapter_ConvertFromAPI_Citations(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"candidates": []any{
+			map[string]any{
+				"content": map[string]any{
+					"role":  "model",
+					"parts": []any{map[string]any{"text": "Spain won Euro 2024."}},
+				},
+				"finishReason": "STOP",
+				"groundingMetadata": map[string]any{
+					"webSearchQueries": []any{"who won euro 2024"},
+					"groundingChunks": []any{
+						map[string]any{"web": map[string]any{"uri": "https://uefa.com/euro", "title": "uefa.com"}},
+						map[string]any{"web": map[string]any{"uri": "https://example.com/news", "title": "example.com"}},
+					},
+					"groundingSupports": []any{
+						map[string]any{
+							"segment":               map[string]any{"startIndex": float64(0), "endIndex": float64(20), "text": "Spain won Euro 2024."},
+							"groundingChunkIndices": []any{float64(0), float64(1)},
+						},
+					},
+				},
+				"citationMetadata": map[string]any{
+					"citationSources": []any{
+						map[string]any{"startIndex": float64(6), "endIndex": float64(19), "uri": "https://github.com/x/y", "license": "mit"},
+					},
+				},
+			},
+		},
+	}
+
+	msg, _, extras := adapter.ConvertFromAPI(apiResp)
+
+	assert.Nil(t, msg.Meta)
+	assert.Equal(t, []llm.Citation{
+		{SourceURI: "https://uefa.com/euro", Title: "uefa.com", StartIndex: 0, EndIndex: 20, Text: "Spain won Euro 2024."},
+		{SourceURI: "https://example.com/news", Title: "example.com", StartIndex: 0, EndIndex: 20, Text: "Spain won Euro 2024."},
+		{SourceURI: "https://github.com/x/y", StartIndex: 6, EndIndex: 19},
+	}, extras.Citations)
 }
 
 func TestAdapter_ConvertFromAPI_FinishReasonMapping(t *testing.T) {
@@ -335,7 +552,7 @@ func TestAdapter_ConvertFromAPI_FinishReasonMapping(t *testing.T) {
 			},
 		}
 
-		_, finishReason := adapter.ConvertFromAPI(apiResp)
+		_, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 		assert.Equal(t, tc.expectedReason, finishReason,
 			"Gemini reason %q should map to %q", tc.geminiReason, tc.expectedReason)
@@ -348,13 +565,67 @@ func TestAdapter_ConvertFromAPI_EmptyCandidates(t *testing.T) {
 		"candidates": []any{},
 	}
 
-	msg, finishReason := adapter.ConvertFromAPI(apiResp)
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 	assert.Equal(t, llm.RoleAssistant, msg.Role)
 	assert.Empty(t, msg.Content)
 	assert.Empty(t, finishReason)
 }
 
+func TestAdapter_ConvertFromAPI_ThinkingExhausted(t *testing.T) {
+	adapter := NewAdapter()
+
+	// 思考耗尽预算：MAX_TOKENS 且无 parts
+	msg, finishReason, extras := adapter.ConvertFromAPI(map[string]any{
+		"candidates": []any{
+			map[string]any{"content": map[string]any{"role": "model"}, "finishReason": "MAX_TOKENS"},
+		},
+	})
+	assert.Equal(t, "length", finishReason)
+	assert.Empty(t, msg.Content)
+	assert.Empty(t, msg.ContentBlocks)
+	assert.Nil(t, msg.Meta)
+	assert.Equal(t, true, extras.Metadata[MetaThinkingExhausted])
+
+	// 正常停止且无 parts：不标记
+	_, finishReason, extras = adapter.ConvertFromAPI(map[string]any{
+		"candidates": []any{map[string]any{"finishReason": "STOP"}},
+	})
+	assert.Equal(t, "stop", finishReason)
+	assert.Nil(t, extras.Metadata)
+}
+
+func TestAdapter_ConvertFromAPI_SafetyBlocked(t *testing.T) {
+	adapter := NewAdapter()
+
+	// 回答被拦截：取 blocked 为 true 的类别
+	msg, finishReason, extras := adapter.ConvertFromAPI(map[string]any{
+		"candidates": []any{map[string]any{
+			"finishReason": "SAFETY",
+			"safetyRatings": []any{
+				map[string]any{"category": "HARM_CATEGORY_HARASSMENT", "probability": "MEDIUM"},
+				map[string]any{"category": "HARM_CATEGORY_DANGEROUS_CONTENT", "probability": "HIGH", "blocked": true},
+			},
+		}},
+	})
+	assert.Equal(t, "content_filter", finishReason)
+	assert.Nil(t, msg.Meta)
+	assert.Equal(t, []string{"HARM_CATEGORY_DANGEROUS_CONTENT"}, extras.Metadata[MetaBlockedCategories])
+
+	// 提示词被拦截：无候选结果，未标记 blocked 时按概率判断
+	_, finishReason, extras = adapter.ConvertFromAPI(map[string]any{
+		"promptFeedback": map[string]any{
+			"blockReason": "SAFETY",
+			"safetyRatings": []any{
+				map[string]any{"category": "HARM_CATEGORY_HARASSMENT", "probability": "MEDIUM"},
+				map[string]any{"category": "HARM_CATEGORY_HATE_SPEECH", "probability": "LOW"},
+			},
+		},
+	})
+	assert.Equal(t, "content_filter", finishReason)
+	assert.Equal(t, []string{"HARM_CATEGORY_HARASSMENT"}, extras.Metadata[MetaBlockedCategories])
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // ConvertUsage 测试
 // ═══════════════════════════════════════════════════════════════════════════
@@ -411,6 +682,25 @@ func TestAdapter_ConvertUsage_WithCachedTokens(t *testing.T) {
 	assert.Equal(t, int64(80), usage.CachedTokens)
 }
 
+func TestAdapter_ConvertUsage_StringCountsMissingTotal(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"usageMetadata": map[string]any{
+			"promptTokenCount":     "100",
+			"candidatesTokenCount": "50",
+			"thoughtsTokenCount":   "30",
+		},
+	}
+
+	usage := adapter.ConvertUsage(apiResp)
+
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(100), usage.InputTokens)
+	assert.Equal(t, int64(50), usage.OutputTokens)
+	assert.Equal(t, int64(30), usage.ReasoningTokens)
+	assert.Equal(t, int64(180), usage.TotalTokens, "缺失时由输入、输出与思考 Token 求和")
+}
+
 func TestAdapter_ConvertUsage_NoUsage(t *testing.T) {
 	adapter := NewAdapter()
 	apiResp := map[string]any{}
@@ -22,6 +22,29 @@
 //	  "generationConfig": {...}
 //	}
 //
+// # 工具调用匹配
+//
+// Gemini 不返回工具调用 ID，按函数名匹配 functionCall 与 functionResponse。
+// 流式与非流式解析共用同一 ID 生成器（core.WithIDGenerator）合成 ID，仅用于本地配对；
+// 回放时 functionResponse.name 取 ToolResultBlock.Name，未设置时按 ToolUseID 查找
+// 历史中对应调用的函数名。Agent 执行工具时会设置 Name。
+//
+// # 引用来源
+//
+// 候选结果的 groundingMetadata（Google Search 接地）与 citationMetadata 解析为
+// []llm.Citation，经 core.ResponseExtras 写入 Response.Citations。
+//
+// # 响应标记
+//
+// 思考耗尽预算（[MetaThinkingExhausted]）与安全拦截类别（[MetaBlockedCategories]）经
+// core.ResponseExtras 写入 Response.Metadata，不会出现在 Message.Meta 中。
+//
+// # 代码执行
+//
+// 启用 codeExecution 工具后，executableCode 与其后的 codeExecutionResult Part 合并为
+// [llm.CodeExecutionResultBlock]（OUTCOME_OK 的输出记为 Stdout，其他 outcome 记为 Stderr），
+// 回放历史时还原为原始 Part。
+//
 // # Thinking 支持
 //
 // Gemini 2.5 系列模型支持 thinking/thoughts 能力：
@@ -37,4 +60,7 @@
 //	    "thinkingBudget": 32768
 //	  }
 //	}
+//
+// 新模型使用 thinkingLevel（"low"/"medium"/"high"）替代 thinkingBudget，
+// 两者不能同时出现，同时配置时以 thinkingLevel 为准。
 package gemini
@@ -36,13 +36,22 @@ import (
 //	  }],
 //	  "usageMetadata": {...}
 //	}
-type EventHandler struct{}
+type EventHandler struct {
+	idGenerator core.IDGenerator // 工具调用 ID 生成器（nil 时使用默认）
+}
 
 // NewEventHandler 创建 Gemini 事件处理器
 func NewEventHandler() *EventHandler {
 	return &EventHandler{}
 }
 
+// SetIDGenerator 设置工具调用 ID 生成器
+//
+// 实现 core.IDGeneratorSetter 接口。
+func (h *EventHandler) SetIDGenerator(gen core.IDGenerator) {
+	h.idGenerator = gen
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // HandleEvent - 处理流式事件
 // ═══════════════════════════════════════════════════════════════════════════
@@ -53,7 +62,9 @@ func NewEventHandler() *EventHandler {
 //   - 响应结构为 candidates[0].content.parts[]
 //   - parts 数组可能包含多个元素（文本、工具调用、thinking）
 //   - thought: true 标记 thinking 内容
-//   - functionCall 格式与 OpenAI 不同
+//   - functionCall 格式与 OpenAI 不同：每个 part 携带完整的调用（含全部 args），
+//     不会分片，因此一个 functionCall 直接生成一个带 ID 的完整工具调用事件
+//   - 最后一个响应块可能同时包含 parts 与 finishReason，先输出内容再输出完成事件
 func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*llm.Event, bool) {
 	var result []*llm.Event
 
@@ -68,28 +79,9 @@ func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*ll
 		return result, false
 	}
 
-	// 检查完成原因
-	if fr, hasFinish := candidate["finishReason"].(string); hasFinish && fr != "" {
-		// 映射 Gemini 完成原因到标准格式
-		finishReason := mapFinishReasonForEvent(fr)
-		result = append(result, &llm.Event{
-			Type:         llm.EventTypeDone,
-			FinishReason: finishReason,
-		})
-		return result, true // 停止处理
-	}
-
-	// 提取 content
-	content, ok := candidate["content"].(map[string]any)
-	if !ok {
-		return result, false
-	}
-
-	// 解析 parts
-	parts, ok := content["parts"].([]any)
-	if !ok || len(parts) == 0 {
-		return result, false
-	}
+	// 解析 parts（可能为空，如仅含 finishReason 的结束块）
+	content, _ := candidate["content"].(map[string]any)
+	parts, _ := content["parts"].([]any)
 
 	// 处理每个 part
 	for i, part := range parts {
@@ -136,7 +128,7 @@ func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*ll
 				Type: llm.EventTypeToolCall,
 				ToolCall: &llm.ToolCallDelta{
 					Index:          i,
-					ID:             generateStreamToolCallID(),
+					ID:             newToolCallID(h.idGenerator),
 					Name:           name,
 					ArgumentsDelta: argsDelta,
 				},
@@ -144,6 +136,17 @@ func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*ll
 		}
 	}
 
+	// 检查完成原因（在内容之后输出）
+	if fr, hasFinish := candidate["finishReason"].(string); hasFinish && fr != "" {
+		// 映射 Gemini 完成原因到标准格式
+		result = append(result, &llm.Event{
+			Type:         llm.EventTypeDone,
+			FinishReason: mapFinishReasonForEvent(fr),
+			Usage:        NewAdapter().ConvertUsage(data),
+		})
+		return result, true // 停止处理
+	}
+
 	return result, false
 }
 
@@ -165,14 +168,6 @@ func mapFinishReasonForEvent(reason string) string {
 	}
 }
 
-// 流式工具调用 ID 计数器
-var streamToolCallCounter int
-
-func generateStreamToolCallID() string {
-	streamToolCallCounter++
-	return "gemini_call_" + string(rune('a'+streamToolCallCounter%26))
-}
-
 // ═══════════════════════════════════════════════════════════════════════════
 // ShouldStopOnData - 检查终止信号
 // ═══════════════════════════════════════════════════════════════════════════
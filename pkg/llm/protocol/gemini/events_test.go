@@ -103,6 +103,40 @@ func TestEventHandler_HandleEvent_FunctionCall(t *testing.T) {
 	assert.NotEmpty(t, events[0].ToolCall.ID)
 }
 
+func TestEventHandler_HandleEvent_FunctionCallWithFinishReason(t *testing.T) {
+	handler := NewEventHandler()
+	handler.SetIDGenerator(core.NewSequentialIDGenerator())
+	data := map[string]any{
+		"candidates": []any{
+			map[string]any{
+				"content": map[string]any{
+					"role": "model",
+					"parts": []any{
+						map[string]any{
+							"functionCall": map[string]any{
+								"name": "get_weather",
+								"args": map[string]any{"city": "Tokyo", "days": float64(3)},
+							},
+						},
+					},
+				},
+				"finishReason": "STOP",
+			},
+		},
+	}
+
+	events, stop := handler.HandleEvent("", data)
+
+	// 工具调用先于完成事件输出，不会被丢弃
+	assert.True(t, stop)
+	require.Len(t, events, 2)
+	assert.Equal(t, llm.EventTypeToolCall, events[0].Type)
+	assert.Equal(t, "call_1", events[0].ToolCall.ID)
+	assert.JSONEq(t, `{"city":"Tokyo","days":3}`, events[0].ToolCall.ArgumentsDelta)
+	assert.Equal(t, llm.EventTypeDone, events[1].Type)
+	assert.Equal(t, "stop", events[1].FinishReason)
+}
+
 func TestEventHandler_HandleEvent_FinishReason_Stop(t *testing.T) {
 	handler := NewEventHandler()
 	data := map[string]any{
@@ -123,6 +157,33 @@ func TestEventHandler_HandleEvent_FinishReason_Stop(t *testing.T) {
 	assert.Equal(t, "stop", events[0].FinishReason) // STOP -> stop
 }
 
+func TestEventHandler_HandleEvent_FinishReasonUsage(t *testing.T) {
+	handler := NewEventHandler()
+	data := map[string]any{
+		"candidates": []any{
+			map[string]any{"finishReason": "STOP"},
+		},
+		"usageMetadata": map[string]any{
+			"promptTokenCount":     float64(10),
+			"candidatesTokenCount": float64(4),
+			"totalTokenCount":      float64(14),
+		},
+	}
+
+	events, _ := handler.HandleEvent("", data)
+
+	require.Len(t, events, 1)
+	require.NotNil(t, events[0].Usage)
+	assert.Equal(t, int64(14), events[0].Usage.TotalTokens)
+
+	// 中间块不携带完成原因，不产生用量
+	events, _ = handler.HandleEvent("", map[string]any{
+		"candidates": []any{map[string]any{"finishReason": "STOP"}},
+	})
+	require.Len(t, events, 1)
+	assert.Nil(t, events[0].Usage)
+}
+
 func TestEventHandler_HandleEvent_FinishReasonMapping(t *testing.T) {
 	handler := NewEventHandler()
 
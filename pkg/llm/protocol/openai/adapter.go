@@ -2,6 +2,7 @@ package openai
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
@@ -34,7 +35,10 @@ func NewAdapter() *Adapter {
 // ConvertToAPI 实现 OpenAI 特有的消息转换逻辑
 //
 // OpenAI 协议要求：
-//   - ToolResult 必须展开为独立的 tool 角色消息
+//   - ToolResult 必须展开为独立的 tool 角色消息；RoleTool 消息（Content + ToolCallID）直接转换
+//   - tool 消息必须紧跟在发起调用的 assistant 消息之后，因此同一轮中与
+//     ToolResult 混合的文本块与图片块统一放在全部 tool 消息之后，作为一条 user 消息发送
+//     （tool 消息的 content 只能是文本，图片无法内联在工具结果中）
 //   - 工具调用参数必须序列化为 JSON 字符串
 //   - 包含工具调用的消息必须有 content 字段（即使为空）
 func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
@@ -48,24 +52,58 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 
 		// ⚠️ OpenAI 特殊处理：ToolResult 展开为独立消息
 		if hasToolResults(msg.ContentBlocks) {
+			var (
+				texts []string
+				rest  []llm.ContentBlock
+			)
 			for _, block := range msg.ContentBlocks {
-				if tr, ok := block.(*llm.ToolResultBlock); ok {
+				switch b := block.(type) {
+				case *llm.ToolResultBlock:
 					result = append(result, map[string]any{
 						"role":         "tool",
-						"tool_call_id": tr.ToolUseID,
-						"content":      tr.Content,
+						"tool_call_id": b.ToolUseID,
+						"content":      b.Content,
 					})
+				case *llm.TextBlock:
+					texts = append(texts, b.Text)
+					rest = append(rest, b)
+				case *llm.ImageBlock:
+					rest = append(rest, b)
 				}
 			}
-			// 处理完所有 ToolResult 后跳过这条消息
+			// 附带的文本与图片放在所有 tool 消息之后，保持调用 → 结果 → 追加说明的因果顺序
+			if parts := contentParts(llm.Message{ContentBlocks: rest}); parts != nil {
+				result = append(result, map[string]any{
+					"role":    string(llm.RoleUser),
+					"content": parts,
+				})
+			} else if len(texts) > 0 {
+				result = append(result, map[string]any{
+					"role":    string(llm.RoleUser),
+					"content": strings.Join(texts, "\n"),
+				})
+			}
+			continue
+		}
+
+		// RoleTool 消息：已是 OpenAI 的 tool 消息形式
+		if msg.Role == llm.RoleTool {
+			result = append(result, map[string]any{
+				"role":         "tool",
+				"tool_call_id": msg.ToolCallID,
+				"content":      extractTextContent(msg),
+			})
 			continue
 		}
 
 		// 构建普通消息
 		m := map[string]any{"role": string(msg.Role)}
 
-		// 提取文本内容
-		if content := extractTextContent(msg); content != "" {
+		// 提取文本内容（用户消息始终携带 content，空消息由 Transformer 决定是否保留）；
+		// 含图片时 content 为 text / image_url 分段数组
+		if parts := contentParts(msg); parts != nil {
+			m["content"] = parts
+		} else if content := extractTextContent(msg); content != "" || msg.Role == llm.RoleUser {
 			m["content"] = content
 		}
 
@@ -86,6 +124,50 @@ func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
 	return result
 }
 
+// contentParts 构建含图片消息的分段 content，消息不含图片时返回 nil
+//
+// 格式：[{"type": "text", "text": "..."}, {"type": "image_url", "image_url": {"url": "...", "detail": "auto"}}]
+// Base64 数据转换为 data URL；detail 未设置时为 "auto"。
+func contentParts(msg llm.Message) []map[string]any {
+	hasImage, hasText := false, false
+	for _, b := range msg.ContentBlocks {
+		switch b.(type) {
+		case *llm.ImageBlock:
+			hasImage = true
+		case *llm.TextBlock:
+			hasText = true
+		}
+	}
+	if !hasImage {
+		return nil
+	}
+
+	// 与 extractTextContent 一致：无 TextBlock 时使用 Content
+	parts := make([]map[string]any, 0, len(msg.ContentBlocks)+1)
+	if !hasText && msg.Content != "" {
+		parts = append(parts, map[string]any{"type": "text", "text": msg.Content})
+	}
+	for _, block := range msg.ContentBlocks {
+		switch b := block.(type) {
+		case *llm.TextBlock:
+			parts = append(parts, map[string]any{"type": "text", "text": b.Text})
+		case *llm.ImageBlock:
+			url := b.URL
+			if url == "" {
+				url = "data:" + b.MediaType + ";base64," + b.Data
+			}
+			parts = append(parts, map[string]any{
+				"type": "image_url",
+				"image_url": map[string]any{
+					"url":    url,
+					"detail": b.ResolvedDetail(),
+				},
+			})
+		}
+	}
+	return parts
+}
+
 // extractToolCalls 提取工具调用（OpenAI 格式）
 //
 // OpenAI 要求：
@@ -124,23 +206,27 @@ func extractToolCalls(blocks []llm.ContentBlock) []map[string]any {
 //	  "choices": [{
 //	    "message": {
 //	      "content": "...",
+//	      "reasoning_content": "...",  // DeepSeek R1 等推理模型（OpenRouter 等网关为 "reasoning"）
 //	      "tool_calls": [{"function": {"arguments": "{...}"}}]
 //	    },
 //	    "finish_reason": "stop"
 //	  }]
 //	}
-func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
+//
+// reasoning_content 作为 ThinkingBlock 放在 ContentBlocks 开头，Content 仍保留文本。
+// choice.logprobs 解析为 extras.Logprobs。
+func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string, core.ResponseExtras) {
 	msg := llm.Message{Role: llm.RoleAssistant}
 
 	// 提取 choices[0]
 	choices, _ := resp["choices"].([]any)
 	if len(choices) == 0 {
-		return msg, ""
+		return msg, "", core.ResponseExtras{}
 	}
 
 	choice, ok := choices[0].(map[string]any)
 	if !ok {
-		return msg, ""
+		return msg, "", core.ResponseExtras{}
 	}
 	messageData, _ := choice["message"].(map[string]any)
 	finishReason, _ := choice["finish_reason"].(string)
@@ -150,9 +236,22 @@ func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
 		msg.Content = content
 	}
 
+	// 提取推理内容 (DeepSeek R1, Kimi thinking, OpenRouter)
+	var reasoningBlocks []llm.ContentBlock
+	if reasoning := reasoningText(messageData); reasoning != "" {
+		reasoningBlocks = append(reasoningBlocks, &llm.ThinkingBlock{Thinking: reasoning})
+	}
+
 	// 提取工具调用
-	if toolCalls, ok := messageData["tool_calls"].([]any); ok {
-		var blocks []llm.ContentBlock
+	toolCalls, hasToolCalls := messageData["tool_calls"].([]any)
+	if !hasToolCalls && len(reasoningBlocks) > 0 {
+		msg.ContentBlocks = reasoningBlocks
+		if msg.Content != "" {
+			msg.ContentBlocks = append(msg.ContentBlocks, &llm.TextBlock{Text: msg.Content})
+		}
+	}
+	if hasToolCalls {
+		blocks := reasoningBlocks
 
 		// 如果有文本内容，添加 TextBlock
 		if msg.Content != "" {
@@ -171,15 +270,10 @@ func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
 			}
 
 			// ⚠️ 关键差异：反序列化 JSON 字符串
-			var args map[string]any
-			if argsStr, ok := fn["arguments"].(string); ok {
-				_ = json.Unmarshal([]byte(argsStr), &args) // ← 从字符串解析
-			}
-
 			blocks = append(blocks, &llm.ToolCall{
 				ID:    core.GetString(tcMap["id"]),
 				Name:  core.GetString(fn["name"]),
-				Input: args,
+				Input: core.GetToolInput(fn["arguments"]), // ← 从字符串解析
 			})
 		}
 
@@ -188,7 +282,59 @@ func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
 		msg.Content = "" // 清空，使用 ContentBlocks
 	}
 
-	return msg, finishReason
+	return msg, finishReason, core.ResponseExtras{Logprobs: parseLogprobs(choice)}
+}
+
+// parseLogprobs 解析 choice.logprobs.content 中的 token 对数概率
+//
+// 非流式响应与流式 delta 结构相同，均位于 choice 下。
+func parseLogprobs(choice map[string]any) []llm.TokenLogprob {
+	logprobs, _ := choice["logprobs"].(map[string]any)
+	content, _ := logprobs["content"].([]any)
+	if len(content) == 0 {
+		return nil
+	}
+
+	result := make([]llm.TokenLogprob, 0, len(content))
+	for _, item := range content {
+		if lp, ok := item.(map[string]any); ok {
+			result = append(result, parseTokenLogprob(lp))
+		}
+	}
+	return result
+}
+
+// parseTokenLogprob 解析单个 token 的对数概率（含 top_logprobs 候选）
+func parseTokenLogprob(lp map[string]any) llm.TokenLogprob {
+	token := llm.TokenLogprob{
+		Token:   core.GetString(lp["token"]),
+		Logprob: core.GetFloat64(lp["logprob"]),
+	}
+	if raw, ok := lp["bytes"].([]any); ok {
+		token.Bytes = make([]int, 0, len(raw))
+		for _, b := range raw {
+			token.Bytes = append(token.Bytes, int(core.GetInt64(b)))
+		}
+	}
+	if top, ok := lp["top_logprobs"].([]any); ok {
+		for _, item := range top {
+			if candidate, ok := item.(map[string]any); ok {
+				token.TopLogprobs = append(token.TopLogprobs, parseTokenLogprob(candidate))
+			}
+		}
+	}
+	return token
+}
+
+// reasoningText 提取推理内容
+//
+// DeepSeek 等使用 reasoning_content，OpenRouter 等网关使用 reasoning，前者优先。
+func reasoningText(m map[string]any) string {
+	if text, ok := m["reasoning_content"].(string); ok && text != "" {
+		return text
+	}
+	text, _ := m["reasoning"].(string)
+	return text
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -200,6 +346,7 @@ func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string) {
 // OpenAI 字段名：
 //   - prompt_tokens, completion_tokens, total_tokens
 //   - completion_tokens_details.reasoning_tokens
+//   - completion_tokens_details.accepted_prediction_tokens / rejected_prediction_tokens
 //   - prompt_tokens_details.cached_tokens
 func (a *Adapter) ConvertUsage(resp map[string]any) *llm.TokenUsage {
 	usage, ok := resp["usage"].(map[string]any)
@@ -213,9 +360,11 @@ func (a *Adapter) ConvertUsage(resp map[string]any) *llm.TokenUsage {
 		TotalTokens:  core.GetInt64(usage["total_tokens"]),
 	}
 
-	// 推理 tokens (o1/o3, DeepSeek R1)
+	// 推理 tokens (o1/o3, DeepSeek R1) 与预测输出 tokens
 	if details, ok := usage["completion_tokens_details"].(map[string]any); ok {
 		result.ReasoningTokens = core.GetInt64(details["reasoning_tokens"])
+		result.AcceptedPredictionTokens = core.GetInt64(details["accepted_prediction_tokens"])
+		result.RejectedPredictionTokens = core.GetInt64(details["rejected_prediction_tokens"])
 	}
 
 	// Prompt Caching tokens
@@ -223,6 +372,11 @@ func (a *Adapter) ConvertUsage(resp map[string]any) *llm.TokenUsage {
 		result.CachedTokens = core.GetInt64(details["cached_tokens"])
 	}
 
+	// 部分兼容网关不返回 total_tokens（completion_tokens 已包含推理 tokens）
+	if result.TotalTokens == 0 {
+		result.TotalTokens = result.InputTokens + result.OutputTokens
+	}
+
 	return result
 }
 
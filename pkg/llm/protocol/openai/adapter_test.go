@@ -2,6 +2,7 @@ package openai
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
@@ -170,6 +171,106 @@ func TestAdapter_ConvertToAPI_ToolResult(t *testing.T) {
 	}
 }
 
+func TestAdapter_ConvertToAPI_ToolResultWithText(t *testing.T) {
+	adapter := NewAdapter()
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather and humidity?"},
+		{
+			Role: llm.RoleAssistant,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ToolCall{ID: "call_123", Name: "get_weather", Input: map[string]any{}},
+				&llm.ToolCall{ID: "call_456", Name: "get_humidity", Input: map[string]any{}},
+			},
+		},
+		{
+			Role: llm.RoleUser,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.TextBlock{Text: "Here are the results."},
+				&llm.ToolResultBlock{ToolUseID: "call_123", Content: "Sunny"},
+				&llm.ToolResultBlock{ToolUseID: "call_456", Content: "65%"},
+				&llm.TextBlock{Text: "Please answer in Celsius."},
+			},
+		},
+	}
+
+	result := adapter.ConvertToAPI(messages)
+
+	// user → assistant(tool_calls) → tool → tool → user(文本)
+	require.Len(t, result, 5)
+	require.Equal(t, "assistant", result[1]["role"])
+	require.Equal(t, "tool", result[2]["role"])
+	require.Equal(t, "call_123", result[2]["tool_call_id"])
+	require.Equal(t, "tool", result[3]["role"])
+	require.Equal(t, "call_456", result[3]["tool_call_id"])
+	require.Equal(t, "user", result[4]["role"])
+	require.Equal(t, "Here are the results.\nPlease answer in Celsius.", result[4]["content"])
+}
+
+func TestAdapter_ConvertToAPI_ToolResultWithImage(t *testing.T) {
+	adapter := NewAdapter()
+	messages := []llm.Message{
+		{
+			Role: llm.RoleAssistant,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ToolCall{ID: "call_123", Name: "screenshot", Input: map[string]any{}},
+			},
+		},
+		{
+			Role: llm.RoleUser,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ToolResultBlock{ToolUseID: "call_123", Content: "Captured."},
+				&llm.ImageBlock{Data: "iVBORw0KGgo=", MediaType: "image/png"},
+				&llm.TextBlock{Text: "What does the screen show?"},
+			},
+		},
+	}
+
+	result := adapter.ConvertToAPI(messages)
+
+	// assistant(tool_calls) → tool → user(图片 + 文本分段)
+	require.Len(t, result, 3)
+	require.Equal(t, "tool", result[1]["role"])
+	require.Equal(t, "Captured.", result[1]["content"])
+	require.Equal(t, "user", result[2]["role"])
+
+	want := []map[string]any{
+		{"type": "image_url", "image_url": map[string]any{"url": "data:image/png;base64,iVBORw0KGgo=", "detail": "auto"}},
+		{"type": "text", "text": "What does the screen show?"},
+	}
+	if !reflect.DeepEqual(want, result[2]["content"]) {
+		t.Errorf("Expected image and text parts after tool message, got %v", result[2]["content"])
+	}
+}
+
+func TestAdapter_ConvertToAPI_ToolRoleMessage(t *testing.T) {
+	adapter := NewAdapter()
+	call := llm.Message{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+		&llm.ToolCall{ID: "call_123", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+	}}
+
+	// 两种表示应得到相同的请求消息
+	blockForm := adapter.ConvertToAPI([]llm.Message{call, {
+		Role:          llm.RoleUser,
+		ContentBlocks: []llm.ContentBlock{&llm.ToolResultBlock{ToolUseID: "call_123", Content: "Sunny"}},
+	}})
+	toolForm := adapter.ConvertToAPI([]llm.Message{call, {
+		Role:       llm.RoleTool,
+		ToolCallID: "call_123",
+		Content:    "Sunny",
+	}})
+
+	if len(toolForm) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(toolForm))
+	}
+
+	want := map[string]any{"role": "tool", "tool_call_id": "call_123", "content": "Sunny"}
+	for name, got := range map[string]map[string]any{"ToolResultBlock": blockForm[1], "RoleTool": toolForm[1]} {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+}
+
 func TestAdapter_ConvertToAPI_SkipSystemMessage(t *testing.T) {
 	adapter := NewAdapter()
 	messages := []llm.Message{
@@ -243,7 +344,7 @@ func TestAdapter_ConvertFromAPI_TextResponse(t *testing.T) {
 		},
 	}
 
-	msg, finishReason := adapter.ConvertFromAPI(apiResp)
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 	if msg.Role != llm.RoleAssistant {
 		t.Errorf("Expected role assistant, got %v", msg.Role)
@@ -282,7 +383,7 @@ func TestAdapter_ConvertFromAPI_ToolCallResponse(t *testing.T) {
 		},
 	}
 
-	msg, finishReason := adapter.ConvertFromAPI(apiResp)
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 	if msg.Role != llm.RoleAssistant {
 		t.Errorf("Expected role assistant, got %v", msg.Role)
@@ -335,13 +436,40 @@ func TestAdapter_ConvertFromAPI_ToolCallResponse(t *testing.T) {
 	}
 }
 
+func TestAdapter_ConvertFromAPI_ReasoningContent(t *testing.T) {
+	adapter := NewAdapter()
+	// DeepSeek R1 非流式响应格式
+	apiResp := map[string]any{
+		"model": "deepseek-reasoner",
+		"choices": []any{
+			map[string]any{
+				"message": map[string]any{
+					"role":              "assistant",
+					"content":           "9.11 < 9.9",
+					"reasoning_content": "Compare the decimal parts: 0.11 vs 0.90.",
+				},
+				"finish_reason": "stop",
+			},
+		},
+	}
+
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
+
+	require.Equal(t, "stop", finishReason)
+	require.Equal(t, "9.11 < 9.9", msg.Content)
+	require.Equal(t, "Compare the decimal parts: 0.11 vs 0.90.", msg.GetReasoning())
+	require.Len(t, msg.ContentBlocks, 2)
+	require.IsType(t, &llm.ThinkingBlock{}, msg.ContentBlocks[0])
+	require.IsType(t, &llm.TextBlock{}, msg.ContentBlocks[1])
+}
+
 func TestAdapter_ConvertFromAPI_EmptyChoices(t *testing.T) {
 	adapter := NewAdapter()
 	apiResp := map[string]any{
 		"choices": []any{},
 	}
 
-	msg, finishReason := adapter.ConvertFromAPI(apiResp)
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
 
 	if msg.Role != llm.RoleAssistant {
 		t.Errorf("Expected role assistant, got %v", msg.Role)
@@ -427,6 +555,53 @@ func TestAdapter_ConvertUsage_WithCachedTokens(t *testing.T) {
 	}
 }
 
+func TestAdapter_ConvertUsage_WithPredictionTokens(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"usage": map[string]any{
+			"prompt_tokens":     float64(100),
+			"completion_tokens": float64(50),
+			"total_tokens":      float64(150),
+			"completion_tokens_details": map[string]any{
+				"accepted_prediction_tokens": float64(40),
+				"rejected_prediction_tokens": float64(6),
+			},
+		},
+	}
+
+	usage := adapter.ConvertUsage(apiResp)
+
+	require.NotNil(t, usage, "Expected usage, got nil")
+
+	if usage.AcceptedPredictionTokens != 40 {
+		t.Errorf("Expected AcceptedPredictionTokens 40, got %d", usage.AcceptedPredictionTokens)
+	}
+	if usage.RejectedPredictionTokens != 6 {
+		t.Errorf("Expected RejectedPredictionTokens 6, got %d", usage.RejectedPredictionTokens)
+	}
+}
+
+func TestAdapter_ConvertUsage_StringCountsMissingTotal(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"usage": map[string]any{
+			"prompt_tokens":     "10",
+			"completion_tokens": "5",
+		},
+	}
+
+	usage := adapter.ConvertUsage(apiResp)
+
+	require.NotNil(t, usage, "Expected usage, got nil")
+
+	if usage.InputTokens != 10 || usage.OutputTokens != 5 {
+		t.Errorf("Expected 10/5 tokens, got %d/%d", usage.InputTokens, usage.OutputTokens)
+	}
+	if usage.TotalTokens != 15 {
+		t.Errorf("Expected TotalTokens 15, got %d", usage.TotalTokens)
+	}
+}
+
 func TestAdapter_ConvertUsage_NoUsage(t *testing.T) {
 	adapter := NewAdapter()
 	apiResp := map[string]any{}
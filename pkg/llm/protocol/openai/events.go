@@ -25,6 +25,7 @@ import (
 //	    "delta": {
 //	      "content": "...",                    // 文本增量
 //	      "reasoning_content": "...",          // 推理内容 (DeepSeek R1)
+//	      "reasoning": "...",                  // 推理内容 (OpenRouter 等网关)
 //	      "tool_calls": [{"index": 0, ...}]   // 工具调用增量
 //	    },
 //	    "finish_reason": "stop"
@@ -66,6 +67,7 @@ func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*ll
 		result = append(result, &llm.Event{
 			Type:         llm.EventTypeDone,
 			FinishReason: fr,
+			Usage:        NewAdapter().ConvertUsage(data),
 		})
 		return result, false
 	}
@@ -76,16 +78,18 @@ func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*ll
 		return result, false
 	}
 
-	// 处理文本内容
-	if content, ok := delta["content"].(string); ok && content != "" {
+	// 处理文本内容（Options.Logprobs 开启时附带本次增量的 token 对数概率）
+	content, _ := delta["content"].(string)
+	if logprobs := parseLogprobs(choice); content != "" || len(logprobs) > 0 {
 		result = append(result, &llm.Event{
 			Type:      llm.EventTypeText,
 			TextDelta: content,
+			Logprobs:  logprobs,
 		})
 	}
 
-	// 处理推理内容 (DeepSeek R1, Kimi thinking)
-	if reasoningContent, ok := delta["reasoning_content"].(string); ok && reasoningContent != "" {
+	// 处理推理内容 (DeepSeek R1, Kimi thinking, OpenRouter)
+	if reasoningContent := reasoningText(delta); reasoningContent != "" {
 		result = append(result, &llm.Event{
 			Type: llm.EventTypeReasoning,
 			Reasoning: &llm.ReasoningDelta{
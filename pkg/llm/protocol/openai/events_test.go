@@ -43,6 +43,28 @@ func TestEventHandler_HandleEvent_TextDelta(t *testing.T) {
 	}
 }
 
+func TestEventHandler_HandleEvent_GatewayReasoning(t *testing.T) {
+	handler := NewEventHandler()
+	data := map[string]any{
+		"choices": []any{
+			map[string]any{
+				"delta": map[string]any{
+					"reasoning": "Step 1...",
+				},
+			},
+		},
+	}
+
+	chunks, _ := handler.HandleEvent("", data)
+
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if chunks[0].Reasoning == nil || chunks[0].Reasoning.ThoughtDelta != "Step 1..." {
+		t.Errorf("Expected ThoughtDelta 'Step 1...', got %+v", chunks[0].Reasoning)
+	}
+}
+
 func TestEventHandler_HandleEvent_ReasoningContent(t *testing.T) {
 	handler := NewEventHandler()
 	data := map[string]any{
@@ -204,6 +226,33 @@ func TestEventHandler_HandleEvent_FinishReason(t *testing.T) {
 	}
 }
 
+func TestEventHandler_HandleEvent_FinishReasonUsage(t *testing.T) {
+	handler := NewEventHandler()
+	data := map[string]any{
+		"choices": []any{
+			map[string]any{"finish_reason": "stop"},
+		},
+		"usage": map[string]any{
+			"prompt_tokens":     float64(10),
+			"completion_tokens": float64(5),
+			"total_tokens":      float64(15),
+		},
+	}
+
+	chunks, _ := handler.HandleEvent("", data)
+
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	usage := chunks[0].Usage
+	if usage == nil {
+		t.Fatal("Expected Usage to be non-nil")
+	}
+	if usage.InputTokens != 10 || usage.OutputTokens != 5 || usage.TotalTokens != 15 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+}
+
 func TestEventHandler_HandleEvent_EmptyChoices(t *testing.T) {
 	handler := NewEventHandler()
 	data := map[string]any{
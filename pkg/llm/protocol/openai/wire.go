@@ -0,0 +1,169 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// OpenAI 线格式互转
+// ═══════════════════════════════════════════════════════════════════════════
+
+// MessagesToWire 将统一消息转换为 OpenAI Chat Completions 线格式
+//
+// 与 [Adapter.ConvertToAPI] 规则相同，但保留系统消息（按原位置输出为 system 角色），
+// 适用于导出到 OpenAI 格式的存储或其他库。
+func MessagesToWire(messages []llm.Message) []map[string]any {
+	adapter := NewAdapter()
+	result := make([]map[string]any, 0, len(messages))
+
+	for _, msg := range messages {
+		if msg.Role == llm.RoleSystem {
+			result = append(result, map[string]any{
+				"role":    string(llm.RoleSystem),
+				"content": extractTextContent(msg),
+			})
+			continue
+		}
+		result = append(result, adapter.ConvertToAPI([]llm.Message{msg})...)
+	}
+
+	return result
+}
+
+// MessagesFromWire 将 OpenAI Chat Completions 线格式转换为统一消息
+//
+// 转换规则：
+//   - system / developer → RoleSystem
+//   - user / assistant 的 content 支持字符串或 text 分段数组（多段以 "\n" 拼接）
+//   - assistant.tool_calls → ToolCall 块（arguments 为空时 Input 为空 map）
+//   - 连续的 tool 消息合并为一条 user 消息，内含多个 ToolResultBlock
+//
+// 未知角色、非文本内容分段或无法解析的工具参数返回错误。
+func MessagesFromWire(raw []map[string]any) ([]llm.Message, error) {
+	result := make([]llm.Message, 0, len(raw))
+
+	for i, m := range raw {
+		role := core.GetString(m["role"])
+
+		content, err := wireContent(m["content"])
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		switch role {
+		case "system", "developer":
+			result = append(result, llm.Message{Role: llm.RoleSystem, Content: content})
+
+		case "user":
+			result = append(result, llm.Message{Role: llm.RoleUser, Content: content})
+
+		case "assistant":
+			msg, err := wireAssistant(m, content)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			result = append(result, msg)
+
+		case "tool":
+			block := &llm.ToolResultBlock{
+				ToolUseID: core.GetString(m["tool_call_id"]),
+				Name:      core.GetString(m["name"]),
+				Content:   content,
+			}
+			// 连续的 tool 消息合并到同一条 user 消息
+			if n := len(result); n > 0 && hasToolResults(result[n-1].ContentBlocks) {
+				result[n-1].ContentBlocks = append(result[n-1].ContentBlocks, block)
+				continue
+			}
+			result = append(result, llm.Message{
+				Role:          llm.RoleUser,
+				ContentBlocks: []llm.ContentBlock{block},
+			})
+
+		default:
+			return nil, fmt.Errorf("message %d: unsupported role %q", i, role)
+		}
+	}
+
+	return result, nil
+}
+
+// wireAssistant 解析 assistant 消息（含 tool_calls）
+func wireAssistant(m map[string]any, content string) (llm.Message, error) {
+	msg := llm.Message{Role: llm.RoleAssistant}
+
+	toolCalls := wireList(m["tool_calls"])
+	if len(toolCalls) == 0 {
+		msg.Content = content
+		return msg, nil
+	}
+
+	if content != "" {
+		msg.ContentBlocks = append(msg.ContentBlocks, &llm.TextBlock{Text: content})
+	}
+	for _, tcMap := range toolCalls {
+		fn, _ := tcMap["function"].(map[string]any)
+		if fn == nil {
+			return msg, errors.New("tool call without function")
+		}
+
+		args := core.GetString(fn["arguments"])
+		if strings.TrimSpace(args) != "" && !json.Valid([]byte(args)) {
+			return msg, fmt.Errorf("tool call %q: invalid arguments JSON", core.GetString(tcMap["id"]))
+		}
+
+		msg.ContentBlocks = append(msg.ContentBlocks, &llm.ToolCall{
+			ID:    core.GetString(tcMap["id"]),
+			Name:  core.GetString(fn["name"]),
+			Input: core.GetToolInput(args),
+		})
+	}
+
+	return msg, nil
+}
+
+// wireContent 解析 content 字段（字符串、null 或 text 分段数组）
+func wireContent(v any) (string, error) {
+	switch c := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return c, nil
+	case []any, []map[string]any:
+		parts := wireList(c)
+		texts := make([]string, 0, len(parts))
+		for _, partMap := range parts {
+			if t := core.GetString(partMap["type"]); t != "text" {
+				return "", fmt.Errorf("unsupported content part type %q", t)
+			}
+			texts = append(texts, core.GetString(partMap["text"]))
+		}
+		return strings.Join(texts, "\n"), nil
+	default:
+		return "", fmt.Errorf("unsupported content type %T", v)
+	}
+}
+
+// wireList 将 JSON 解码得到的 []any 或直接构造的 []map[string]any 统一为对象列表
+func wireList(v any) []map[string]any {
+	switch l := v.(type) {
+	case []map[string]any:
+		return l
+	case []any:
+		result := make([]map[string]any, 0, len(l))
+		for _, item := range l {
+			if m, ok := item.(map[string]any); ok {
+				result = append(result, m)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+package openai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolConversation 含工具调用与工具结果的完整对话
+func toolConversation() []llm.Message {
+	return []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are helpful."},
+		{Role: llm.RoleUser, Content: "Weather in Paris and Rome?"},
+		{
+			Role: llm.RoleAssistant,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.TextBlock{Text: "Checking both."},
+				&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+				&llm.ToolCall{ID: "call_2", Name: "get_weather", Input: map[string]any{"city": "Rome"}},
+			},
+		},
+		{
+			Role: llm.RoleUser,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ToolResultBlock{ToolUseID: "call_1", Content: "Sunny"},
+				&llm.ToolResultBlock{ToolUseID: "call_2", Content: "Rainy"},
+			},
+		},
+		{Role: llm.RoleAssistant, Content: "Paris is sunny, Rome is rainy."},
+	}
+}
+
+func TestMessagesWire_RoundTrip(t *testing.T) {
+	messages := toolConversation()
+
+	wire := MessagesToWire(messages)
+	require.Len(t, wire, 6)
+	assert.Equal(t, "system", wire[0]["role"])
+	assert.Equal(t, "tool", wire[3]["role"])
+	assert.Equal(t, "tool", wire[4]["role"])
+
+	// 经过 JSON 序列化（模拟存储）后再转换回来
+	data, err := json.Marshal(wire)
+	require.NoError(t, err)
+	var stored []map[string]any
+	require.NoError(t, json.Unmarshal(data, &stored))
+
+	got, err := MessagesFromWire(stored)
+	require.NoError(t, err)
+	assert.Equal(t, messages, got)
+
+	// 未经序列化的直接转换同样可逆
+	got, err = MessagesFromWire(wire)
+	require.NoError(t, err)
+	assert.Equal(t, messages, got)
+}
+
+func TestMessagesFromWire_StoredLog(t *testing.T) {
+	raw := `[
+		{"role": "developer", "content": "Be brief."},
+		{"role": "user", "content": [{"type": "text", "text": "What time"}, {"type": "text", "text": "is it?"}]},
+		{"role": "assistant", "content": null, "tool_calls": [
+			{"id": "call_9", "type": "function", "function": {"name": "get_time", "arguments": ""}}
+		]},
+		{"role": "tool", "tool_call_id": "call_9", "content": "12:00"},
+		{"role": "assistant", "content": "It is noon."}
+	]`
+	var stored []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(raw), &stored))
+
+	messages, err := MessagesFromWire(stored)
+	require.NoError(t, err)
+	require.Len(t, messages, 5)
+
+	assert.Equal(t, llm.RoleSystem, messages[0].Role)
+	assert.Equal(t, "What time\nis it?", messages[1].Content)
+
+	calls := messages[2].GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "call_9", calls[0].ID)
+	assert.NotNil(t, calls[0].Input)
+	assert.Empty(t, calls[0].Input)
+
+	result, ok := messages[3].ContentBlocks[0].(*llm.ToolResultBlock)
+	require.True(t, ok)
+	assert.Equal(t, "call_9", result.ToolUseID)
+	assert.Equal(t, "12:00", result.Content)
+
+	assert.Equal(t, "It is noon.", messages[4].Content)
+}
+
+func TestMessagesFromWire_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]any
+	}{
+		{"未知角色", map[string]any{"role": "function", "content": "x"}},
+		{"非文本分段", map[string]any{"role": "user", "content": []any{map[string]any{"type": "image_url"}}}},
+		{"无效参数", map[string]any{"role": "assistant", "tool_calls": []any{
+			map[string]any{"id": "call_1", "function": map[string]any{"name": "f", "arguments": "{bad"}},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MessagesFromWire([]map[string]any{tt.raw})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package responses
+
+import (
+	"encoding/json"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// OpenAI Responses 协议适配器
+// ═══════════════════════════════════════════════════════════════════════════
+
+// MetaResponseID Response.Metadata 中保存响应 ID 的键
+const MetaResponseID = "response_id"
+
+// Adapter OpenAI Responses API 协议适配器
+//
+// 实现 core.ProtocolAdapter 接口。
+//
+// 关键协议差异：
+//  1. 工具调用与工具结果是 input 数组中的独立条目，而非消息字段
+//  2. 工具参数序列化为 JSON 字符串，使用 call_id 关联
+//  3. 系统消息：独立的 instructions 参数
+//  4. Token 字段名：input_tokens, output_tokens
+type Adapter struct{}
+
+// NewAdapter 创建 Responses 协议适配器
+func NewAdapter() *Adapter {
+	return &Adapter{}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ConvertToAPI - 消息转换为 input 条目
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ConvertToAPI 将消息转换为 Responses API 的 input 数组
+//
+// 转换规则：
+//   - 文本内容 → {"role": ..., "content": "..."}
+//   - ToolCall → {"type": "function_call", "call_id", "name", "arguments"}
+//   - ToolResult → {"type": "function_call_output", "call_id", "output"}
+func (a *Adapter) ConvertToAPI(messages []llm.Message) []map[string]any {
+	result := make([]map[string]any, 0, len(messages))
+
+	for _, msg := range messages {
+		// 跳过系统消息（由 instructions 参数处理）
+		if msg.Role == llm.RoleSystem {
+			continue
+		}
+
+		// 空的用户消息由 Transformer 决定是否保留，保留时以空字符串发送
+		if content := extractTextContent(msg); content != "" || (msg.Role == llm.RoleUser && len(msg.ContentBlocks) == 0) {
+			result = append(result, map[string]any{
+				"role":    string(msg.Role),
+				"content": content,
+			})
+		}
+
+		for _, block := range msg.ContentBlocks {
+			switch b := block.(type) {
+			case *llm.ToolCall:
+				args, _ := json.Marshal(b.Input) //nolint:errchkjson // best effort
+				result = append(result, map[string]any{
+					"type":      "function_call",
+					"call_id":   b.ID,
+					"name":      b.Name,
+					"arguments": string(args),
+				})
+			case *llm.ToolResultBlock:
+				result = append(result, map[string]any{
+					"type":    "function_call_output",
+					"call_id": b.ToolUseID,
+					"output":  b.Content,
+				})
+			}
+		}
+	}
+
+	return result
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ConvertFromAPI - 解析 output 条目
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ConvertFromAPI 解析 Responses API 响应为统一 Message
+//
+// output 条目映射：
+//   - message → TextBlock
+//   - reasoning → ThinkingBlock（取 summary 文本）
+//   - function_call → ToolCall
+//
+// 响应 ID 写入 extras.Metadata[MetaResponseID]。
+func (a *Adapter) ConvertFromAPI(resp map[string]any) (llm.Message, string, core.ResponseExtras) {
+	msg := llm.Message{Role: llm.RoleAssistant}
+
+	var (
+		blocks   []llm.ContentBlock
+		text     string
+		hasTools bool
+	)
+
+	output, _ := resp["output"].([]any)
+	for _, item := range output {
+		itemMap, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		switch itemMap["type"] {
+		case "message":
+			content, _ := itemMap["content"].([]any)
+			for _, part := range content {
+				partMap, ok := part.(map[string]any)
+				if !ok || partMap["type"] != "output_text" {
+					continue
+				}
+				t := core.GetString(partMap["text"])
+				text += t
+				blocks = append(blocks, &llm.TextBlock{Text: t})
+			}
+
+		case "reasoning":
+			summary, _ := itemMap["summary"].([]any)
+			for _, part := range summary {
+				if partMap, ok := part.(map[string]any); ok {
+					if t := core.GetString(partMap["text"]); t != "" {
+						blocks = append(blocks, &llm.ThinkingBlock{Thinking: t})
+					}
+				}
+			}
+
+		case "function_call":
+			hasTools = true
+			blocks = append(blocks, &llm.ToolCall{
+				ID:    core.GetString(itemMap["call_id"]),
+				Name:  core.GetString(itemMap["name"]),
+				Input: core.GetToolInput(itemMap["arguments"]),
+			})
+		}
+	}
+
+	// 仅有文本时使用 Content，否则使用 ContentBlocks
+	if len(blocks) > 0 && !onlyText(blocks) {
+		msg.ContentBlocks = blocks
+	} else {
+		msg.Content = text
+	}
+
+	var extras core.ResponseExtras
+	if id := core.GetString(resp["id"]); id != "" {
+		extras.SetMetadata(MetaResponseID, id)
+	}
+
+	return msg, finishReason(resp, hasTools), extras
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ConvertUsage - 解析 Token 使用量
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ConvertUsage 解析 Responses API 的 Token 使用量
+//
+// 字段名：
+//   - input_tokens, output_tokens, total_tokens
+//   - input_tokens_details.cached_tokens
+//   - output_tokens_details.reasoning_tokens
+func (a *Adapter) ConvertUsage(resp map[string]any) *llm.TokenUsage {
+	usage, ok := resp["usage"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	result := &llm.TokenUsage{
+		InputTokens:  core.GetInt64(usage["input_tokens"]),
+		OutputTokens: core.GetInt64(usage["output_tokens"]),
+		TotalTokens:  core.GetInt64(usage["total_tokens"]),
+	}
+
+	if details, ok := usage["input_tokens_details"].(map[string]any); ok {
+		result.CachedTokens = core.GetInt64(details["cached_tokens"])
+	}
+	if details, ok := usage["output_tokens_details"].(map[string]any); ok {
+		result.ReasoningTokens = core.GetInt64(details["reasoning_tokens"])
+	}
+
+	// 缺少 total_tokens 时按输入 + 输出计算（output_tokens 已包含推理 tokens）
+	if result.TotalTokens == 0 {
+		result.TotalTokens = result.InputTokens + result.OutputTokens
+	}
+
+	return result
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// GetSystemMessageHandling - 系统消息策略
+// ═══════════════════════════════════════════════════════════════════════════
+
+// GetSystemMessageHandling 返回 Responses API 的系统消息处理策略
+//
+// 使用 SystemSeparate：系统消息作为独立的 "instructions" 参数传递。
+func (a *Adapter) GetSystemMessageHandling() core.SystemMessageStrategy {
+	return core.SystemSeparate
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 辅助函数
+// ═══════════════════════════════════════════════════════════════════════════
+
+// extractTextContent 提取消息文本内容
+func extractTextContent(msg llm.Message) string {
+	if msg.Content != "" {
+		return msg.Content
+	}
+	var text string
+	for _, block := range msg.ContentBlocks {
+		if tb, ok := block.(*llm.TextBlock); ok {
+			text += tb.Text
+		}
+	}
+	return text
+}
+
+// onlyText 检查内容块是否全部为文本
+func onlyText(blocks []llm.ContentBlock) bool {
+	for _, block := range blocks {
+		if _, ok := block.(*llm.TextBlock); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// finishReason 根据响应状态推导标准 finish_reason
+//
+// 映射：
+//   - 含 function_call 条目 → tool_calls
+//   - status=incomplete 且原因为 max_output_tokens → length
+//   - status=failed → error
+//   - 其他 → stop
+func finishReason(resp map[string]any, hasTools bool) string {
+	if hasTools {
+		return "tool_calls"
+	}
+	switch resp["status"] {
+	case "incomplete":
+		if details, ok := resp["incomplete_details"].(map[string]any); ok {
+			if details["reason"] == "max_output_tokens" {
+				return "length"
+			}
+			return core.GetString(details["reason"])
+		}
+		return "length"
+	case "failed":
+		return "error"
+	default:
+		return "stop"
+	}
+}
+
+// 确保 Adapter 实现了 ProtocolAdapter 接口
+var _ core.ProtocolAdapter = (*Adapter)(nil)
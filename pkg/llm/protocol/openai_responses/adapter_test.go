@@ -0,0 +1,184 @@
+package responses
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ConvertToAPI 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestAdapter_ConvertToAPI_TextMessage(t *testing.T) {
+	adapter := NewAdapter()
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "Be brief"},
+		{Role: llm.RoleUser, Content: "Hello"},
+	}
+
+	result := adapter.ConvertToAPI(messages)
+
+	require.Len(t, result, 1)
+	assert.Equal(t, "user", result[0]["role"])
+	assert.Equal(t, "Hello", result[0]["content"])
+}
+
+func TestAdapter_ConvertToAPI_ToolRoundTrip(t *testing.T) {
+	adapter := NewAdapter()
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather in Paris?"},
+		{
+			Role: llm.RoleAssistant,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.TextBlock{Text: "Checking."},
+				&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+			},
+		},
+		{
+			Role: llm.RoleUser,
+			ContentBlocks: []llm.ContentBlock{
+				&llm.ToolResultBlock{ToolUseID: "call_1", Content: "Sunny"},
+			},
+		},
+	}
+
+	result := adapter.ConvertToAPI(messages)
+
+	require.Len(t, result, 4)
+	assert.Equal(t, "Checking.", result[1]["content"])
+	assert.Equal(t, "function_call", result[2]["type"])
+	assert.Equal(t, "call_1", result[2]["call_id"])
+	assert.Equal(t, "get_weather", result[2]["name"])
+	assert.JSONEq(t, `{"city":"Paris"}`, result[2]["arguments"].(string))
+	assert.Equal(t, "function_call_output", result[3]["type"])
+	assert.Equal(t, "call_1", result[3]["call_id"])
+	assert.Equal(t, "Sunny", result[3]["output"])
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ConvertFromAPI 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestAdapter_ConvertFromAPI_TextResponse(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"id":     "resp_123",
+		"status": "completed",
+		"output": []any{
+			map[string]any{
+				"type": "message",
+				"role": "assistant",
+				"content": []any{
+					map[string]any{"type": "output_text", "text": "Hi there"},
+				},
+			},
+		},
+	}
+
+	msg, finishReason, extras := adapter.ConvertFromAPI(apiResp)
+
+	assert.Equal(t, llm.RoleAssistant, msg.Role)
+	assert.Equal(t, "Hi there", msg.Content)
+	assert.Empty(t, msg.ContentBlocks)
+	assert.Equal(t, "stop", finishReason)
+	assert.Nil(t, msg.Meta)
+	assert.Equal(t, "resp_123", extras.Metadata[MetaResponseID])
+}
+
+func TestAdapter_ConvertFromAPI_ReasoningAndToolCall(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"status": "completed",
+		"output": []any{
+			map[string]any{
+				"type":    "reasoning",
+				"summary": []any{map[string]any{"type": "summary_text", "text": "Need weather"}},
+			},
+			map[string]any{
+				"type":      "function_call",
+				"call_id":   "call_1",
+				"name":      "get_weather",
+				"arguments": `{"city":"Paris"}`,
+			},
+		},
+	}
+
+	msg, finishReason, _ := adapter.ConvertFromAPI(apiResp)
+
+	assert.Equal(t, "tool_calls", finishReason)
+	require.Len(t, msg.ContentBlocks, 2)
+
+	thinking, ok := msg.ContentBlocks[0].(*llm.ThinkingBlock)
+	require.True(t, ok)
+	assert.Equal(t, "Need weather", thinking.Thinking)
+
+	calls := msg.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "call_1", calls[0].ID)
+	assert.Equal(t, "get_weather", calls[0].Name)
+	assert.Equal(t, "Paris", calls[0].Input["city"])
+}
+
+func TestAdapter_ConvertFromAPI_Incomplete(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"status":             "incomplete",
+		"incomplete_details": map[string]any{"reason": "max_output_tokens"},
+	}
+
+	_, finishReason, _ := adapter.ConvertFromAPI(apiResp)
+
+	assert.Equal(t, "length", finishReason)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ConvertUsage 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestAdapter_ConvertUsage(t *testing.T) {
+	adapter := NewAdapter()
+	apiResp := map[string]any{
+		"usage": map[string]any{
+			"input_tokens":          float64(100),
+			"output_tokens":         float64(50),
+			"total_tokens":          float64(150),
+			"input_tokens_details":  map[string]any{"cached_tokens": float64(40)},
+			"output_tokens_details": map[string]any{"reasoning_tokens": float64(20)},
+		},
+	}
+
+	usage := adapter.ConvertUsage(apiResp)
+
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(100), usage.InputTokens)
+	assert.Equal(t, int64(50), usage.OutputTokens)
+	assert.Equal(t, int64(150), usage.TotalTokens)
+	assert.Equal(t, int64(40), usage.CachedTokens)
+	assert.Equal(t, int64(20), usage.ReasoningTokens)
+
+	assert.Nil(t, adapter.ConvertUsage(map[string]any{}))
+}
+
+func TestAdapter_ConvertUsage_StringCountsMissingTotal(t *testing.T) {
+	usage := NewAdapter().ConvertUsage(map[string]any{
+		"usage": map[string]any{
+			"input_tokens":  "100",
+			"output_tokens": json.Number("50"),
+		},
+	})
+
+	require.NotNil(t, usage)
+	assert.Equal(t, int64(100), usage.InputTokens)
+	assert.Equal(t, int64(50), usage.OutputTokens)
+	assert.Equal(t, int64(150), usage.TotalTokens)
+}
+
+func TestAdapter_GetSystemMessageHandling(t *testing.T) {
+	assert.Equal(t, core.SystemSeparate, NewAdapter().GetSystemMessageHandling())
+}
@@ -0,0 +1,41 @@
+// Package responses 实现 OpenAI Responses API (/v1/responses) 的协议适配器
+//
+// Responses API 是 OpenAI 较新的接口，新特性通常优先在此发布。
+// 与 Chat Completions 相比，请求与响应结构差异较大。
+//
+// # 协议特点
+//
+//   - 输入格式：input 数组，消息与 function_call / function_call_output 条目混排
+//   - 系统消息：使用独立的 instructions 字段
+//   - 输出格式：output 数组（message、reasoning、function_call 条目）
+//   - 工具格式：扁平的 {type: "function", name, parameters}
+//   - 服务端状态：通过 previous_response_id 续接上一轮对话
+//
+// # 请求格式示例
+//
+//	{
+//	  "model": "gpt-4o",
+//	  "instructions": "...",
+//	  "input": [
+//	    {"role": "user", "content": "..."},
+//	    {"type": "function_call", "call_id": "call_1", "name": "...", "arguments": "{...}"},
+//	    {"type": "function_call_output", "call_id": "call_1", "output": "..."}
+//	  ],
+//	  "previous_response_id": "resp_..."
+//	}
+//
+// # 响应格式示例
+//
+//	{
+//	  "id": "resp_...",
+//	  "status": "completed",
+//	  "output": [
+//	    {"type": "reasoning", "summary": [{"type": "summary_text", "text": "..."}]},
+//	    {"type": "message", "content": [{"type": "output_text", "text": "..."}]},
+//	    {"type": "function_call", "call_id": "call_1", "name": "...", "arguments": "{...}"}
+//	  ],
+//	  "usage": {"input_tokens": 10, "output_tokens": 20, "total_tokens": 30}
+//	}
+//
+// 响应 ID 写入 Response.Metadata["response_id"]，供下一轮请求设置 Options.PreviousResponseID。
+package responses
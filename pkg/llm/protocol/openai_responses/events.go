@@ -0,0 +1,158 @@
+package responses
+
+import (
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// Responses API SSE 事件处理器
+// ═══════════════════════════════════════════════════════════════════════════
+
+// EventHandler Responses API SSE 事件处理器
+//
+// 实现 core.EventHandler 接口。
+//
+// 流式格式：
+//   - 有显式事件类型（event: response.output_text.delta 等）
+//   - 使用 output_index 关联工具调用
+//   - 无终止信号字符串（使用 response.completed 事件）
+//
+// 事件类型：
+//   - response.output_text.delta:             文本增量
+//   - response.output_item.added:             输出条目开始（包含工具调用初始化）
+//   - response.function_call_arguments.delta: 工具参数增量
+//   - response.reasoning_summary_text.delta:  推理摘要增量
+//   - response.completed / response.incomplete: 响应结束
+//   - response.failed / error:                 错误
+type EventHandler struct{}
+
+// NewEventHandler 创建 Responses 事件处理器
+func NewEventHandler() *EventHandler {
+	return &EventHandler{}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// HandleEvent - 处理流式事件
+// ═══════════════════════════════════════════════════════════════════════════
+
+// HandleEvent 处理 Responses API 流式事件
+//
+// 完成事件的 Delta 字段携带 {"response_id": "..."}，供续接对话使用。
+func (h *EventHandler) HandleEvent(eventType string, data map[string]any) ([]*llm.Event, bool) {
+	var result []*llm.Event
+
+	// 部分代理不转发 event 行，回退到 data.type
+	if eventType == "" {
+		eventType = core.GetString(data["type"])
+	}
+
+	switch eventType {
+	case "response.output_text.delta":
+		if delta := core.GetString(data["delta"]); delta != "" {
+			result = append(result, &llm.Event{
+				Type:      llm.EventTypeText,
+				TextDelta: delta,
+			})
+		}
+
+	case "response.output_item.added":
+		// 工具调用开始
+		if item, ok := data["item"].(map[string]any); ok && item["type"] == "function_call" {
+			result = append(result, &llm.Event{
+				Type: llm.EventTypeToolCall,
+				ToolCall: &llm.ToolCallDelta{
+					Index:          int(core.GetFloat64(data["output_index"])),
+					ID:             core.GetString(item["call_id"]),
+					Name:           core.GetString(item["name"]),
+					ArgumentsDelta: core.GetString(item["arguments"]),
+				},
+			})
+		}
+
+	case "response.function_call_arguments.delta":
+		if delta := core.GetString(data["delta"]); delta != "" {
+			result = append(result, &llm.Event{
+				Type: llm.EventTypeToolCall,
+				ToolCall: &llm.ToolCallDelta{
+					Index:          int(core.GetFloat64(data["output_index"])),
+					ArgumentsDelta: delta,
+				},
+			})
+		}
+
+	case "response.reasoning_summary_text.delta":
+		if delta := core.GetString(data["delta"]); delta != "" {
+			result = append(result, &llm.Event{
+				Type: llm.EventTypeReasoning,
+				Reasoning: &llm.ReasoningDelta{
+					ThoughtDelta: delta,
+				},
+			})
+		}
+
+	case "response.completed", "response.incomplete":
+		resp, _ := data["response"].(map[string]any)
+		result = append(result, &llm.Event{
+			Type:         llm.EventTypeDone,
+			FinishReason: finishReason(resp, hasFunctionCall(resp)),
+			Usage:        NewAdapter().ConvertUsage(resp),
+			Delta:        map[string]any{MetaResponseID: core.GetString(resp["id"])},
+		})
+		return result, true
+
+	case "response.failed", "error":
+		result = append(result, &llm.Event{
+			Type:         llm.EventTypeError,
+			ErrorMessage: errorMessage(data),
+		})
+		return result, true
+
+	default:
+		// 其他事件（response.created、content_part 等）无需处理
+	}
+
+	return result, false
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ShouldStopOnData - 检查终止信号
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ShouldStopOnData 检查是否应在特定数据时停止
+//
+// Responses API 使用事件类型表示结束，所以总是返回 false。
+func (h *EventHandler) ShouldStopOnData(data string) bool {
+	return false
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 辅助函数
+// ═══════════════════════════════════════════════════════════════════════════
+
+// hasFunctionCall 检查响应 output 中是否包含工具调用
+func hasFunctionCall(resp map[string]any) bool {
+	output, _ := resp["output"].([]any)
+	for _, item := range output {
+		if itemMap, ok := item.(map[string]any); ok && itemMap["type"] == "function_call" {
+			return true
+		}
+	}
+	return false
+}
+
+// errorMessage 提取错误事件中的错误消息
+func errorMessage(data map[string]any) string {
+	if msg := core.GetString(data["message"]); msg != "" {
+		return msg
+	}
+	if resp, ok := data["response"].(map[string]any); ok {
+		if errData, ok := resp["error"].(map[string]any); ok {
+			return core.GetString(errData["message"])
+		}
+	}
+	return "response failed"
+}
+
+// 确保 EventHandler 实现了 core.EventHandler 接口
+var _ core.EventHandler = (*EventHandler)(nil)
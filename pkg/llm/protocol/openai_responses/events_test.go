@@ -0,0 +1,87 @@
+package responses
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestEventHandler_TextDelta(t *testing.T) {
+	h := NewEventHandler()
+
+	events, stop := h.HandleEvent("response.output_text.delta", map[string]any{"delta": "Hel"})
+
+	assert.False(t, stop)
+	require.Len(t, events, 1)
+	assert.Equal(t, llm.EventTypeText, events[0].Type)
+	assert.Equal(t, "Hel", events[0].TextDelta)
+}
+
+func TestEventHandler_ToolCall(t *testing.T) {
+	h := NewEventHandler()
+
+	events, _ := h.HandleEvent("response.output_item.added", map[string]any{
+		"output_index": float64(1),
+		"item": map[string]any{
+			"type":      "function_call",
+			"call_id":   "call_1",
+			"name":      "get_weather",
+			"arguments": "",
+		},
+	})
+	require.Len(t, events, 1)
+	assert.Equal(t, 1, events[0].ToolCall.Index)
+	assert.Equal(t, "call_1", events[0].ToolCall.ID)
+	assert.Equal(t, "get_weather", events[0].ToolCall.Name)
+
+	events, _ = h.HandleEvent("response.function_call_arguments.delta", map[string]any{
+		"output_index": float64(1),
+		"delta":        `{"city":`,
+	})
+	require.Len(t, events, 1)
+	assert.Equal(t, 1, events[0].ToolCall.Index)
+	assert.Equal(t, `{"city":`, events[0].ToolCall.ArgumentsDelta)
+}
+
+func TestEventHandler_Completed(t *testing.T) {
+	h := NewEventHandler()
+
+	events, stop := h.HandleEvent("response.completed", map[string]any{
+		"response": map[string]any{
+			"id":     "resp_1",
+			"status": "completed",
+			"output": []any{map[string]any{"type": "function_call"}},
+		},
+	})
+
+	assert.True(t, stop)
+	require.Len(t, events, 1)
+	assert.Equal(t, llm.EventTypeDone, events[0].Type)
+	assert.Equal(t, "tool_calls", events[0].FinishReason)
+	assert.Equal(t, map[string]any{MetaResponseID: "resp_1"}, events[0].Delta)
+}
+
+func TestEventHandler_FallbackToDataType(t *testing.T) {
+	h := NewEventHandler()
+
+	events, _ := h.HandleEvent("", map[string]any{"type": "response.output_text.delta", "delta": "x"})
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "x", events[0].TextDelta)
+}
+
+func TestEventHandler_Failed(t *testing.T) {
+	h := NewEventHandler()
+
+	events, stop := h.HandleEvent("response.failed", map[string]any{
+		"response": map[string]any{"error": map[string]any{"message": "server overloaded"}},
+	})
+
+	assert.True(t, stop)
+	require.Len(t, events, 1)
+	assert.Equal(t, llm.EventTypeError, events[0].Type)
+	assert.Equal(t, "server overloaded", events[0].ErrorMessage)
+}
@@ -0,0 +1,259 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// Message Batches API
+// ═══════════════════════════════════════════════════════════════════════════
+
+// BatchStatus 批处理状态
+type BatchStatus string
+
+const (
+	// BatchStatusInProgress 处理中
+	BatchStatusInProgress BatchStatus = "in_progress"
+
+	// BatchStatusCanceling 取消中
+	BatchStatusCanceling BatchStatus = "canceling"
+
+	// BatchStatusEnded 已结束（结果可获取）
+	BatchStatusEnded BatchStatus = "ended"
+)
+
+// BatchRequest 批处理中的单个请求
+//
+// 请求体复用 Complete 的请求构建逻辑，CustomID 用于在结果中关联请求。
+type BatchRequest struct {
+	CustomID string
+	Messages []llm.Message
+	Options  *llm.Options
+}
+
+// BatchRequestCounts 批处理中各状态的请求数量
+type BatchRequestCounts struct {
+	Processing int64
+	Succeeded  int64
+	Errored    int64
+	Canceled   int64
+	Expired    int64
+}
+
+// Batch 批处理信息
+type Batch struct {
+	ID            string
+	Status        BatchStatus
+	RequestCounts BatchRequestCounts
+	ResultsURL    string
+	CreatedAt     string
+	EndedAt       string
+}
+
+// IsEnded 批处理是否已结束
+func (b *Batch) IsEnded() bool {
+	return b.Status == BatchStatusEnded
+}
+
+// BatchResult 批处理中单个请求的结果
+type BatchResult struct {
+	CustomID string
+	Type     string        // "succeeded", "errored", "canceled", "expired"
+	Response *llm.Response // 仅 Type 为 succeeded 时有值
+	Error    string        // 仅 Type 为 errored 时有值（原始错误 JSON）
+}
+
+// CreateBatch 创建消息批处理
+//
+// 每个请求与 Complete 经过相同的校验与构建流程（工具定义、图片、模型能力、缓存参数等），
+// 请求体不含 stream 字段；任一请求校验失败时不发送批处理。返回批处理 ID，
+// 之后可通过 GetBatch 轮询状态，状态为 ended 后调用 GetBatchResults 获取结果。
+//
+// 批处理以单个 HTTP 请求提交：各请求的 Options.Headers 合并后随批处理发送
+// （anthropic-beta 取并集，其余同名请求头取值须一致），Options.APIKey 须一致。
+func (c *Client) CreateBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	items := make([]map[string]any, 0, len(requests))
+	headers := map[string]string{}
+	var (
+		betas  []string
+		apiKey string
+	)
+	for i, r := range requests {
+		prepared, err := c.PrepareRequest(ctx, r.Messages, r.Options, c, false)
+		if err != nil {
+			return "", fmt.Errorf("batch request %q: %w", r.CustomID, err)
+		}
+		delete(prepared.Body, "stream")
+		items = append(items, map[string]any{
+			"custom_id": r.CustomID,
+			"params":    prepared.Body,
+		})
+
+		opts := prepared.Options
+		if opts == nil {
+			opts = &llm.Options{}
+		}
+		if i > 0 && opts.APIKey != apiKey {
+			return "", llm.NewRequestError("validate",
+				fmt.Errorf("batch request %q: all requests in a batch must use the same APIKey", r.CustomID))
+		}
+		apiKey = opts.APIKey
+		for k, v := range opts.Headers {
+			if strings.EqualFold(k, "anthropic-beta") {
+				for beta := range strings.SplitSeq(v, ",") {
+					if beta = strings.TrimSpace(beta); beta != "" && !slices.Contains(betas, beta) {
+						betas = append(betas, beta)
+					}
+				}
+				continue
+			}
+			if prev, ok := headers[k]; ok && prev != v {
+				return "", llm.NewRequestError("validate",
+					fmt.Errorf("batch request %q: conflicting values for header %q", r.CustomID, k))
+			}
+			headers[k] = v
+		}
+	}
+	if len(betas) > 0 {
+		headers["anthropic-beta"] = strings.Join(betas, ",")
+	}
+
+	bodyBytes, err := json.Marshal(map[string]any{"requests": items})
+	if err != nil {
+		return "", llm.NewRequestError("marshal request", err)
+	}
+
+	var apiResp map[string]any
+	req := core.ApplyHeaders(c.NewRequest(ctx), headers)
+	if apiKey != "" {
+		req.SetHeaders(c.config.BuildAPIKeyHeaders(apiKey))
+	}
+	resp, err := req.
+		SetBody(bodyBytes).
+		SetResult(&apiResp).
+		Post("/messages/batches")
+	if err != nil {
+		return "", llm.NewHTTPError("request failed", err)
+	}
+	if err := c.CheckResponse(resp); err != nil {
+		return "", err
+	}
+
+	return parseBatch(apiResp).ID, nil
+}
+
+// GetBatch 获取批处理状态
+func (c *Client) GetBatch(ctx context.Context, batchID string) (*Batch, error) {
+	var apiResp map[string]any
+	resp, err := c.NewRequest(ctx).
+		SetResult(&apiResp).
+		Get("/messages/batches/" + batchID)
+	if err != nil {
+		return nil, llm.NewHTTPError("request failed", err)
+	}
+	if err := c.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	return parseBatch(apiResp), nil
+}
+
+// GetBatchResults 获取批处理结果
+//
+// 结果以 JSONL 格式返回，每行对应一个请求。仅在批处理状态为 ended 后可用。
+func (c *Client) GetBatchResults(ctx context.Context, batchID string) ([]BatchResult, error) {
+	resp, err := c.NewRequest(ctx).
+		Get("/messages/batches/" + batchID + "/results")
+	if err != nil {
+		return nil, llm.NewHTTPError("request failed", err)
+	}
+	if err := c.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	scanner := bufio.NewScanner(bytes.NewReader(resp.Body()))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var item map[string]any
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, llm.NewResponseError("results", err)
+		}
+		results = append(results, c.parseBatchResult(item))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, llm.NewResponseError("results", err)
+	}
+
+	return results, nil
+}
+
+// parseBatch 解析批处理对象
+func parseBatch(data map[string]any) *Batch {
+	batch := &Batch{}
+	batch.ID, _ = data["id"].(string)
+	if status, ok := data["processing_status"].(string); ok {
+		batch.Status = BatchStatus(status)
+	}
+	batch.ResultsURL, _ = data["results_url"].(string)
+	batch.CreatedAt, _ = data["created_at"].(string)
+	batch.EndedAt, _ = data["ended_at"].(string)
+
+	if counts, ok := data["request_counts"].(map[string]any); ok {
+		batch.RequestCounts = BatchRequestCounts{
+			Processing: core.GetInt64(counts["processing"]),
+			Succeeded:  core.GetInt64(counts["succeeded"]),
+			Errored:    core.GetInt64(counts["errored"]),
+			Canceled:   core.GetInt64(counts["canceled"]),
+			Expired:    core.GetInt64(counts["expired"]),
+		}
+	}
+
+	return batch
+}
+
+// parseBatchResult 解析单条批处理结果
+func (c *Client) parseBatchResult(item map[string]any) BatchResult {
+	result := BatchResult{}
+	result.CustomID, _ = item["custom_id"].(string)
+
+	res, _ := item["result"].(map[string]any)
+	result.Type, _ = res["type"].(string)
+
+	switch result.Type {
+	case "succeeded":
+		message, _ := res["message"].(map[string]any)
+		msg, finishReason, usage, extras := c.transformer.ParseAPIResponse(message)
+		model, _ := message["model"].(string)
+		result.Response = &llm.Response{
+			Message:      msg,
+			FinishReason: finishReason,
+			Model:        model,
+			Reasoning:    msg.GetReasoning(),
+			Usage:        usage,
+		}
+		extras.Apply(result.Response)
+		result.Response.SetCacheStatus()
+	case "errored":
+		if errData, ok := res["error"]; ok {
+			errBytes, _ := json.Marshal(errData) //nolint:errchkjson // best effort
+			result.Error = string(errBytes)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,248 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// Message Batches API 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestClient_Batch_CreatePollResults(t *testing.T) {
+	var polls atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /messages/batches", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-key", r.Header.Get("X-Api-Key"))
+
+		var reqBody map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+
+		requests, ok := reqBody["requests"].([]any)
+		require.True(t, ok)
+		require.Len(t, requests, 2)
+
+		first, _ := requests[0].(map[string]any)
+		assert.Equal(t, "req-1", first["custom_id"])
+		params, _ := first["params"].(map[string]any)
+		assert.Equal(t, "claude-3-5-haiku-latest", params["model"])
+		assert.Equal(t, "Be brief", params["system"])
+		assert.NotContains(t, params, "stream")
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":                "msgbatch_123",
+			"type":              "message_batch",
+			"processing_status": "in_progress",
+			"request_counts":    map[string]any{"processing": 2},
+		})
+	})
+	mux.HandleFunc("GET /messages/batches/msgbatch_123", func(w http.ResponseWriter, r *http.Request) {
+		status := "in_progress"
+		counts := map[string]any{"processing": 2}
+		if polls.Add(1) > 1 {
+			status = "ended"
+			counts = map[string]any{"succeeded": 1, "errored": 1}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":                "msgbatch_123",
+			"processing_status": status,
+			"request_counts":    counts,
+			"results_url":       "https://api.anthropic.com/v1/messages/batches/msgbatch_123/results",
+		})
+	})
+	mux.HandleFunc("GET /messages/batches/msgbatch_123/results", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-jsonl")
+		_, _ = w.Write([]byte(`{"custom_id":"req-1","result":{"type":"succeeded","message":{"model":"claude-3-5-haiku-20241022","content":[{"type":"text","text":"Hi!","citations":[{"type":"web_search_result_location","url":"https://example.com/hi","title":"Greetings","cited_text":"Hi"}]}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}}}
+{"custom_id":"req-2","result":{"type":"errored","error":{"type":"invalid_request_error","message":"bad"}}}
+`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	batchID, err := client.CreateBatch(ctx, []BatchRequest{
+		{CustomID: "req-1", Messages: []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}, Options: &llm.Options{System: "Be brief"}},
+		{CustomID: "req-2", Messages: []llm.Message{{Role: llm.RoleUser, Content: "Bye"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "msgbatch_123", batchID)
+
+	// 第一次轮询：处理中
+	batch, err := client.GetBatch(ctx, batchID)
+	require.NoError(t, err)
+	assert.Equal(t, BatchStatusInProgress, batch.Status)
+	assert.False(t, batch.IsEnded())
+	assert.Equal(t, int64(2), batch.RequestCounts.Processing)
+
+	// 第二次轮询：已结束
+	batch, err = client.GetBatch(ctx, batchID)
+	require.NoError(t, err)
+	assert.True(t, batch.IsEnded())
+	assert.Equal(t, int64(1), batch.RequestCounts.Succeeded)
+	assert.Equal(t, int64(1), batch.RequestCounts.Errored)
+
+	results, err := client.GetBatchResults(ctx, batchID)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "req-1", results[0].CustomID)
+	assert.Equal(t, "succeeded", results[0].Type)
+	require.NotNil(t, results[0].Response)
+	assert.Equal(t, "Hi!", results[0].Response.Message.Content)
+	assert.Equal(t, "stop", results[0].Response.FinishReason)
+	assert.Equal(t, "claude-3-5-haiku-20241022", results[0].Response.Model)
+	assert.Equal(t, int64(7), results[0].Response.Usage.TotalTokens)
+	assert.Equal(t, []llm.Citation{
+		{SourceURI: "https://example.com/hi", Title: "Greetings", StartIndex: 0, EndIndex: 3, Text: "Hi"},
+	}, results[0].Response.Citations)
+	assert.Nil(t, results[0].Response.Message.Meta)
+
+	assert.Equal(t, "req-2", results[1].CustomID)
+	assert.Equal(t, "errored", results[1].Type)
+	assert.Nil(t, results[1].Response)
+	assert.Contains(t, results[1].Error, "invalid_request_error")
+}
+
+func TestClient_Batch_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"type":"not_found_error"}}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	_, err = client.GetBatch(context.Background(), "missing")
+	require.Error(t, err)
+	assert.True(t, llm.IsAPIError(err))
+	assert.Equal(t, http.StatusNotFound, llm.GetStatusCode(err))
+
+	_, err = client.GetBatchResults(context.Background(), "missing")
+	require.Error(t, err)
+	assert.True(t, llm.IsAPIError(err))
+}
+
+func TestClient_Batch_ValidatesRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "msgbatch_123"})
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	msgs := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+	tests := []struct {
+		name     string
+		requests []BatchRequest
+		wantErr  error
+	}{
+		{
+			name: "无效工具定义",
+			requests: []BatchRequest{
+				{CustomID: "ok", Messages: msgs},
+				{CustomID: "bad", Messages: msgs, Options: &llm.Options{Tools: []llm.ToolSchema{{Name: "search"}}}},
+			},
+			wantErr: llm.ErrInvalidTool,
+		},
+		{
+			name: "无效图片",
+			requests: []BatchRequest{
+				{CustomID: "bad", Messages: []llm.Message{{
+					Role:          llm.RoleUser,
+					ContentBlocks: []llm.ContentBlock{&llm.ImageBlock{}},
+				}}},
+			},
+		},
+		{
+			name: "无效缓存 TTL",
+			requests: []BatchRequest{
+				{CustomID: "bad", Messages: msgs, Options: &llm.Options{CacheTTL: 2 * time.Hour}},
+			},
+		},
+		{
+			name: "APIKey 不一致",
+			requests: []BatchRequest{
+				{CustomID: "a", Messages: msgs, Options: &llm.Options{APIKey: "key-a"}},
+				{CustomID: "b", Messages: msgs, Options: &llm.Options{APIKey: "key-b"}},
+			},
+		},
+		{
+			name: "请求头冲突",
+			requests: []BatchRequest{
+				{CustomID: "a", Messages: msgs, Options: &llm.Options{Headers: map[string]string{"X-Tenant": "a"}}},
+				{CustomID: "b", Messages: msgs, Options: &llm.Options{Headers: map[string]string{"X-Tenant": "b"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.CreateBatch(context.Background(), tt.requests)
+			require.Error(t, err)
+			assert.True(t, llm.IsRequestError(err))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+	assert.Zero(t, calls.Load(), "校验失败时不应发送批处理")
+}
+
+func TestClient_Batch_HeadersAndAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "tenant-key", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "acme", r.Header.Get("X-Tenant"))
+		assert.Equal(t, "files-api-2025-04-14,extended-cache-ttl-2025-04-11", r.Header.Get("Anthropic-Beta"))
+
+		var reqBody map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		requests, _ := reqBody["requests"].([]any)
+		require.Len(t, requests, 2)
+		first, _ := requests[0].(map[string]any)
+		params, _ := first["params"].(map[string]any)
+		assert.NotContains(t, params, "betas")
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "msgbatch_123"})
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	msgs := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+	batchID, err := client.CreateBatch(context.Background(), []BatchRequest{
+		{CustomID: "a", Messages: msgs, Options: &llm.Options{
+			APIKey:   "tenant-key",
+			CacheTTL: time.Hour,
+			Headers:  map[string]string{"X-Tenant": "acme", "anthropic-beta": "files-api-2025-04-14"},
+		}},
+		{CustomID: "b", Messages: msgs, Options: &llm.Options{
+			APIKey:  "tenant-key",
+			Headers: map[string]string{"X-Tenant": "acme"},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "msgbatch_123", batchID)
+}
@@ -2,7 +2,10 @@ package anthropic
 
 import (
 	"context"
+	"fmt"
 	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
@@ -33,6 +36,24 @@ type Config struct {
 
 	// AnthropicVersion API 版本，默认 2023-06-01
 	AnthropicVersion string
+
+	// CompletePath 自定义 Complete 端点路径，默认 /messages
+	CompletePath string
+
+	// StreamPath 自定义 Stream 端点路径，默认 /messages
+	StreamPath string
+
+	// InsecureSkipVerify 跳过 TLS 证书校验
+	//
+	// ⚠️ 仅用于测试：连接使用自签名证书的本地服务（Ollama、vLLM 等）。
+	// 开启后连接可被中间人攻击，切勿在生产环境使用。默认关闭。
+	InsecureSkipVerify bool
+
+	// ProbeOnInit 创建客户端时请求模型元数据接口（GET /models/{model}）
+	//
+	// 认证失败等配置错误在 New 中立即返回 ConfigError，并缓存模型信息（见 ModelInfo）。
+	// 默认关闭，New 不发起网络请求。
+	ProbeOnInit bool
 }
 
 // Client Anthropic Claude API 客户端
@@ -52,13 +73,23 @@ type Client struct {
 
 // New 创建新的 Anthropic 客户端
 //
-// 参数 config 必须包含 APIKey。
-func New(config *Config) (*Client, error) {
+// 参数 config 必须包含 APIKey；opts 为可选的 core.ClientOption。
+// config 的副本经 [Config.Normalize] 校验并填充默认值，传入的 config 不会被修改。
+func New(config *Config, opts ...core.ClientOption) (*Client, error) {
+	if config == nil {
+		return nil, llm.NewConfigError("config is required", nil)
+	}
+	finalConfig := config.clone()
+	if err := finalConfig.Normalize(); err != nil {
+		return nil, err
+	}
+
 	// 创建 BaseClient
 	baseClient, err := core.NewBaseClient(
-		config,
+		finalConfig,
 		anthropic.NewAdapter(),
 		anthropic.NewEventHandler(),
+		opts...,
 	)
 	if err != nil {
 		return nil, err
@@ -67,42 +98,56 @@ func New(config *Config) (*Client, error) {
 	// 创建 transformer 用于 buildRequest
 	transformer := core.NewTransformer(anthropic.NewAdapter())
 
-	// 保存处理后的配置
-	finalConfig := *config
-	if finalConfig.Model == "" {
-		finalConfig.Model = "claude-3-5-haiku-latest"
-	}
-	if finalConfig.BaseURL == "" {
-		finalConfig.BaseURL = "https://api.anthropic.com/v1"
-	}
-	if finalConfig.Timeout == 0 {
-		finalConfig.Timeout = 120 * time.Second
-	}
-	if finalConfig.AnthropicVersion == "" {
-		finalConfig.AnthropicVersion = "2023-06-01"
-	}
-
 	client := &Client{
 		BaseClient:  baseClient,
-		config:      &finalConfig,
+		config:      finalConfig,
 		transformer: transformer,
 	}
 
-	// 设置端点构建器（Anthropic 使用固定端点）
-	baseClient.SetEndpointBuilder(&anthropicEndpointBuilder{})
+	// 设置端点构建器（Anthropic 默认使用固定端点 /messages）
+	baseClient.SetEndpointBuilder(&core.PathEndpointBuilder{
+		CompletePath: finalConfig.CompletePath,
+		StreamPath:   finalConfig.StreamPath,
+	})
+
+	if finalConfig.ProbeOnInit {
+		if _, err := baseClient.ProbeModel(context.Background(), "/models/"+finalConfig.Model); err != nil {
+			return nil, err
+		}
+	}
 
 	return client, nil
 }
 
-// anthropicEndpointBuilder Anthropic 端点构建器
-type anthropicEndpointBuilder struct{}
+// Clone 复制客户端并修改配置
+//
+// override 接收配置副本（Headers 已深拷贝），修改不会影响原客户端。克隆不重新验证
+// 配置，且与原客户端共享底层 HTTP 客户端：BaseURL、APIKey、Timeout、Headers 等
+// 连接级配置以原客户端为准，需要修改时请使用 New。适用于切换默认模型等请求级配置。
+func (c *Client) Clone(override func(*Config)) *Client {
+	config := c.config.clone()
+	if override != nil {
+		override(config)
+	}
 
-func (b *anthropicEndpointBuilder) BuildCompleteEndpoint() string {
-	return "/messages"
+	baseClient := c.BaseClient.CloneWithConfig(config)
+	baseClient.SetEndpointBuilder(&core.PathEndpointBuilder{
+		CompletePath: config.CompletePath,
+		StreamPath:   config.StreamPath,
+	})
+
+	return &Client{
+		BaseClient:  baseClient,
+		config:      config,
+		transformer: c.transformer,
+	}
 }
 
-func (b *anthropicEndpointBuilder) BuildStreamEndpoint() string {
-	return "/messages"
+// clone 深拷贝配置
+func (c *Config) clone() *Config {
+	cp := *c
+	cp.Headers = maps.Clone(c.Headers)
+	return &cp
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -142,6 +187,38 @@ func (c *Config) Validate() error {
 	if c.APIKey == "" {
 		return llm.NewConfigError("API key is required", nil)
 	}
+	if err := core.ValidateEndpointPath("complete path", c.CompletePath); err != nil {
+		return err
+	}
+	return core.ValidateEndpointPath("stream path", c.StreamPath)
+}
+
+// Normalize 校验配置并填充默认值
+//
+// 可在从文件加载配置后、创建客户端前调用，提前发现配置错误：
+//   - APIKey 为空、端点路径非法或 Timeout 为负时返回 ConfigError
+//   - 填充 BaseURL、Model、Timeout、AnthropicVersion 与端点路径的默认值
+//   - 模型未注册时仅记录警告，不返回错误
+func (c *Config) Normalize() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := core.ValidateTimeout(c.Timeout); err != nil {
+		return err
+	}
+
+	c.BaseURL, c.Model, c.Timeout = c.GetDefaults()
+	if c.AnthropicVersion == "" {
+		c.AnthropicVersion = "2023-06-01"
+	}
+	if c.CompletePath == "" {
+		c.CompletePath = "/messages"
+	}
+	if c.StreamPath == "" {
+		c.StreamPath = "/messages"
+	}
+
+	core.WarnUnknownModel(c.ProviderName(), c.Model)
 	return nil
 }
 
@@ -157,12 +234,7 @@ func (c *Config) GetDefaults() (string, string, time.Duration) {
 		model = "claude-3-5-haiku-latest"
 	}
 
-	timeout := c.Timeout
-	if timeout == 0 {
-		timeout = 120 * time.Second
-	}
-
-	return baseURL, model, timeout
+	return baseURL, model, core.GetDefaultTimeout(c.Timeout)
 }
 
 // BuildHeaders 构建请求头
@@ -182,6 +254,12 @@ func (c *Config) BuildHeaders() map[string]string {
 	return headers
 }
 
+// BuildAPIKeyHeaders 构建单次调用的认证头（Options.APIKey）
+// 实现 core.APIKeyHeaderBuilder 接口
+func (c *Config) BuildAPIKeyHeaders(apiKey string) map[string]string {
+	return map[string]string{"X-Api-Key": apiKey}
+}
+
 // ProviderName 返回 Provider 名称
 func (c *Config) ProviderName() string {
 	return "anthropic"
@@ -192,13 +270,119 @@ func (c *Config) GetModel() string {
 	return c.Model
 }
 
+// SkipTLSVerify 实现 core.TLSVerifySkipper 接口
+func (c *Config) SkipTLSVerify() bool {
+	return c.InsecureSkipVerify
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // core.RequestBuilder 接口实现
 // ═══════════════════════════════════════════════════════════════════════════
 
 // BuildRequest 实现 core.RequestBuilder 接口
 func (c *Client) BuildRequest(messages []llm.Message, opts *llm.Options, stream bool) (map[string]any, error) {
-	return c.buildRequest(messages, opts, stream), nil
+	if err := anthropic.ValidateImagePlacement(messages); err != nil {
+		return nil, llm.NewRequestError("validate", err)
+	}
+	if opts != nil && opts.CacheTTL != 0 {
+		if _, err := cacheTTLValue(opts.CacheTTL); err != nil {
+			return nil, err
+		}
+	}
+	req := c.buildRequest(messages, opts, stream)
+	apiMessages, _ := req["messages"].([]map[string]any)
+	if n := countCacheBreakpoints(apiMessages); n > MaxCacheBreakpoints {
+		return nil, llm.NewRequestError("validate", fmt.Errorf(
+			"too many cache breakpoints: %d (anthropic allows at most %d)", n, MaxCacheBreakpoints))
+	}
+	return req, nil
+}
+
+// BuildRequestHeaders 实现 core.RequestHeaderBuilder 接口
+//
+// Messages API 从 anthropic-beta 请求头读取 beta 特性（请求体中的 betas 字段无效）：
+// 工具 input_examples 与 1 小时缓存 TTL 需要开启对应 beta。已在客户端级或单次请求头
+// 中设置的 anthropic-beta 值保留在前。
+func (c *Client) BuildRequestHeaders(opts *llm.Options) map[string]string {
+	betas := requestBetas(opts)
+	if len(betas) == 0 {
+		return nil
+	}
+
+	var values []string
+	sources := []map[string]string{c.config.Headers}
+	if opts != nil {
+		sources = append(sources, opts.Headers)
+	}
+	for _, headers := range sources {
+		for k, v := range headers {
+			if strings.EqualFold(k, "anthropic-beta") && v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return map[string]string{"anthropic-beta": strings.Join(append(values, betas...), ",")}
+}
+
+// requestBetas 返回请求需要开启的 beta 特性
+func requestBetas(opts *llm.Options) []string {
+	if opts == nil {
+		return nil
+	}
+	var betas []string
+	if slices.ContainsFunc(opts.Tools, func(t llm.ToolSchema) bool { return len(t.InputExamples) > 0 }) {
+		betas = append(betas, "advanced-tool-use-2025-11-20")
+	}
+	if opts.CacheTTL == time.Hour {
+		betas = append(betas, "extended-cache-ttl-2025-04-11")
+	}
+	return betas
+}
+
+// MaxCacheBreakpoints Anthropic 单次请求允许的缓存断点数量上限
+const MaxCacheBreakpoints = 4
+
+// countCacheBreakpoints 统计已构建的请求消息中的缓存断点（cache_control）数量
+//
+// 按实际发送的内容块计数：包括 Message.CacheBreakpoint 标记（系统消息不在消息数组中），
+// 以及设置 CacheTTL 时自动添加在最后一条消息上的断点；转换后被丢弃的消息不计数。
+func countCacheBreakpoints(apiMessages []map[string]any) int {
+	var n int
+	for _, m := range apiMessages {
+		content, _ := m["content"].([]map[string]any)
+		for _, block := range content {
+			if _, ok := block["cache_control"]; ok {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// cacheTTLValue 将缓存 TTL 转换为 Anthropic cache_control.ttl 取值
+//
+// Anthropic 仅支持 5 分钟（默认）与 1 小时（beta）两档。
+func cacheTTLValue(ttl time.Duration) (string, error) {
+	switch ttl {
+	case 5 * time.Minute:
+		return "5m", nil
+	case time.Hour:
+		return "1h", nil
+	default:
+		return "", fmt.Errorf("unsupported cache TTL %s: anthropic supports 5m or 1h", ttl)
+	}
+}
+
+// toolChoice 将工具选择策略映射为 Anthropic tool_choice，空值返回 nil
+func toolChoice(choice llm.ToolChoice) map[string]any {
+	switch choice {
+	case "":
+		return nil
+	case llm.ToolChoiceRequired:
+		return map[string]any{"type": "any"}
+	default:
+		return map[string]any{"type": string(choice)}
+	}
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -212,8 +396,11 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 		opts = &llm.Options{}
 	}
 
-	// 确定模型
-	model := c.config.Model
+	// 确定模型（Options.Model 优先）
+	model := opts.Model
+	if model == "" {
+		model = c.config.Model
+	}
 
 	// 提取系统提示
 	var systemPrompt string
@@ -235,13 +422,21 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 	req := map[string]any{
 		"model":      model,
 		"messages":   apiMessages,
-		"max_tokens": 8192, // Anthropic 要求必须提供
+		"max_tokens": 8192, // Anthropic 要求必须提供；模型未注册时的回退值（见 core.ApplyDefaultMaxTokens）
 		"stream":     stream,
 	}
 
-	// Anthropic 使用独立的 system 参数
-	if systemPrompt != "" {
-		req["system"] = systemPrompt
+	// Anthropic 使用独立的 system 参数，多段时使用文本块数组
+	switch parts := core.SystemParts(systemPrompt, opts); len(parts) {
+	case 0:
+	case 1:
+		req["system"] = parts[0]
+	default:
+		blocks := make([]map[string]any, 0, len(parts))
+		for _, part := range parts {
+			blocks = append(blocks, map[string]any{"type": "text", "text": part})
+		}
+		req["system"] = blocks
 	}
 
 	// 应用选项
@@ -257,11 +452,13 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 	if len(opts.StopSequences) > 0 {
 		req["stop_sequences"] = opts.StopSequences
 	}
+	if opts.EndUserID != "" {
+		req["metadata"] = map[string]any{"user_id": opts.EndUserID}
+	}
 
 	// 工具定义
 	if len(opts.Tools) > 0 {
 		tools := make([]map[string]any, 0, len(opts.Tools))
-		hasExamples := false
 		for _, tool := range opts.Tools {
 			toolDef := map[string]any{
 				"name":         tool.Name,
@@ -271,15 +468,32 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 			// 添加 input_examples（如果有）
 			if len(tool.InputExamples) > 0 {
 				toolDef["input_examples"] = tool.InputExamples
-				hasExamples = true
 			}
 			tools = append(tools, toolDef)
 		}
 		req["tools"] = tools
+		if choice := toolChoice(opts.ToolChoice); choice != nil {
+			req["tool_choice"] = choice
+		}
+	}
 
-		// 如果有 examples，添加 beta header
-		if hasExamples {
-			req["betas"] = []string{"advanced-tool-use-2025-11-20"}
+	// Prompt Caching：在最后一条消息的最后一个内容块设置缓存断点，缓存整个前缀；
+	// Message.CacheBreakpoint 标记的断点使用相同的 TTL（1h 所需的 beta 见 BuildRequestHeaders）
+	if ttl, err := cacheTTLValue(opts.CacheTTL); err == nil && len(apiMessages) > 0 {
+		for _, m := range apiMessages {
+			content, _ := m["content"].([]map[string]any)
+			for _, block := range content {
+				if cc, ok := block["cache_control"].(map[string]any); ok {
+					cc["ttl"] = ttl
+				}
+			}
+		}
+		last := apiMessages[len(apiMessages)-1]
+		if content, ok := last["content"].([]map[string]any); ok && len(content) > 0 {
+			content[len(content)-1]["cache_control"] = map[string]any{
+				"type": "ephemeral",
+				"ttl":  ttl,
+			}
 		}
 	}
 
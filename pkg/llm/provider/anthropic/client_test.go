@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -33,6 +34,17 @@ func TestNew_MissingAPIKey(t *testing.T) {
 	assert.Contains(t, err.Error(), "API key is required")
 }
 
+func TestNew_InvalidStreamPath(t *testing.T) {
+	client, err := New(&Config{
+		APIKey:     "test-key",
+		StreamPath: "messages/stream",
+	})
+
+	assert.Nil(t, client)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must start with '/'")
+}
+
 func TestNew_Success(t *testing.T) {
 	client, err := New(&Config{
 		APIKey: "test-key",
@@ -56,6 +68,69 @@ func TestNew_DefaultValues(t *testing.T) {
 	assert.Contains(t, client.config.BaseURL, "api.anthropic.com")
 }
 
+func TestNewWithOptions(t *testing.T) {
+	client, err := NewWithOptions(
+		WithAPIKey("test-key"),
+		WithBaseURL("https://custom.api.example.com/v1"),
+		WithModel("claude-3-opus"),
+		WithTimeout(30*time.Second),
+		WithAnthropicVersion("2024-01-01"),
+		WithHeader("X-Custom-Header", "custom-value"),
+	)
+	require.NoError(t, err)
+
+	expected, err := New(&Config{
+		APIKey:           "test-key",
+		BaseURL:          "https://custom.api.example.com/v1",
+		Model:            "claude-3-opus",
+		Timeout:          30 * time.Second,
+		AnthropicVersion: "2024-01-01",
+		Headers:          map[string]string{"X-Custom-Header": "custom-value"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected.config, client.config)
+
+	// 与 New 相同的校验
+	_, err = NewWithOptions(WithModel("claude-3-opus"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "API key is required")
+}
+
+func TestConfig_Normalize(t *testing.T) {
+	config := &Config{APIKey: "test-key", Model: "claude-sonnet-4-20250514"}
+	require.NoError(t, config.Normalize())
+
+	assert.Equal(t, "https://api.anthropic.com/v1", config.BaseURL)
+	assert.Equal(t, "claude-sonnet-4-20250514", config.Model)
+	assert.Equal(t, 120*time.Second, config.Timeout)
+	assert.Equal(t, "2023-06-01", config.AnthropicVersion)
+	assert.Equal(t, "/messages", config.CompletePath)
+
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{"空 API Key", &Config{}, "API key is required"},
+		{"负超时", &Config{APIKey: "test-key", Timeout: -time.Second}, "invalid timeout"},
+		{"非法路径", &Config{APIKey: "test-key", CompletePath: "messages"}, "must start with '/'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Normalize()
+			require.Error(t, err)
+			assert.True(t, llm.IsConfigError(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	// New 不修改传入的配置
+	original := &Config{APIKey: "test-key"}
+	_, err := New(original)
+	require.NoError(t, err)
+	assert.Empty(t, original.Model)
+}
+
 func TestNew_CustomValues(t *testing.T) {
 	client, err := New(&Config{
 		APIKey:           "test-key",
@@ -140,6 +215,72 @@ func TestClient_Complete_Success(t *testing.T) {
 	assert.Equal(t, int64(5), resp.Usage.OutputTokens)
 }
 
+func TestClient_ModelOverride(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "claude-3-5-haiku-latest"})
+	require.NoError(t, err)
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}},
+		&llm.Options{Model: "claude-sonnet-4-5"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet-4-5", body["model"])
+	assert.Equal(t, "claude-sonnet-4-5", resp.Model)
+}
+
+func TestClient_APIKeyOverride(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("X-Api-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	_, err = client.Complete(context.Background(), messages, &llm.Options{APIKey: "tenant-key"})
+	require.NoError(t, err)
+	_, err = client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"tenant-key", "test-key"}, keys)
+}
+
+func TestClient_Clone(t *testing.T) {
+	var models []any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		models = append(models, body["model"])
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	parent, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "claude-3-5-haiku-latest"})
+	require.NoError(t, err)
+	clone := parent.Clone(func(c *Config) { c.Model = "claude-sonnet-4-5" })
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	_, err = parent.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	_, err = clone.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{"claude-3-5-haiku-latest", "claude-sonnet-4-5"}, models)
+	assert.Equal(t, "claude-3-5-haiku-latest", parent.config.Model)
+}
+
 func TestClient_Complete_WithToolCall(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := map[string]any{
@@ -278,6 +419,59 @@ func TestClient_Complete_WithOptions(t *testing.T) {
 	require.NotNil(t, resp)
 }
 
+func TestClient_Complete_DefaultMaxTokens(t *testing.T) {
+	var maxTokens []any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&reqBody)
+		maxTokens = append(maxTokens, reqBody["max_tokens"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content": [{"type": "text", "text": "OK"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "claude-sonnet-4-20250514"})
+	require.NoError(t, err)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	// 未设置 MaxTokens：使用模型上限
+	_, err = client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	// 未注册的模型：回退到必填的默认值
+	_, err = client.Complete(context.Background(), messages, &llm.Options{Model: "claude-unknown"})
+	require.NoError(t, err)
+
+	require.Len(t, maxTokens, 2)
+	assert.InDelta(t, 64000, maxTokens[0], 0.001)
+	assert.InDelta(t, 8192, maxTokens[1], 0.001)
+}
+
+func TestClient_Complete_Citations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"content": [{"type": "text", "text": "The sky is blue.", "citations": [
+				{"type": "web_search_result_location", "url": "https://example.com/sky", "title": "Sky", "cited_text": "Blue sky."}
+			]}],
+			"stop_reason": "end_turn"
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Sky?"}}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []llm.Citation{
+		{SourceURI: "https://example.com/sky", Title: "Sky", StartIndex: 0, EndIndex: 16, Text: "Blue sky."},
+	}, resp.Citations)
+	assert.Nil(t, resp.Message.Meta, "引用不写入 Message.Meta")
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // Stream 测试
 // ═══════════════════════════════════════════════════════════════════════════
@@ -493,10 +687,326 @@ func TestClient_BuildRequest_WithThinking(t *testing.T) {
 	require.NotNil(t, resp)
 }
 
+func TestClient_BuildRequest_EndUserID(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	req := client.buildRequest(nil, &llm.Options{EndUserID: "user-42"}, false)
+	assert.Equal(t, map[string]any{"user_id": "user-42"}, req["metadata"])
+	assert.NotContains(t, req, "user")
+
+	req = client.buildRequest(nil, &llm.Options{}, false)
+	assert.NotContains(t, req, "metadata")
+}
+
+func TestClient_BuildRequest_ToolChoice(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	tools := []llm.ToolSchema{{Name: "get_weather", Description: "Get weather"}}
+	tests := []struct {
+		choice llm.ToolChoice
+		want   any
+	}{
+		{llm.ToolChoiceNone, map[string]any{"type": "none"}},
+		{llm.ToolChoiceAuto, map[string]any{"type": "auto"}},
+		{llm.ToolChoiceRequired, map[string]any{"type": "any"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.choice), func(t *testing.T) {
+			req := client.buildRequest(nil, &llm.Options{Tools: tools, ToolChoice: tt.choice}, false)
+			assert.Len(t, req["tools"], 1, "工具定义应保留")
+			assert.Equal(t, tt.want, req["tool_choice"])
+		})
+	}
+
+	// 未设置或没有工具时不发送
+	assert.NotContains(t, client.buildRequest(nil, &llm.Options{Tools: tools}, false), "tool_choice")
+	assert.NotContains(t, client.buildRequest(nil, &llm.Options{ToolChoice: llm.ToolChoiceNone}, false), "tool_choice")
+}
+
+func TestClient_BuildRequest_SystemParts(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	req := client.buildRequest(nil, &llm.Options{System: "You are helpful.", SystemParts: []string{"Answer in French."}}, false)
+	assert.Equal(t, []map[string]any{
+		{"type": "text", "text": "You are helpful."},
+		{"type": "text", "text": "Answer in French."},
+	}, req["system"])
+
+	// 单段保持字符串
+	req = client.buildRequest(nil, &llm.Options{SystemParts: []string{"Answer in French."}}, false)
+	assert.Equal(t, "Answer in French.", req["system"])
+}
+
+func TestClient_BuildRequest_CacheTTL(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Long document..."},
+		{Role: llm.RoleAssistant, Content: "Got it."},
+		{Role: llm.RoleUser, Content: "Summarize"},
+	}
+
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		wantTTL  string
+		wantBeta string
+	}{
+		{"5 分钟", 5 * time.Minute, "5m", ""},
+		{"1 小时需要 beta", time.Hour, "1h", "extended-cache-ttl-2025-04-11"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := client.BuildRequest(messages, &llm.Options{CacheTTL: tt.ttl}, false)
+			require.NoError(t, err)
+
+			apiMessages := req["messages"].([]map[string]any)
+			last := apiMessages[len(apiMessages)-1]["content"].([]map[string]any)
+			assert.Equal(t, map[string]any{"type": "ephemeral", "ttl": tt.wantTTL}, last[len(last)-1]["cache_control"])
+
+			// 仅最后一条消息设置缓存断点
+			first := apiMessages[0]["content"].([]map[string]any)
+			assert.NotContains(t, first[0], "cache_control")
+
+			// beta 通过请求头开启，不写入请求体
+			assert.NotContains(t, req, "betas")
+			assert.Equal(t, tt.wantBeta, client.BuildRequestHeaders(&llm.Options{CacheTTL: tt.ttl})["anthropic-beta"])
+		})
+	}
+
+	t.Run("不支持的 TTL", func(t *testing.T) {
+		_, err := client.BuildRequest(messages, &llm.Options{CacheTTL: 10 * time.Minute}, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "5m or 1h")
+	})
+}
+
+func TestClient_BuildRequest_CacheBreakpoints(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are helpful.", CacheBreakpoint: true}, // 系统消息不计数
+		{Role: llm.RoleUser, Content: "Long document...", CacheBreakpoint: true},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+			&llm.TextBlock{Text: "Reading"},
+			&llm.TextBlock{Text: "Got it."},
+		}, CacheBreakpoint: true},
+		{Role: llm.RoleUser, Content: "Summarize"},
+	}
+
+	req, err := client.BuildRequest(messages, &llm.Options{CacheTTL: time.Hour}, false)
+	require.NoError(t, err)
+
+	apiMessages := req["messages"].([]map[string]any)
+	require.Len(t, apiMessages, 3)
+	want := map[string]any{"type": "ephemeral", "ttl": "1h"}
+	assert.Equal(t, want, apiMessages[0]["content"].([]map[string]any)[0]["cache_control"])
+	assistant := apiMessages[1]["content"].([]map[string]any)
+	assert.NotContains(t, assistant[0], "cache_control", "断点仅设置在消息的最后一个块")
+	assert.Equal(t, want, assistant[1]["cache_control"])
+	assert.Equal(t, want, apiMessages[2]["content"].([]map[string]any)[0]["cache_control"])
+
+	// 未设置 CacheTTL 时使用默认 TTL
+	req, err = client.BuildRequest(messages, nil, false)
+	require.NoError(t, err)
+	apiMessages = req["messages"].([]map[string]any)
+	assert.Equal(t, map[string]any{"type": "ephemeral"}, apiMessages[0]["content"].([]map[string]any)[0]["cache_control"])
+	assert.NotContains(t, apiMessages[2]["content"].([]map[string]any)[0], "cache_control")
+
+	t.Run("最后一条消息未发送时不重复计数", func(t *testing.T) {
+		var many []llm.Message
+		for range 4 {
+			many = append(many,
+				llm.Message{Role: llm.RoleUser, Content: "Q", CacheBreakpoint: true},
+				llm.Message{Role: llm.RoleAssistant, Content: "A"},
+			)
+		}
+		// 末尾仅含未签名思考块的消息被丢弃，自动断点落在已标记的消息上
+		many = append(many[:len(many)-1], llm.Message{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+			&llm.ThinkingBlock{Thinking: "hmm"},
+		}})
+		_, err := client.BuildRequest(many, &llm.Options{CacheTTL: 5 * time.Minute}, false)
+		require.NoError(t, err)
+	})
+
+	t.Run("超过上限", func(t *testing.T) {
+		var many []llm.Message
+		for range 4 {
+			many = append(many,
+				llm.Message{Role: llm.RoleUser, Content: "Q", CacheBreakpoint: true},
+				llm.Message{Role: llm.RoleAssistant, Content: "A"},
+			)
+		}
+		_, err := client.BuildRequest(many, nil, false)
+		require.NoError(t, err, "恰好 4 个断点")
+
+		// CacheTTL 的自动断点计入上限
+		_, err = client.BuildRequest(many, &llm.Options{CacheTTL: 5 * time.Minute}, false)
+		require.Error(t, err)
+		assert.True(t, llm.IsRequestError(err))
+		assert.Contains(t, err.Error(), "too many cache breakpoints: 5")
+	})
+}
+
+func TestClient_Complete_BetaHeader(t *testing.T) {
+	var (
+		beta string
+		body map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		beta = r.Header.Get("Anthropic-Beta")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Headers: map[string]string{"anthropic-beta": "files-api-2025-04-14"}})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	_, err = client.Complete(context.Background(), messages, &llm.Options{CacheTTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, "files-api-2025-04-14,extended-cache-ttl-2025-04-11", beta)
+	assert.NotContains(t, body, "betas")
+
+	// 不需要 beta 时保留客户端级请求头
+	_, err = client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "files-api-2025-04-14", beta)
+}
+
+func TestClient_Complete_Images(t *testing.T) {
+	var (
+		calls int
+		body  map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content": [{"type": "text", "text": "a cat"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	image := &llm.ImageBlock{URL: "https://example.com/cat.png"}
+
+	// 用户消息中的图片以 image 块发送
+	_, err = client.Complete(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "What is this?", ContentBlocks: []llm.ContentBlock{image}},
+	}, nil)
+	require.NoError(t, err)
+	messages, _ := body["messages"].([]any)
+	require.Len(t, messages, 1)
+	content, _ := messages[0].(map[string]any)["content"].([]any)
+	require.Len(t, content, 2)
+	assert.Equal(t, "image", content[1].(map[string]any)["type"])
+
+	// RoleTool 工具结果中的图片位于 tool_result 之后
+	_, err = client.Complete(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "Take a screenshot"},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{&llm.ToolCall{ID: "toolu_1", Name: "screenshot"}}},
+		{Role: llm.RoleTool, ToolCallID: "toolu_1", ContentBlocks: []llm.ContentBlock{&llm.TextBlock{Text: "captured"}, image}},
+	}, nil)
+	require.NoError(t, err)
+	messages, _ = body["messages"].([]any)
+	require.Len(t, messages, 3)
+	content, _ = messages[2].(map[string]any)["content"].([]any)
+	require.Len(t, content, 2)
+	assert.Equal(t, "tool_result", content[0].(map[string]any)["type"])
+	assert.Equal(t, "image", content[1].(map[string]any)["type"])
+
+	// 助手消息中的图片无法表示，发送前报错
+	_, err = client.Complete(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "Hi"},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{image}},
+	}, nil)
+	require.Error(t, err)
+	assert.True(t, llm.IsRequestError(err))
+	assert.ErrorIs(t, err, llm.ErrInvalidImage)
+	assert.Equal(t, 2, calls)
+}
+
+func TestClient_Complete_CacheStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"content": [{"type": "text", "text": "ok"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 10, "output_tokens": 5, "cache_read_input_tokens": 1200, "cache_creation_input_tokens": 300}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}, nil)
+
+	require.NoError(t, err)
+	assert.True(t, resp.CacheHit)
+	assert.Equal(t, int64(1200), resp.CacheReadTokens)
+	assert.Equal(t, int64(300), resp.CacheWriteTokens)
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 接口实现验证
 // ═══════════════════════════════════════════════════════════════════════════
 
+func TestClient_ZeroArgToolCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("event: content_block_start\n" +
+				`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_time","input":{}}}` + "\n\n" +
+				"event: content_block_delta\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":""}}` + "\n\n" +
+				"event: message_delta\n" +
+				`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"}}` + "\n\n" +
+				"event: message_stop\n" +
+				`data: {"type":"message_stop"}` + "\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content": [{"type": "tool_use", "id": "toolu_1", "name": "get_time"}], "stop_reason": "tool_use"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "What time is it?"}}
+
+	// 非流式：缺失 input 字段
+	resp, err := client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	calls := resp.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.NotNil(t, calls[0].Input)
+	assert.Empty(t, calls[0].Input)
+
+	// 流式：无参数增量
+	stream, err := client.Stream(context.Background(), messages, nil)
+	require.NoError(t, err)
+	result, err := core.CollectStream(stream)
+	require.NoError(t, err)
+	calls = result.Response.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "get_time", calls[0].Name)
+	assert.NotNil(t, calls[0].Input)
+	assert.Empty(t, calls[0].Input)
+}
+
 func TestClient_ImplementsProvider(t *testing.T) {
 	var _ llm.Provider = (*Client)(nil)
 }
@@ -33,6 +33,16 @@
 //	}
 //	fmt.Println(resp.Message.Content)
 //
+// 只需设置少量字段时，可使用函数式选项代替 Config 字面量：
+//
+//	client, err := anthropic.NewWithOptions(
+//	    anthropic.WithAPIKey("sk-ant-..."),
+//	    anthropic.WithModel("claude-3-5-haiku-latest"),
+//	    anthropic.WithAnthropicVersion("2023-06-01"),
+//	)
+//
+// 从文件加载的配置可先调用 [Config.Normalize] 校验并填充默认值，New 内部同样会调用。
+//
 // # 与 OpenAI 兼容包的区别
 //
 // 本包直接使用 Anthropic 原生 API，主要区别：
@@ -40,7 +50,45 @@
 //   - 认证方式：使用 X-Api-Key 头部而非 Bearer Token
 //   - 系统提示：作为独立参数而非消息
 //   - 响应格式：content 数组而非 choices 数组
-//   - 流式事件：使用 Anthropic 特有的事件类型
+//   - 流式事件：使用 Anthropic 特有的事件类型；每个内容块开始时额外发出
+//     llm.EventTypeBlockStart（BlockType 为 text、thinking、tool_use 等），
+//     便于界面区分思考与回答，不关心块边界的消费者可忽略
+//
+// # 图片输入
+//
+// 用户消息中的 llm.ImageBlock 以 image 块发送（URL 或 Base64 数据），Detail 被忽略；
+// RoleTool 工具结果消息中的图片随工具结果所在的用户内容发送（位于 tool_result 之后）。
+// 助手消息中的图片无法表示，BuildRequest 返回 RequestError（包装 llm.ErrInvalidImage），
+// 不会被静默丢弃。
+//
+// # 思考内容回放
+//
+// Extended thinking 的思考块解析为 llm.ThinkingBlock（带签名），被安全系统加密的
+// redacted_thinking 解析为 llm.RedactedThinkingBlock。两者在后续请求中原样回放，
+// 多轮工具调用时应将响应消息完整追加到历史，不要丢弃这些块。
+//
+// # Prompt Caching
+//
+// 设置 Options.CacheTTL（仅支持 5m 与 1h）后，最后一条消息的最后一个内容块会带上
+// cache_control 断点，缓存整个请求前缀；1h 会自动在 anthropic-beta 请求头中附带 extended-cache-ttl。
+// 缓存效果可通过 Response.CacheHit / CacheReadTokens / CacheWriteTokens 观察。
+//
+// 对话不断增长时，可在历史消息上设置 Message.CacheBreakpoint 增量缓存前缀
+// （断点使用 CacheTTL，未设置时为默认 5m）。包括自动断点在内最多 [MaxCacheBreakpoints] 个，
+// 超出时 BuildRequest 返回 RequestError。
+//
+// # 批处理
+//
+// Message Batches API 以更低成本异步处理大量请求：
+//
+//	batchID, err := client.CreateBatch(ctx, []anthropic.BatchRequest{
+//	    {CustomID: "req-1", Messages: messages},
+//	})
+//	batch, err := client.GetBatch(ctx, batchID)    // 轮询直到 batch.IsEnded()
+//	results, err := client.GetBatchResults(ctx, batchID)
+//
+// 每个请求与 Complete 经过相同的校验，任一请求无效时 CreateBatch 直接返回错误；
+// 各请求的 Options.Headers 合并后随批处理发送，Options.APIKey 须一致。
 //
 // # 线程安全
 //
@@ -0,0 +1,130 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 显式缓存 (cachedContents)
+// ═══════════════════════════════════════════════════════════════════════════
+
+// CreateCache 创建显式上下文缓存
+//
+// 将 messages（及 opts.System）上传为服务端缓存，TTL 取自 opts.CacheTTL，
+// 必须为正的整秒数。缓存绑定 opts.Model（为空时使用配置模型），引用缓存的请求
+// 须使用同一模型。返回缓存名称（如 "cachedContents/abc"），后续请求通过
+// Options.CachedContent 引用：
+//
+//	name, err := client.CreateCache(ctx, docs, &llm.Options{CacheTTL: time.Hour})
+//	resp, err := client.Complete(ctx, question, &llm.Options{CachedContent: name})
+//
+// 引用缓存的请求只发送缓存之后的新消息，且不发送 systemInstruction（系统提示已在
+// 缓存中，Options.System 与 SystemParts 被忽略）。调用方不应重发已缓存的内容；
+// 请求消息以本客户端（及其克隆）CreateCache 上传的消息开头时，这部分前缀被自动移除，
+// 因此也可以传入完整历史。
+func (c *Client) CreateCache(ctx context.Context, messages []llm.Message, opts *llm.Options) (string, error) {
+	if opts == nil {
+		opts = &llm.Options{}
+	}
+	ttl, err := cacheTTLValue(opts.CacheTTL)
+	if err != nil {
+		return "", llm.NewRequestError("validate", err)
+	}
+
+	req := c.buildRequest(messages, opts, false)
+	body := map[string]any{
+		"model":    "models/" + c.resolveModel(opts),
+		"contents": req["contents"],
+		"ttl":      ttl,
+	}
+	if system, ok := req["systemInstruction"]; ok {
+		body["systemInstruction"] = system
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return "", llm.NewRequestError("marshal request", err)
+	}
+
+	var apiResp map[string]any
+	httpReq := c.NewRequest(ctx).
+		SetBody(bodyBytes).
+		SetResult(&apiResp)
+	resp, err := core.ApplyHeaders(httpReq, opts.Headers).Post(c.buildCacheEndpoint())
+	if err != nil {
+		return "", llm.NewHTTPError("request failed", err)
+	}
+	if err := c.CheckResponse(resp); err != nil {
+		return "", err
+	}
+
+	name := core.GetString(apiResp["name"])
+	if name == "" {
+		return "", llm.NewResponseError("name", nil)
+	}
+
+	cached := conversationMessages(messages)
+	c.caches.Store(name, cachedPrefix{count: len(cached), hash: core.RequestHash("", cached, nil)})
+	return name, nil
+}
+
+// cachedPrefix 已上传到缓存的消息前缀（不含系统消息）
+type cachedPrefix struct {
+	count int    // 消息条数
+	hash  string // core.RequestHash 摘要
+}
+
+// trimCachedPrefix 移除请求开头与缓存内容相同的消息
+//
+// 缓存不是由本客户端创建，或请求未以缓存的消息开头时，返回去除系统消息后的原消息。
+func (c *Client) trimCachedPrefix(name string, messages []llm.Message) []llm.Message {
+	messages = conversationMessages(messages)
+
+	v, ok := c.caches.Load(name)
+	if !ok {
+		return messages
+	}
+	prefix := v.(cachedPrefix) //nolint:forcetypeassert // 仅存储 cachedPrefix
+	if prefix.count == 0 || len(messages) < prefix.count ||
+		core.RequestHash("", messages[:prefix.count], nil) != prefix.hash {
+		return messages
+	}
+	return messages[prefix.count:]
+}
+
+// conversationMessages 去除系统消息（系统提示由 systemInstruction 或缓存承载）
+func conversationMessages(messages []llm.Message) []llm.Message {
+	result := make([]llm.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role != llm.RoleSystem {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// buildCacheEndpoint 构建 cachedContents 端点
+func (c *Client) buildCacheEndpoint() string {
+	if c.useVertexAI {
+		location := c.config.VertexLocation
+		if location == "" {
+			location = "us-central1"
+		}
+		return fmt.Sprintf("/projects/%s/locations/%s/cachedContents", c.config.VertexProject, location)
+	}
+	return "/cachedContents?key=" + c.config.APIKey
+}
+
+// cacheTTLValue 将缓存 TTL 转换为 Gemini 的 Duration 字符串（如 "300s"）
+func cacheTTLValue(ttl time.Duration) (string, error) {
+	if ttl <= 0 || ttl%time.Second != 0 {
+		return "", fmt.Errorf("invalid cache TTL %s: gemini requires a positive whole number of seconds", ttl)
+	}
+	return fmt.Sprintf("%ds", int64(ttl/time.Second)), nil
+}
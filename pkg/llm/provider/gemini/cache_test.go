@@ -0,0 +1,137 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CreateCache(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cachedContents", r.URL.Path)
+		assert.Equal(t, "test-key", r.URL.Query().Get("key"))
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "cachedContents/abc123"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: ModelGemini15Flash})
+	require.NoError(t, err)
+
+	name, err := client.CreateCache(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "Large document"},
+	}, &llm.Options{System: "You are a reviewer", CacheTTL: 10 * time.Minute})
+
+	require.NoError(t, err)
+	assert.Equal(t, "cachedContents/abc123", name)
+	assert.Equal(t, "600s", body["ttl"])
+	assert.Equal(t, "models/gemini-1.5-flash", body["model"])
+	assert.NotNil(t, body["systemInstruction"])
+	assert.NotNil(t, body["contents"])
+}
+
+func TestClient_CreateCache_InvalidTTL(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	for _, ttl := range []time.Duration{0, -time.Minute, 1500 * time.Millisecond} {
+		_, err := client.CreateCache(context.Background(), nil, &llm.Options{CacheTTL: ttl})
+		require.Error(t, err, "ttl %s", ttl)
+		assert.True(t, llm.IsRequestError(err))
+	}
+}
+
+func TestClient_Complete_InvalidCacheTTL(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key", BaseURL: "http://invalid-host-12345:9999"})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	for _, ttl := range []time.Duration{-time.Minute, 1500 * time.Millisecond} {
+		_, err := client.Complete(context.Background(), messages, &llm.Options{CacheTTL: ttl})
+		require.Error(t, err, "ttl %s", ttl)
+		assert.True(t, llm.IsRequestError(err), "发送前返回 RequestError，ttl %s", ttl)
+	}
+
+	_, err = client.BuildRequest(messages, &llm.Options{CacheTTL: time.Hour}, false)
+	require.NoError(t, err)
+}
+
+func TestClient_BuildRequest_CachedContent(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	req := client.buildRequest(nil, &llm.Options{CachedContent: "cachedContents/abc123"}, false)
+	assert.Equal(t, "cachedContents/abc123", req["cachedContent"])
+
+	req = client.buildRequest(nil, nil, false)
+	assert.NotContains(t, req, "cachedContent")
+}
+
+func TestClient_BuildRequest_CachedContentOmitsSystem(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are a reviewer"},
+		{Role: llm.RoleUser, Content: "Question"},
+	}
+	req := client.buildRequest(messages, &llm.Options{
+		CachedContent: "cachedContents/abc123",
+		System:        "Be concise",
+		SystemParts:   []string{"Extra"},
+	}, false)
+
+	assert.NotContains(t, req, "systemInstruction")
+	assert.Len(t, req["contents"], 1)
+}
+
+func TestClient_BuildRequest_CachedContentTrimsPrefix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "cachedContents/abc123"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	cached := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are a reviewer"},
+		{Role: llm.RoleUser, Content: "Large document"},
+		{Role: llm.RoleAssistant, Content: "Received"},
+	}
+	name, err := client.CreateCache(context.Background(), cached, &llm.Options{CacheTTL: time.Minute})
+	require.NoError(t, err)
+
+	history := append(slices.Clone(cached), llm.Message{Role: llm.RoleUser, Content: "Question"})
+	opts := &llm.Options{CachedContent: name}
+
+	// 完整历史：缓存前缀被移除，克隆共享缓存记录
+	for _, c := range []*Client{client, client.Clone(nil)} {
+		req := c.buildRequest(history, opts, false)
+		contents, ok := req["contents"].([]map[string]any)
+		require.True(t, ok)
+		require.Len(t, contents, 1)
+		assert.Equal(t, "user", contents[0]["role"])
+	}
+
+	// 仅新消息：原样发送
+	req := client.buildRequest(history[3:], opts, false)
+	assert.Len(t, req["contents"], 1)
+
+	// 前缀不匹配：原样发送
+	changed := []llm.Message{{Role: llm.RoleUser, Content: "Other document"}, history[3]}
+	req = client.buildRequest(changed, opts, false)
+	assert.Len(t, req["contents"], 2)
+}
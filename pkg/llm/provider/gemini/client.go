@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
@@ -25,10 +28,17 @@ const (
 	// DefaultTimeout 默认超时时间
 	DefaultTimeout = 120 * time.Second
 
-	// DefaultMaxTokens 默认最大输出 tokens
+	// DefaultMaxTokens 默认最大输出 tokens（模型未注册时的回退值，见 core.ApplyDefaultMaxTokens）
 	DefaultMaxTokens = 8192
 )
 
+// Thinking 级别常量
+const (
+	ThinkingLevelLow    = "low"
+	ThinkingLevelMedium = "medium"
+	ThinkingLevelHigh   = "high"
+)
+
 // 模型常量
 const (
 	ModelGemini25Pro       = "gemini-2.5-pro"
@@ -60,14 +70,48 @@ type Config struct {
 	// Headers 额外的请求头
 	Headers map[string]string
 
+	// InsecureSkipVerify 跳过 TLS 证书校验
+	//
+	// ⚠️ 仅用于测试：连接使用自签名证书的本地服务（Ollama、vLLM 等）。
+	// 开启后连接可被中间人攻击，切勿在生产环境使用。默认关闭。
+	InsecureSkipVerify bool
+
+	// ProbeOnInit 创建客户端时请求模型元数据接口（GET /models/{model}）
+	//
+	// 认证失败等配置错误在 New 中立即返回 ConfigError，并缓存模型信息（见 ModelInfo）。
+	// 默认关闭，New 不发起网络请求。
+	ProbeOnInit bool
+
+	// KeepEmptyUserMessages 保留没有内容的用户消息（默认丢弃）
+	//
+	// 用于以空的用户轮次表示 "继续" 的流程，见 core.Transformer.SetKeepEmptyUserMessages。
+	KeepEmptyUserMessages bool
+
+	// SafetySettings 按类别设置安全拦截阈值，作为请求的 safetySettings 发送
+	//
+	// 未设置的类别使用 Gemini 默认阈值。被拦截时 FinishReason 为 "content_filter"，
+	// 拦截类别见 Response.Metadata["blocked_categories"]。
+	SafetySettings []SafetySetting
+
 	// Thinking 配置（Gemini 2.5 系列）
-	EnableThinking bool  // 启用 thinking 模式
-	ThinkingBudget int32 // thinking tokens 预算，0 表示动态
+	EnableThinking bool   // 启用 thinking 模式
+	ThinkingBudget int32  // thinking tokens 预算，0 表示动态
+	ThinkingLevel  string // thinking 级别（新模型）: "low", "medium", "high"，优先于 ThinkingBudget
 
 	// Vertex AI 配置
 	VertexProject  string // GCP 项目 ID
 	VertexLocation string // GCP 区域，默认 us-central1
-	VertexCredFile string // 服务账户凭证文件路径
+	VertexCredFile string // 服务账户凭证文件路径（JSON 密钥），用于获取 OAuth2 访问令牌
+}
+
+// SafetySetting 单个类别的安全拦截阈值
+//
+// 示例：
+//
+//	gemini.SafetySetting{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"}
+type SafetySetting struct {
+	Category  string `json:"category"`  // 危害类别，如 HARM_CATEGORY_HARASSMENT、HARM_CATEGORY_DANGEROUS_CONTENT
+	Threshold string `json:"threshold"` // 拦截阈值，如 BLOCK_NONE、BLOCK_ONLY_HIGH、BLOCK_MEDIUM_AND_ABOVE、BLOCK_LOW_AND_ABOVE
 }
 
 // Client Gemini LLM 客户端
@@ -86,71 +130,133 @@ type Client struct {
 
 	// 内部状态
 	useVertexAI bool
+	caches      *sync.Map // 缓存名称 → cachedPrefix（见 CreateCache），克隆间共享
 }
 
 // New 创建新的 Gemini 客户端
 //
 // 参数 config 必须包含 APIKey（Gemini API）或 VertexProject（Vertex AI）。
-func New(config *Config) (*Client, error) {
-	// 验证配置
+// Gemini 不返回工具调用 ID，可通过 core.WithIDGenerator 注入确定性生成器。
+// config 的副本经 [Config.Normalize] 校验并填充默认值，传入的 config 不会被修改。
+func New(config *Config, opts ...core.ClientOption) (*Client, error) {
 	if config == nil {
 		return nil, llm.NewConfigError("config is required", nil)
 	}
+	finalConfig := config.clone()
+	if err := finalConfig.Normalize(); err != nil {
+		return nil, err
+	}
 
 	// 确定后端类型
-	useVertexAI := config.VertexProject != ""
+	useVertexAI := finalConfig.VertexProject != ""
 
-	// 验证配置
-	if !useVertexAI && config.APIKey == "" {
-		return nil, llm.NewConfigError("API key is required for Gemini API backend", nil)
-	}
-
-	// 保存处理后的配置（应用默认值）
-	finalConfig := *config
-	if finalConfig.Model == "" {
-		finalConfig.Model = DefaultModel
-	}
-	if finalConfig.BaseURL == "" {
-		if useVertexAI {
-			location := finalConfig.VertexLocation
-			if location == "" {
-				location = "us-central1"
-			}
-			finalConfig.BaseURL = fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1", location)
-		} else {
-			finalConfig.BaseURL = DefaultBaseURL
+	// Vertex AI 服务账户认证：每个请求携带 Bearer 访问令牌（调用方的选项优先）
+	if useVertexAI && finalConfig.VertexCredFile != "" {
+		tokens, err := newServiceAccountTokenSource(finalConfig.VertexCredFile)
+		if err != nil {
+			return nil, llm.NewConfigError("load vertex credentials", err)
 		}
-	}
-	if finalConfig.Timeout == 0 {
-		finalConfig.Timeout = DefaultTimeout
+		opts = append([]core.ClientOption{core.WithTokenSource(tokens)}, opts...)
 	}
 
 	// 创建 BaseClient
 	baseClient, err := core.NewBaseClient(
-		&finalConfig,
+		finalConfig,
 		gemini.NewAdapter(),
 		gemini.NewEventHandler(),
+		opts...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// 创建 transformer 用于 buildRequest
-	transformer := core.NewTransformer(gemini.NewAdapter())
+	transformer := newTransformer(finalConfig)
 
 	client := &Client{
 		BaseClient:  baseClient,
-		config:      &finalConfig,
+		config:      finalConfig,
 		transformer: transformer,
 		useVertexAI: useVertexAI,
+		caches:      &sync.Map{},
 	}
 
 	// 设置端点构建器（Gemini 需要动态端点）
 	baseClient.SetEndpointBuilder(client)
 
+	if finalConfig.ProbeOnInit {
+		if _, err := baseClient.ProbeModel(context.Background(), client.buildModelInfoEndpoint(finalConfig.Model)); err != nil {
+			return nil, err
+		}
+	}
+
 	return client, nil
 }
 
+// newTransformer 创建 buildRequest 使用的消息转换器，应用配置中的转换选项
+func newTransformer(config *Config) *core.Transformer {
+	transformer := core.NewTransformer(gemini.NewAdapter())
+	transformer.SetKeepEmptyUserMessages(config.KeepEmptyUserMessages)
+	return transformer
+}
+
+// Clone 复制客户端并修改配置
+//
+// override 接收配置副本（Headers 已深拷贝），修改不会影响原客户端。克隆不重新验证
+// 配置，且与原客户端共享底层 HTTP 客户端：BaseURL、Timeout、Headers、后端类型（Gemini API / Vertex AI） 等
+// 连接级配置以原客户端为准，需要修改时请使用 New。适用于切换默认模型等请求级配置。
+func (c *Client) Clone(override func(*Config)) *Client {
+	config := c.config.clone()
+	if override != nil {
+		override(config)
+	}
+
+	client := &Client{
+		BaseClient:  c.BaseClient.CloneWithConfig(config),
+		config:      config,
+		transformer: newTransformer(config),
+		useVertexAI: c.useVertexAI,
+		caches:      c.caches,
+	}
+	client.SetEndpointBuilder(client)
+
+	return client
+}
+
+// mediaResolution 根据图片块的 Detail 返回 generationConfig.mediaResolution
+//
+// Gemini 的分辨率作用于整个请求：任一图片要求 high 时为 MEDIA_RESOLUTION_HIGH，
+// 否则有图片要求 low 时为 MEDIA_RESOLUTION_LOW；均为 auto 时返回空（由服务端决定）。
+func mediaResolution(messages []llm.Message) string {
+	low := false
+	for _, msg := range messages {
+		for _, block := range msg.ContentBlocks {
+			img, ok := block.(*llm.ImageBlock)
+			if !ok {
+				continue
+			}
+			switch img.ResolvedDetail() {
+			case llm.ImageDetailHigh:
+				return "MEDIA_RESOLUTION_HIGH"
+			case llm.ImageDetailLow:
+				low = true
+			}
+		}
+	}
+	if low {
+		return "MEDIA_RESOLUTION_LOW"
+	}
+	return ""
+}
+
+// clone 深拷贝配置
+func (c *Config) clone() *Config {
+	cp := *c
+	cp.Headers = maps.Clone(c.Headers)
+	cp.SafetySettings = slices.Clone(c.SafetySettings)
+	return &cp
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // Provider 接口实现
 // ═══════════════════════════════════════════════════════════════════════════
@@ -158,6 +264,11 @@ func New(config *Config) (*Client, error) {
 // Complete 同步完成
 //
 // 实现 [llm.Provider] 接口。发送消息到 Gemini 并等待完整响应。
+//
+// 思考阶段耗尽输出预算（无任何输出）时不返回错误：FinishReason 为 "length"，
+// Response.Metadata["thinking_exhausted"] 为 true，Usage.ReasoningTokens 为思考消耗。
+// 安全拦截时 FinishReason 为 "content_filter"，Response.Metadata["blocked_categories"]
+// 为被拦截的类别（[]string）。
 func (c *Client) Complete(ctx context.Context, messages []llm.Message, opts *llm.Options) (*llm.Response, error) {
 	return c.BaseClient.Complete(ctx, messages, opts, c)
 }
@@ -189,6 +300,36 @@ func (c *Config) Validate() error {
 	if !useVertexAI && c.APIKey == "" {
 		return llm.NewConfigError("API key is required for Gemini API backend", nil)
 	}
+	if !useVertexAI && (c.VertexLocation != "" || c.VertexCredFile != "") {
+		return llm.NewConfigError("vertex project is required when vertex location or credentials are set", nil)
+	}
+	if !isValidThinkingLevel(c.ThinkingLevel) {
+		return llm.NewConfigError("invalid thinking level: "+c.ThinkingLevel, nil)
+	}
+	return nil
+}
+
+// Normalize 校验配置并填充默认值
+//
+// 可在从文件加载配置后、创建客户端前调用，提前发现配置错误：
+//   - 未设置 VertexProject 时 APIKey 为空、设置了 Vertex 专属字段（VertexLocation /
+//     VertexCredFile），或 ThinkingLevel 非法、Timeout 为负时返回 ConfigError
+//   - 填充 BaseURL（按后端类型）、Model、Timeout 与 VertexLocation 的默认值
+//   - 模型未注册时仅记录警告，不返回错误
+func (c *Config) Normalize() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := core.ValidateTimeout(c.Timeout); err != nil {
+		return err
+	}
+
+	if c.VertexProject != "" && c.VertexLocation == "" {
+		c.VertexLocation = "us-central1"
+	}
+	c.BaseURL, c.Model, c.Timeout = c.GetDefaults()
+
+	core.WarnUnknownModel(c.ProviderName(), c.Model)
 	return nil
 }
 
@@ -213,12 +354,7 @@ func (c *Config) GetDefaults() (string, string, time.Duration) {
 		model = DefaultModel
 	}
 
-	timeout := c.Timeout
-	if timeout == 0 {
-		timeout = DefaultTimeout
-	}
-
-	return baseURL, model, timeout
+	return baseURL, model, core.GetDefaultTimeout(c.Timeout)
 }
 
 // BuildHeaders 构建请求头
@@ -244,6 +380,11 @@ func (c *Config) GetModel() string {
 	return c.Model
 }
 
+// SkipTLSVerify 实现 core.TLSVerifySkipper 接口
+func (c *Config) SkipTLSVerify() bool {
+	return c.InsecureSkipVerify
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // core.EndpointBuilder 接口实现
 // ═══════════════════════════════════════════════════════════════════════════
@@ -251,13 +392,28 @@ func (c *Config) GetModel() string {
 // BuildCompleteEndpoint 构建 Complete 端点
 // 实现 core.EndpointBuilder 接口
 func (c *Client) BuildCompleteEndpoint() string {
-	return c.buildEndpoint(false)
+	return c.buildEndpoint(c.resolveModel(nil), false)
 }
 
 // BuildStreamEndpoint 构建 Stream 端点
 // 实现 core.EndpointBuilder 接口
 func (c *Client) BuildStreamEndpoint() string {
-	return c.buildEndpoint(true)
+	return c.buildEndpoint(c.resolveModel(nil), true)
+}
+
+// BuildModelEndpoint 构建指定模型的端点（Options.Model 覆盖时使用）
+// 实现 core.ModelEndpointBuilder 接口
+func (c *Client) BuildModelEndpoint(model string, stream bool) string {
+	return c.buildEndpoint(model, stream)
+}
+
+// BuildAPIKeyEndpoint 构建使用指定 API Key 的端点（Options.APIKey 覆盖时使用）
+// 实现 core.APIKeyEndpointBuilder 接口；Vertex AI 使用访问令牌认证，端点不含 API Key
+func (c *Client) BuildAPIKeyEndpoint(model, apiKey string, stream bool) string {
+	if model == "" {
+		model = c.resolveModel(nil)
+	}
+	return c.buildKeyedEndpoint(model, apiKey, stream)
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -265,7 +421,15 @@ func (c *Client) BuildStreamEndpoint() string {
 // ═══════════════════════════════════════════════════════════════════════════
 
 // BuildRequest 实现 core.RequestBuilder 接口
+//
+// Options.CacheTTL 仅在 CreateCache 时使用，非零时同样按 Gemini 的取值规则校验，
+// 避免无效值被静默忽略。
 func (c *Client) BuildRequest(messages []llm.Message, opts *llm.Options, stream bool) (map[string]any, error) {
+	if opts != nil && opts.CacheTTL != 0 {
+		if _, err := cacheTTLValue(opts.CacheTTL); err != nil {
+			return nil, err
+		}
+	}
 	return c.buildRequest(messages, opts, stream), nil
 }
 
@@ -277,10 +441,27 @@ func (c *Client) BuildRequest(messages []llm.Message, opts *llm.Options, stream
 // 请求构建
 // ═══════════════════════════════════════════════════════════════════════════
 
-// buildEndpoint 构建 API 端点
-func (c *Client) buildEndpoint(stream bool) string {
-	model := c.config.Model
+// resolveModel 解析单次调用使用的模型（Options.Model 优先，其次为配置模型）
+//
+// 客户端创建后配置不再修改（切换模型使用 Clone 或 Options.Model），
+// 并发调用读取 config 无需加锁。
+func (c *Client) resolveModel(opts *llm.Options) string {
+	if opts != nil && opts.Model != "" {
+		return opts.Model
+	}
+	return c.config.Model
+}
 
+// buildEndpoint 构建指定模型的 API 端点
+//
+// 流式端点附加 alt=sse：streamGenerateContent 默认返回流式 JSON 数组
+// （[{...},\n{...}]），指定后才以 SSE（data: 行）输出，与 core.SSEParser 一致。
+func (c *Client) buildEndpoint(model string, stream bool) string {
+	return c.buildKeyedEndpoint(model, c.config.APIKey, stream)
+}
+
+// buildKeyedEndpoint 构建指定模型与 API Key 的 API 端点
+func (c *Client) buildKeyedEndpoint(model, apiKey string, stream bool) string {
 	if c.useVertexAI {
 		// Vertex AI 端点格式
 		// /projects/{project}/locations/{location}/publishers/google/models/{model}:generateContent
@@ -290,7 +471,7 @@ func (c *Client) buildEndpoint(stream bool) string {
 		}
 		action := "generateContent"
 		if stream {
-			action = "streamGenerateContent"
+			action = "streamGenerateContent?alt=sse"
 		}
 		return fmt.Sprintf("/projects/%s/locations/%s/publishers/google/models/%s:%s",
 			c.config.VertexProject, location, model, action)
@@ -298,11 +479,23 @@ func (c *Client) buildEndpoint(stream bool) string {
 
 	// Gemini API 端点格式
 	// /models/{model}:generateContent?key={apiKey}
-	action := "generateContent"
 	if stream {
-		action = "streamGenerateContent"
+		return fmt.Sprintf("/models/%s:streamGenerateContent?alt=sse&key=%s", model, apiKey)
+	}
+	return fmt.Sprintf("/models/%s:generateContent?key=%s", model, apiKey)
+}
+
+// buildModelInfoEndpoint 构建模型元数据端点（ProbeOnInit 使用）
+func (c *Client) buildModelInfoEndpoint(model string) string {
+	if c.useVertexAI {
+		location := c.config.VertexLocation
+		if location == "" {
+			location = "us-central1"
+		}
+		return fmt.Sprintf("/projects/%s/locations/%s/publishers/google/models/%s",
+			c.config.VertexProject, location, model)
 	}
-	return fmt.Sprintf("/models/%s:%s?key=%s", model, action, c.config.APIKey)
+	return fmt.Sprintf("/models/%s?key=%s", model, c.config.APIKey)
 }
 
 // buildRequest 构建 API 请求体
@@ -312,34 +505,29 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, _ bool)
 		opts = &llm.Options{}
 	}
 
-	// 提取系统提示
-	var systemPrompt string
-	if opts.System != "" {
-		systemPrompt = opts.System
-	} else {
-		for _, msg := range messages {
-			if msg.Role == llm.RoleSystem {
-				systemPrompt = msg.Content
-				break
-			}
-		}
+	// 引用缓存时只发送缓存之后的新消息（见 CreateCache）
+	if opts.CachedContent != "" {
+		messages = c.trimCachedPrefix(opts.CachedContent, messages)
 	}
 
-	// 使用 Transformer 转换消息
-	apiMessages := c.transformer.BuildAPIMessages(messages, systemPrompt)
+	// 使用 Transformer 转换消息并解析系统提示
+	apiMessages, systemPrompt := c.transformer.BuildRequest(messages, opts.System)
 
 	// 构建请求
 	req := map[string]any{
 		"contents": apiMessages,
 	}
 
-	// 系统指令（如果有）
-	if systemPrompt != "" {
-		req["systemInstruction"] = map[string]any{
-			"parts": []map[string]any{
-				{"text": systemPrompt},
-			},
+	// 引用已创建的缓存：系统指令已在缓存中，Gemini 拒绝同时设置 systemInstruction
+	if opts.CachedContent != "" {
+		req["cachedContent"] = opts.CachedContent
+	} else if parts := core.SystemParts(systemPrompt, opts); len(parts) > 0 {
+		// 系统指令（如果有），SystemParts 逐段作为独立的 part
+		textParts := make([]map[string]any, 0, len(parts))
+		for _, part := range parts {
+			textParts = append(textParts, map[string]any{"text": part})
 		}
+		req["systemInstruction"] = map[string]any{"parts": textParts}
 	}
 
 	// 生成配置
@@ -362,23 +550,46 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, _ bool)
 	}
 
 	// 结构化输出
-	if opts.ResponseFormat != nil && opts.ResponseFormat.Type == "json_schema" {
-		genConfig["responseMimeType"] = "application/json"
-		if opts.ResponseFormat.Schema != nil {
-			genConfig["responseSchema"] = opts.ResponseFormat.Schema
+	if opts.ResponseFormat != nil {
+		switch opts.ResponseFormat.Type {
+		case "json_schema":
+			genConfig["responseMimeType"] = "application/json"
+			if opts.ResponseFormat.Schema != nil {
+				genConfig["responseSchema"] = opts.ResponseFormat.Schema
+			}
+		case "enum":
+			// 单标签分类：直接输出枚举值文本
+			genConfig["responseMimeType"] = "text/x.enum"
+			genConfig["responseSchema"] = map[string]any{
+				"type": "STRING",
+				"enum": opts.ResponseFormat.EnumValues,
+			}
 		}
 	}
 
+	// 图片分辨率提示
+	if resolution := mediaResolution(messages); resolution != "" {
+		genConfig["mediaResolution"] = resolution
+	}
+
 	if len(genConfig) > 0 {
 		req["generationConfig"] = genConfig
 	}
 
+	// 安全设置
+	if len(c.config.SafetySettings) > 0 {
+		req["safetySettings"] = c.config.SafetySettings
+	}
+
 	// Thinking 配置（Gemini 2.5 系列）
-	if c.config.EnableThinking && supportsThinking(c.config.Model) {
+	// Google 不允许 thinkingLevel 与 thinkingBudget 同时出现，设置了级别时忽略预算
+	if c.config.EnableThinking && supportsThinking(c.resolveModel(opts)) {
 		thinkingConfig := map[string]any{
 			"includeThoughts": true,
 		}
-		if c.config.ThinkingBudget > 0 {
+		if c.config.ThinkingLevel != "" {
+			thinkingConfig["thinkingLevel"] = c.config.ThinkingLevel
+		} else if c.config.ThinkingBudget > 0 {
 			thinkingConfig["thinkingBudget"] = c.config.ThinkingBudget
 		}
 		req["thinkingConfig"] = thinkingConfig
@@ -397,6 +608,11 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, _ bool)
 		req["tools"] = []map[string]any{
 			{"functionDeclarations": functionDeclarations},
 		}
+		if mode := functionCallingMode(opts.ToolChoice); mode != "" {
+			req["toolConfig"] = map[string]any{
+				"functionCallingConfig": map[string]any{"mode": mode},
+			}
+		}
 	}
 
 	return req
@@ -406,6 +622,18 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, _ bool)
 // 辅助函数
 // ═══════════════════════════════════════════════════════════════════════════
 
+// functionCallingMode 将工具选择策略映射为 functionCallingConfig.mode，空值返回 ""
+func functionCallingMode(choice llm.ToolChoice) string {
+	switch choice {
+	case "":
+		return ""
+	case llm.ToolChoiceRequired:
+		return "ANY"
+	default:
+		return strings.ToUpper(string(choice))
+	}
+}
+
 // supportsThinking 检查模型是否支持 thinking 能力
 func supportsThinking(model string) bool {
 	switch model {
@@ -416,9 +644,21 @@ func supportsThinking(model string) bool {
 	}
 }
 
+// isValidThinkingLevel 检查 thinking 级别是否有效（空值表示未设置）
+func isValidThinkingLevel(level string) bool {
+	switch level {
+	case "", ThinkingLevelLow, ThinkingLevelMedium, ThinkingLevelHigh:
+		return true
+	default:
+		return false
+	}
+}
+
 // convertToGeminiSchema 将标准 JSON Schema 转换为 Gemini 格式
 //
 // Gemini 使用 genai.Schema 格式，与标准 JSON Schema 略有不同。
+// 工具缺少 InputSchema 时 Complete/Stream 已由 core.ValidateTools 拒绝，
+// nil 回退为 OBJECT 仅用于嵌套属性与直接构建请求体的场景。
 func convertToGeminiSchema(schema map[string]any) map[string]any {
 	if schema == nil {
 		return map[string]any{
@@ -3,12 +3,16 @@ package gemini
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -73,6 +77,65 @@ func TestNew_CustomValues(t *testing.T) {
 	assert.Equal(t, "https://custom.api.example.com/v1", client.config.BaseURL)
 }
 
+func TestNewWithOptions(t *testing.T) {
+	client, err := NewWithOptions(
+		WithAPIKey("test-key"),
+		WithBaseURL("https://custom.api.example.com/v1"),
+		WithModel("gemini-2.5-pro"),
+		WithTimeout(30*time.Second),
+		WithHeader("X-Custom-Header", "custom-value"),
+		WithThinking(1024),
+	)
+	require.NoError(t, err)
+
+	expected, err := New(&Config{
+		APIKey:         "test-key",
+		BaseURL:        "https://custom.api.example.com/v1",
+		Model:          "gemini-2.5-pro",
+		Timeout:        30 * time.Second,
+		Headers:        map[string]string{"X-Custom-Header": "custom-value"},
+		EnableThinking: true,
+		ThinkingBudget: 1024,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected.config, client.config)
+
+	// Vertex AI 后端
+	vertex, err := NewWithOptions(WithVertexAI("my-project", "", ""))
+	require.NoError(t, err)
+	assert.True(t, vertex.useVertexAI)
+	assert.Contains(t, vertex.config.BaseURL, "us-central1-aiplatform.googleapis.com")
+}
+
+func TestConfig_Normalize(t *testing.T) {
+	config := &Config{VertexProject: "my-project"}
+	require.NoError(t, config.Normalize())
+
+	assert.Equal(t, "us-central1", config.VertexLocation)
+	assert.Equal(t, "https://us-central1-aiplatform.googleapis.com/v1", config.BaseURL)
+	assert.Equal(t, DefaultModel, config.Model)
+	assert.Equal(t, 120*time.Second, config.Timeout)
+
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{"无 API Key 且无 Vertex 项目", &Config{}, "API key is required"},
+		{"Vertex 字段缺少项目", &Config{APIKey: "test-key", VertexLocation: "europe-west4"}, "vertex project is required"},
+		{"负超时", &Config{APIKey: "test-key", Timeout: -time.Second}, "invalid timeout"},
+		{"非法 thinking 级别", &Config{APIKey: "test-key", ThinkingLevel: "max"}, "invalid thinking level"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Normalize()
+			require.Error(t, err)
+			assert.True(t, llm.IsConfigError(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
 func TestNew_VertexAI_NoAPIKeyRequired(t *testing.T) {
 	// Vertex AI 模式不需要 API key
 	client, err := New(&Config{
@@ -102,6 +165,50 @@ func TestNew_VertexAI_DefaultLocation(t *testing.T) {
 // Complete 测试
 // ═══════════════════════════════════════════════════════════════════════════
 
+func TestNew_ProbeOnInit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-2.5-flash", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "models/gemini-2.5-flash", "inputTokenLimit": 1048576, "outputTokenLimit": 65535}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-2.5-flash", ProbeOnInit: true})
+	require.NoError(t, err)
+
+	// 接口返回的上限覆盖注册表，工具支持沿用注册表
+	info, ok := client.ModelInfo()
+	require.True(t, ok)
+	assert.Equal(t, 1048576, info.ContextWindow)
+	assert.Equal(t, 65535, info.MaxOutputTokens)
+	assert.True(t, info.SupportsTools)
+}
+
+func TestClient_CompleteRaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-2.0-flash:generateContent", r.URL.Path)
+		assert.Equal(t, "test-key", r.URL.Query().Get("key"))
+
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, map[string]any{"contents": []any{}, "newFeature": map[string]any{"enabled": true}}, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates": []}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-2.0-flash"})
+	require.NoError(t, err)
+
+	resp, err := client.CompleteRaw(context.Background(), map[string]any{
+		"contents":   []any{},
+		"newFeature": map[string]any{"enabled": true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []any{}, resp["candidates"])
+}
+
 func TestClient_Complete_Success(t *testing.T) {
 	// Mock HTTP Server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -169,6 +276,146 @@ func TestClient_Complete_Success(t *testing.T) {
 	assert.Equal(t, int64(5), resp.Usage.OutputTokens)
 }
 
+func TestClient_Complete_ModelVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [{"text": "Hi"}]}, "finishReason": "STOP"}],
+			"modelVersion": "gemini-2.5-flash-002"
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-2.5-flash"})
+	require.NoError(t, err)
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}, nil)
+	require.NoError(t, err)
+
+	// Model 为请求的别名，ResolvedModel 为服务端报告的版本
+	assert.Equal(t, "gemini-2.5-flash", resp.Model)
+	assert.Equal(t, "gemini-2.5-flash-002", resp.ResolvedModel)
+	assert.Empty(t, resp.SystemFingerprint)
+}
+
+func TestClient_ModelOverride(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if strings.Contains(r.URL.Path, "streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}` + "\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	opts := &llm.Options{Model: ModelGemini25Pro}
+
+	resp, err := client.Complete(context.Background(), messages, opts)
+	require.NoError(t, err)
+	assert.Equal(t, ModelGemini25Pro, resp.Model)
+
+	events, err := client.Stream(context.Background(), messages, opts)
+	require.NoError(t, err)
+	for range events {
+	}
+
+	// 未覆盖时使用配置模型
+	_, err = client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/models/gemini-2.5-pro:generateContent",
+		"/models/gemini-2.5-pro:streamGenerateContent",
+		"/models/gemini-1.5-flash:generateContent",
+	}, paths)
+}
+
+func TestClient_ModelOverride_Concurrent(t *testing.T) {
+	// 按路径中的模型名回显，验证并发调用之间的模型互不干扰
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		model := strings.TrimPrefix(r.URL.Path, "/models/")
+		model = model[:strings.Index(model, ":")]
+		if strings.Contains(r.URL.Path, "streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"candidates":[{"content":{"parts":[{"text":"` + model + `"}]},"finishReason":"STOP"}]}` + "\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"` + model + `"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, EnableThinking: true})
+	require.NoError(t, err)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Go(func() {
+			model := fmt.Sprintf("model-%d", i)
+			opts := &llm.Options{Model: model}
+			if i%4 == 0 {
+				// 未覆盖时使用配置模型
+				model, opts = DefaultModel, nil
+			}
+
+			if i%2 == 0 {
+				resp, err := client.Complete(context.Background(), messages, opts)
+				if assert.NoError(t, err) {
+					assert.Equal(t, model, resp.Message.Content)
+					assert.Equal(t, model, resp.Model)
+				}
+				return
+			}
+
+			events, err := client.Stream(context.Background(), messages, opts)
+			if !assert.NoError(t, err) {
+				return
+			}
+			var text string
+			for e := range events {
+				text += e.TextDelta
+			}
+			assert.Equal(t, model, text)
+		})
+	}
+	wg.Wait()
+}
+
+func TestClient_Clone(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	parent, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-2.5-flash"})
+	require.NoError(t, err)
+	clone := parent.Clone(func(c *Config) { c.Model = "gemini-2.5-pro" })
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	_, err = parent.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	_, err = clone.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	// 端点中的模型随克隆配置变化
+	require.Len(t, paths, 2)
+	assert.Contains(t, paths[0], "/models/gemini-2.5-flash:")
+	assert.Contains(t, paths[1], "/models/gemini-2.5-pro:")
+	assert.Equal(t, "gemini-2.5-flash", parent.config.Model)
+}
+
 func TestClient_Complete_WithToolCall(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := map[string]any{
@@ -219,6 +466,112 @@ func TestClient_Complete_WithToolCall(t *testing.T) {
 	assert.Equal(t, "get_weather", toolCalls[0].Name)
 }
 
+func TestClient_Complete_ThinkingExhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model"}, "finishReason": "MAX_TOKENS"}],
+			"usageMetadata": {"promptTokenCount": 10, "totalTokenCount": 1034, "thoughtsTokenCount": 1024}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := client.Complete(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "Prove the Riemann hypothesis"},
+	}, &llm.Options{MaxTokens: 1024})
+
+	require.NoError(t, err)
+	assert.Equal(t, "length", resp.FinishReason)
+	assert.Empty(t, resp.Message.Content)
+	assert.Nil(t, resp.Message.Meta)
+	assert.Equal(t, true, resp.Metadata["thinking_exhausted"])
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, int64(1024), resp.Usage.ReasoningTokens)
+}
+
+func TestClient_Complete_SafetySettings(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"candidates": [{
+				"finishReason": "SAFETY",
+				"safetyRatings": [
+					{"category": "HARM_CATEGORY_HARASSMENT", "probability": "HIGH", "blocked": true},
+					{"category": "HARM_CATEGORY_HATE_SPEECH", "probability": "NEGLIGIBLE"}
+				]
+			}]
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+		SafetySettings: []SafetySetting{
+			{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"},
+			{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := client.Complete(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "Hello"},
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{
+		map[string]any{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_ONLY_HIGH"},
+		map[string]any{"category": "HARM_CATEGORY_DANGEROUS_CONTENT", "threshold": "BLOCK_NONE"},
+	}, body["safetySettings"])
+
+	assert.Equal(t, "content_filter", resp.FinishReason)
+	assert.Equal(t, []string{"HARM_CATEGORY_HARASSMENT"}, resp.Metadata["blocked_categories"])
+	assert.Nil(t, resp.Message.Meta)
+}
+
+func TestClient_DeterministicToolCallIDs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_time","args":{}}}]}}]}` + "\n\n" +
+				`data: {"candidates":[{"finishReason":"STOP"}]}` + "\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [
+			{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}},
+			{"functionCall": {"name": "get_weather", "args": {"city": "Rome"}}}
+		]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL},
+		core.WithIDGenerator(core.NewSequentialIDGenerator()))
+	require.NoError(t, err)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}}
+
+	resp, err := client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	calls := resp.Message.GetToolCalls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_1", calls[0].ID)
+	assert.Equal(t, "call_2", calls[1].ID)
+
+	// 流式与非流式共享同一生成器
+	stream, err := client.Stream(context.Background(), messages, nil)
+	require.NoError(t, err)
+	result, err := core.CollectStream(stream)
+	require.NoError(t, err)
+	calls = result.Response.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "call_3", calls[0].ID)
+}
+
 func TestClient_Complete_HTTPError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -328,10 +681,80 @@ func TestClient_Complete_WithOptions(t *testing.T) {
 	require.NotNil(t, resp)
 }
 
+func TestClient_Complete_DefaultMaxTokens(t *testing.T) {
+	var maxTokens []any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&reqBody)
+		genConfig, _ := reqBody["generationConfig"].(map[string]any)
+		maxTokens = append(maxTokens, genConfig["maxOutputTokens"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "OK"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	config := &Config{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-2.5-flash"}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	// 使用模型上限而非固定的 DefaultMaxTokens
+	client, err := New(config)
+	require.NoError(t, err)
+	_, err = client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	// 关闭自动填充后回退到 DefaultMaxTokens
+	client, err = New(config, core.WithDefaultMaxTokens(false))
+	require.NoError(t, err)
+	_, err = client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	require.Len(t, maxTokens, 2)
+	assert.InDelta(t, 65536, maxTokens[0], 0.001)
+	assert.InDelta(t, DefaultMaxTokens, maxTokens[1], 0.001)
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // Stream 测试
 // ═══════════════════════════════════════════════════════════════════════════
 
+func TestClient_Stream_AltSSE(t *testing.T) {
+	// 模拟真实 API：未指定 alt=sse 时返回流式 JSON 数组，指定后返回 SSE
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunks := []string{
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"The"}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":" quick"}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":" fox"}]},"finishReason":"STOP"}]}`,
+		}
+		flusher, _ := w.(http.Flusher)
+		if r.URL.Query().Get("alt") != "sse" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("[" + strings.Join(chunks, ",\r\n") + "]"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			_, _ = w.Write([]byte("data: " + chunk + "\r\n\r\n"))
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	stream, err := client.Stream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}, nil)
+	require.NoError(t, err)
+
+	var texts []string
+	for event := range stream {
+		if event.Type == llm.EventTypeText {
+			texts = append(texts, event.TextDelta)
+		}
+	}
+	assert.Equal(t, []string{"The", " quick", " fox"}, texts)
+}
+
 func TestClient_Stream_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 验证端点是 streamGenerateContent
@@ -387,6 +810,83 @@ func TestClient_Stream_Success(t *testing.T) {
 	assert.NotEmpty(t, events)
 }
 
+func TestClient_Stream_FunctionCallOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// 唯一的响应块：首个 part 即 functionCall，且与 finishReason 同块
+		_, _ = w.Write([]byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"search","args":{"query":"go generics","limit":5}}}]},"finishReason":"STOP"}]}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	stream, err := client.Stream(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "Find docs"},
+	}, nil)
+	require.NoError(t, err)
+
+	var events []*llm.Event //nolint:prealloc // channel 收集数量未知
+	for e := range stream {
+		events = append(events, e)
+	}
+
+	require.Len(t, events, 2)
+	require.Equal(t, llm.EventTypeToolCall, events[0].Type)
+	tc := events[0].ToolCall
+	assert.True(t, strings.HasPrefix(tc.ID, "call_"))
+	assert.Equal(t, "search", tc.Name)
+	assert.JSONEq(t, `{"query":"go generics","limit":5}`, tc.ArgumentsDelta)
+	assert.Equal(t, llm.EventTypeDone, events[1].Type)
+}
+
+func TestClient_Stream_ToolResultRoundTrip(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		if strings.Contains(r.URL.Path, "streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"search","args":{"query":"go generics"}}}]},"finishReason":"STOP"}]}` + "\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Found it."}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Find docs"}}
+	stream, err := client.Stream(context.Background(), messages, nil)
+	require.NoError(t, err)
+	result, err := core.CollectStream(stream)
+	require.NoError(t, err)
+
+	calls := result.Response.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+
+	// 流式生成的 ID 原样用于配对，结果块不设置 Name
+	messages = append(messages, result.Response.Message, llm.Message{
+		Role:          llm.RoleUser,
+		ContentBlocks: []llm.ContentBlock{&llm.ToolResultBlock{ToolUseID: calls[0].ID, Content: "3 results"}},
+	})
+	resp, err := client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Found it.", resp.Message.Content)
+
+	require.Len(t, bodies, 2)
+	contents := bodies[1]["contents"].([]any)
+	require.Len(t, contents, 3)
+	call := contents[1].(map[string]any)["parts"].([]any)[0].(map[string]any)["functionCall"].(map[string]any)
+	response := contents[2].(map[string]any)["parts"].([]any)[0].(map[string]any)["functionResponse"].(map[string]any)
+	assert.Equal(t, "search", call["name"])
+	assert.Equal(t, "search", response["name"], "functionResponse 应使用函数名而非生成的 ID")
+}
+
 func TestClient_Stream_HTTPError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -436,13 +936,15 @@ func TestClient_BuildEndpoint_GeminiAPI(t *testing.T) {
 	require.NoError(t, err)
 
 	// Complete 端点
-	endpoint := client.buildEndpoint(false)
+	endpoint := client.buildEndpoint("gemini-1.5-pro", false)
 	assert.Contains(t, endpoint, "/models/gemini-1.5-pro:generateContent")
 	assert.Contains(t, endpoint, "key=test-key")
 
 	// Stream 端点
-	streamEndpoint := client.buildEndpoint(true)
+	streamEndpoint := client.buildEndpoint("gemini-1.5-pro", true)
 	assert.Contains(t, streamEndpoint, "/models/gemini-1.5-pro:streamGenerateContent")
+	assert.Contains(t, streamEndpoint, "alt=sse")
+	assert.Contains(t, streamEndpoint, "key=test-key")
 }
 
 func TestClient_BuildEndpoint_VertexAI(t *testing.T) {
@@ -454,13 +956,13 @@ func TestClient_BuildEndpoint_VertexAI(t *testing.T) {
 	require.NoError(t, err)
 
 	// Complete 端点
-	endpoint := client.buildEndpoint(false)
+	endpoint := client.buildEndpoint("gemini-1.5-pro", false)
 	assert.Contains(t, endpoint, "/projects/my-project/locations/asia-northeast1")
 	assert.Contains(t, endpoint, "/publishers/google/models/gemini-1.5-pro:generateContent")
 
 	// Stream 端点
-	streamEndpoint := client.buildEndpoint(true)
-	assert.Contains(t, streamEndpoint, ":streamGenerateContent")
+	streamEndpoint := client.buildEndpoint("gemini-1.5-pro", true)
+	assert.True(t, strings.HasSuffix(streamEndpoint, ":streamGenerateContent?alt=sse"), streamEndpoint)
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -617,6 +1119,62 @@ func TestClient_BuildRequest_ThinkingNotSupportedModel(t *testing.T) {
 	require.NotNil(t, resp)
 }
 
+func TestClient_BuildRequest_ThinkingLevel(t *testing.T) {
+	testCases := []struct {
+		name       string
+		model      string
+		level      string
+		budget     int32
+		wantConfig bool
+		wantLevel  any
+		wantBudget any
+	}{
+		{"level only", ModelGemini25Pro, ThinkingLevelHigh, 0, true, "high", nil},
+		{"budget only", ModelGemini25Flash, "", 2048, true, nil, int32(2048)},
+		{"level takes precedence over budget", ModelGemini25Pro, ThinkingLevelLow, 4096, true, "low", nil},
+		{"flash-lite rejects level", ModelGemini25FlashLite, ThinkingLevelMedium, 0, false, nil, nil},
+		{"flash-lite rejects budget", ModelGemini25FlashLite, "", 2048, false, nil, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := New(&Config{
+				APIKey:         "test-key",
+				Model:          tc.model,
+				EnableThinking: true,
+				ThinkingLevel:  tc.level,
+				ThinkingBudget: tc.budget,
+			})
+			require.NoError(t, err)
+
+			req := client.buildRequest([]llm.Message{{Role: llm.RoleUser, Content: "Hi"}}, nil, false)
+
+			thinkingConfig, ok := req["thinkingConfig"].(map[string]any)
+			if !tc.wantConfig {
+				assert.False(t, ok, "unsupported model should not have thinkingConfig")
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, true, thinkingConfig["includeThoughts"])
+			assert.Equal(t, tc.wantLevel, thinkingConfig["thinkingLevel"])
+			assert.Equal(t, tc.wantBudget, thinkingConfig["thinkingBudget"])
+		})
+	}
+}
+
+func TestNew_InvalidThinkingLevel(t *testing.T) {
+	client, err := New(&Config{
+		APIKey:        "test-key",
+		Model:         ModelGemini25Pro,
+		ThinkingLevel: "extreme",
+	})
+
+	assert.Nil(t, client)
+	require.Error(t, err)
+	assert.True(t, llm.IsConfigError(err))
+	assert.Contains(t, err.Error(), "invalid thinking level")
+}
+
 func TestClient_BuildRequest_WithResponseFormat(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var reqBody map[string]any
@@ -668,10 +1226,125 @@ func TestClient_BuildRequest_WithResponseFormat(t *testing.T) {
 	require.NotNil(t, resp)
 }
 
+func TestClient_BuildRequest_EnumResponseFormat(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	req := client.buildRequest([]llm.Message{{Role: llm.RoleUser, Content: "I love it"}}, &llm.Options{
+		ResponseFormat: &llm.ResponseFormat{Type: "enum", EnumValues: []string{"positive", "negative", "neutral"}},
+	}, false)
+
+	genConfig, ok := req["generationConfig"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "text/x.enum", genConfig["responseMimeType"])
+	assert.Equal(t, map[string]any{
+		"type": "STRING",
+		"enum": []string{"positive", "negative", "neutral"},
+	}, genConfig["responseSchema"])
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 辅助函数测试
 // ═══════════════════════════════════════════════════════════════════════════
 
+func TestClient_BuildRequest_ToolChoice(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	tools := []llm.ToolSchema{{Name: "get_weather", Description: "Get weather"}}
+	tests := []struct {
+		choice llm.ToolChoice
+		want   string
+	}{
+		{llm.ToolChoiceNone, "NONE"},
+		{llm.ToolChoiceAuto, "AUTO"},
+		{llm.ToolChoiceRequired, "ANY"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.choice), func(t *testing.T) {
+			req := client.buildRequest(nil, &llm.Options{Tools: tools, ToolChoice: tt.choice}, false)
+			assert.Contains(t, req, "tools", "工具定义应保留")
+			assert.Equal(t, map[string]any{
+				"functionCallingConfig": map[string]any{"mode": tt.want},
+			}, req["toolConfig"])
+		})
+	}
+
+	// 未设置或没有工具时不发送
+	assert.NotContains(t, client.buildRequest(nil, &llm.Options{Tools: tools}, false), "toolConfig")
+	assert.NotContains(t, client.buildRequest(nil, &llm.Options{ToolChoice: llm.ToolChoiceNone}, false), "toolConfig")
+}
+
+func TestClient_BuildRequest_SystemParts(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	// 系统消息为首段，SystemParts 逐段追加（空段跳过）
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are a travel assistant."},
+		{Role: llm.RoleUser, Content: "Hi"},
+	}
+	req := client.buildRequest(messages, &llm.Options{
+		SystemParts: []string{"Always answer in French.", "", "Use tools for live data."},
+	}, false)
+	assert.Equal(t, map[string]any{"parts": []map[string]any{
+		{"text": "You are a travel assistant."},
+		{"text": "Always answer in French."},
+		{"text": "Use tools for live data."},
+	}}, req["systemInstruction"])
+
+	// 仅字符串系统提示时为单个 part
+	req = client.buildRequest(messages, &llm.Options{System: "Be brief."}, false)
+	assert.Equal(t, map[string]any{"parts": []map[string]any{{"text": "Be brief."}}}, req["systemInstruction"])
+
+	// 均未设置时不发送
+	assert.NotContains(t, client.buildRequest(messages[1:], nil, false), "systemInstruction")
+}
+
+func TestClient_BuildRequest_ImageDetail(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	image := func(detail string) []llm.Message {
+		return []llm.Message{{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+			&llm.TextBlock{Text: "Describe"},
+			&llm.ImageBlock{Data: "aGVsbG8=", MediaType: "image/png", Detail: detail},
+		}}}
+	}
+
+	req := client.buildRequest(image(llm.ImageDetailLow), nil, false)
+	genConfig, _ := req["generationConfig"].(map[string]any)
+	assert.Equal(t, "MEDIA_RESOLUTION_LOW", genConfig["mediaResolution"])
+	contents, _ := req["contents"].([]map[string]any)
+	require.Len(t, contents, 1)
+	assert.Equal(t, []map[string]any{
+		{"text": "Describe"},
+		{"inlineData": map[string]any{"mimeType": "image/png", "data": "aGVsbG8="}},
+	}, contents[0]["parts"])
+
+	// 任一图片要求 high 时整个请求使用高分辨率
+	messages := append(image(llm.ImageDetailLow), image(llm.ImageDetailHigh)...)
+	genConfig, _ = client.buildRequest(messages, nil, false)["generationConfig"].(map[string]any)
+	assert.Equal(t, "MEDIA_RESOLUTION_HIGH", genConfig["mediaResolution"])
+
+	// 默认 auto：由服务端决定，不发送
+	for _, detail := range []string{"", llm.ImageDetailAuto} {
+		genConfig, _ = client.buildRequest(image(detail), nil, false)["generationConfig"].(map[string]any)
+		assert.NotContains(t, genConfig, "mediaResolution", "detail %q", detail)
+	}
+}
+
+func TestClient_BuildRequest_EndUserIDIgnored(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	require.NoError(t, err)
+
+	req := client.buildRequest(nil, &llm.Options{EndUserID: "user-42"}, false)
+
+	assert.NotContains(t, req, "user")
+	assert.NotContains(t, req, "metadata")
+	assert.NotContains(t, req, "labels")
+}
+
 func TestSupportsThinking(t *testing.T) {
 	testCases := []struct {
 		model    string
@@ -780,6 +1453,91 @@ func TestConvertToGeminiSchema_Enum(t *testing.T) {
 // 接口实现验证
 // ═══════════════════════════════════════════════════════════════════════════
 
+func TestClient_ZeroArgToolCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_time"}}]}}]}` + "\n\n" +
+				`data: {"candidates":[{"finishReason":"STOP"}]}` + "\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [{"functionCall": {"name": "get_time", "args": {}}}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "What time is it?"}}
+
+	// 非流式：空 args
+	resp, err := client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	calls := resp.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.NotNil(t, calls[0].Input)
+	assert.Empty(t, calls[0].Input)
+
+	// 流式：缺失 args 字段
+	stream, err := client.Stream(context.Background(), messages, nil)
+	require.NoError(t, err)
+	result, err := core.CollectStream(stream)
+	require.NoError(t, err)
+	calls = result.Response.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "get_time", calls[0].Name)
+	assert.NotNil(t, calls[0].Input)
+	assert.Empty(t, calls[0].Input)
+}
+
 func TestClient_ImplementsProvider(t *testing.T) {
 	var _ llm.Provider = (*Client)(nil)
 }
+
+func TestClient_RequestHeaders(t *testing.T) {
+	var trace string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace = r.Header.Get("X-Trace-Id")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	_, err = client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}},
+		&llm.Options{Headers: map[string]string{"X-Trace-Id": "trace-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "trace-1", trace)
+}
+
+func TestClient_APIKeyOverride(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.URL.Query().Get("key"))
+		if r.URL.Query().Get("alt") == "sse" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"candidates\": [{\"content\": {\"role\": \"model\", \"parts\": [{\"text\": \"ok\"}]}, \"finishReason\": \"STOP\"}]}\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	_, err = client.Complete(context.Background(), messages, &llm.Options{APIKey: "tenant-key"})
+	require.NoError(t, err)
+	events, err := client.Stream(context.Background(), messages, &llm.Options{APIKey: "tenant-key", Model: "gemini-2.5-pro"})
+	require.NoError(t, err)
+	for range events {
+	}
+	_, err = client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"tenant-key", "tenant-key", "test-key"}, keys)
+}
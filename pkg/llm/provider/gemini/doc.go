@@ -11,6 +11,16 @@
 //
 //	resp, err := provider.Complete(ctx, messages, opts)
 //
+// 也可使用函数式选项：
+//
+//	provider, err := gemini.NewWithOptions(
+//	    gemini.WithAPIKey("your-api-key"),
+//	    gemini.WithModel("gemini-2.5-flash"),
+//	    gemini.WithThinking(24576),
+//	)
+//
+// 从文件加载的配置可先调用 [Config.Normalize] 校验并填充默认值，New 内部同样会调用。
+//
 // # Vertex AI 后端
 //
 //	provider, err := gemini.New(&gemini.Config{
@@ -20,6 +30,20 @@
 //	    VertexCredFile: "/path/to/credentials.json",
 //	})
 //
+// VertexCredFile 为服务账户 JSON 密钥：客户端以其私钥签名 JWT 换取 OAuth2 访问令牌
+// （cloud-platform 作用域），每个请求携带 "Authorization: Bearer" 头，令牌在过期前
+// 自动刷新。其他认证方式可通过 core.WithTokenSource 提供令牌。
+//
+// # 多段系统指令
+//
+// Options.SystemParts 中的每一段作为 systemInstruction 的独立 part，位于 Options.System
+// （或首条系统消息）之后，适用于将角色设定与工具使用说明分开维护：
+//
+//	resp, err := provider.Complete(ctx, messages, &llm.Options{
+//	    System:      persona,
+//	    SystemParts: []string{toolGuide},
+//	})
+//
 // # Thinking 模式
 //
 // Gemini 2.5 系列支持 thinking 能力：
@@ -30,6 +54,46 @@
 //	    ThinkingBudget: 24576,  // 最大 24K tokens
 //	})
 //
+// # 显式缓存
+//
+// [Client.CreateCache] 按 Options.CacheTTL（正整数秒）创建 cachedContents，
+// 之后通过 Options.CachedContent 引用：
+//
+//	name, _ := client.CreateCache(ctx, docs, &llm.Options{CacheTTL: time.Hour})
+//	resp, _ := client.Complete(ctx, question, &llm.Options{CachedContent: name})
+//
+// Complete / Stream 不使用 CacheTTL，但非零的无效值同样在发送前返回 RequestError。
+//
+// 缓存已包含系统提示与初始上下文，引用缓存的请求只发送新的对话轮次，不发送
+// systemInstruction。调用方不应重发已缓存的内容；以 CreateCache 上传的消息开头的
+// 完整历史会被自动去除该前缀。
+//
+// # 思考耗尽预算
+//
+// 思考阶段用完 MaxTokens 时 Gemini 返回 MAX_TOKENS 且没有任何输出。此时 Complete
+// 不返回错误，而是返回空消息，并通过以下字段说明原因：
+//
+//	resp.FinishReason                     // "length"
+//	resp.Metadata["thinking_exhausted"]   // true
+//	resp.Usage.ReasoningTokens            // 思考消耗的 token 数
+//
+// # 安全设置
+//
+// Config.SafetySettings 按类别调整拦截阈值。回答或提示词被拦截时 Complete 不返回错误，
+// FinishReason 为 "content_filter"，被拦截的类别见 Metadata：
+//
+//	client, _ := gemini.New(&gemini.Config{
+//	    APIKey: key,
+//	    SafetySettings: []gemini.SafetySetting{
+//	        {Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"},
+//	    },
+//	})
+//	resp.Metadata["blocked_categories"]   // []string{"HARM_CATEGORY_HARASSMENT"}
+//
+// # 不支持的选项
+//
+// Options.EndUserID：Gemini API 没有终端用户标识字段，该选项被忽略，不会写入请求。
+//
 // # 支持的模型
 //
 //   - gemini-2.5-pro: 最强模型，32K thinking tokens
@@ -0,0 +1,86 @@
+package gemini
+
+import "time"
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 函数式选项
+// ═══════════════════════════════════════════════════════════════════════════
+
+// Option 配置选项函数，用于 [NewWithOptions]
+//
+// Option 直接修改 Config，未提供对应 With 函数的字段可用自定义函数设置：
+//
+//	client, err := gemini.NewWithOptions(
+//		gemini.WithAPIKey(key),
+//		func(c *gemini.Config) { c.InsecureSkipVerify = true },
+//	)
+type Option func(*Config)
+
+// NewWithOptions 使用函数式选项创建客户端
+//
+// 按顺序应用 opts 构建 Config 后调用 [New]，未设置的字段使用 New 的默认值。
+// 需要传入 core.ClientOption（如重试配置）时请使用 New。
+func NewWithOptions(opts ...Option) (*Client, error) {
+	config := &Config{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return New(config)
+}
+
+// WithAPIKey 设置 API 密钥
+func WithAPIKey(key string) Option {
+	return func(c *Config) {
+		c.APIKey = key
+	}
+}
+
+// WithBaseURL 设置 API 基础地址
+func WithBaseURL(url string) Option {
+	return func(c *Config) {
+		c.BaseURL = url
+	}
+}
+
+// WithModel 设置默认模型名称
+func WithModel(model string) Option {
+	return func(c *Config) {
+		c.Model = model
+	}
+}
+
+// WithTimeout 设置请求超时时间
+func WithTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = d
+	}
+}
+
+// WithHeader 添加额外的请求头，可多次调用
+func WithHeader(key, value string) Option {
+	return func(c *Config) {
+		if c.Headers == nil {
+			c.Headers = make(map[string]string)
+		}
+		c.Headers[key] = value
+	}
+}
+
+// WithThinking 启用 thinking 模式，budget 为 thinking tokens 预算（0 表示动态）
+func WithThinking(budget int32) Option {
+	return func(c *Config) {
+		c.EnableThinking = true
+		c.ThinkingBudget = budget
+	}
+}
+
+// WithVertexAI 使用 Vertex AI 后端
+//
+// location 为空时使用 us-central1，credFile 为服务账户凭证文件路径。
+func WithVertexAI(project, location, credFile string) Option {
+	return func(c *Config) {
+		c.VertexProject = project
+		c.VertexLocation = location
+		c.VertexCredFile = credFile
+	}
+}
@@ -0,0 +1,214 @@
+package gemini
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// Vertex AI 服务账户认证
+// ═══════════════════════════════════════════════════════════════════════════
+
+const (
+	// vertexScope Vertex AI 访问令牌的 OAuth2 作用域
+	vertexScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// defaultTokenURI 凭证文件未指定 token_uri 时使用的令牌端点
+	defaultTokenURI = "https://oauth2.googleapis.com/token"
+
+	// tokenRefreshMargin 令牌过期前提前刷新的时间，避免请求途中过期
+	tokenRefreshMargin = time.Minute
+)
+
+// serviceAccountTokenSource 基于服务账户密钥的访问令牌（OAuth2 JWT Bearer 授权）
+//
+// 以服务账户私钥签名 JWT 断言，向 token_uri 换取访问令牌；令牌缓存至过期前
+// tokenRefreshMargin，并发调用共享同一令牌。实现 core.TokenSource 接口。
+type serviceAccountTokenSource struct {
+	email    string
+	keyID    string
+	key      *rsa.PrivateKey
+	tokenURI string
+	client   *http.Client
+	now      func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// newServiceAccountTokenSource 读取服务账户凭证文件（Google Cloud 控制台下载的 JSON 密钥）
+func newServiceAccountTokenSource(path string) (*serviceAccountTokenSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read credentials file: %w", err)
+	}
+
+	var creds struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKeyID string `json:"private_key_id"`
+		PrivateKey   string `json:"private_key"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("parse credentials file: %w", err)
+	}
+	if creds.Type != "service_account" {
+		return nil, fmt.Errorf("unsupported credentials type %q, want service_account", creds.Type)
+	}
+	if creds.ClientEmail == "" {
+		return nil, errors.New("credentials file: client_email is required")
+	}
+
+	key, err := parsePrivateKey(creds.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("credentials file: %w", err)
+	}
+
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultTokenURI
+	}
+
+	return &serviceAccountTokenSource{
+		email:    creds.ClientEmail,
+		keyID:    creds.PrivateKeyID,
+		key:      key,
+		tokenURI: tokenURI,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+// parsePrivateKey 解析 PEM 编码的 RSA 私钥（PKCS#8，兼容 PKCS#1）
+func parsePrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("private_key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private_key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private_key is not an RSA key")
+	}
+	return key, nil
+}
+
+// Token 返回有效的访问令牌，缓存的令牌即将过期时重新获取
+func (s *serviceAccountTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.token != "" && now.Add(tokenRefreshMargin).Before(s.expiry) {
+		return s.token, nil
+	}
+
+	token, expiresIn, err := s.fetch(ctx, now)
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+	s.expiry = now.Add(expiresIn)
+	return s.token, nil
+}
+
+// fetch 签名 JWT 断言并换取访问令牌
+func (s *serviceAccountTokenSource) fetch(ctx context.Context, now time.Time) (string, time.Duration, error) {
+	assertion, err := s.assertion(now)
+	if err != nil {
+		return "", 0, err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", 0, fmt.Errorf("parse token response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", 0, errors.New("token response has no access_token")
+	}
+	return result.AccessToken, time.Duration(result.ExpiresIn) * time.Second, nil
+}
+
+// assertion 构造 RS256 签名的 JWT 断言（有效期 1 小时）
+func (s *serviceAccountTokenSource) assertion(now time.Time) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if s.keyID != "" {
+		header["kid"] = s.keyID
+	}
+	claims := map[string]any{
+		"iss":   s.email,
+		"scope": vertexScope,
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign assertion: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
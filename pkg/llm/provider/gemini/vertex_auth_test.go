@@ -0,0 +1,127 @@
+package gemini
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+// newTokenServer 模拟 OAuth2 令牌端点，依次签发 token-1、token-2 ...
+func newTokenServer(t *testing.T, calls *atomic.Int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+		assert.Len(t, strings.Split(r.PostForm.Get("assertion"), "."), 3)
+
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"expires_in":   3600,
+			"token_type":   "Bearer",
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeCredentials 生成测试私钥并写入服务账户凭证文件
+func writeCredentials(t *testing.T, tokenURI string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	creds, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "llm@my-project.iam.gserviceaccount.com",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURI,
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(path, creds, 0o600))
+	return path
+}
+
+func TestServiceAccountTokenSource_Refresh(t *testing.T) {
+	var calls atomic.Int64
+	tokenServer := newTokenServer(t, &calls)
+
+	src, err := newServiceAccountTokenSource(writeCredentials(t, tokenServer.URL))
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	src.now = func() time.Time { return now }
+
+	token, err := src.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// 有效期内复用缓存
+	now = now.Add(30 * time.Minute)
+	token, err = src.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+	assert.Equal(t, int64(1), calls.Load())
+
+	// 进入过期前的刷新窗口后重新获取
+	now = now.Add(30 * time.Minute)
+	token, err = src.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+	assert.Equal(t, int64(2), calls.Load())
+}
+
+func TestClient_VertexAI_BearerToken(t *testing.T) {
+	var calls atomic.Int64
+	tokenServer := newTokenServer(t, &calls)
+
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hi"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{
+		BaseURL:        server.URL,
+		VertexProject:  "my-project",
+		VertexCredFile: writeCredentials(t, tokenServer.URL),
+	})
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err = client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer token-1", auth)
+	}
+	assert.Equal(t, int64(1), calls.Load(), "token is cached across requests")
+}
+
+func TestNew_VertexAI_InvalidCredentials(t *testing.T) {
+	_, err := New(&Config{VertexProject: "my-project", VertexCredFile: filepath.Join(t.TempDir(), "missing.json")})
+	require.Error(t, err)
+	assert.True(t, llm.IsConfigError(err))
+}
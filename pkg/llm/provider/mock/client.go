@@ -2,10 +2,12 @@ package mock
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 	"time"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 )
 
 // CallRecord 记录一次调用的详情
@@ -25,6 +27,8 @@ type Client struct {
 	respFunc        ResponseFunc              // 动态响应函数
 	msgFunc         MessageResponseFunc       // 完整消息响应函数（支持工具调用）
 	delay           time.Duration             // 响应延迟
+	usage           *llm.TokenUsage           // 固定用量（nil 时按消息数估算）
+	idGenerator     core.IDGenerator          // 场景工具调用 ID 生成器（nil 时使用默认）
 	err             error                     // 返回错误
 	calls           []CallRecord              // 调用记录
 	counter         int                       // 调用计数
@@ -137,6 +141,22 @@ func WithDelay(d time.Duration) Option {
 	}
 }
 
+// WithUsage 设置固定的 Token 用量（Complete 每次返回相同用量，Stream 在完成事件中携带）
+func WithUsage(usage llm.TokenUsage) Option {
+	return func(c *Client) {
+		c.usage = &usage
+	}
+}
+
+// WithIDGenerator 设置场景工具调用 ID 的生成器
+//
+// 注入 core.SequentialIDGenerator 等确定性生成器可使工具调用 ID 在测试中保持稳定。
+func WithIDGenerator(gen core.IDGenerator) Option {
+	return func(c *Client) {
+		c.idGenerator = gen
+	}
+}
+
 // WithError 设置返回错误
 func WithError(err error) Option {
 	return func(c *Client) {
@@ -231,6 +251,7 @@ func (c *Client) Complete(ctx context.Context, messages []llm.Message, opts *llm
 	c.counter++
 	delay := c.delay
 	err := c.err
+	fixedUsage := c.usage
 
 	// 记录调用
 	c.calls = append(c.calls, CallRecord{
@@ -272,6 +293,7 @@ func (c *Client) Complete(ctx context.Context, messages []llm.Message, opts *llm
 	}
 
 	// 如果有完整消息响应，使用它
+	var resp *llm.Response
 	if msgResp != nil {
 		msgResp.Role = llm.RoleAssistant
 		finishReason := "stop"
@@ -282,7 +304,7 @@ func (c *Client) Complete(ctx context.Context, messages []llm.Message, opts *llm
 				break
 			}
 		}
-		return &llm.Response{
+		resp = &llm.Response{
 			Message:      *msgResp,
 			FinishReason: finishReason,
 			Usage: &llm.TokenUsage{
@@ -290,22 +312,28 @@ func (c *Client) Complete(ctx context.Context, messages []llm.Message, opts *llm
 				OutputTokens: 20,
 				TotalTokens:  int64(len(messages)*10 + 20),
 			},
-		}, nil
+		}
+	} else {
+		// 返回预设响应
+		resp = &llm.Response{
+			Message: llm.Message{
+				Role:    llm.RoleAssistant,
+				Content: response,
+			},
+			FinishReason: "stop",
+			Usage: &llm.TokenUsage{
+				InputTokens:  int64(len(messages) * 10),
+				OutputTokens: int64(len(response) / 4),
+				TotalTokens:  int64(len(messages)*10 + len(response)/4),
+			},
+		}
 	}
 
-	// 返回预设响应
-	return &llm.Response{
-		Message: llm.Message{
-			Role:    llm.RoleAssistant,
-			Content: response,
-		},
-		FinishReason: "stop",
-		Usage: &llm.TokenUsage{
-			InputTokens:  int64(len(messages) * 10),
-			OutputTokens: int64(len(response) / 4),
-			TotalTokens:  int64(len(messages)*10 + len(response)/4),
-		},
-	}, nil
+	if fixedUsage != nil {
+		usage := *fixedUsage
+		resp.Usage = &usage
+	}
+	return resp, nil
 }
 
 // Stream 流式完成
@@ -314,6 +342,7 @@ func (c *Client) Stream(ctx context.Context, messages []llm.Message, opts *llm.O
 	c.counter++
 	delay := c.delay
 	err := c.err
+	fixedUsage := c.usage
 
 	// 记录调用
 	c.calls = append(c.calls, CallRecord{
@@ -322,8 +351,17 @@ func (c *Client) Stream(ctx context.Context, messages []llm.Message, opts *llm.O
 		Time:     time.Now(),
 	})
 
-	// 获取响应
-	response := c.getResponse(messages)
+	// 获取响应（优先级与 Complete 一致）
+	var msgResp *llm.Message
+	if c.currentScenario != "" {
+		msgResp = c.getScenarioResponse(messages)
+	}
+	if msgResp == nil {
+		msgResp = c.getMessage(messages)
+	}
+	if msgResp == nil {
+		msgResp = &llm.Message{Content: c.getResponse(messages)}
+	}
 	c.mu.Unlock()
 
 	// 立即返回错误
@@ -331,7 +369,12 @@ func (c *Client) Stream(ctx context.Context, messages []llm.Message, opts *llm.O
 		return nil, err
 	}
 
-	chunks := make(chan *llm.Event, len(response)+1)
+	events := streamEvents(msgResp)
+	if fixedUsage != nil {
+		usage := *fixedUsage
+		events[len(events)-1].Usage = &usage
+	}
+	chunks := make(chan *llm.Event, len(events))
 
 	go func() {
 		defer close(chunks)
@@ -345,28 +388,63 @@ func (c *Client) Stream(ctx context.Context, messages []llm.Message, opts *llm.O
 			}
 		}
 
-		// 逐字符流式返回
-		for _, ch := range response {
+		for _, event := range events {
 			select {
 			case <-ctx.Done():
 				return
-			case chunks <- &llm.Event{
-				Type:      "text",
-				TextDelta: string(ch),
-			}:
+			case chunks <- event:
 			}
 		}
-
-		// 发送完成信号
-		chunks <- &llm.Event{
-			Type:         "done",
-			FinishReason: "stop",
-		}
 	}()
 
 	return chunks, nil
 }
 
+// streamEvents 将完整消息拆分为流式事件
+//
+// 思考块输出为 thinking 事件，文本逐字符输出，工具调用各输出一个 tool_call
+// 事件（参数为完整 JSON），最后以 done 结束；包含工具调用时完成原因为 "tool_calls"。
+func streamEvents(msg *llm.Message) []*llm.Event {
+	var events []*llm.Event
+	var calls []*llm.ToolCall
+	text := msg.Content
+
+	for _, block := range msg.ContentBlocks {
+		switch b := block.(type) {
+		case *llm.ThinkingBlock:
+			events = append(events, &llm.Event{
+				Type:      llm.EventTypeThinking,
+				Reasoning: &llm.ReasoningDelta{ThoughtDelta: b.Thinking, Signature: b.Signature},
+			})
+		case *llm.TextBlock:
+			if msg.Content == "" {
+				text += b.Text
+			}
+		case *llm.ToolCall:
+			calls = append(calls, b)
+		}
+	}
+
+	// 逐字符流式返回
+	for _, ch := range text {
+		events = append(events, &llm.Event{Type: llm.EventTypeText, TextDelta: string(ch)})
+	}
+
+	finishReason := "stop"
+	for i, call := range calls {
+		args, _ := json.Marshal(call.Input) //nolint:errchkjson // best effort
+		events = append(events, &llm.Event{
+			Type:     llm.EventTypeToolCall,
+			Index:    i,
+			ToolCall: &llm.ToolCallDelta{Index: i, ID: call.ID, Name: call.Name, ArgumentsDelta: string(args)},
+		})
+		finishReason = "tool_calls"
+	}
+
+	// 发送完成信号
+	return append(events, &llm.Event{Type: llm.EventTypeDone, FinishReason: finishReason})
+}
+
 // Close 关闭连接
 func (c *Client) Close() error {
 	return nil
@@ -519,7 +597,7 @@ func (c *Client) getScenarioResponse(messages []llm.Message) *llm.Message {
 
 	// 构建响应
 	data := createTemplateData(messages)
-	msg := s.buildTurnResponse(messages, data)
+	msg := s.buildTurnResponse(messages, data, c.idGenerator)
 
 	// 推进轮次
 	s.turnIdx++
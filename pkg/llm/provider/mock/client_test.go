@@ -228,6 +228,35 @@ func TestClient_Stream(t *testing.T) {
 		assert.Equal(t, 0, count)
 	})
 
+	t.Run("stream tool calls", func(t *testing.T) {
+		client := New(WithMessageFunc(func(messages []llm.Message, callCount int) llm.Message {
+			return llm.Message{Content: "Checking", ContentBlocks: []llm.ContentBlock{
+				&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+			}}
+		}))
+		defer func() { _ = client.Close() }()
+
+		stream, err := client.Stream(context.Background(), nil, nil)
+		require.NoError(t, err)
+
+		var calls []*llm.ToolCallDelta
+		var finishReason string
+		for chunk := range stream {
+			switch chunk.Type {
+			case llm.EventTypeToolCall:
+				calls = append(calls, chunk.ToolCall)
+			case llm.EventTypeDone:
+				finishReason = chunk.FinishReason
+			}
+		}
+
+		require.Len(t, calls, 1)
+		assert.Equal(t, "call_1", calls[0].ID)
+		assert.Equal(t, "get_weather", calls[0].Name)
+		assert.JSONEq(t, `{"city":"Paris"}`, calls[0].ArgumentsDelta)
+		assert.Equal(t, "tool_calls", finishReason)
+	})
+
 	t.Run("stream records call", func(t *testing.T) {
 		client := New(WithResponse("OK"))
 		defer func() { _ = client.Close() }()
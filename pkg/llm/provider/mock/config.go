@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 	"gopkg.in/yaml.v3"
 )
 
@@ -171,7 +172,7 @@ type scenarioState struct {
 }
 
 // buildTurnResponse 构建当前轮次的响应消息
-func (s *scenarioState) buildTurnResponse(messages []llm.Message, data map[string]string) llm.Message {
+func (s *scenarioState) buildTurnResponse(messages []llm.Message, data map[string]string, gen core.IDGenerator) llm.Message {
 	if s.turnIdx >= len(s.scenario.Turns) {
 		return llm.Message{
 			Role:    llm.RoleAssistant,
@@ -200,7 +201,7 @@ func (s *scenarioState) buildTurnResponse(messages []llm.Message, data map[strin
 		for _, tool := range turn.Tools {
 			renderedInput := renderToolInput(tool.Input, messages)
 			blocks = append(blocks, &llm.ToolCall{
-				ID:    generateToolID(tool.Name),
+				ID:    generateToolID(gen, tool.Name),
 				Name:  tool.Name,
 				Input: renderedInput,
 			})
@@ -313,8 +314,11 @@ func createTemplateData(messages []llm.Message) map[string]string {
 }
 
 // generateToolID 生成工具调用 ID
-func generateToolID(toolName string) string {
-	return fmt.Sprintf("call_%s_%d", toolName, time.Now().UnixNano())
+func generateToolID(gen core.IDGenerator, toolName string) string {
+	if gen == nil {
+		gen = core.DefaultIDGenerator
+	}
+	return fmt.Sprintf("call_%s_%s", toolName, gen.NewID())
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -663,6 +664,32 @@ func TestScenario_MultipleTools(t *testing.T) {
 	assert.Equal(t, "tool2", toolCall2.Name)
 }
 
+func TestScenario_ToolCalls_DeterministicIDs(t *testing.T) {
+	cfg := &Config{
+		Scenarios: []Scenario{
+			{
+				Name: "multi",
+				Turns: []Turn{
+					{Tools: []ToolCall{{Name: "tool1"}, {Name: "tool2"}}},
+				},
+			},
+		},
+	}
+
+	client := New(WithConfig(cfg), WithIDGenerator(core.NewSequentialIDGenerator()))
+	client.UseScenario("multi")
+
+	resp, err := client.Complete(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "multi"},
+	}, nil)
+	require.NoError(t, err)
+
+	calls := resp.Message.GetToolCalls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_tool1_1", calls[0].ID)
+	assert.Equal(t, "call_tool2_2", calls[1].ID)
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // 调试辅助方法测试
 // ═══════════════════════════════════════════════════════════════════════════
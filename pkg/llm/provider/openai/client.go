@@ -33,6 +33,44 @@ type Config struct {
 
 	// Headers 额外的请求头
 	Headers map[string]string
+
+	// CompletePath 自定义 Complete 端点路径，默认 /chat/completions
+	CompletePath string
+
+	// StreamPath 自定义 Stream 端点路径，默认 /chat/completions
+	StreamPath string
+
+	// ReasoningToggle Options.IncludeReasoning 映射的请求字段，默认 ReasoningToggleFlag
+	ReasoningToggle ReasoningToggle
+
+	// PrefixCompletion 最后一条消息为 assistant 时标记 "prefix": true（DeepSeek 对话前缀续写）
+	//
+	// 模型从该消息内容之后继续生成。DeepSeek 要求 BaseURL 为 beta 地址（见 DeepSeekBetaBaseURL）。
+	PrefixCompletion bool
+
+	// KeepEmptyUserMessages 保留没有内容的用户消息（默认丢弃）
+	//
+	// 用于以空的用户轮次表示 "继续" 的流程，见 core.Transformer.SetKeepEmptyUserMessages。
+	KeepEmptyUserMessages bool
+
+	// PreserveInlineSystem 在原位置保留对话中间的系统消息（默认仅发送首条系统消息）
+	//
+	// 用于支持多条内联系统消息的模型或调试，见 core.Transformer.SetPreserveInlineSystem。
+	// 此时系统提示仅由 Options.System 或开头连续的系统消息构成，中间的系统消息只在原位置发送一次。
+	// Responses API 以 instructions 传递系统提示，不受影响。
+	PreserveInlineSystem bool
+
+	// InsecureSkipVerify 跳过 TLS 证书校验
+	//
+	// ⚠️ 仅用于测试：连接使用自签名证书的本地服务（Ollama、vLLM 等）。
+	// 开启后连接可被中间人攻击，切勿在生产环境使用。默认关闭。
+	InsecureSkipVerify bool
+
+	// ProbeOnInit 创建客户端时请求模型元数据接口（GET /models/{model}）
+	//
+	// 认证失败等配置错误在 New 中立即返回 ConfigError，并缓存模型信息（见 ModelInfo）。
+	// 默认关闭，New 不发起网络请求。
+	ProbeOnInit bool
 }
 
 // Client OpenAI 兼容的 LLM 客户端
@@ -53,19 +91,44 @@ type Client struct {
 // New 创建新的 OpenAI 客户端
 //
 // 参数 config 必须包含 APIKey。如果 BaseURL 为空，默认使用 OpenAI 官方地址。
-func New(config *Config) (*Client, error) {
+// opts 为可选的 core.ClientOption。config 的副本经 [Config.Normalize] 校验并填充默认值，
+// 传入的 config 不会被修改。
+func New(config *Config, opts ...core.ClientOption) (*Client, error) {
+	if config == nil {
+		return nil, llm.NewConfigError("config is required", nil)
+	}
+	config = config.clone()
+	if err := config.Normalize(); err != nil {
+		return nil, err
+	}
+
 	// 创建 BaseClient
 	baseClient, err := core.NewBaseClient(
 		config,
 		openai.NewAdapter(),
 		openai.NewEventHandler(),
+		opts...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// 创建 transformer 用于 buildRequest
-	transformer := core.NewTransformer(openai.NewAdapter())
+	transformer := newTransformer(openai.NewAdapter(), config)
+
+	// 自定义端点路径（部分网关流式与非流式路径不同）
+	if config.CompletePath != "" || config.StreamPath != "" {
+		baseClient.SetEndpointBuilder(&core.PathEndpointBuilder{
+			CompletePath: pathOrDefault(config.CompletePath, defaultEndpoint),
+			StreamPath:   pathOrDefault(config.StreamPath, defaultEndpoint),
+		})
+	}
+
+	if config.ProbeOnInit {
+		if _, err := baseClient.ProbeModel(context.Background(), "/models/"+config.Model); err != nil {
+			return nil, err
+		}
+	}
 
 	return &Client{
 		BaseClient:  baseClient,
@@ -74,6 +137,90 @@ func New(config *Config) (*Client, error) {
 	}, nil
 }
 
+// Clone 复制客户端并修改配置
+//
+// override 接收配置副本（Headers 已深拷贝），修改不会影响原客户端。克隆不重新验证
+// 配置，且与原客户端共享底层 HTTP 客户端：BaseURL、APIKey、Timeout、Headers 等
+// 连接级配置以原客户端为准，需要修改时请使用 New。适用于切换默认模型等请求级配置。
+func (c *Client) Clone(override func(*Config)) *Client {
+	config := c.config.clone()
+	if override != nil {
+		override(config)
+	}
+
+	baseClient := c.BaseClient.CloneWithConfig(config)
+	baseClient.SetEndpointBuilder(&core.PathEndpointBuilder{
+		CompletePath: pathOrDefault(config.CompletePath, defaultEndpoint),
+		StreamPath:   pathOrDefault(config.StreamPath, defaultEndpoint),
+	})
+
+	return &Client{
+		BaseClient:  baseClient,
+		config:      config,
+		transformer: newTransformer(openai.NewAdapter(), config),
+	}
+}
+
+// newTransformer 创建 buildRequest 使用的消息转换器，应用配置中的转换选项
+func newTransformer(adapter core.ProtocolAdapter, config *Config) *core.Transformer {
+	transformer := core.NewTransformer(adapter)
+	transformer.SetKeepEmptyUserMessages(config.KeepEmptyUserMessages)
+	transformer.SetPreserveInlineSystem(config.PreserveInlineSystem)
+	return transformer
+}
+
+// clone 深拷贝配置
+func (c *Config) clone() *Config {
+	cp := *c
+	cp.Headers = maps.Clone(c.Headers)
+	return &cp
+}
+
+// defaultBaseURL OpenAI 官方 API 地址
+const defaultBaseURL = "https://api.openai.com/v1"
+
+// defaultEndpoint OpenAI Chat Completions 端点
+const defaultEndpoint = "/chat/completions"
+
+// defaultEnumSchemaName 枚举模拟 Schema 的默认名称
+const defaultEnumSchemaName = "classification"
+
+// applyExtraBody 将 Options.ExtraBody 合并到请求体顶层（同名字段覆盖）
+func applyExtraBody(req map[string]any, opts *llm.Options) {
+	maps.Copy(req, opts.ExtraBody)
+}
+
+// enumJSONSchema 用 json_schema 模拟枚举输出
+//
+// OpenAI 不支持纯文本枚举，且 json_schema 根节点必须为对象，
+// 因此将标签包装为 {"value": "<标签>"}，并启用 strict 保证取值受限。
+func enumJSONSchema(format *llm.ResponseFormat) map[string]any {
+	name := format.Name
+	if name == "" {
+		name = defaultEnumSchemaName
+	}
+	return map[string]any{
+		"name":   name,
+		"strict": true,
+		"schema": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"value": map[string]any{"type": "string", "enum": format.EnumValues},
+			},
+			"required":             []string{"value"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// pathOrDefault 返回自定义路径，未设置时返回默认路径
+func pathOrDefault(path, def string) string {
+	if path == "" {
+		return def
+	}
+	return path
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // Provider 接口实现
 // ═══════════════════════════════════════════════════════════════════════════
@@ -111,6 +258,30 @@ func (c *Config) Validate() error {
 	if c.APIKey == "" {
 		return llm.NewConfigError("API key is required", nil)
 	}
+	if err := core.ValidateEndpointPath("complete path", c.CompletePath); err != nil {
+		return err
+	}
+	return core.ValidateEndpointPath("stream path", c.StreamPath)
+}
+
+// Normalize 校验配置并填充默认值
+//
+// 可在从文件加载配置后、创建客户端前调用，提前发现配置错误：
+//   - APIKey 为空、端点路径非法或 Timeout 为负时返回 ConfigError
+//   - 填充 BaseURL、Model、Timeout 的默认值（见 GetDefaults）
+//   - 使用 OpenAI 官方地址且模型未注册时仅记录警告（兼容服务的模型名不做检查）
+func (c *Config) Normalize() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := core.ValidateTimeout(c.Timeout); err != nil {
+		return err
+	}
+
+	c.BaseURL, c.Model, c.Timeout = c.GetDefaults()
+	if c.BaseURL == defaultBaseURL {
+		core.WarnUnknownModel(c.ProviderName(), c.Model)
+	}
 	return nil
 }
 
@@ -118,7 +289,7 @@ func (c *Config) Validate() error {
 func (c *Config) GetDefaults() (string, string, time.Duration) {
 	baseURL := c.BaseURL
 	if baseURL == "" {
-		baseURL = "https://api.openai.com/v1"
+		baseURL = defaultBaseURL
 	}
 
 	model := c.Model
@@ -126,12 +297,7 @@ func (c *Config) GetDefaults() (string, string, time.Duration) {
 		model = "gpt-4o"
 	}
 
-	timeout := c.Timeout
-	if timeout == 0 {
-		timeout = 120 * time.Second
-	}
-
-	return baseURL, model, timeout
+	return baseURL, model, core.GetDefaultTimeout(c.Timeout)
 }
 
 // BuildHeaders 构建请求头
@@ -144,6 +310,12 @@ func (c *Config) BuildHeaders() map[string]string {
 	return headers
 }
 
+// BuildAPIKeyHeaders 构建单次调用的认证头（Options.APIKey）
+// 实现 core.APIKeyHeaderBuilder 接口
+func (c *Config) BuildAPIKeyHeaders(apiKey string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + apiKey}
+}
+
 // ProviderName 返回 Provider 名称
 func (c *Config) ProviderName() string {
 	return "openai"
@@ -154,6 +326,11 @@ func (c *Config) GetModel() string {
 	return c.Model
 }
 
+// SkipTLSVerify 实现 core.TLSVerifySkipper 接口
+func (c *Config) SkipTLSVerify() bool {
+	return c.InsecureSkipVerify
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // core.RequestBuilder 接口实现
 // ═══════════════════════════════════════════════════════════════════════════
@@ -167,6 +344,30 @@ func (c *Client) BuildRequest(messages []llm.Message, opts *llm.Options, stream
 // 请求构建
 // ═══════════════════════════════════════════════════════════════════════════
 
+// messageSystemPrompt 从消息中提取系统提示
+//
+// 默认取第一条系统消息。开启 PreserveInlineSystem 时对话中间的系统消息保留在原位置，
+// 只取开头连续的系统消息（以空行连接），避免同一条消息既被提升为系统提示又在原位置发送。
+func (c *Client) messageSystemPrompt(messages []llm.Message) string {
+	if !c.config.PreserveInlineSystem {
+		for _, msg := range messages {
+			if msg.Role == llm.RoleSystem {
+				return msg.Content
+			}
+		}
+		return ""
+	}
+
+	var parts []string
+	for _, msg := range messages {
+		if msg.Role != llm.RoleSystem {
+			break
+		}
+		parts = append(parts, msg.Content)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
 // buildRequest 构建 API 请求体
 func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream bool) map[string]any {
 	// 合并选项
@@ -174,28 +375,30 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 		opts = &llm.Options{}
 	}
 
-	// 确定模型
-	model := c.config.Model
+	// 确定模型（Options.Model 优先）
+	model := opts.Model
+	if model == "" {
+		model = c.config.Model
+	}
 	if model == "" {
 		model = "gpt-4o"
 	}
 
 	// 提取系统提示
-	var systemPrompt string
-	if opts.System != "" {
-		systemPrompt = opts.System
-	} else {
-		for _, msg := range messages {
-			if msg.Role == llm.RoleSystem {
-				systemPrompt = msg.Content
-				break
-			}
-		}
+	systemPrompt := opts.System
+	if systemPrompt == "" {
+		systemPrompt = c.messageSystemPrompt(messages)
 	}
+	systemPrompt = strings.Join(core.SystemParts(systemPrompt, opts), "\n\n")
 
 	// 使用 Transformer 转换消息
 	apiMessages := c.transformer.BuildAPIMessages(messages, systemPrompt)
 
+	// 对话前缀续写 (DeepSeek beta)
+	if c.config.PrefixCompletion {
+		markPrefix(apiMessages)
+	}
+
 	// 构建请求
 	req := map[string]any{
 		"model":    model,
@@ -205,7 +408,7 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 
 	// 应用选项
 	if opts.MaxTokens > 0 {
-		req["max_tokens"] = opts.MaxTokens
+		req[maxTokensField(model)] = opts.MaxTokens
 	}
 	if opts.Temperature >= 0 {
 		req["temperature"] = opts.Temperature
@@ -219,9 +422,18 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 	if opts.PresencePenalty != 0 {
 		req["presence_penalty"] = opts.PresencePenalty
 	}
+	if opts.EndUserID != "" {
+		req["user"] = opts.EndUserID
+	}
 	if len(opts.StopSequences) > 0 {
 		req["stop"] = opts.StopSequences
 	}
+	if opts.Logprobs {
+		req["logprobs"] = true
+		if opts.TopLogprobs > 0 {
+			req["top_logprobs"] = opts.TopLogprobs
+		}
+	}
 
 	// 工具定义
 	if len(opts.Tools) > 0 {
@@ -250,6 +462,9 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 			})
 		}
 		req["tools"] = tools
+		if opts.ToolChoice != "" {
+			req["tool_choice"] = string(opts.ToolChoice)
+		}
 	}
 
 	// Reasoning 力度 (Reasoning 模型)
@@ -257,6 +472,16 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 		req["reasoning_effort"] = opts.Reasoning
 	}
 
+	// 推理内容开关 (网关)
+	if opts.IncludeReasoning != nil {
+		switch c.config.ReasoningToggle {
+		case ReasoningToggleObject:
+			req["reasoning"] = map[string]any{"exclude": !*opts.IncludeReasoning}
+		default:
+			req["include_reasoning"] = *opts.IncludeReasoning
+		}
+	}
+
 	// 结构化输出
 	if opts.ResponseFormat != nil {
 		switch opts.ResponseFormat.Type {
@@ -270,8 +495,22 @@ func (c *Client) buildRequest(messages []llm.Message, opts *llm.Options, stream
 			}
 		case "json_object":
 			req["response_format"] = map[string]any{"type": "json_object"}
+		case "enum":
+			req["response_format"] = map[string]any{
+				"type":        "json_schema",
+				"json_schema": enumJSONSchema(opts.ResponseFormat),
+			}
+		}
+	}
+
+	// 预测输出
+	if opts.PredictedOutput != "" {
+		req["prediction"] = map[string]any{
+			"type":    "content",
+			"content": opts.PredictedOutput,
 		}
 	}
 
+	applyExtraBody(req, opts)
 	return req
 }
@@ -1,8 +1,18 @@
 package openai
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 )
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -115,3 +125,1136 @@ func TestClient_buildRequest(t *testing.T) {
 		t.Error("Expected messages field in request")
 	}
 }
+
+func TestClient_buildRequest_PredictedOutput(t *testing.T) {
+	client, err := New(&Config{
+		APIKey: "test-key",
+		Model:  "gpt-4o",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// 未设置时不发送 prediction
+	req := client.buildRequest(nil, nil, false)
+	if _, ok := req["prediction"]; ok {
+		t.Error("Expected no prediction field when PredictedOutput is empty")
+	}
+
+	req = client.buildRequest(nil, &llm.Options{PredictedOutput: "package main\n"}, false)
+	prediction, ok := req["prediction"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected prediction field, got %v", req["prediction"])
+	}
+	if prediction["type"] != "content" {
+		t.Errorf("Expected prediction type 'content', got %v", prediction["type"])
+	}
+	if prediction["content"] != "package main\n" {
+		t.Errorf("Expected prediction content, got %v", prediction["content"])
+	}
+}
+
+func TestClient_buildRequest_ExtraBody(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key", Model: "Qwen/Qwen2.5-7B-Instruct"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	schema := map[string]any{"type": "object", "properties": map[string]any{"name": map[string]any{"type": "string"}}}
+	req := client.buildRequest(nil, &llm.Options{
+		Temperature: 0.7,
+		ExtraBody: map[string]any{
+			"top_k":       20,
+			"guided_json": schema,
+			"temperature": 0.1,
+		},
+	}, false)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+
+	if decoded["top_k"] != float64(20) {
+		t.Errorf("Expected top_k 20, got %v", decoded["top_k"])
+	}
+	guided, ok := decoded["guided_json"].(map[string]any)
+	if !ok || guided["type"] != "object" {
+		t.Errorf("Expected guided_json schema, got %v", decoded["guided_json"])
+	}
+	if decoded["temperature"] != 0.1 {
+		t.Errorf("Expected ExtraBody to override temperature, got %v", decoded["temperature"])
+	}
+	if decoded["model"] != "Qwen/Qwen2.5-7B-Instruct" {
+		t.Errorf("Expected standard fields to be kept, got model %v", decoded["model"])
+	}
+}
+
+func TestClient_buildRequest_SystemParts(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	req := client.buildRequest(messages, &llm.Options{System: "You are helpful.", SystemParts: []string{"Answer in French."}}, false)
+
+	apiMessages, _ := req["messages"].([]map[string]any)
+	if len(apiMessages) != 2 {
+		t.Fatalf("Expected 2 messages, got %v", req["messages"])
+	}
+	if apiMessages[0]["role"] != "system" || apiMessages[0]["content"] != "You are helpful.\n\nAnswer in French." {
+		t.Errorf("Expected joined system message, got %v", apiMessages[0])
+	}
+}
+
+func TestClient_buildRequest_PreserveInlineSystem(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key", PreserveInlineSystem: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "Be brief."},
+		{Role: llm.RoleUser, Content: "Hi"},
+		{Role: llm.RoleSystem, Content: "Switch to French."},
+		{Role: llm.RoleUser, Content: "Bye"},
+	}
+
+	for name, msgs := range map[string][]llm.Message{
+		"开头有系统消息":  messages,
+		"仅中间有系统消息": messages[1:],
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := client.buildRequest(msgs, nil, false)
+			apiMessages, _ := req["messages"].([]map[string]any)
+
+			var systems []any
+			for _, m := range apiMessages {
+				if m["role"] == "system" {
+					systems = append(systems, m["content"])
+				}
+			}
+
+			inline := 0
+			for _, content := range systems {
+				if content == "Switch to French." {
+					inline++
+				}
+			}
+			if inline != 1 {
+				t.Errorf("Expected inline system message exactly once, got %d in %v", inline, systems)
+			}
+			if name == "开头有系统消息" && (len(systems) != 2 || systems[0] != "Be brief.") {
+				t.Errorf("Expected leading system prompt then inline message, got %v", systems)
+			}
+		})
+	}
+}
+
+func TestClient_buildRequest_ImageDetail(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	messages := []llm.Message{{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+		&llm.TextBlock{Text: "Compare these"},
+		&llm.ImageBlock{URL: "https://example.com/a.png", Detail: llm.ImageDetailLow},
+		&llm.ImageBlock{Data: "aGVsbG8=", MediaType: "image/png"},
+	}}}
+	req := client.buildRequest(messages, nil, false)
+
+	apiMessages, _ := req["messages"].([]map[string]any)
+	if len(apiMessages) != 1 {
+		t.Fatalf("Expected 1 message, got %v", req["messages"])
+	}
+	parts, ok := apiMessages[0]["content"].([]map[string]any)
+	if !ok || len(parts) != 3 {
+		t.Fatalf("Expected 3 content parts, got %v", apiMessages[0]["content"])
+	}
+	if parts[0]["type"] != "text" || parts[0]["text"] != "Compare these" {
+		t.Errorf("Expected text part, got %v", parts[0])
+	}
+
+	want := []map[string]any{
+		{"url": "https://example.com/a.png", "detail": "low"},
+		{"url": "data:image/png;base64,aGVsbG8=", "detail": "auto"}, // 未设置时默认为 auto
+	}
+	for i, w := range want {
+		part := parts[i+1]
+		imageURL, _ := part["image_url"].(map[string]any)
+		if part["type"] != "image_url" || imageURL["url"] != w["url"] || imageURL["detail"] != w["detail"] {
+			t.Errorf("Part %d: expected image_url %v, got %v", i+1, w, part)
+		}
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 自定义端点路径测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestClient_buildRequest_EndUserID(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	req := client.buildRequest(nil, &llm.Options{EndUserID: "user-42"}, false)
+	if req["user"] != "user-42" {
+		t.Errorf("Expected user 'user-42', got %v", req["user"])
+	}
+
+	req = client.buildRequest(nil, nil, false)
+	if _, ok := req["user"]; ok {
+		t.Error("Expected no user field when EndUserID is empty")
+	}
+}
+
+func TestClient_buildRequest_MaxCompletionTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		field string
+	}{
+		{"gpt-4o", "max_tokens"},
+		{"deepseek-reasoner", "max_tokens"},
+		{"o1-mini", "max_completion_tokens"},
+		{"o3", "max_completion_tokens"},
+		{"gpt-5-mini", "max_completion_tokens"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			client, err := New(&Config{APIKey: "test-key", Model: tt.model})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			req := client.buildRequest(nil, &llm.Options{MaxTokens: 1024}, false)
+			if req[tt.field] != 1024 {
+				t.Errorf("Expected %s 1024, got %v", tt.field, req[tt.field])
+			}
+			for _, other := range []string{"max_tokens", "max_completion_tokens"} {
+				if _, ok := req[other]; ok && other != tt.field {
+					t.Errorf("Expected no %s field, got %v", other, req[other])
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Complete_DefaultMaxTokens_ReasoningModel(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "o1-mini"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	// 自动填充的上限同样使用 max_completion_tokens
+	if body["max_completion_tokens"] != float64(65536) {
+		t.Errorf("Expected max_completion_tokens 65536, got %v", body["max_completion_tokens"])
+	}
+	if _, ok := body["max_tokens"]; ok {
+		t.Errorf("Expected no max_tokens field, got %v", body["max_tokens"])
+	}
+}
+
+func TestClient_buildRequest_EnumResponseFormat(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	req := client.buildRequest(nil, &llm.Options{
+		ResponseFormat: &llm.ResponseFormat{Type: "enum", EnumValues: []string{"spam", "ham"}},
+	}, false)
+
+	data, _ := json.Marshal(req["response_format"])
+	want := `{"type":"json_schema","json_schema":{"name":"classification","strict":true,"schema":{
+		"type":"object",
+		"properties":{"value":{"type":"string","enum":["spam","ham"]}},
+		"required":["value"],
+		"additionalProperties":false
+	}}}`
+	var got, expected any
+	_ = json.Unmarshal(data, &got)
+	_ = json.Unmarshal([]byte(want), &expected)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected response_format: %s", data)
+	}
+}
+
+func TestClient_buildRequest_IncludeReasoning(t *testing.T) {
+	include := true
+
+	tests := []struct {
+		name    string
+		toggle  ReasoningToggle
+		include *bool
+		field   string
+		want    string
+	}{
+		{"未设置", "", nil, "", ""},
+		{"默认字段", "", &include, "include_reasoning", `true`},
+		{"OpenRouter reasoning 对象", ReasoningToggleObject, &include, "reasoning", `{"exclude":false}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(&Config{APIKey: "test-key", ReasoningToggle: tt.toggle})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			req := client.buildRequest(nil, &llm.Options{IncludeReasoning: tt.include}, false)
+
+			if tt.field == "" {
+				if _, ok := req["include_reasoning"]; ok {
+					t.Error("include_reasoning should be omitted")
+				}
+				if _, ok := req["reasoning"]; ok {
+					t.Error("reasoning should be omitted")
+				}
+				return
+			}
+			data, _ := json.Marshal(req[tt.field])
+			if string(data) != tt.want {
+				t.Errorf("Expected %s=%s, got %s", tt.field, tt.want, data)
+			}
+		})
+	}
+}
+
+func TestClient_BuildRequest_ToolChoice(t *testing.T) {
+	client, err := New(&Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	tools := []llm.ToolSchema{{Name: "get_weather", Description: "Get weather"}}
+	req := client.buildRequest(nil, &llm.Options{Tools: tools, ToolChoice: llm.ToolChoiceNone}, false)
+	if got, _ := req["tools"].([]map[string]any); len(got) != 1 {
+		t.Errorf("Expected tool definitions to be kept, got %v", req["tools"])
+	}
+	if req["tool_choice"] != "none" {
+		t.Errorf("Expected tool_choice none, got %v", req["tool_choice"])
+	}
+
+	req = client.buildRequest(nil, &llm.Options{Tools: tools, ToolChoice: llm.ToolChoiceRequired}, false)
+	if req["tool_choice"] != "required" {
+		t.Errorf("Expected tool_choice required, got %v", req["tool_choice"])
+	}
+
+	// 未设置或没有工具时不发送
+	if _, ok := client.buildRequest(nil, &llm.Options{Tools: tools}, false)["tool_choice"]; ok {
+		t.Error("tool_choice should not be sent when unset")
+	}
+	if _, ok := client.buildRequest(nil, &llm.Options{ToolChoice: llm.ToolChoiceNone}, false)["tool_choice"]; ok {
+		t.Error("tool_choice should not be sent without tools")
+	}
+}
+
+func TestClient_ToolFallbackJSON(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant",` +
+			`"content":"{\"tool\": \"get_weather\", \"arguments\": {\"city\": \"Paris\"}}"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "llama3"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}}, &llm.Options{
+		Tools:            []llm.ToolSchema{{Name: "get_weather", Description: "Get weather", InputSchema: map[string]any{"type": "object"}}},
+		ToolFallbackMode: llm.ToolFallbackModeJSON,
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	if _, ok := body["tools"]; ok {
+		t.Error("tools should not be sent in fallback mode")
+	}
+	if format, _ := body["response_format"].(map[string]any); format["type"] != "json_object" {
+		t.Errorf("Expected json_object response_format, got %v", body["response_format"])
+	}
+
+	calls := resp.Message.GetToolCalls()
+	if len(calls) != 1 || calls[0].Name != "get_weather" || calls[0].Input["city"] != "Paris" {
+		t.Errorf("Unexpected tool calls: %+v", calls)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %s", resp.FinishReason)
+	}
+}
+
+func TestClient_ToolFallbackAuto(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant",` +
+			`"content":"{\"tool\": \"get_weather\", \"arguments\": {\"city\": \"Paris\"}}"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	// o1-mini 在注册表中不支持工具调用；还原的工具调用 ID 使用注入的生成器
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "o1-mini"},
+		core.WithIDGenerator(core.NewSequentialIDGenerator()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}}, &llm.Options{
+		Tools:            []llm.ToolSchema{{Name: "get_weather", Description: "Get weather", InputSchema: map[string]any{"type": "object"}}},
+		ToolFallbackMode: llm.ToolFallbackModeAuto,
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	if _, ok := body["tools"]; ok {
+		t.Error("tools should not be sent in fallback mode")
+	}
+	calls := resp.Message.GetToolCalls()
+	if len(calls) != 1 || calls[0].Name != "get_weather" || calls[0].Input["city"] != "Paris" {
+		t.Fatalf("Unexpected tool calls: %+v", calls)
+	}
+	if calls[0].ID != "call_1" {
+		t.Errorf("Expected tool call ID from injected generator, got %s", calls[0].ID)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %s", resp.FinishReason)
+	}
+
+	// OpenAI 风格的 RoleTool 历史改写为文本，不发送孤立的 tool 消息
+	history := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather?"},
+		resp.Message,
+		{Role: llm.RoleTool, ToolCallID: calls[0].ID, Content: "Sunny"},
+	}
+	if _, err := client.Complete(context.Background(), history, &llm.Options{
+		Tools:            []llm.ToolSchema{{Name: "get_weather", Description: "Get weather", InputSchema: map[string]any{"type": "object"}}},
+		ToolFallbackMode: llm.ToolFallbackModeAuto,
+	}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	messages, _ := body["messages"].([]any)
+	for _, m := range messages {
+		msg, _ := m.(map[string]any)
+		if msg["role"] == "tool" || msg["tool_calls"] != nil || msg["tool_call_id"] != nil {
+			t.Errorf("Unexpected tool message in fallback mode: %v", msg)
+		}
+	}
+}
+
+func TestClient_Logprobs(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"choices":[{"delta":{"content":"Yes"},"logprobs":{"content":[` +
+				`{"token":"Yes","logprob":-0.01,"bytes":[89,101,115],"top_logprobs":[{"token":"Yes","logprob":-0.01},{"token":"No","logprob":-4.6}]}]}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"content":"."},"logprobs":{"content":[{"token":".","logprob":-0.5,"top_logprobs":[]}]}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}` + "\n\n" +
+				"data: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Yes"},"finish_reason":"stop",` +
+			`"logprobs":{"content":[{"token":"Yes","logprob":-0.01,"top_logprobs":[]}]}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Is it sunny?"}}
+	opts := &llm.Options{Logprobs: true, TopLogprobs: 2}
+
+	resp, err := client.Complete(context.Background(), messages, opts)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if body["logprobs"] != true || body["top_logprobs"] != float64(2) {
+		t.Errorf("Expected logprobs and top_logprobs in request, got %v / %v", body["logprobs"], body["top_logprobs"])
+	}
+	if len(resp.Logprobs) != 1 || resp.Logprobs[0].Token != "Yes" {
+		t.Errorf("Expected Response.Logprobs for Complete, got %+v", resp.Logprobs)
+	}
+	if resp.Message.Meta != nil {
+		t.Errorf("Expected Message.Meta to stay nil, got %v", resp.Message.Meta)
+	}
+
+	events, err := client.Stream(context.Background(), messages, opts)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	// 逐事件检查并转发给 CollectStream 聚合
+	forwarded := make(chan *llm.Event, 10)
+	var perEvent [][]llm.TokenLogprob
+	go func() {
+		defer close(forwarded)
+		for event := range events {
+			if event.Type == llm.EventTypeText {
+				perEvent = append(perEvent, event.Logprobs)
+			}
+			forwarded <- event
+		}
+	}()
+	result, err := core.CollectStream(forwarded)
+	if err != nil {
+		t.Fatalf("CollectStream failed: %v", err)
+	}
+
+	if len(perEvent) != 2 || len(perEvent[0]) != 1 || len(perEvent[1]) != 1 {
+		t.Fatalf("Expected one logprob per text event, got %+v", perEvent)
+	}
+	first := perEvent[0][0]
+	if first.Token != "Yes" || first.Logprob != -0.01 || !reflect.DeepEqual(first.Bytes, []int{89, 101, 115}) {
+		t.Errorf("Unexpected first logprob: %+v", first)
+	}
+	if len(first.TopLogprobs) != 2 || first.TopLogprobs[1].Token != "No" || first.TopLogprobs[1].Logprob != -4.6 {
+		t.Errorf("Unexpected top logprobs: %+v", first.TopLogprobs)
+	}
+
+	aggregated := result.Response.Logprobs
+	if len(aggregated) != 2 || aggregated[0].Token != "Yes" || aggregated[1].Token != "." {
+		t.Errorf("Expected aggregated logprobs [Yes .], got %+v", aggregated)
+	}
+	if result.Response.Message.Content != "Yes." {
+		t.Errorf("Expected content 'Yes.', got %q", result.Response.Message.Content)
+	}
+}
+
+func TestClient_CustomPaths(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/chat":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+		case "/v1/chat/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+				"data: [DONE]\n\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{
+		APIKey:       "test-key",
+		BaseURL:      server.URL,
+		CompletePath: "/v1/chat",
+		StreamPath:   "/v1/chat/stream",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+
+	resp, err := client.Complete(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.Message.Content != "ok" {
+		t.Errorf("Expected content 'ok', got %q", resp.Message.Content)
+	}
+
+	events, err := client.Stream(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	var text strings.Builder
+	for event := range events {
+		text.WriteString(event.TextDelta)
+	}
+	if text.String() != "ok" {
+		t.Errorf("Expected streamed text 'ok', got %q", text.String())
+	}
+
+	if len(paths) != 2 || paths[0] != "/v1/chat" || paths[1] != "/v1/chat/stream" {
+		t.Errorf("Unexpected request paths: %v", paths)
+	}
+}
+
+func TestConfig_Normalize(t *testing.T) {
+	config := &Config{APIKey: "test-key"}
+	if err := config.Normalize(); err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+	if config.BaseURL != "https://api.openai.com/v1" || config.Model != "gpt-4o" || config.Timeout != 120*time.Second {
+		t.Errorf("Unexpected defaults: %+v", config)
+	}
+
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{"empty API key", &Config{}, "API key is required"},
+		{"negative timeout", &Config{APIKey: "test-key", Timeout: -time.Second}, "invalid timeout"},
+		{"invalid stream path", &Config{APIKey: "test-key", StreamPath: "chat"}, "must start with '/'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Normalize()
+			if !llm.IsConfigError(err) {
+				t.Fatalf("Expected ConfigError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// New 不修改传入的配置
+	original := &Config{APIKey: "test-key"}
+	if _, err := New(original); err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if original.Model != "" {
+		t.Errorf("New modified caller config: %+v", original)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	client, err := NewWithOptions(
+		WithAPIKey("test-key"),
+		WithBaseURL("https://custom.api.com/v1"),
+		WithModel("gpt-4o-mini"),
+		WithTimeout(60*time.Second),
+		WithHeader("X-Team", "core"),
+		func(c *Config) { c.CompletePath = "/v1/chat" },
+	)
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+
+	expected, err := New(&Config{
+		APIKey:       "test-key",
+		BaseURL:      "https://custom.api.com/v1",
+		Model:        "gpt-4o-mini",
+		Timeout:      60 * time.Second,
+		Headers:      map[string]string{"X-Team": "core"},
+		CompletePath: "/v1/chat",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if !reflect.DeepEqual(client.config, expected.config) {
+		t.Errorf("Expected config %+v, got %+v", expected.config, client.config)
+	}
+
+	// 与 New 相同的校验
+	if _, err := NewWithOptions(WithModel("gpt-4o-mini")); err == nil {
+		t.Error("Expected error for missing API key")
+	}
+}
+
+func TestNew_InvalidPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{"complete path without slash", &Config{APIKey: "test-key", CompletePath: "chat"}},
+		{"stream path without slash", &Config{APIKey: "test-key", StreamPath: "chat/stream"}},
+		{"blank stream path", &Config{APIKey: "test-key", StreamPath: "  "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(tt.config)
+			if err == nil {
+				t.Fatal("Expected error for invalid path")
+			}
+			if client != nil {
+				t.Error("Expected nil client")
+			}
+			if !llm.IsConfigError(err) {
+				t.Errorf("Expected ConfigError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestNew_ProbeOnInit(t *testing.T) {
+	var probes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		if r.Method != http.MethodGet || r.URL.Path != "/models/my-model" {
+			t.Errorf("Unexpected probe request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"message": "Incorrect API key provided"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "my-model", "context_length": 32768, "top_provider": {"max_completion_tokens": 4096}}`))
+	}))
+	defer server.Close()
+
+	t.Run("bad key fails at construction", func(t *testing.T) {
+		client, err := New(&Config{APIKey: "bad-key", BaseURL: server.URL, Model: "my-model", ProbeOnInit: true})
+		if err == nil {
+			t.Fatal("Expected error for bad key")
+		}
+		if client != nil {
+			t.Error("Expected nil client")
+		}
+		if !llm.IsConfigError(err) {
+			t.Errorf("Expected ConfigError, got %T", err)
+		}
+		if llm.GetStatusCode(err) != http.StatusUnauthorized {
+			t.Errorf("Expected wrapped 401, got %d", llm.GetStatusCode(err))
+		}
+	})
+
+	t.Run("caches model info", func(t *testing.T) {
+		client, err := New(&Config{APIKey: "good-key", BaseURL: server.URL, Model: "my-model", ProbeOnInit: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		info, ok := client.ModelInfo()
+		if !ok || info.ContextWindow != 32768 || info.MaxOutputTokens != 4096 {
+			t.Errorf("Unexpected model info: %+v (ok=%v)", info, ok)
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		probes = 0
+		if _, err := New(&Config{APIKey: "bad-key", BaseURL: server.URL, Model: "my-model"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if probes != 0 {
+			t.Errorf("Expected no network request, got %d", probes)
+		}
+	})
+}
+
+func TestClient_Complete_MaxToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": null, "tool_calls": [
+			{"id": "call_1", "type": "function", "function": {"name": "search", "arguments": "{\"q\":\"a\"}"}},
+			{"id": "call_2", "type": "function", "function": {"name": "search", "arguments": "{\"q\":\"b\"}"}},
+			{"id": "call_3", "type": "function", "function": {"name": "search", "arguments": "{\"q\":\"c\"}"}}
+		]}, "finish_reason": "tool_calls"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Search"}}
+
+	resp, err := client.Complete(context.Background(), messages, &llm.Options{MaxToolCalls: 2})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	calls := resp.Message.GetToolCalls()
+	if len(calls) != 2 || calls[0].ID != "call_1" || calls[1].ID != "call_2" {
+		t.Errorf("Expected first 2 tool calls, got %+v", calls)
+	}
+	if !resp.ToolCallsTruncated {
+		t.Error("Expected ToolCallsTruncated")
+	}
+
+	resp, err = client.Complete(context.Background(), messages, &llm.Options{MaxToolCalls: 2, StrictMaxToolCalls: true})
+	if !errors.Is(err, llm.ErrTooManyToolCalls) || !llm.IsResponseError(err) {
+		t.Errorf("Expected ResponseError wrapping ErrTooManyToolCalls, got %v", err)
+	}
+	if resp == nil || len(resp.Message.GetToolCalls()) != 3 {
+		t.Error("Expected untouched response alongside the error")
+	}
+}
+
+func TestClient_CompleteRaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/chat" {
+			t.Errorf("Expected custom complete path, got %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Expected auth header, got %q", got)
+		}
+
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["experimental_feature"] != true || body["model"] != "raw-model" {
+			t.Errorf("Expected body sent as-is, got %v", body)
+		}
+		if _, ok := body["messages"]; ok {
+			t.Error("Expected request builder to be skipped")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "resp_1", "experimental_output": [1, 2]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, CompletePath: "/v2/chat"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	resp, err := client.CompleteRaw(context.Background(), map[string]any{"model": "raw-model", "experimental_feature": true})
+	if err != nil {
+		t.Fatalf("CompleteRaw() error = %v", err)
+	}
+	if resp["id"] != "resp_1" || !reflect.DeepEqual(resp["experimental_output"], []any{float64(1), float64(2)}) {
+		t.Errorf("Unexpected response: %v", resp)
+	}
+}
+
+func TestClient_CompleteRaw_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"message": "unknown field"}}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = client.CompleteRaw(context.Background(), map[string]any{"bogus": 1})
+	if llm.GetStatusCode(err) != http.StatusBadRequest {
+		t.Errorf("Expected APIError 400, got %v", err)
+	}
+}
+
+func TestClient_ModelOverride(t *testing.T) {
+	var models []any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		models = append(models, body["model"])
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gpt-4o-mini"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	resp, err := client.Complete(context.Background(), messages, &llm.Options{Model: "gpt-4.1"})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.Model != "gpt-4.1" {
+		t.Errorf("Expected response model 'gpt-4.1', got %q", resp.Model)
+	}
+
+	if _, err := client.Complete(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	if len(models) != 2 || models[0] != "gpt-4.1" || models[1] != "gpt-4o-mini" {
+		t.Errorf("Expected models [gpt-4.1 gpt-4o-mini], got %v", models)
+	}
+}
+
+func TestClient_APIKeyOverride(t *testing.T) {
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	if _, err := client.Complete(context.Background(), messages, &llm.Options{APIKey: "tenant-key"}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	events, err := client.Stream(context.Background(), messages, &llm.Options{APIKey: "tenant-key"})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	for range events {
+	}
+	if _, err := client.Complete(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	want := []string{"Bearer tenant-key", "Bearer tenant-key", "Bearer test-key"}
+	if len(auths) != len(want) {
+		t.Fatalf("Expected %d requests, got %d", len(want), len(auths))
+	}
+	for i := range want {
+		if auths[i] != want[i] {
+			t.Errorf("Request %d: expected Authorization %q, got %q", i, want[i], auths[i])
+		}
+	}
+}
+
+func TestClient_DefaultTimeout(t *testing.T) {
+	llm.SetDefaultTimeout(50 * time.Millisecond)
+	t.Cleanup(func() { llm.SetDefaultTimeout(0) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+
+	// 继承全局默认超时
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.Complete(context.Background(), messages, nil); err == nil {
+		t.Error("Expected timeout error with global default timeout")
+	}
+
+	// 显式配置优先
+	client, err = New(&Config{APIKey: "test-key", BaseURL: server.URL, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.Complete(context.Background(), messages, nil); err != nil {
+		t.Errorf("Expected success with explicit timeout, got %v", err)
+	}
+}
+
+func TestClient_Clone(t *testing.T) {
+	var models []any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		models = append(models, body["model"])
+		if r.Header.Get("X-Team") != "core" {
+			t.Errorf("Expected shared header X-Team, got %q", r.Header.Get("X-Team"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	parent, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gpt-4o-mini", Headers: map[string]string{"X-Team": "core"}})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	clone := parent.Clone(func(c *Config) {
+		c.Model = "gpt-4.1"
+		c.Headers["X-Extra"] = "clone-only"
+	})
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+	if _, err := parent.Complete(context.Background(), messages, nil); err != nil {
+		t.Fatalf("parent Complete failed: %v", err)
+	}
+	if _, err := clone.Complete(context.Background(), messages, nil); err != nil {
+		t.Fatalf("clone Complete failed: %v", err)
+	}
+
+	if len(models) != 2 || models[0] != "gpt-4o-mini" || models[1] != "gpt-4.1" {
+		t.Errorf("Expected models [gpt-4o-mini gpt-4.1], got %v", models)
+	}
+	// 配置互不影响
+	if parent.config.Model != "gpt-4o-mini" {
+		t.Errorf("Parent model changed to %q", parent.config.Model)
+	}
+	if _, ok := parent.config.Headers["X-Extra"]; ok {
+		t.Error("Clone override leaked into parent headers")
+	}
+}
+
+func TestClient_Complete_Reasoning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"model": "deepseek-reasoner",
+			"choices": [{
+				"message": {"role": "assistant", "content": "Paris", "reasoning_content": "The capital of France is Paris."},
+				"finish_reason": "stop"
+			}]
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "deepseek-reasoner"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Capital of France?"}}, nil)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.Message.Content != "Paris" {
+		t.Errorf("Expected content 'Paris', got %q", resp.Message.Content)
+	}
+	if resp.Reasoning != "The capital of France is Paris." {
+		t.Errorf("Expected reasoning, got %q", resp.Reasoning)
+	}
+}
+
+func TestClient_Complete_SystemFingerprint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"model": "gpt-4o-2024-08-06",
+			"system_fingerprint": "fp_3aa7262c27",
+			"choices": [{"message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}]
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}, nil)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.SystemFingerprint != "fp_3aa7262c27" {
+		t.Errorf("Expected system fingerprint 'fp_3aa7262c27', got %q", resp.SystemFingerprint)
+	}
+	if resp.ResolvedModel != "gpt-4o-2024-08-06" {
+		t.Errorf("Expected resolved model 'gpt-4o-2024-08-06', got %q", resp.ResolvedModel)
+	}
+}
+
+func TestClient_StatusCodeAndLatency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		time.Sleep(10 * time.Millisecond)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+				"data: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hello"}}
+
+	resp, err := client.Complete(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", resp.StatusCode)
+	}
+	if resp.Latency < 10*time.Millisecond {
+		t.Errorf("Expected latency >= 10ms, got %v", resp.Latency)
+	}
+
+	events, err := client.Stream(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	var meta *llm.StreamMeta
+	for event := range events {
+		if event.Type == llm.EventTypeDone {
+			meta = event.StreamMeta
+		}
+	}
+	if meta == nil {
+		t.Fatal("Expected StreamMeta on done event")
+	}
+	if meta.StatusCode != http.StatusOK {
+		t.Errorf("Expected stream status code 200, got %d", meta.StatusCode)
+	}
+	if meta.TimeToFirstByte < 10*time.Millisecond {
+		t.Errorf("Expected time to first byte >= 10ms, got %v", meta.TimeToFirstByte)
+	}
+}
+
+func TestClient_ZeroArgToolCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"get_time","arguments":""}}]}}]}` + "\n\n" +
+				`data: {"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n" +
+				"data: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_time", "arguments": ""}}]}, "finish_reason": "tool_calls"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "What time is it?"}}
+
+	resp, err := client.Complete(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	calls := resp.Message.GetToolCalls()
+	if len(calls) != 1 || calls[0].Input == nil || len(calls[0].Input) != 0 {
+		t.Errorf("Expected one tool call with empty non-nil input, got %+v", calls)
+	}
+
+	stream, err := client.Stream(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	result, err := core.CollectStream(stream)
+	if err != nil {
+		t.Fatalf("CollectStream failed: %v", err)
+	}
+	calls = result.Response.Message.GetToolCalls()
+	if len(calls) != 1 || calls[0].Input == nil || len(calls[0].Input) != 0 {
+		t.Errorf("Expected one streamed tool call with empty non-nil input, got %+v", calls)
+	}
+}
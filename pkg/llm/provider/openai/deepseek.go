@@ -0,0 +1,141 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/openai"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// DeepSeek 扩展
+// ═══════════════════════════════════════════════════════════════════════════
+
+// DeepSeekBetaBaseURL DeepSeek beta 接口地址（对话前缀续写与 FIM 补全）
+const DeepSeekBetaBaseURL = "https://api.deepseek.com/beta"
+
+// fimEndpoint FIM 补全端点（OpenAI 旧版 Completions 格式）
+const fimEndpoint = "/completions"
+
+// markPrefix 为最后一条 assistant 消息标记 prefix
+func markPrefix(apiMessages []map[string]any) {
+	if len(apiMessages) == 0 {
+		return
+	}
+	last := apiMessages[len(apiMessages)-1]
+	if last["role"] == string(llm.RoleAssistant) {
+		last["prefix"] = true
+	}
+}
+
+// CompleteFIM FIM（Fill-In-the-Middle）补全
+//
+// 生成 prompt 与 suffix 之间的内容，常用于代码补全。请求发送到 BaseURL + /completions，
+// DeepSeek 需使用 [DeepSeekBetaBaseURL]。opts 中 Model、MaxTokens、Temperature、TopP、
+// StopSequences、Headers 与 ReturnPromptTokens 生效，其余字段忽略。
+//
+// ReturnPromptTokens 为 true 时发送 echo 与 logprobs，从返回的 logprobs.tokens 中
+// 提取提示词分词（Response.PromptTokensDetail，非估算），补全文本中回显的 prompt 被去除。
+//
+// 返回的 Message.Content 为补全文本，FinishReason 与 Usage 按 Chat Completions 同样解析。
+func (c *Client) CompleteFIM(ctx context.Context, prompt, suffix string, opts *llm.Options) (*llm.Response, error) {
+	if opts == nil {
+		opts = &llm.Options{}
+	}
+
+	model := opts.Model
+	if model == "" {
+		model = c.config.Model
+	}
+	body := map[string]any{
+		"model":  model,
+		"prompt": prompt,
+	}
+	if suffix != "" {
+		body["suffix"] = suffix
+	}
+	if opts.MaxTokens > 0 {
+		body["max_tokens"] = opts.MaxTokens
+	}
+	if opts.Temperature > 0 {
+		body["temperature"] = opts.Temperature
+	}
+	if opts.TopP > 0 {
+		body["top_p"] = opts.TopP
+	}
+	if len(opts.StopSequences) > 0 {
+		body["stop"] = opts.StopSequences
+	}
+	if opts.ReturnPromptTokens {
+		body["echo"] = true
+		body["logprobs"] = 0
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, llm.NewRequestError("marshal request", err)
+	}
+
+	var apiResp map[string]any
+	httpReq := c.NewRequest(ctx).
+		SetBody(bodyBytes).
+		SetResult(&apiResp)
+	resp, err := core.ApplyHeaders(httpReq, opts.Headers).Post(fimEndpoint)
+	if err != nil {
+		return nil, llm.NewHTTPError("request failed", err)
+	}
+	if err := c.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	choices, _ := apiResp["choices"].([]any)
+	if len(choices) == 0 {
+		return nil, llm.NewResponseError("choices", nil)
+	}
+	choice, _ := choices[0].(map[string]any)
+
+	text := core.GetString(choice["text"])
+	usage := openai.NewAdapter().ConvertUsage(apiResp)
+	response := &llm.Response{
+		FinishReason: core.GetString(choice["finish_reason"]),
+		Usage:        usage,
+	}
+	if opts.ReturnPromptTokens {
+		// echo 模式下 text 以 prompt 开头
+		text = strings.TrimPrefix(text, prompt)
+		response.PromptTokensDetail = echoPromptTokens(choice, usage, prompt)
+	}
+	response.Message = llm.Message{Role: llm.RoleAssistant, Content: text}
+
+	return response, nil
+}
+
+// echoPromptTokens 从 echo 模式的 logprobs.tokens 中提取提示词分词
+//
+// 前 usage.InputTokens 个 token 属于 prompt；未返回 logprobs 时回退为本地估算。
+func echoPromptTokens(choice map[string]any, usage *llm.TokenUsage, prompt string) *llm.PromptTokensDetail {
+	logprobs, _ := choice["logprobs"].(map[string]any)
+	raw, _ := logprobs["tokens"].([]any)
+	if usage == nil || usage.InputTokens <= 0 || int64(len(raw)) < usage.InputTokens {
+		n := core.EstimateTokens(prompt)
+		return &llm.PromptTokensDetail{
+			Total:     n,
+			Segments:  []llm.PromptTokenSegment{{Kind: "prompt", Tokens: n, End: n}},
+			Estimated: true,
+		}
+	}
+
+	n := usage.InputTokens
+	tokens := make([]string, 0, n)
+	for _, t := range raw[:n] {
+		tokens = append(tokens, core.GetString(t))
+	}
+	return &llm.PromptTokensDetail{
+		Total:    n,
+		Segments: []llm.PromptTokenSegment{{Kind: "prompt", Tokens: n, End: n}},
+		Tokens:   tokens,
+	}
+}
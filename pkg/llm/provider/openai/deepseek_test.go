@@ -0,0 +1,155 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+)
+
+func TestClient_Complete_DeepSeekReasoning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"model": "deepseek-reasoner",
+			"choices": [{
+				"message": {"role": "assistant", "reasoning_content": "9.11 < 9.9 since 0.11 < 0.9.", "content": "9.9 is greater."},
+				"finish_reason": "stop"
+			}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 40, "total_tokens": 52, "completion_tokens_details": {"reasoning_tokens": 30}}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "deepseek-reasoner"})
+	require.NoError(t, err)
+
+	resp, err := client.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "9.11 or 9.9?"}}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "9.9 is greater.", resp.Message.Content)
+	assert.Equal(t, "9.11 < 9.9 since 0.11 < 0.9.", resp.Reasoning)
+	require.NotEmpty(t, resp.Message.ContentBlocks)
+	thinking, ok := resp.Message.ContentBlocks[0].(*llm.ThinkingBlock)
+	require.True(t, ok)
+	assert.Equal(t, resp.Reasoning, thinking.Thinking)
+	assert.Equal(t, int64(30), resp.Usage.ReasoningTokens)
+}
+
+func TestClient_PrefixCompletion(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "\n    return a + b"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Write an add function"},
+		{Role: llm.RoleAssistant, Content: "```python\ndef add(a, b):"},
+	}
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "deepseek-chat", PrefixCompletion: true})
+	require.NoError(t, err)
+	_, err = client.Complete(context.Background(), messages, &llm.Options{StopSequences: []string{"```"}})
+	require.NoError(t, err)
+
+	apiMessages := body["messages"].([]any)
+	require.Len(t, apiMessages, 2)
+	assert.Equal(t, true, apiMessages[1].(map[string]any)["prefix"])
+	assert.NotContains(t, apiMessages[0], "prefix")
+
+	// 最后一条不是 assistant 消息时不标记
+	_, err = client.Complete(context.Background(), messages[:1], nil)
+	require.NoError(t, err)
+	assert.NotContains(t, body["messages"].([]any)[0], "prefix")
+
+	// 未开启时不标记
+	client, err = New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "deepseek-chat"})
+	require.NoError(t, err)
+	_, err = client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, body["messages"].([]any)[1], "prefix")
+}
+
+func TestClient_CompleteFIM(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"choices": [{"index": 0, "text": "    return a + b\n", "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 6, "total_tokens": 16}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "deepseek-chat"})
+	require.NoError(t, err)
+
+	resp, err := client.CompleteFIM(context.Background(), "def add(a, b):\n", "\nprint(add(1, 2))", &llm.Options{MaxTokens: 64})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"model":      "deepseek-chat",
+		"prompt":     "def add(a, b):\n",
+		"suffix":     "\nprint(add(1, 2))",
+		"max_tokens": float64(64),
+	}, body)
+	assert.Equal(t, "    return a + b\n", resp.Message.Content)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, int64(16), resp.Usage.TotalTokens)
+
+	t.Run("HTTP 错误", func(t *testing.T) {
+		errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer errServer.Close()
+
+		client, err := New(&Config{APIKey: "test-key", BaseURL: errServer.URL})
+		require.NoError(t, err)
+		_, err = client.CompleteFIM(context.Background(), "x", "", nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, llm.GetStatusCode(err))
+	})
+}
+
+func TestClient_CompleteFIM_ReturnPromptTokens(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"choices": [{
+				"text": "def add(a, b): return a + b",
+				"logprobs": {"tokens": ["def", " add", "(a", ", b", "):", " return", " a", " +", " b"]},
+				"finish_reason": "stop"
+			}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 4, "total_tokens": 9}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "deepseek-chat"})
+	require.NoError(t, err)
+
+	resp, err := client.CompleteFIM(context.Background(), "def add(a, b):", "", &llm.Options{ReturnPromptTokens: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, true, body["echo"])
+	assert.Equal(t, float64(0), body["logprobs"])
+	assert.Equal(t, " return a + b", resp.Message.Content)
+	require.NotNil(t, resp.PromptTokensDetail)
+	assert.False(t, resp.PromptTokensDetail.Estimated)
+	assert.Equal(t, int64(5), resp.PromptTokensDetail.Total)
+	assert.Equal(t, []string{"def", " add", "(a", ", b", "):"}, resp.PromptTokensDetail.Tokens)
+}
@@ -30,6 +30,15 @@
 //	// 流式完成
 //	stream, err := client.Stream(ctx, messages, nil)
 //
+// 只需设置少量字段时，可使用函数式选项代替 Config 字面量：
+//
+//	client, err := openai.NewWithOptions(
+//	    openai.WithAPIKey("sk-xxx"),
+//	    openai.WithModel("gpt-4o-mini"),
+//	)
+//
+// 从文件加载的配置可先调用 [Config.Normalize] 校验并填充默认值，New 内部同样会调用。
+//
 // # 支持的服务
 //
 // 本包支持所有遵循 OpenAI Chat Completions API 格式的服务：
@@ -57,10 +66,106 @@
 //	result := openai.ParseStream(stream)
 //	fmt.Println(result.Message.GetContent())
 //
+// # 自定义端点
+//
+// 部分网关的流式与非流式路径不同，可通过 CompletePath / StreamPath 指定：
+//
+//	client, _ := openai.New(&openai.Config{
+//	    APIKey:       "sk-...",
+//	    BaseURL:      "https://gateway.example.com",
+//	    CompletePath: "/v1/chat/completions",
+//	    StreamPath:   "/v1/chat/stream",
+//	})
+//
+// # 网关推理内容
+//
+// OpenRouter 等网关默认不返回推理内容，设置 Options.IncludeReasoning 显式开启。
+// 请求字段由 Config.ReasoningToggle 决定（provider.New 对 OpenRouter 自动使用 reasoning 对象）：
+//
+//	include := true
+//	resp, _ := client.Complete(ctx, messages, &llm.Options{IncludeReasoning: &include})
+//
+//	// ReasoningToggleFlag:   {"include_reasoning": true}
+//	// ReasoningToggleObject: {"reasoning": {"exclude": false}}
+//
+// 网关在 delta.reasoning / message.reasoning 中返回的推理内容按 reasoning_content 同等处理。
+//
+// # 输出上限
+//
+// Options.MaxTokens（含按模型注册表自动填充的值）一般以 max_tokens 发送；OpenAI Reasoning
+// 模型（o1、o3、o4、gpt-5 系列）拒绝 max_tokens，改为发送 max_completion_tokens。
+//
+// # Token 对数概率
+//
+// 设置 Options.Logprobs（可选 TopLogprobs）时发送 logprobs / top_logprobs：Complete 的结果
+// 位于 Response.Logprobs；流式时每个文本事件携带本次增量的 Event.Logprobs，可用于实时
+// 置信度判断（如概率过低时取消），core.CollectStream 将其依次聚合到 Response.Logprobs。
+//
+// # 服务端特定参数
+//
+// vLLM 等 OpenAI 兼容服务支持标准 schema 之外的采样与约束解码参数，通过 Options.ExtraBody
+// 合并到请求体顶层（相当于 OpenAI SDK 的 extra_body）：
+//
+//	resp, _ := client.Complete(ctx, messages, &llm.Options{ExtraBody: map[string]any{
+//	    "top_k":              20,
+//	    "repetition_penalty": 1.1,
+//	    "guided_json":        schema,
+//	}})
+//
+// 这些字段为服务端特定参数，OpenAI 官方 API 会拒绝未知字段；同名时覆盖标准字段。
+//
+// # DeepSeek
+//
+// DeepSeek 使用本包接入（provider.New 的 deepseek 类型），需注意：
+//   - 推理内容：deepseek-reasoner 在 reasoning_content 中返回思考过程，流式与非流式均解析为
+//     ThinkingBlock / Response.Reasoning；回放历史时不发送推理内容（API 拒绝输入中的 reasoning_content）
+//   - 系统消息：仅支持位于开头的单条系统消息，本包只发送第一条系统消息（或 Options.System）
+//   - deepseek-reasoner 不支持工具调用与 temperature 等采样参数（见模型注册表与 Reasoning 模型限制）
+//
+// beta 功能需将 BaseURL 设为 [DeepSeekBetaBaseURL]：
+//
+//	client, _ := openai.New(&openai.Config{
+//	    APIKey:           "sk-...",
+//	    BaseURL:          openai.DeepSeekBetaBaseURL,
+//	    Model:            "deepseek-chat",
+//	    PrefixCompletion: true, // 最后一条 assistant 消息作为续写前缀
+//	})
+//	resp, _ := client.Complete(ctx, []llm.Message{
+//	    {Role: llm.RoleUser, Content: "Write quicksort"},
+//	    {Role: llm.RoleAssistant, Content: "```python\n"},
+//	}, nil)
+//
+//	// FIM 补全：生成 prompt 与 suffix 之间的内容
+//	resp, _ = client.CompleteFIM(ctx, "def fib(n):\n", "\nprint(fib(10))", nil)
+//
+// CompleteFIM 设置 Options.ReturnPromptTokens 时发送 echo 与 logprobs，
+// Response.PromptTokensDetail.Tokens 为服务端实际分词；Chat Completions 无对应参数，使用本地估算。
+//
+// # Responses API
+//
+// [ResponsesClient] 使用 /responses 端点（协议见 protocol/openai_responses），
+// 支持通过 previous_response_id 续接服务端保存的对话：
+//
+//	client, _ := openai.NewResponses(&openai.Config{APIKey: "sk-..."})
+//	resp, _ := client.Complete(ctx, messages, nil)
+//	next, _ := client.Complete(ctx, followUp, &llm.Options{
+//	    PreviousResponseID: resp.Metadata["response_id"].(string),
+//	})
+//
+// # 枚举输出
+//
+// ResponseFormat.Type 为 "enum" 时，OpenAI 没有纯文本枚举模式，通过 strict json_schema
+// 模拟，模型返回 {"value": "<标签>"} 而非裸标签（Gemini 则直接返回标签文本）：
+//
+//	resp, _ := client.Complete(ctx, messages, &llm.Options{
+//	    ResponseFormat: &llm.ResponseFormat{Type: "enum", EnumValues: []string{"positive", "negative"}},
+//	})
+//	// resp.Message.Content == `{"value":"positive"}`
+//
 // # 错误处理
 //
 // API 错误会包装为标准 error，包含 HTTP 状态码和响应内容。
-// 建议使用重试中间件处理临时性错误。
+// 临时性错误（429、5xx）可通过 core.WithRetry 自动重试，优先按 Retry-After 等待。
 //
 // # 线程安全
 //
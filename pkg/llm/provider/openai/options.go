@@ -0,0 +1,67 @@
+package openai
+
+import "time"
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 函数式选项
+// ═══════════════════════════════════════════════════════════════════════════
+
+// Option 配置选项函数，用于 [NewWithOptions]
+//
+// Option 直接修改 Config，未提供对应 With 函数的字段可用自定义函数设置：
+//
+//	client, err := openai.NewWithOptions(
+//		openai.WithAPIKey(key),
+//		func(c *openai.Config) { c.InsecureSkipVerify = true },
+//	)
+type Option func(*Config)
+
+// NewWithOptions 使用函数式选项创建客户端
+//
+// 按顺序应用 opts 构建 Config 后调用 [New]，未设置的字段使用 New 的默认值。
+// 需要传入 core.ClientOption（如重试配置）时请使用 New。
+func NewWithOptions(opts ...Option) (*Client, error) {
+	config := &Config{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return New(config)
+}
+
+// WithAPIKey 设置 API 密钥
+func WithAPIKey(key string) Option {
+	return func(c *Config) {
+		c.APIKey = key
+	}
+}
+
+// WithBaseURL 设置 API 基础地址
+func WithBaseURL(url string) Option {
+	return func(c *Config) {
+		c.BaseURL = url
+	}
+}
+
+// WithModel 设置默认模型名称
+func WithModel(model string) Option {
+	return func(c *Config) {
+		c.Model = model
+	}
+}
+
+// WithTimeout 设置请求超时时间
+func WithTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = d
+	}
+}
+
+// WithHeader 添加额外的请求头，可多次调用
+func WithHeader(key, value string) Option {
+	return func(c *Config) {
+		if c.Headers == nil {
+			c.Headers = make(map[string]string)
+		}
+		c.Headers[key] = value
+	}
+}
@@ -41,6 +41,21 @@ func IsReasoningModel(model string) bool {
 	return false
 }
 
+// maxCompletionTokensPrefixes 使用 max_completion_tokens 的模型前缀
+// OpenAI Reasoning 模型拒绝 max_tokens，输出上限（含推理 tokens）须使用 max_completion_tokens
+var maxCompletionTokensPrefixes = []string{"o1", "o3", "o4", "gpt-5"}
+
+// maxTokensField 返回模型的输出上限字段名（max_tokens 或 max_completion_tokens）
+func maxTokensField(model string) string {
+	modelLower := strings.ToLower(model)
+	for _, prefix := range maxCompletionTokensPrefixes {
+		if strings.HasPrefix(modelLower, prefix) {
+			return "max_completion_tokens"
+		}
+	}
+	return "max_tokens"
+}
+
 // AdaptTemperatureForModel 根据模型类型适配温度参数
 //
 // Reasoning 模型强制返回 1.0，其他模型返回原值
@@ -70,3 +85,18 @@ func IsValidReasoningEffort(effort string) bool {
 		return effort == ""
 	}
 }
+
+// ReasoningToggle 推理内容开关的请求字段形式
+//
+// OpenRouter 等网关默认不返回推理内容，需要在请求体中显式开启：
+//
+//	{"include_reasoning": true}         // ReasoningToggleFlag（旧字段，多数网关兼容）
+//	{"reasoning": {"exclude": false}}   // ReasoningToggleObject（OpenRouter 统一 reasoning 对象）
+//
+// OpenRouter 的 reasoning 对象还支持 effort、max_tokens 等字段，本开关只设置 exclude。
+type ReasoningToggle string
+
+const (
+	ReasoningToggleFlag   ReasoningToggle = "include_reasoning"
+	ReasoningToggleObject ReasoningToggle = "reasoning"
+)
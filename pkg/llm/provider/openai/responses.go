@@ -0,0 +1,220 @@
+package openai
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+	responses "github.com/lwmacct/251215-go-pkg-llm/pkg/llm/protocol/openai_responses"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// Responses API 客户端
+// ═══════════════════════════════════════════════════════════════════════════
+
+// defaultResponsesEndpoint OpenAI Responses API 端点
+const defaultResponsesEndpoint = "/responses"
+
+// ResponsesClient OpenAI Responses API 客户端
+//
+// 实现 [llm.Provider] 接口，请求发往 /responses 而非 /chat/completions。
+// 配置与 [Client] 相同（CompletePath / StreamPath 可覆盖默认端点）。
+//
+// 服务端状态：Complete 返回的 Response.Metadata["response_id"] 可作为下一轮
+// Options.PreviousResponseID，此时只需发送新增消息。
+type ResponsesClient struct {
+	*core.BaseClient
+
+	config      *Config
+	transformer *core.Transformer
+}
+
+// NewResponses 创建 Responses API 客户端
+//
+// 与 [New] 相同，config 的副本经 [Config.Normalize] 校验并填充默认值。
+func NewResponses(config *Config, opts ...core.ClientOption) (*ResponsesClient, error) {
+	if config == nil {
+		return nil, llm.NewConfigError("config is required", nil)
+	}
+	config = config.clone()
+	if err := config.Normalize(); err != nil {
+		return nil, err
+	}
+
+	baseClient, err := core.NewBaseClient(
+		config,
+		responses.NewAdapter(),
+		responses.NewEventHandler(),
+		opts...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	baseClient.SetEndpointBuilder(&core.PathEndpointBuilder{
+		CompletePath: pathOrDefault(config.CompletePath, defaultResponsesEndpoint),
+		StreamPath:   pathOrDefault(config.StreamPath, defaultResponsesEndpoint),
+	})
+
+	return &ResponsesClient{
+		BaseClient:  baseClient,
+		config:      config,
+		transformer: newTransformer(responses.NewAdapter(), config),
+	}, nil
+}
+
+// Clone 复制客户端并修改配置
+//
+// override 接收配置副本（Headers 已深拷贝），修改不会影响原客户端。克隆不重新验证
+// 配置，且与原客户端共享底层 HTTP 客户端：BaseURL、APIKey、Timeout、Headers 等
+// 连接级配置以原客户端为准，需要修改时请使用 NewResponses。适用于切换默认模型等请求级配置。
+func (c *ResponsesClient) Clone(override func(*Config)) *ResponsesClient {
+	config := c.config.clone()
+	if override != nil {
+		override(config)
+	}
+
+	baseClient := c.BaseClient.CloneWithConfig(config)
+	baseClient.SetEndpointBuilder(&core.PathEndpointBuilder{
+		CompletePath: pathOrDefault(config.CompletePath, defaultResponsesEndpoint),
+		StreamPath:   pathOrDefault(config.StreamPath, defaultResponsesEndpoint),
+	})
+
+	return &ResponsesClient{
+		BaseClient:  baseClient,
+		config:      config,
+		transformer: newTransformer(responses.NewAdapter(), config),
+	}
+}
+
+// Complete 同步完成
+//
+// 实现 [llm.Provider] 接口。响应 ID 写入 Response.Metadata["response_id"]。
+func (c *ResponsesClient) Complete(ctx context.Context, messages []llm.Message, opts *llm.Options) (*llm.Response, error) {
+	return c.BaseClient.Complete(ctx, messages, opts, c)
+}
+
+// Stream 流式完成
+//
+// 实现 [llm.Provider] 接口。完成事件的 Delta 携带 {"response_id": "..."}。
+func (c *ResponsesClient) Stream(ctx context.Context, messages []llm.Message, opts *llm.Options) (<-chan *llm.Event, error) {
+	return c.BaseClient.Stream(ctx, messages, opts, c)
+}
+
+// Close 关闭客户端
+//
+// 实现 [llm.Provider] 接口。当前实现为空操作。
+func (c *ResponsesClient) Close() error {
+	return nil
+}
+
+// BuildRequest 实现 core.RequestBuilder 接口
+func (c *ResponsesClient) BuildRequest(messages []llm.Message, opts *llm.Options, stream bool) (map[string]any, error) {
+	return c.buildRequest(messages, opts, stream), nil
+}
+
+// buildRequest 构建 Responses API 请求体
+func (c *ResponsesClient) buildRequest(messages []llm.Message, opts *llm.Options, stream bool) map[string]any {
+	if opts == nil {
+		opts = &llm.Options{}
+	}
+
+	model := opts.Model
+	if model == "" {
+		model = c.config.Model
+	}
+	if model == "" {
+		model = "gpt-4o"
+	}
+
+	// 提取系统提示
+	var systemPrompt string
+	if opts.System != "" {
+		systemPrompt = opts.System
+	} else {
+		for _, msg := range messages {
+			if msg.Role == llm.RoleSystem {
+				systemPrompt = msg.Content
+				break
+			}
+		}
+	}
+	systemPrompt = strings.Join(core.SystemParts(systemPrompt, opts), "\n\n")
+
+	req := map[string]any{
+		"model":  model,
+		"input":  c.transformer.BuildAPIMessages(messages, systemPrompt),
+		"stream": stream,
+	}
+	if systemPrompt != "" {
+		req["instructions"] = systemPrompt
+	}
+
+	// 服务端状态
+	if opts.PreviousResponseID != "" {
+		req["previous_response_id"] = opts.PreviousResponseID
+	}
+	if opts.EndUserID != "" {
+		req["user"] = opts.EndUserID
+	}
+
+	// 应用选项
+	if opts.MaxTokens > 0 {
+		req["max_output_tokens"] = opts.MaxTokens
+	}
+	if opts.Temperature > 0 {
+		req["temperature"] = opts.Temperature
+	}
+	if opts.TopP > 0 {
+		req["top_p"] = opts.TopP
+	}
+
+	// 工具（扁平结构，无 function 包装）
+	if len(opts.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(opts.Tools))
+		for _, tool := range opts.Tools {
+			tools = append(tools, map[string]any{
+				"type":        "function",
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  tool.InputSchema,
+			})
+		}
+		req["tools"] = tools
+		if opts.ToolChoice != "" {
+			req["tool_choice"] = string(opts.ToolChoice)
+		}
+	}
+
+	// Reasoning 力度
+	if opts.Reasoning != "" {
+		req["reasoning"] = map[string]any{"effort": opts.Reasoning}
+	}
+
+	// 结构化输出
+	if opts.ResponseFormat != nil {
+		switch opts.ResponseFormat.Type {
+		case "json_schema":
+			req["text"] = map[string]any{
+				"format": map[string]any{
+					"type":   "json_schema",
+					"name":   opts.ResponseFormat.Name,
+					"schema": opts.ResponseFormat.Schema,
+				},
+			}
+		case "json_object":
+			req["text"] = map[string]any{"format": map[string]any{"type": "json_object"}}
+		case "enum":
+			format := enumJSONSchema(opts.ResponseFormat)
+			format["type"] = "json_schema"
+			req["text"] = map[string]any{"format": format}
+		}
+	}
+
+	applyExtraBody(req, opts)
+	return req
+}
+
+// 确保 ResponsesClient 实现了 llm.Provider 接口
+var _ llm.Provider = (*ResponsesClient)(nil)
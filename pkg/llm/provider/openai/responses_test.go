@@ -0,0 +1,193 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
+)
+
+func TestResponsesClient_Complete_Text(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/responses", r.URL.Path)
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "resp_1",
+			"model": "gpt-4o",
+			"status": "completed",
+			"output": [{"type": "message", "role": "assistant", "content": [{"type": "output_text", "text": "Hello!"}]}],
+			"usage": {"input_tokens": 5, "output_tokens": 2, "total_tokens": 7}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := NewResponses(&Config{APIKey: "test-key", BaseURL: server.URL, Model: "gpt-4o"})
+	require.NoError(t, err)
+
+	resp, err := client.Complete(context.Background(), []llm.Message{
+		{Role: llm.RoleSystem, Content: "Be friendly"},
+		{Role: llm.RoleUser, Content: "Hi"},
+	}, &llm.Options{PreviousResponseID: "resp_0", MaxTokens: 100})
+	require.NoError(t, err)
+
+	// 请求体
+	assert.Equal(t, "Be friendly", body["instructions"])
+	assert.Equal(t, "resp_0", body["previous_response_id"])
+	assert.InDelta(t, 100, body["max_output_tokens"], 0)
+	input, ok := body["input"].([]any)
+	require.True(t, ok)
+	require.Len(t, input, 1)
+
+	// 响应
+	assert.Equal(t, "Hello!", resp.Message.Content)
+	assert.Nil(t, resp.Message.Meta)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, "resp_1", resp.Metadata["response_id"])
+	assert.Equal(t, int64(7), resp.Usage.TotalTokens)
+}
+
+func TestResponsesClient_Complete_ToolRoundTrip(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(bodies) == 1 {
+			_, _ = w.Write([]byte(`{
+				"id": "resp_1",
+				"status": "completed",
+				"output": [{"type": "function_call", "call_id": "call_1", "name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}]
+			}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"id": "resp_2",
+			"status": "completed",
+			"output": [{"type": "message", "content": [{"type": "output_text", "text": "It is sunny."}]}]
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := NewResponses(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	tools := []llm.ToolSchema{{
+		Name:        "get_weather",
+		Description: "Get weather",
+		InputSchema: map[string]any{"type": "object"},
+	}}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Weather in Paris?"}}
+
+	// 第一轮：模型请求调用工具
+	resp, err := client.Complete(context.Background(), messages, &llm.Options{Tools: tools})
+	require.NoError(t, err)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	calls := resp.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "Paris", calls[0].Input["city"])
+
+	sentTools, ok := bodies[0]["tools"].([]any)
+	require.True(t, ok)
+	assert.Equal(t, "get_weather", sentTools[0].(map[string]any)["name"])
+
+	// 第二轮：回传工具结果
+	messages = append(messages, resp.Message, llm.Message{
+		Role:          llm.RoleUser,
+		ContentBlocks: []llm.ContentBlock{&llm.ToolResultBlock{ToolUseID: calls[0].ID, Content: "Sunny"}},
+	})
+	resp, err = client.Complete(context.Background(), messages, &llm.Options{Tools: tools})
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny.", resp.Message.Content)
+
+	input, ok := bodies[1]["input"].([]any)
+	require.True(t, ok)
+	require.Len(t, input, 3)
+	assert.Equal(t, "function_call", input[1].(map[string]any)["type"])
+	assert.Equal(t, "function_call_output", input[2].(map[string]any)["type"])
+	assert.Equal(t, "call_1", input[2].(map[string]any)["call_id"])
+}
+
+func TestResponsesClient_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: response.output_text.delta\ndata: {\"delta\":\"Hi\"}\n\n" +
+			"event: response.completed\ndata: {\"response\":{\"id\":\"resp_9\",\"status\":\"completed\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewResponses(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	events, err := client.Stream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}, nil)
+	require.NoError(t, err)
+
+	var text string
+	var done *llm.Event
+	for e := range events {
+		switch e.Type {
+		case llm.EventTypeText:
+			text += e.TextDelta
+		case llm.EventTypeDone:
+			done = e
+		}
+	}
+
+	assert.Equal(t, "Hi", text)
+	require.NotNil(t, done)
+	assert.Equal(t, "stop", done.FinishReason)
+	assert.Equal(t, map[string]any{"response_id": "resp_9"}, done.Delta)
+}
+
+func TestResponsesClient_ZeroArgToolCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("event: response.output_item.added\n" +
+				`data: {"output_index":0,"item":{"type":"function_call","call_id":"call_1","name":"get_time","arguments":""}}` + "\n\n" +
+				"event: response.completed\n" +
+				`data: {"response":{"id":"resp_1","status":"completed","output":[{"type":"function_call"}]}}` + "\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "resp_1",
+			"status": "completed",
+			"output": [{"type": "function_call", "call_id": "call_1", "name": "get_time", "arguments": "{}"}]
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := NewResponses(&Config{APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "What time is it?"}}
+
+	resp, err := client.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	calls := resp.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.NotNil(t, calls[0].Input)
+	assert.Empty(t, calls[0].Input)
+
+	stream, err := client.Stream(context.Background(), messages, nil)
+	require.NoError(t, err)
+	result, err := core.CollectStream(stream)
+	require.NoError(t, err)
+	calls = result.Response.Message.GetToolCalls()
+	require.Len(t, calls, 1)
+	assert.NotNil(t, calls[0].Input)
+	assert.Empty(t, calls[0].Input)
+}
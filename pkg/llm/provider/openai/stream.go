@@ -1,9 +1,8 @@
 package openai
 
 import (
-	"encoding/json"
-
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 )
 
 // StreamResult 流式解析结果
@@ -148,13 +147,10 @@ func (p *StreamParser) buildMessage() llm.Message {
 			continue
 		}
 
-		var args map[string]any
-		_ = json.Unmarshal([]byte(buf.argsBuf), &args)
-
 		blocks = append(blocks, &llm.ToolCall{
 			ID:    buf.id,
 			Name:  buf.name,
-			Input: args,
+			Input: core.GetToolInput(buf.argsBuf), // 无参数时为空 map
 		})
 	}
 
@@ -260,7 +260,26 @@ func TestStreamParser_buildMessage_InvalidJSON(t *testing.T) {
 	require.Len(t, msg.ContentBlocks, 1)
 	tool, ok := msg.ContentBlocks[0].(*llm.ToolCall)
 	require.True(t, ok)
-	assert.Nil(t, tool.Input) // Invalid JSON results in nil
+	assert.NotNil(t, tool.Input) // 无法解析时为空 map
+	assert.Empty(t, tool.Input)
+}
+
+func TestStreamParser_ZeroArgToolCall(t *testing.T) {
+	for _, args := range []string{"", "{}"} {
+		stream := make(chan *llm.Event, 3)
+		stream <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ID: "call_1", Name: "get_time"}}
+		if args != "" {
+			stream <- &llm.Event{Type: llm.EventTypeToolCall, ToolCall: &llm.ToolCallDelta{Index: 0, ArgumentsDelta: args}}
+		}
+		stream <- &llm.Event{Type: llm.EventTypeDone, FinishReason: "tool_calls"}
+		close(stream)
+
+		result := ParseStream(stream)
+		calls := result.Message.GetToolCalls()
+		require.Len(t, calls, 1)
+		assert.NotNil(t, calls[0].Input, "args %q", args)
+		assert.Empty(t, calls[0].Input)
+	}
 }
 
 func TestParseStream(t *testing.T) {
@@ -17,6 +17,7 @@ import (
 	"fmt"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/core"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/anthropic"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/gemini"
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/mock"
@@ -34,11 +35,15 @@ func New(cfg *llm.Config) (llm.Provider, error) {
 	}
 
 	apiKey := cfg.APIKey
-	providerType := cfg.Type
 
-	// 确定 Provider 类型（默认 OpenRouter）
-	if providerType == "" {
-		providerType = llm.ProviderTypeOpenRouter
+	// 确定 Provider 类型（默认 OpenRouter，支持别名）
+	providerType := llm.ProviderTypeOpenRouter
+	if cfg.Type != "" {
+		t, err := llm.ParseProviderType(string(cfg.Type))
+		if err != nil {
+			return nil, err
+		}
+		providerType = t
 	}
 
 	// Ollama 不需要 API Key
@@ -65,6 +70,24 @@ func New(cfg *llm.Config) (llm.Provider, error) {
 	}
 }
 
+// clientOptions 将通用配置转换为 core.ClientOption
+func clientOptions(cfg *llm.Config) []core.ClientOption {
+	var opts []core.ClientOption
+	if len(cfg.FinishReasonOverrides) > 0 {
+		opts = append(opts, core.WithFinishReasonOverrides(cfg.FinishReasonOverrides))
+	}
+	if cfg.MaxConcurrentRequests > 0 {
+		opts = append(opts, core.WithMaxConcurrentRequests(cfg.MaxConcurrentRequests))
+	}
+	if cfg.MaxRetries > 0 {
+		// 退避参数沿用全局默认值（llm.SetDefaultRetry），仅覆盖尝试次数
+		retry := llm.DefaultRetry()
+		retry.MaxAttempts = cfg.MaxRetries + 1
+		opts = append(opts, core.WithRetry(retry))
+	}
+	return opts
+}
+
 // extractHeaders 从 Extra 中提取 headers
 func extractHeaders(cfg *llm.Config) map[string]string {
 	if cfg.Extra == nil {
@@ -94,7 +117,23 @@ func newOpenAI(cfg *llm.Config, apiKey string, ptype llm.ProviderType) (llm.Prov
 		Model:   model,
 		Timeout: cfg.Timeout,
 		Headers: extractHeaders(cfg),
-	})
+
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		ProbeOnInit:        cfg.ProbeOnInit,
+
+		CompletePath: cfg.CompletePath,
+		StreamPath:   cfg.StreamPath,
+
+		ReasoningToggle: reasoningToggle(ptype),
+	}, clientOptions(cfg)...)
+}
+
+// reasoningToggle 返回 Provider 类型对应的推理内容开关字段
+func reasoningToggle(ptype llm.ProviderType) openai.ReasoningToggle {
+	if ptype == llm.ProviderTypeOpenRouter {
+		return openai.ReasoningToggleObject
+	}
+	return openai.ReasoningToggleFlag
 }
 
 // newAnthropic 创建 Anthropic Provider
@@ -115,7 +154,13 @@ func newAnthropic(cfg *llm.Config, apiKey string) (llm.Provider, error) {
 		Model:   model,
 		Timeout: cfg.Timeout,
 		Headers: extractHeaders(cfg),
-	})
+
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		ProbeOnInit:        cfg.ProbeOnInit,
+
+		CompletePath: cfg.CompletePath,
+		StreamPath:   cfg.StreamPath,
+	}, clientOptions(cfg)...)
 }
 
 // newGemini 创建 Gemini Provider
@@ -136,7 +181,10 @@ func newGemini(cfg *llm.Config, apiKey string) (llm.Provider, error) {
 		Model:   model,
 		Timeout: cfg.Timeout,
 		Headers: extractHeaders(cfg),
-	})
+
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		ProbeOnInit:        cfg.ProbeOnInit,
+	}, clientOptions(cfg)...)
 }
 
 // ═══════════════════════════════════════════════════════════════════════════
@@ -1,9 +1,16 @@
 package provider
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/anthropic"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/gemini"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -113,6 +120,28 @@ func TestNew_UnsupportedType(t *testing.T) {
 	assert.Nil(t, p)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "unsupported")
+	assert.True(t, llm.IsConfigError(err))
+	assert.Contains(t, err.Error(), "openai")
+}
+
+func TestNew_TypeAlias(t *testing.T) {
+	tests := []struct {
+		alias string
+		want  any
+	}{
+		{"claude", &anthropic.Client{}},
+		{"gpt", &openai.Client{}},
+		{"Google", &gemini.Client{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			p, err := New(&llm.Config{Type: llm.ProviderType(tt.alias), APIKey: "test-key"})
+			require.NoError(t, err)
+			defer func() { _ = p.Close() }()
+			assert.IsType(t, tt.want, p)
+		})
+	}
 }
 
 func TestNew_OpenAICompatibleProviders(t *testing.T) {
@@ -247,3 +276,34 @@ func TestNew_ExtraWithoutHeaders(t *testing.T) {
 	require.NotNil(t, p)
 	defer func() { _ = p.Close() }()
 }
+
+func TestNew_MaxRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}
+
+	p, err := New(&llm.Config{Type: llm.ProviderTypeOpenAI, APIKey: "test-key", BaseURL: server.URL, MaxRetries: 2})
+	require.NoError(t, err)
+	resp, err := p.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Message.Content)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// 未设置时不重试
+	attempts.Store(0)
+	p, err = New(&llm.Config{Type: llm.ProviderTypeOpenAI, APIKey: "test-key", BaseURL: server.URL})
+	require.NoError(t, err)
+	_, err = p.Complete(context.Background(), messages, nil)
+	require.Error(t, err)
+	assert.Equal(t, int32(1), attempts.Load())
+}
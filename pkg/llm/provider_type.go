@@ -1,6 +1,11 @@
 package llm
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
 
 // ProviderType LLM Provider 类型
 type ProviderType string
@@ -73,6 +78,55 @@ var providerRegistry = map[ProviderType]providerMeta{
 	ProviderTypeMistral:    {true, "https://api.mistral.ai/v1", "mistral-large-latest", "MISTRAL_API_KEY", "MISTRAL_MODEL", "MISTRAL_BASE_URL"},
 }
 
+// providerAliases 类型别名（小写）→ 规范类型
+var providerAliases = map[string]ProviderType{
+	"claude":  ProviderTypeAnthropic,
+	"gpt":     ProviderTypeOpenAI,
+	"chatgpt": ProviderTypeOpenAI,
+	"google":  ProviderTypeGemini,
+	"kimi":    ProviderTypeMoonshot,
+	"zhipu":   ProviderTypeGLM,
+}
+
+// ParseProviderType 从配置字符串解析 Provider 类型
+//
+// 忽略大小写与首尾空白，支持别名（如 "claude" → anthropic、"gpt" → openai、
+// "google" → gemini）。未知类型返回 [ConfigError]，错误信息列出全部有效值。
+func ParseProviderType(s string) (ProviderType, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if t := ProviderType(name); t.Validate() == nil {
+		return t, nil
+	}
+	if t, ok := providerAliases[name]; ok {
+		return t, nil
+	}
+	return "", ProviderType(s).Validate()
+}
+
+// ValidProviderTypes 返回全部已注册的 Provider 类型（按字母排序）
+func ValidProviderTypes() []ProviderType {
+	types := make([]ProviderType, 0, len(providerRegistry))
+	for t := range providerRegistry {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Validate 校验类型是否已注册
+//
+// 仅接受规范名称，别名请先经 [ParseProviderType] 解析。
+func (t ProviderType) Validate() error {
+	if _, ok := providerRegistry[t]; ok {
+		return nil
+	}
+	valid := make([]string, 0, len(providerRegistry))
+	for _, v := range ValidProviderTypes() {
+		valid = append(valid, string(v))
+	}
+	return NewConfigError(fmt.Sprintf("unsupported provider type %q (valid: %s)", string(t), strings.Join(valid, ", ")), nil)
+}
+
 // String 返回字符串表示
 func (t ProviderType) String() string {
 	return string(t)
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// ParseProviderType 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestParseProviderType(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ProviderType
+	}{
+		{"openai", ProviderTypeOpenAI},
+		{"  Anthropic ", ProviderTypeAnthropic},
+		{"claude", ProviderTypeAnthropic},
+		{"GPT", ProviderTypeOpenAI},
+		{"gemini", ProviderTypeGemini},
+		{"google", ProviderTypeGemini},
+		{"kimi", ProviderTypeMoonshot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseProviderType(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseProviderType_Unknown(t *testing.T) {
+	got, err := ParseProviderType("antropic")
+
+	assert.Empty(t, got)
+	require.Error(t, err)
+	assert.True(t, IsConfigError(err))
+	assert.Contains(t, err.Error(), `"antropic"`)
+	assert.Contains(t, err.Error(), "anthropic, azure, deepseek")
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// Validate 测试
+// ═══════════════════════════════════════════════════════════════════════════
+
+func TestProviderType_Validate(t *testing.T) {
+	for _, pt := range ValidProviderTypes() {
+		assert.NoError(t, pt.Validate(), pt)
+	}
+
+	// 别名不是规范名称
+	err := ProviderType("claude").Validate()
+	require.Error(t, err)
+	assert.True(t, IsConfigError(err))
+}
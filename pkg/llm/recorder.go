@@ -0,0 +1,328 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 请求记录装饰器
+// ═══════════════════════════════════════════════════════════════════════════
+
+// Record 单次调用的评测记录（JSONL 中的一行）
+//
+// 消息与响应以 [RecordedMessage] 扁平化保存，可直接解码并通过
+// [RecordedMessage.Message] 还原为 Message 重放。
+type Record struct {
+	Time         time.Time         `json:"time"`                    // 调用开始时间
+	DurationMs   int64             `json:"duration_ms"`             // 耗时（毫秒）
+	Stream       bool              `json:"stream,omitempty"`        // 是否为流式调用（响应为聚合结果）
+	Model        string            `json:"model,omitempty"`         // 实际使用的模型（未知时为 Options.Model）
+	Messages     []RecordedMessage `json:"messages"`                // 请求消息
+	Options      *Options          `json:"options,omitempty"`       // 请求选项（敏感请求头已脱敏）
+	Response     *RecordedMessage  `json:"response,omitempty"`      // 响应消息，失败时为 nil
+	FinishReason string            `json:"finish_reason,omitempty"` // 完成原因
+	Usage        *TokenUsage       `json:"usage,omitempty"`         // Token 用量（流式调用不可用）
+	Error        string            `json:"error,omitempty"`         // 错误信息（已脱敏）
+}
+
+// RecordedMessage 记录中的消息
+//
+// 仅保留评测所需的文本、推理、工具调用与工具结果，其他内容块被丢弃。
+type RecordedMessage struct {
+	Role        Role               `json:"role"`
+	Content     string             `json:"content,omitempty"`
+	Reasoning   string             `json:"reasoning,omitempty"`
+	ToolCalls   []*ToolCall        `json:"tool_calls,omitempty"`
+	ToolResults []*ToolResultBlock `json:"tool_results,omitempty"`
+	ToolCallID  string             `json:"tool_call_id,omitempty"`
+}
+
+// Message 还原为 Message
+func (m RecordedMessage) Message() Message {
+	msg := Message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+	if m.Reasoning != "" {
+		msg.ContentBlocks = append(msg.ContentBlocks, &ThinkingBlock{Thinking: m.Reasoning})
+	}
+	for _, call := range m.ToolCalls {
+		msg.ContentBlocks = append(msg.ContentBlocks, call)
+	}
+	for _, result := range m.ToolResults {
+		msg.ContentBlocks = append(msg.ContentBlocks, result)
+	}
+	return msg
+}
+
+// NewRecorder 包装 Provider，将每次调用的请求与响应以 JSONL 写入 w
+//
+//   - Complete: 调用结束后写入一行（包括失败的调用）
+//   - Stream: 事件原样透传，流结束（完成、错误或取消）后写入聚合结果
+//
+// 写入互斥，可并发使用；写入失败被忽略，不影响调用结果。
+// 记录的是副本，返回值与调用方对象不被修改。认证相关请求头与错误信息中
+// 形似密钥的内容被替换为 "[REDACTED]"。
+//
+// 使用示例：
+//
+//	f, _ := os.OpenFile("eval.jsonl", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+//	p = llm.NewRecorder(p, f)
+func NewRecorder(p Provider, w io.Writer) Provider {
+	return &recorderProvider{Provider: p, w: w, now: time.Now}
+}
+
+// recorderProvider 请求记录装饰器
+type recorderProvider struct {
+	Provider
+
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// Complete 实现 Provider 接口
+func (r *recorderProvider) Complete(ctx context.Context, messages []Message, opts *Options) (*Response, error) {
+	start := r.now()
+	resp, err := r.Provider.Complete(ctx, messages, opts)
+
+	rec := r.newRecord(start, messages, opts)
+	if err != nil {
+		rec.Error = redactSecrets(err.Error())
+	} else if resp != nil {
+		out := recordMessage(&resp.Message)
+		rec.Response = &out
+		rec.FinishReason = resp.FinishReason
+		rec.Usage = resp.Usage
+		if resp.Model != "" {
+			rec.Model = resp.Model
+		}
+	}
+	r.write(rec)
+
+	return resp, err
+}
+
+// Stream 实现 Provider 接口
+func (r *recorderProvider) Stream(ctx context.Context, messages []Message, opts *Options) (<-chan *Event, error) {
+	start := r.now()
+	events, err := r.Provider.Stream(ctx, messages, opts)
+	if err != nil {
+		rec := r.newRecord(start, messages, opts)
+		rec.Stream = true
+		rec.Error = redactSecrets(err.Error())
+		r.write(rec)
+		return nil, err
+	}
+
+	out := make(chan *Event, 10)
+	go func() {
+		defer close(out)
+
+		var agg streamAggregate
+		defer func() {
+			rec := r.newRecord(start, messages, opts)
+			rec.Stream = true
+			agg.apply(rec, ctx.Err())
+			r.write(rec)
+		}()
+
+		for event := range events {
+			agg.add(event)
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// newRecord 构造记录的请求部分
+func (r *recorderProvider) newRecord(start time.Time, messages []Message, opts *Options) *Record {
+	rec := &Record{
+		Time:       start,
+		DurationMs: r.now().Sub(start).Milliseconds(),
+		Messages:   make([]RecordedMessage, 0, len(messages)),
+	}
+	for i := range messages {
+		rec.Messages = append(rec.Messages, recordMessage(&messages[i]))
+	}
+	if opts != nil {
+		o := *opts
+		o.Headers = redactHeaders(opts.Headers)
+		rec.Options = &o
+		rec.Model = opts.Model
+	}
+	return rec
+}
+
+// write 序列化并写入一行
+func (r *recorderProvider) write(rec *Record) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = r.w.Write(line)
+}
+
+// recordMessage 将 Message 扁平化为记录格式
+func recordMessage(msg *Message) RecordedMessage {
+	out := RecordedMessage{Role: msg.Role, ToolCallID: msg.ToolCallID, Content: msg.Content}
+
+	var texts []string
+	for _, block := range msg.ContentBlocks {
+		switch b := block.(type) {
+		case *TextBlock:
+			texts = append(texts, b.Text)
+		case *ThinkingBlock:
+			out.Reasoning += b.Thinking
+		case *ToolCall:
+			out.ToolCalls = append(out.ToolCalls, cloneBlock(b).(*ToolCall))
+		case *ToolResultBlock:
+			out.ToolResults = append(out.ToolResults, cloneBlock(b).(*ToolResultBlock))
+		}
+	}
+	if out.Content == "" {
+		out.Content = strings.Join(texts, "")
+	}
+	return out
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 流式聚合
+// ═══════════════════════════════════════════════════════════════════════════
+
+// streamAggregate 聚合流式事件为完整响应
+type streamAggregate struct {
+	text         strings.Builder
+	reasoning    strings.Builder
+	calls        map[int]*streamToolCall
+	finishReason string
+	err          string
+	done         bool
+}
+
+// streamToolCall 聚合中的工具调用
+type streamToolCall struct {
+	id   string
+	name string
+	args strings.Builder
+}
+
+// add 累积单个事件
+func (a *streamAggregate) add(event *Event) {
+	switch event.Type {
+	case EventTypeText:
+		a.text.WriteString(event.TextDelta)
+	case EventTypeReasoning, EventTypeThinking:
+		if event.Reasoning != nil {
+			a.reasoning.WriteString(event.Reasoning.ThoughtDelta)
+		}
+	case EventTypeToolCall:
+		if event.ToolCall == nil {
+			return
+		}
+		if a.calls == nil {
+			a.calls = make(map[int]*streamToolCall)
+		}
+		call, ok := a.calls[event.ToolCall.Index]
+		if !ok {
+			call = &streamToolCall{}
+			a.calls[event.ToolCall.Index] = call
+		}
+		if event.ToolCall.ID != "" {
+			call.id = event.ToolCall.ID
+		}
+		if event.ToolCall.Name != "" {
+			call.name = event.ToolCall.Name
+		}
+		call.args.WriteString(event.ToolCall.ArgumentsDelta)
+	case EventTypeDone:
+		a.done = true
+		a.finishReason = event.FinishReason
+	case EventTypeError:
+		switch {
+		case event.Error != nil:
+			a.err = event.Error.Error()
+		case event.ErrorMessage != "":
+			a.err = event.ErrorMessage
+		default:
+			a.err = "stream error"
+		}
+	}
+}
+
+// apply 将聚合结果写入记录，ctxErr 为流提前结束时的取消原因
+func (a *streamAggregate) apply(rec *Record, ctxErr error) {
+	switch {
+	case a.err != "":
+		rec.Error = redactSecrets(a.err)
+		return
+	case !a.done && ctxErr != nil:
+		rec.Error = ctxErr.Error()
+		return
+	}
+
+	out := RecordedMessage{Role: RoleAssistant, Content: a.text.String(), Reasoning: a.reasoning.String()}
+	indexes := make([]int, 0, len(a.calls))
+	for i := range a.calls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		call := a.calls[i]
+		var input map[string]any
+		if args := call.args.String(); args != "" {
+			_ = json.Unmarshal([]byte(args), &input)
+		}
+		out.ToolCalls = append(out.ToolCalls, &ToolCall{ID: call.id, Name: call.name, Input: input})
+	}
+	rec.Response = &out
+	rec.FinishReason = a.finishReason
+}
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 脱敏
+// ═══════════════════════════════════════════════════════════════════════════
+
+// redactedValue 脱敏占位符
+const redactedValue = "[REDACTED]"
+
+// secretPattern 匹配错误信息中形似密钥的内容（Bearer 令牌、sk- 前缀密钥、URL 中的 key 参数）
+var secretPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"']+|\bsk-[a-z0-9_\-]{8,}|([?&](?:api_?)?key=)[^&\s"':]+`)
+
+// redactHeaders 返回敏感请求头已脱敏的副本
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	out := maps.Clone(headers)
+	for k := range out {
+		lower := strings.ToLower(k)
+		if strings.Contains(lower, "authorization") || strings.Contains(lower, "key") ||
+			strings.Contains(lower, "token") || strings.Contains(lower, "secret") {
+			out[k] = redactedValue
+		}
+	}
+	return out
+}
+
+// redactSecrets 替换文本中形似密钥的内容
+func redactSecrets(s string) string {
+	return secretPattern.ReplaceAllStringFunc(s, func(match string) string {
+		sub := secretPattern.FindStringSubmatch(match)
+		return sub[1] + sub[2] + redactedValue
+	})
+}
@@ -0,0 +1,162 @@
+package llm_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/mock"
+)
+
+// decodeRecords 解码 JSONL 输出
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []llm.Record {
+	t.Helper()
+	var records []llm.Record
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var rec llm.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec), scanner.Text())
+		records = append(records, rec)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestNewRecorder_Complete(t *testing.T) {
+	inner := mock.New(
+		mock.WithMessageFunc(func([]llm.Message, int) llm.Message {
+			return llm.Message{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+				&llm.ThinkingBlock{Thinking: "It is sunny."},
+				&llm.TextBlock{Text: "Sunny, 22°C."},
+			}}
+		}),
+		mock.WithUsage(llm.TokenUsage{InputTokens: 12, OutputTokens: 5, TotalTokens: 17}),
+	)
+	var buf bytes.Buffer
+	p := llm.NewRecorder(inner, &buf)
+
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "Weather in Paris?"},
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+		}},
+		{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{
+			&llm.ToolResultBlock{ToolUseID: "call_1", Name: "get_weather", Content: "22°C"},
+		}},
+	}
+	opts := &llm.Options{
+		Model:       "gpt-4o",
+		Temperature: 0.2,
+		Headers:     map[string]string{"Authorization": "Bearer sk-live-123456789", "X-Trace": "abc"},
+	}
+
+	resp, err := p.Complete(context.Background(), messages, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny, 22°C.", resp.Message.GetContent())
+	assert.Equal(t, "Bearer sk-live-123456789", opts.Headers["Authorization"], "调用方选项不被修改")
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 1)
+	rec := records[0]
+
+	assert.False(t, rec.Time.IsZero())
+	assert.False(t, rec.Stream)
+	assert.Equal(t, "gpt-4o", rec.Model)
+	assert.InDelta(t, 0.2, rec.Options.Temperature, 1e-9)
+	assert.Equal(t, "[REDACTED]", rec.Options.Headers["Authorization"])
+	assert.Equal(t, "abc", rec.Options.Headers["X-Trace"])
+
+	// 请求消息可还原重放
+	require.Len(t, rec.Messages, len(messages))
+	for i := range messages {
+		assert.Equal(t, messages[i], rec.Messages[i].Message())
+	}
+
+	require.NotNil(t, rec.Response)
+	assert.Equal(t, "Sunny, 22°C.", rec.Response.Content)
+	assert.Equal(t, "It is sunny.", rec.Response.Reasoning)
+	assert.Equal(t, "stop", rec.FinishReason)
+	require.NotNil(t, rec.Usage)
+	assert.Equal(t, int64(17), rec.Usage.TotalTokens)
+	assert.Empty(t, rec.Error)
+}
+
+func TestNewRecorder_CompleteError(t *testing.T) {
+	inner := mock.New(mock.WithError(errors.New(`GET https://example.com/v1/models?key=AIzaSecret123: 401`)))
+	var buf bytes.Buffer
+	p := llm.NewRecorder(inner, &buf)
+
+	_, err := p.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AIzaSecret123", "返回的错误不被修改")
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Nil(t, records[0].Response)
+	assert.Nil(t, records[0].Options)
+	assert.Equal(t, "GET https://example.com/v1/models?key=[REDACTED]: 401", records[0].Error)
+}
+
+func TestNewRecorder_Stream(t *testing.T) {
+	inner := mock.New(mock.WithMessageFunc(func([]llm.Message, int) llm.Message {
+		return llm.Message{Role: llm.RoleAssistant, Content: "Let me check.", ContentBlocks: []llm.ContentBlock{
+			&llm.ToolCall{ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+		}}
+	}))
+	var buf bytes.Buffer
+	p := llm.NewRecorder(inner, &buf)
+
+	events, err := p.Stream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Weather?"}}, &llm.Options{Model: "m"})
+	require.NoError(t, err)
+
+	var text string
+	var calls int
+	for e := range events {
+		text += e.TextDelta
+		if e.Type == llm.EventTypeToolCall {
+			calls++
+		}
+	}
+	assert.Equal(t, "Let me check.", text)
+	assert.Equal(t, 1, calls)
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 1)
+	rec := records[0]
+	assert.True(t, rec.Stream)
+	require.NotNil(t, rec.Response)
+	assert.Equal(t, "Let me check.", rec.Response.Content)
+	require.Len(t, rec.Response.ToolCalls, 1)
+	assert.Equal(t, "get_weather", rec.Response.ToolCalls[0].Name)
+	assert.Equal(t, map[string]any{"city": "Paris"}, rec.Response.ToolCalls[0].Input)
+	assert.Equal(t, "tool_calls", rec.FinishReason)
+}
+
+func TestNewRecorder_Concurrent(t *testing.T) {
+	var buf bytes.Buffer
+	p := llm.NewRecorder(mock.New(mock.WithResponse("ok")), &buf)
+
+	const n = 20
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			_, err := p.Complete(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "ping"}}, nil)
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, n)
+	for _, rec := range records {
+		assert.Equal(t, "ok", rec.Response.Content)
+	}
+}
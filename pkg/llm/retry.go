@@ -0,0 +1,54 @@
+package llm
+
+import "time"
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 重试配置
+// ═══════════════════════════════════════════════════════════════════════════
+
+// 默认重试退避参数
+const (
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 10 * time.Second
+)
+
+// RetryConfig 重试配置
+//
+// 仅对 [IsRetryableError] 判定为可重试的错误（429、5xx）生效，配额耗尽（[QuotaError]）不重试。
+// 退避时间按 BaseDelay * 2^n 指数增长，不超过 MaxDelay；设置 Jitter 时在此基础上随机缩短。
+// 客户端通过 core.WithRetry 设置，未设置时使用 [SetDefaultRetry] 的全局默认值。
+type RetryConfig struct {
+	MaxAttempts int           // 最大尝试次数（含首次），<= 1 表示不重试
+	BaseDelay   time.Duration // 首次重试前的等待时间，0 使用 DefaultRetryBaseDelay
+	MaxDelay    time.Duration // 单次等待上限，0 使用 DefaultRetryMaxDelay；服务端 Retry-After 超过此值时不再重试
+	Jitter      float64       // 随机抖动比例（0 ~ 1）：等待时间在 [d*(1-Jitter), d] 内随机，避免多个客户端同时重试
+}
+
+// Backoff 返回第 attempt 次重试（从 1 开始）前的等待时间
+func (r RetryConfig) Backoff(attempt int) time.Duration {
+	base, maxDelay := r.BaseDelay, r.MaxDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// JitteredBackoff 返回应用随机抖动后的第 attempt 次重试前的等待时间
+//
+// r 为 [0, 1) 内的随机数；Jitter <= 0 时等于 [RetryConfig.Backoff]，超过 1 按 1 处理。
+func (r RetryConfig) JitteredBackoff(attempt int, rnd float64) time.Duration {
+	delay := r.Backoff(attempt)
+	jitter := min(r.Jitter, 1)
+	if jitter <= 0 {
+		return delay
+	}
+	return delay - time.Duration(float64(delay)*jitter*rnd)
+}
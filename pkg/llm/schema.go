@@ -0,0 +1,183 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// JSON Schema 校验
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ErrSchemaViolation 响应内容不符合 ResponseFormat 的 Schema
+var ErrSchemaViolation = errors.New("response does not match schema")
+
+// ValidateJSONSchema 按 JSON Schema 校验已解码的 JSON 值
+//
+// value 为 json.Unmarshal 到 any 的结果。仅支持结构化输出常用的子集：
+// type、enum、properties、required、additionalProperties、items。
+// 其他关键字被忽略。返回的错误包含出错路径（如 "$.items[0].name"）。
+func ValidateJSONSchema(value any, schema map[string]any) error {
+	return validateSchema("$", value, schema)
+}
+
+// validateSchema 递归校验
+func validateSchema(path string, value any, schema map[string]any) error {
+	if schema == nil {
+		return nil
+	}
+
+	if types := schemaStrings(schema["type"]); len(types) > 0 {
+		if !slices.ContainsFunc(types, func(t string) bool { return matchesType(value, t) }) {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		if !enumContains(enum, value) {
+			return fmt.Errorf("%s: value %v is not one of the allowed values", path, value)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return validateObject(path, v, schema)
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range v {
+			if err := validateSchema(fmt.Sprintf("%s[%d]", path, i), item, items); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateObject 校验对象的 required、properties 与 additionalProperties
+func validateObject(path string, obj map[string]any, schema map[string]any) error {
+	for _, name := range schemaStrings(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		propSchema, declared := props[k].(map[string]any)
+		if declared {
+			if err := validateSchema(path+"."+k, obj[k], propSchema); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				return fmt.Errorf("%s: unexpected property %q", path, k)
+			}
+		case map[string]any:
+			if err := validateSchema(path+"."+k, obj[k], extra); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType 判断值是否符合 JSON Schema 类型
+func matchesType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+// jsonTypeName 返回值的 JSON 类型名
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// enumContains 判断 enum 列表（[]any 或 []string）是否包含值
+func enumContains(enum any, value any) bool {
+	switch list := enum.(type) {
+	case []string:
+		s, ok := value.(string)
+		return ok && slices.Contains(list, s)
+	case []any:
+		for _, item := range list {
+			if reflect.DeepEqual(item, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// schemaStrings 读取字符串或字符串列表（[]string / []any）
+func schemaStrings(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []string:
+		return s
+	case []any:
+		result := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
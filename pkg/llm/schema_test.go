@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string"},
+			"age":  map[string]any{"type": "integer"},
+			"tags": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"role": map[string]any{"type": "string", "enum": []string{"admin", "user"}},
+		},
+		"required":             []string{"name"},
+		"additionalProperties": false,
+	}
+
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"合法", `{"name": "Ann", "age": 3, "tags": ["a"], "role": "user"}`, ""},
+		{"缺少必填字段", `{"age": 3}`, `$: missing required property "name"`},
+		{"类型错误", `{"name": 1}`, "$.name: expected string, got number"},
+		{"整数", `{"name": "Ann", "age": 3.5}`, "$.age: expected integer, got number"},
+		{"数组元素", `{"name": "Ann", "tags": ["a", 2]}`, "$.tags[1]: expected string, got number"},
+		{"枚举", `{"name": "Ann", "role": "root"}`, "$.role: value root is not one of the allowed values"},
+		{"额外字段", `{"name": "Ann", "extra": true}`, `$: unexpected property "extra"`},
+		{"根类型", `[]`, "$: expected object, got array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value any
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &value))
+
+			err := ValidateJSONSchema(value, schema)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
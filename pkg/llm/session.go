@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 多轮会话
+// ═══════════════════════════════════════════════════════════════════════════
+
+// Session 多轮会话，维护对话历史与会话级默认选项
+//
+// 会话级选项（System、Temperature、Tools 等）对每一轮生效，单次 Send 传入的选项
+// 按字段覆盖（见 [MergeOptions]），无需每轮重复设置。并发调用 Send 按顺序执行。
+//
+// 使用示例：
+//
+//	s := llm.NewSession(p, &llm.Options{System: "You are a travel agent.", Temperature: 0.7, Tools: tools})
+//	resp, err := s.Send(ctx, "Find me a flight to Tokyo", nil)
+//	resp, err = s.Send(ctx, "Summarize the options", &llm.Options{ToolChoice: llm.ToolChoiceNone})
+type Session struct {
+	mu       sync.Mutex
+	provider Provider
+	options  *Options
+	messages []Message
+}
+
+// NewSession 创建会话，opts 为会话级默认选项（可为 nil）
+func NewSession(p Provider, opts *Options) *Session {
+	return &Session{provider: p, options: opts}
+}
+
+// SetOptions 替换会话级默认选项，对之后的轮次生效
+func (s *Session) SetOptions(opts *Options) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options = opts
+}
+
+// Send 发送用户消息并将回复追加到历史，opts 按字段覆盖会话级选项
+func (s *Session) Send(ctx context.Context, content string, opts *Options) (*Response, error) {
+	return s.SendMessage(ctx, Message{Role: RoleUser, Content: content}, opts)
+}
+
+// SendMessage 发送任意消息（如包含 ToolResultBlock 的用户消息）并将回复追加到历史
+//
+// 调用失败时不修改历史，可直接重试。
+func (s *Session) SendMessage(ctx context.Context, msg Message, opts *Options) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := append(slices.Clone(s.messages), msg)
+	resp, err := s.provider.Complete(ctx, messages, MergeOptions(s.options, opts))
+	if err != nil {
+		return resp, err
+	}
+	s.messages = append(messages, resp.Message)
+	return resp, nil
+}
+
+// Messages 返回对话历史的副本
+func (s *Session) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.messages)
+}
+
+// MergeOptions 合并选项，override 中的非零字段覆盖 base 的同名字段
+//
+// 按字段整体覆盖：切片与 map（Tools、Headers 等）不逐项合并，非空时整体替换。
+// 零值视为未设置，因此无法通过 override 将字段改回零值（如 Temperature 0），
+// 需要时直接修改会话级选项。两者均为 nil 时返回 nil，否则返回新的 Options。
+func MergeOptions(base, override *Options) *Options {
+	switch {
+	case base == nil && override == nil:
+		return nil
+	case base == nil:
+		merged := *override
+		return &merged
+	case override == nil:
+		merged := *base
+		return &merged
+	}
+
+	merged := *base
+	dst := reflect.ValueOf(&merged).Elem()
+	src := reflect.ValueOf(override).Elem()
+	for i := range src.NumField() {
+		if field := src.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+	return &merged
+}
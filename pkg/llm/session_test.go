@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// optionsCapturingProvider 记录每次调用选项的测试 Provider
+type optionsCapturingProvider struct {
+	countingProvider
+
+	opts []*Options
+	err  error
+}
+
+func (p *optionsCapturingProvider) Complete(ctx context.Context, messages []Message, opts *Options) (*Response, error) {
+	p.opts = append(p.opts, opts)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.countingProvider.Complete(ctx, messages, opts)
+}
+
+func TestSession_DefaultOptions(t *testing.T) {
+	p := &optionsCapturingProvider{}
+	tools := []ToolSchema{{Name: "search"}}
+	s := NewSession(p, &Options{System: "Be brief.", Temperature: 0.7, Tools: tools})
+
+	_, err := s.Send(context.Background(), "Hi", nil)
+	require.NoError(t, err)
+	_, err = s.Send(context.Background(), "Again", nil)
+	require.NoError(t, err)
+
+	require.Len(t, p.opts, 2)
+	for _, opts := range p.opts {
+		assert.Equal(t, "Be brief.", opts.System)
+		assert.InDelta(t, 0.7, opts.Temperature, 1e-9)
+		assert.Equal(t, tools, opts.Tools)
+	}
+
+	// 历史包含每轮的用户消息与回复
+	msgs := s.Messages()
+	require.Len(t, msgs, 4)
+	assert.Equal(t, "Again", msgs[2].Content)
+	assert.Equal(t, "echo: Again", msgs[3].Content)
+}
+
+func TestSession_PerCallOverride(t *testing.T) {
+	p := &optionsCapturingProvider{}
+	base := &Options{System: "Be brief.", Temperature: 0.7, Tools: []ToolSchema{{Name: "search"}}}
+	s := NewSession(p, base)
+
+	override := []ToolSchema{{Name: "calculator"}}
+	_, err := s.Send(context.Background(), "Hi", &Options{Temperature: 0.2, Tools: override, ToolChoice: ToolChoiceRequired})
+	require.NoError(t, err)
+
+	opts := p.opts[0]
+	assert.InDelta(t, 0.2, opts.Temperature, 1e-9)
+	assert.Equal(t, override, opts.Tools)
+	assert.Equal(t, ToolChoiceRequired, opts.ToolChoice)
+	assert.Equal(t, "Be brief.", opts.System, "unset fields fall back to session options")
+
+	// 会话级选项不被单次覆盖修改
+	assert.InDelta(t, 0.7, base.Temperature, 1e-9)
+	assert.Empty(t, base.ToolChoice)
+}
+
+func TestSession_FailedTurnKeepsHistory(t *testing.T) {
+	p := &optionsCapturingProvider{err: errors.New("boom")}
+	s := NewSession(p, nil)
+
+	_, err := s.Send(context.Background(), "Hi", nil)
+	require.Error(t, err)
+	assert.Empty(t, s.Messages())
+	assert.Nil(t, p.opts[0])
+}
+
+func TestMergeOptions(t *testing.T) {
+	assert.Nil(t, MergeOptions(nil, nil))
+
+	base := &Options{Model: "a", MaxTokens: 100}
+	merged := MergeOptions(base, nil)
+	assert.Equal(t, base, merged)
+	assert.NotSame(t, base, merged)
+
+	merged = MergeOptions(base, &Options{MaxTokens: 200, Headers: map[string]string{"X-Trace": "1"}})
+	assert.Equal(t, &Options{Model: "a", MaxTokens: 200, Headers: map[string]string{"X-Trace": "1"}}, merged)
+}
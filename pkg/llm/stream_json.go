@@ -0,0 +1,176 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 结构化输出流式解析
+// ═══════════════════════════════════════════════════════════════════════════
+
+// StreamJSON 流式解析模型输出的 JSON 数组，逐个元素解码为 T
+//
+// 适用于模型流式返回对象数组的场景（如实体抽取），每当一个顶层数组元素
+// 完整到达即解码并发送到 channel，无需等待整个响应结束。
+//
+// 行为：
+//   - 跳过第一个 '[' 之前的内容（如 ```json 代码块前缀）
+//   - 无法解码为 T 的元素被跳过
+//   - 流结束时未完成的尾部元素被丢弃
+//   - 返回的 Response 在 channel 关闭后才填充完整（文本、完成原因、完成事件携带的用量）
+//   - ctx 取消后停止发送并关闭 channel，剩余事件在后台丢弃，不阻塞底层流
+//   - 流式错误事件记录在 Response.FinishReason = "error" 与 Metadata["error"]
+//
+// 使用示例：
+//
+//	items, resp, err := llm.StreamJSON[Entity](ctx, p, messages, opts)
+//	if err != nil {
+//	    return err
+//	}
+//	for item := range items {
+//	    process(item)
+//	}
+//	fmt.Println(resp.FinishReason)
+func StreamJSON[T any](ctx context.Context, p Provider, messages []Message, opts *Options) (<-chan T, *Response, error) {
+	events, err := p.Stream(ctx, messages, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make(chan T, 10)
+	resp := &Response{Message: Message{Role: RoleAssistant}}
+
+	go func() {
+		defer close(out)
+
+		var text strings.Builder
+		scanner := &jsonArrayScanner{}
+
+		for event := range events {
+			switch event.Type {
+			case EventTypeText:
+				text.WriteString(event.TextDelta)
+				for _, raw := range scanner.Feed(event.TextDelta) {
+					var item T
+					if err := json.Unmarshal([]byte(raw), &item); err != nil {
+						continue
+					}
+					select {
+					case out <- item:
+					case <-ctx.Done():
+						resp.Message.Content = text.String()
+						// 继续消费剩余事件，避免上游阻塞
+						go func() {
+							for range events {
+								// 丢弃剩余事件
+							}
+						}()
+						return
+					}
+				}
+			case EventTypeDone:
+				resp.FinishReason = event.FinishReason
+				if event.Usage != nil {
+					resp.Usage = event.Usage
+				}
+			case EventTypeError:
+				resp.FinishReason = "error"
+				resp.Metadata = map[string]any{"error": event.ErrorMessage}
+			default:
+				// 忽略其他事件类型
+			}
+		}
+
+		resp.Message.Content = text.String()
+	}()
+
+	return out, resp, nil
+}
+
+// jsonArrayScanner 增量扫描 JSON 数组，提取完整的顶层元素
+type jsonArrayScanner struct {
+	buf       strings.Builder
+	pos       int  // 已扫描位置
+	depth     int  // 当前嵌套深度（1 表示位于顶层数组内）
+	inString  bool // 是否位于字符串内
+	escaped   bool // 上一个字符是否为转义符
+	elemStart int  // 当前元素起始位置，-1 表示无
+	started   bool // 是否已遇到顶层 '['
+	done      bool // 顶层数组是否已结束
+}
+
+// Feed 喂入新的文本片段，返回本次新完成的元素原始 JSON
+func (s *jsonArrayScanner) Feed(chunk string) []string {
+	if s.done {
+		return nil
+	}
+	s.buf.WriteString(chunk)
+	data := s.buf.String()
+
+	var elems []string
+	for ; s.pos < len(data); s.pos++ {
+		ch := data[s.pos]
+
+		if !s.started {
+			if ch == '[' {
+				s.started = true
+				s.depth = 1
+				s.elemStart = -1
+			}
+			continue
+		}
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case ch == '\\':
+				s.escaped = true
+			case ch == '"':
+				s.inString = false
+			}
+			continue
+		}
+
+		// 顶层元素起始
+		if s.depth == 1 && s.elemStart == -1 {
+			switch ch {
+			case ' ', '\t', '\n', '\r', ',':
+				continue
+			case ']':
+				s.done = true
+				return elems
+			}
+			s.elemStart = s.pos
+		}
+
+		switch ch {
+		case '"':
+			s.inString = true
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			s.depth--
+			if s.depth == 1 {
+				// 对象/数组元素闭合
+				elems = append(elems, data[s.elemStart:s.pos+1])
+				s.elemStart = -1
+			} else if s.depth == 0 {
+				// 顶层数组结束（原始值元素在此之前）
+				elems = append(elems, strings.TrimSpace(data[s.elemStart:s.pos]))
+				s.done = true
+				return elems
+			}
+		case ',':
+			if s.depth == 1 {
+				// 原始值元素（数字、字符串、布尔）结束
+				elems = append(elems, strings.TrimSpace(data[s.elemStart:s.pos]))
+				s.elemStart = -1
+			}
+		}
+	}
+
+	return elems
+}
@@ -0,0 +1,136 @@
+package llm_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/mock"
+)
+
+type entity struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func TestStreamJSON_EmitsArrayElements(t *testing.T) {
+	p := mock.New(mock.WithResponse(`[{"name":"Alice","type":"person"}, {"name":"Paris, \"FR\"","type":"city"},{"name":"ACME","type":"org"}]`))
+
+	items, resp, err := llm.StreamJSON[entity](context.Background(), p, []llm.Message{
+		{Role: llm.RoleUser, Content: "extract entities"},
+	}, nil)
+	require.NoError(t, err)
+
+	var got []entity
+	for item := range items {
+		got = append(got, item)
+	}
+
+	require.Len(t, got, 3)
+	assert.Equal(t, entity{Name: "Alice", Type: "person"}, got[0])
+	assert.Equal(t, entity{Name: `Paris, "FR"`, Type: "city"}, got[1])
+	assert.Equal(t, entity{Name: "ACME", Type: "org"}, got[2])
+
+	// channel 关闭后 Response 已聚合完成
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, llm.RoleAssistant, resp.Message.Role)
+	assert.Contains(t, resp.Message.Content, `"ACME"`)
+}
+
+func TestStreamJSON_IncompleteTrailingElement(t *testing.T) {
+	p := mock.New(mock.WithResponse("```json\n[{\"name\":\"Alice\"},{\"name\":\"Bo"))
+
+	items, resp, err := llm.StreamJSON[entity](context.Background(), p, nil, nil)
+	require.NoError(t, err)
+
+	var got []entity
+	for item := range items {
+		got = append(got, item)
+	}
+
+	require.Len(t, got, 1)
+	assert.Equal(t, "Alice", got[0].Name)
+	assert.Equal(t, "stop", resp.FinishReason)
+}
+
+func TestStreamJSON_PrimitiveElements(t *testing.T) {
+	p := mock.New(mock.WithResponse(`[1, 2, [3], 4]`))
+
+	items, _, err := llm.StreamJSON[int](context.Background(), p, nil, nil)
+	require.NoError(t, err)
+
+	var got []int
+	for item := range items {
+		got = append(got, item)
+	}
+
+	// [3] 无法解码为 int，被跳过
+	assert.Equal(t, []int{1, 2, 4}, got)
+}
+
+func TestStreamJSON_StreamError(t *testing.T) {
+	p := mock.New(mock.WithError(assert.AnError))
+
+	items, resp, err := llm.StreamJSON[entity](context.Background(), p, nil, nil)
+	require.Error(t, err)
+	assert.Nil(t, items)
+	assert.Nil(t, resp)
+}
+
+func TestStreamJSON_Usage(t *testing.T) {
+	usage := llm.TokenUsage{InputTokens: 12, OutputTokens: 8, TotalTokens: 20}
+	p := mock.New(mock.WithResponse(`[1, 2]`), mock.WithUsage(usage))
+
+	items, resp, err := llm.StreamJSON[int](context.Background(), p, nil, nil)
+	require.NoError(t, err)
+	for range items {
+	}
+
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, usage, *resp.Usage)
+}
+
+func TestStreamJSON_CancelDrainsUpstream(t *testing.T) {
+	events := make(chan *llm.Event) // 无缓冲：上游每次发送都需要下游接收
+	p := &eventsProvider{Provider: mock.New(), events: events}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	items, _, err := llm.StreamJSON[int](ctx, p, nil, nil)
+	require.NoError(t, err)
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		defer close(events)
+		events <- &llm.Event{Type: llm.EventTypeText, TextDelta: "[1,"}
+		<-items // 接收首个元素后取消，不再读取
+		cancel()
+		for i := range 20 {
+			events <- &llm.Event{Type: llm.EventTypeText, TextDelta: strconv.Itoa(i) + ","}
+		}
+	}()
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("ctx 取消后上游发送被阻塞")
+	}
+	for range items {
+	}
+}
+
+// eventsProvider 返回预设事件 channel 的 Provider
+type eventsProvider struct {
+	llm.Provider
+
+	events chan *llm.Event
+}
+
+func (p *eventsProvider) Stream(context.Context, []llm.Message, *llm.Options) (<-chan *llm.Event, error) {
+	return p.events, nil
+}
@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 工具注册表
+// ═══════════════════════════════════════════════════════════════════════════
+
+// ToolFunc 工具处理函数
+//
+// 返回的字符串作为 ToolResultBlock.Content 回传给模型。
+type ToolFunc func(ctx context.Context, input map[string]any) (string, error)
+
+// ErrUnknownTool 调用了未注册的工具
+var ErrUnknownTool = errors.New("unknown tool")
+
+// ErrInvalidTool 工具定义无效（名称重复、缺少输入 Schema 等，见 core.ValidateTools）
+var ErrInvalidTool = errors.New("invalid tool definition")
+
+// ToolRegistry 工具注册表
+//
+// 将 [ToolSchema] 与处理函数成对注册，保证请求中声明的每个工具都有对应实现，
+// 避免 Options.Tools 与处理函数表各自维护导致不一致。并发安全。
+//
+// 示例：
+//
+//	reg := llm.NewToolRegistry()
+//	_ = reg.Register(llm.ToolSchema{Name: "get_weather", InputSchema: schema}, getWeather)
+//
+//	opts := &llm.Options{Tools: reg.Schemas()}
+//	content, err := reg.Invoke(ctx, call)
+type ToolRegistry struct {
+	mu       sync.RWMutex
+	order    []string
+	schemas  map[string]ToolSchema
+	handlers map[string]ToolFunc
+}
+
+// NewToolRegistry 创建空的工具注册表
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		schemas:  make(map[string]ToolSchema),
+		handlers: make(map[string]ToolFunc),
+	}
+}
+
+// Register 注册工具
+//
+// 同名工具重复注册时覆盖原定义（保留原顺序）。名称为空或处理函数为 nil 时返回错误。
+func (r *ToolRegistry) Register(schema ToolSchema, fn ToolFunc) error {
+	if schema.Name == "" {
+		return errors.New("tool name is required")
+	}
+	if fn == nil {
+		return fmt.Errorf("tool %q: handler is required", schema.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.schemas[schema.Name]; !exists {
+		r.order = append(r.order, schema.Name)
+	}
+	r.schemas[schema.Name] = schema
+	r.handlers[schema.Name] = fn
+	return nil
+}
+
+// Schemas 返回全部工具定义（按注册顺序），可直接用作 Options.Tools
+func (r *ToolRegistry) Schemas() []ToolSchema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.order) == 0 {
+		return nil
+	}
+	schemas := make([]ToolSchema, 0, len(r.order))
+	for _, name := range r.order {
+		schemas = append(schemas, r.schemas[name])
+	}
+	return schemas
+}
+
+// Lookup 查找工具处理函数
+func (r *ToolRegistry) Lookup(name string) (ToolFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fn, ok := r.handlers[name]
+	return fn, ok
+}
+
+// Invoke 执行工具调用
+//
+// 工具未注册时返回包装 [ErrUnknownTool] 的错误。
+func (r *ToolRegistry) Invoke(ctx context.Context, call *ToolCall) (string, error) {
+	fn, ok := r.Lookup(call.Name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownTool, call.Name)
+	}
+	return fn(ctx, call.Input)
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoTool(_ context.Context, input map[string]any) (string, error) {
+	return "echo: " + input["text"].(string), nil
+}
+
+func TestToolRegistry_Register(t *testing.T) {
+	reg := NewToolRegistry()
+
+	require.NoError(t, reg.Register(ToolSchema{Name: "echo", Description: "v1"}, echoTool))
+	require.NoError(t, reg.Register(ToolSchema{Name: "time"}, echoTool))
+	require.NoError(t, reg.Register(ToolSchema{Name: "echo", Description: "v2"}, echoTool))
+
+	require.Error(t, reg.Register(ToolSchema{}, echoTool))
+	require.Error(t, reg.Register(ToolSchema{Name: "nil_handler"}, nil))
+
+	_, ok := reg.Lookup("echo")
+	assert.True(t, ok)
+	_, ok = reg.Lookup("nil_handler")
+	assert.False(t, ok)
+}
+
+func TestToolRegistry_Schemas(t *testing.T) {
+	assert.Nil(t, NewToolRegistry().Schemas())
+
+	reg := NewToolRegistry()
+	require.NoError(t, reg.Register(ToolSchema{Name: "b"}, echoTool))
+	require.NoError(t, reg.Register(ToolSchema{Name: "a"}, echoTool))
+	require.NoError(t, reg.Register(ToolSchema{Name: "b", Description: "updated"}, echoTool))
+
+	schemas := reg.Schemas()
+	require.Len(t, schemas, 2)
+	assert.Equal(t, "b", schemas[0].Name)
+	assert.Equal(t, "updated", schemas[0].Description)
+	assert.Equal(t, "a", schemas[1].Name)
+}
+
+func TestToolRegistry_Invoke(t *testing.T) {
+	reg := NewToolRegistry()
+	require.NoError(t, reg.Register(ToolSchema{Name: "echo"}, echoTool))
+
+	content, err := reg.Invoke(context.Background(), &ToolCall{ID: "call_1", Name: "echo", Input: map[string]any{"text": "hi"}})
+	require.NoError(t, err)
+	assert.Equal(t, "echo: hi", content)
+
+	_, err = reg.Invoke(context.Background(), &ToolCall{ID: "call_2", Name: "missing"})
+	require.ErrorIs(t, err, ErrUnknownTool)
+	assert.Equal(t, "unknown tool: missing", err.Error())
+}
@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════
+// 请求/响应转换装饰器
+// ═══════════════════════════════════════════════════════════════════════════
+
+// WithTransform 包装 Provider，在请求发出前与响应返回后应用转换
+//
+// 典型用途是合规场景下的 PII 脱敏：请求转换在消息离开本地前执行，
+// 响应转换在结果交给调用方（及日志）前执行。
+//
+//   - req: 转换发送的消息，接收的是副本，可直接修改
+//   - resp: 转换 Complete 的响应
+//   - event: 可选，转换 Stream 的每个事件；返回 nil 表示丢弃该事件
+//
+// 任一转换函数为 nil 时跳过对应步骤。调用方传入的消息切片不会被修改。
+//
+// 使用示例：
+//
+//	p = llm.WithTransform(p, redactMessages, redactResponse, redactEvent)
+func WithTransform(p Provider, req func([]Message) []Message, resp func(*Response) *Response, event ...func(*Event) *Event) Provider {
+	t := &transformProvider{
+		Provider: p,
+		req:      req,
+		resp:     resp,
+	}
+	if len(event) > 0 {
+		t.event = event[0]
+	}
+	return t
+}
+
+// transformProvider 应用转换的 Provider 装饰器
+type transformProvider struct {
+	Provider
+
+	req   func([]Message) []Message
+	resp  func(*Response) *Response
+	event func(*Event) *Event
+}
+
+// Complete 实现 Provider 接口
+func (t *transformProvider) Complete(ctx context.Context, messages []Message, opts *Options) (*Response, error) {
+	resp, err := t.Provider.Complete(ctx, t.transformRequest(messages), opts)
+	if err != nil {
+		return nil, err
+	}
+	if t.resp != nil {
+		resp = t.resp(resp)
+	}
+	return resp, nil
+}
+
+// Stream 实现 Provider 接口
+func (t *transformProvider) Stream(ctx context.Context, messages []Message, opts *Options) (<-chan *Event, error) {
+	events, err := t.Provider.Stream(ctx, t.transformRequest(messages), opts)
+	if err != nil || t.event == nil {
+		return events, err
+	}
+
+	out := make(chan *Event, 10)
+	go func() {
+		defer close(out)
+		for event := range events {
+			if event = t.event(event); event == nil {
+				continue
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// transformRequest 对消息副本应用请求转换
+func (t *transformProvider) transformRequest(messages []Message) []Message {
+	if t.req == nil {
+		return messages
+	}
+	return t.req(cloneMessages(messages))
+}
+
+// cloneMessages 深拷贝消息列表（含内容块），保证转换函数不影响调用方数据
+func cloneMessages(messages []Message) []Message {
+	if messages == nil {
+		return nil
+	}
+	cloned := make([]Message, len(messages))
+	for i, msg := range messages {
+		cloned[i] = msg
+		cloned[i].Meta = maps.Clone(msg.Meta)
+		if msg.ContentBlocks != nil {
+			blocks := make([]ContentBlock, len(msg.ContentBlocks))
+			for j, block := range msg.ContentBlocks {
+				blocks[j] = cloneBlock(block)
+			}
+			cloned[i].ContentBlocks = blocks
+		}
+	}
+	return cloned
+}
+
+// cloneBlock 复制内容块
+func cloneBlock(block ContentBlock) ContentBlock {
+	switch b := block.(type) {
+	case *TextBlock:
+		c := *b
+		return &c
+	case *ToolResultBlock:
+		c := *b
+		return &c
+	case *ImageBlock:
+		c := *b
+		return &c
+	case *ToolCall:
+		c := *b
+		c.Input = maps.Clone(b.Input)
+		return &c
+	case *ThinkingBlock:
+		c := *b
+		return &c
+	case *RedactedThinkingBlock:
+		c := *b
+		return &c
+	case *CodeExecutionResultBlock:
+		c := *b
+		c.Files = slices.Clone(b.Files)
+		return &c
+	default:
+		return block
+	}
+}
@@ -0,0 +1,145 @@
+package llm_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm"
+	"github.com/lwmacct/251215-go-pkg-llm/pkg/llm/provider/mock"
+)
+
+var emailPattern = regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.]+`)
+
+func redactEmails(s string) string {
+	return emailPattern.ReplaceAllString(s, "[EMAIL]")
+}
+
+func redactMessages(messages []llm.Message) []llm.Message {
+	for i := range messages {
+		messages[i].Content = redactEmails(messages[i].Content)
+		for _, block := range messages[i].ContentBlocks {
+			if tb, ok := block.(*llm.TextBlock); ok {
+				tb.Text = redactEmails(tb.Text)
+			}
+		}
+	}
+	return messages
+}
+
+func TestWithTransform_Complete(t *testing.T) {
+	var seen []llm.Message
+	inner := mock.New(mock.WithResponseFunc(func(messages []llm.Message, _ int) string {
+		seen = messages
+		return "Contact bob@example.com for details"
+	}))
+
+	p := llm.WithTransform(inner, redactMessages, func(resp *llm.Response) *llm.Response {
+		resp.Message.Content = redactEmails(resp.Message.Content)
+		return resp
+	})
+
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "I am alice@example.com"},
+		{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{&llm.TextBlock{Text: "cc carol@corp.io"}}},
+	}
+
+	resp, err := p.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	// 请求方向已脱敏
+	require.Len(t, seen, 2)
+	assert.Equal(t, "I am [EMAIL]", seen[0].Content)
+	assert.Equal(t, "cc [EMAIL]", seen[1].ContentBlocks[0].(*llm.TextBlock).Text)
+
+	// 响应方向已脱敏
+	assert.Equal(t, "Contact [EMAIL] for details", resp.Message.Content)
+
+	// 调用方消息未被修改
+	assert.Equal(t, "I am alice@example.com", messages[0].Content)
+	assert.Equal(t, "cc carol@corp.io", messages[1].ContentBlocks[0].(*llm.TextBlock).Text)
+}
+
+func TestWithTransform_Stream(t *testing.T) {
+	inner := mock.New(mock.WithResponse("mail: bob@example.com"))
+
+	// 逐事件转换：Mock 按字符输出，这里屏蔽 '@' 并丢弃空白事件
+	p := llm.WithTransform(inner, nil, nil, func(e *llm.Event) *llm.Event {
+		if e.Type != llm.EventTypeText {
+			return e
+		}
+		if strings.TrimSpace(e.TextDelta) == "" {
+			return nil
+		}
+		masked := *e
+		masked.TextDelta = strings.ReplaceAll(e.TextDelta, "@", "*")
+		return &masked
+	})
+
+	events, err := p.Stream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}, nil)
+	require.NoError(t, err)
+
+	var text strings.Builder
+	var done bool
+	for e := range events {
+		switch e.Type {
+		case llm.EventTypeText:
+			text.WriteString(e.TextDelta)
+		case llm.EventTypeDone:
+			done = true
+		}
+	}
+
+	assert.Equal(t, "mail:bob*example.com", text.String())
+	assert.True(t, done)
+}
+
+func TestWithTransform_ClonesImageBlocks(t *testing.T) {
+	inner := mock.New(mock.WithResponse("ok"))
+	p := llm.WithTransform(inner, func(messages []llm.Message) []llm.Message {
+		for _, block := range messages[0].ContentBlocks {
+			if img, ok := block.(*llm.ImageBlock); ok {
+				img.URL = "https://redacted.invalid/image.png"
+			}
+		}
+		return messages
+	}, nil)
+
+	img := &llm.ImageBlock{URL: "https://example.com/cat.png", Detail: llm.ImageDetailLow}
+	messages := []llm.Message{{Role: llm.RoleUser, ContentBlocks: []llm.ContentBlock{&llm.TextBlock{Text: "What is this?"}, img}}}
+
+	_, err := p.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	// 调用方的图片块未被修改
+	assert.Equal(t, "https://example.com/cat.png", img.URL)
+	assert.Same(t, img, messages[0].ContentBlocks[1])
+}
+
+func TestWithTransform_ClonesRedactedThinkingBlocks(t *testing.T) {
+	inner := mock.New(mock.WithResponse("ok"))
+	p := llm.WithTransform(inner, func(messages []llm.Message) []llm.Message {
+		for _, block := range messages[0].ContentBlocks {
+			if rb, ok := block.(*llm.RedactedThinkingBlock); ok {
+				rb.Data = ""
+			}
+		}
+		return messages
+	}, nil)
+
+	redacted := &llm.RedactedThinkingBlock{Data: "EmwKAhgB"}
+	messages := []llm.Message{
+		{Role: llm.RoleAssistant, ContentBlocks: []llm.ContentBlock{redacted, &llm.TextBlock{Text: "Done"}}},
+		{Role: llm.RoleUser, Content: "Continue"},
+	}
+
+	_, err := p.Complete(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	// 调用方的加密思考块未被修改，回放时签名数据仍然完整
+	assert.Equal(t, "EmwKAhgB", redacted.Data)
+}
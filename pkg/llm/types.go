@@ -1,6 +1,10 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 // ═══════════════════════════════════════════════════════════════════════════
 // Provider 接口
@@ -24,37 +28,133 @@ type Provider interface {
 
 // Options Provider 选项
 type Options struct {
+	// 模型覆盖：非空时替换配置中的模型，仅对本次调用生效
+	Model string `json:"model,omitempty"`
+
 	// 基础配置
 	System      string  `json:"system,omitempty"`
 	MaxTokens   int     `json:"max_tokens,omitempty"`
 	Temperature float64 `json:"temperature,omitempty"`
 
+	// 结构化系统提示：追加在系统提示（System 或首条系统消息）之后的独立段落（见 core.SystemParts）。
+	// Gemini 作为 systemInstruction 的多个 parts、Anthropic 作为 system 文本块数组发送；
+	// OpenAI 以空行拼接为单条系统消息
+	SystemParts []string `json:"system_parts,omitempty"`
+
 	// 采样参数
 	TopP             float64  `json:"top_p,omitempty"`
 	FrequencyPenalty float64  `json:"frequency_penalty,omitempty"`
 	PresencePenalty  float64  `json:"presence_penalty,omitempty"`
 	StopSequences    []string `json:"stop_sequences,omitempty"`
 
+	// 输出 token 的对数概率 (OpenAI 兼容)：Logprobs 开启后填充 Response.Logprobs，流式时
+	// 随文本事件返回 Event.Logprobs；TopLogprobs 为每个位置额外返回的候选数（0-20）
+	Logprobs    bool `json:"logprobs,omitempty"`
+	TopLogprobs int  `json:"top_logprobs,omitempty"`
+
 	// Reasoning 模型参数 (o1/o3, DeepSeek R1 等)
 	Reasoning       string `json:"reasoning,omitempty"`        // 推理力度: "minimal", "low", "medium", "high"
 	EnableReasoning bool   `json:"enable_reasoning,omitempty"` // 启用原生推理 tokens
 	ReasoningBudget int    `json:"reasoning_budget,omitempty"` // 推理 token 预算 (Anthropic 最小 1024)
 
+	// 推理内容开关 (OpenRouter 等网关)：nil 不发送，由网关决定是否返回推理内容
+	IncludeReasoning *bool `json:"include_reasoning,omitempty"`
+
 	// 结构化输出
-	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	ValidateResponse bool            `json:"validate_response,omitempty"` // 按 ResponseFormat.Schema 校验响应文本（见 core.ValidateResponse）
 
 	// 工具
-	Tools []ToolSchema `json:"tools,omitempty"`
+	Tools              []ToolSchema `json:"tools,omitempty"`
+	AccumulateToolArgs bool         `json:"accumulate_tool_args,omitempty"` // 流式工具调用事件携带累积参数 (ToolCallDelta.ArgumentsSoFar)
+
+	// 工具选择策略：空值不发送（Provider 默认 auto）；仅在 Tools 非空时生效。
+	// ToolChoiceNone 保留工具定义但禁止调用，适用于需要纯文本回答的步骤（如总结）
+	ToolChoice ToolChoice `json:"tool_choice,omitempty"`
+
+	// 单次响应的工具调用数量上限（见 core.LimitToolCalls），<= 0 表示不限；
+	// 超出时默认只保留前 N 个，StrictMaxToolCalls 为 true 时返回 ResponseError
+	MaxToolCalls       int  `json:"max_tool_calls,omitempty"`
+	StrictMaxToolCalls bool `json:"strict_max_tool_calls,omitempty"`
+
+	// 能力检查（见 CheckCapabilities）
+	StrictCapabilities bool `json:"strict_capabilities,omitempty"` // 模型不支持工具时返回 RequestError
+	ToolFallback       bool `json:"tool_fallback,omitempty"`       // Deprecated: 使用 ToolFallbackMode: ToolFallbackModeAuto，行为相同
+
+	// 工具调用降级模式：以系统提示 + JSON 输出模拟工具调用（见 core.ApplyToolFallback），
+	// auto 仅在模型不支持工具时降级，json 无论模型能力如何都降级
+	ToolFallbackMode ToolFallbackMode `json:"tool_fallback_mode,omitempty"`
+
+	// 预测输出 (OpenAI Predicted Outputs)，用于大部分输出已知的编辑场景
+	PredictedOutput string `json:"predicted_output,omitempty"`
+
+	// 服务端特定的请求体字段 (OpenAI 兼容服务，如 vLLM 的 top_k、min_p、repetition_penalty、guided_json)：
+	// 合并到请求体顶层，同名时覆盖标准字段；取值含义由服务端决定，Anthropic / Gemini 不发送
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+
+	// 终端用户标识（滥用监控）：OpenAI "user"、Anthropic "metadata.user_id"；Gemini 无对应字段，不发送
+	EndUserID string `json:"end_user_id,omitempty"`
+
+	// 服务端会话状态 (OpenAI Responses API)，续接指定响应之后的对话
+	PreviousResponseID string `json:"previous_response_id,omitempty"`
+
+	// Prompt Caching
+	CacheTTL      time.Duration `json:"cache_ttl,omitempty"`      // 缓存有效期 (Anthropic: 5m/1h；Gemini: 创建缓存时的 TTL)
+	CachedContent string        `json:"cached_content,omitempty"` // 引用已创建的缓存 (Gemini cachedContents 名称)
+
+	// 调试：在 Response.PromptTokensDetail 中返回提示词的 token 边界，用于排查缓存未命中
+	// （断点未对齐）。Provider 无法返回分词结果时使用本地估算（见 core.EstimatePromptTokens）
+	ReturnPromptTokens bool `json:"return_prompt_tokens,omitempty"`
+
+	// 单次请求头：覆盖客户端级同名请求头，认证头（Authorization 等）除外
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// 单次调用的 API Key：覆盖客户端配置的凭据（Authorization / X-Api-Key 头或 Gemini key= 参数），
+	// 不修改共享客户端，适用于多租户按调用方计费。不序列化，避免写入日志与录制文件
+	APIKey string `json:"-"`
 
 	// 扩展
 	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
+// ErrTooManyToolCalls 响应中的工具调用数量超过 Options.MaxToolCalls
+var ErrTooManyToolCalls = errors.New("too many tool calls")
+
+// ToolChoice 工具选择策略
+//
+// 映射：OpenAI tool_choice 字符串（required 即 "required"）、Anthropic tool_choice.type
+// （required 映射为 "any"）、Gemini functionCallingConfig.mode（AUTO / NONE / ANY）。
+type ToolChoice string
+
+const (
+	ToolChoiceAuto     ToolChoice = "auto"     // 模型自行决定是否调用工具
+	ToolChoiceNone     ToolChoice = "none"     // 禁止调用工具，工具定义仍随请求发送
+	ToolChoiceRequired ToolChoice = "required" // 必须调用至少一个工具
+)
+
+// ToolFallbackMode 工具调用降级模式
+type ToolFallbackMode string
+
+const (
+	// ToolFallbackModeJSON 工具定义嵌入系统提示，开启 json_object 模式，并将模型输出的
+	// {"tool": ..., "arguments": ...} 解析为 ToolCall。用于不支持原生工具调用的模型。
+	ToolFallbackModeJSON ToolFallbackMode = "json"
+
+	// ToolFallbackModeAuto 模型按注册表（见 LookupModel）不支持工具调用时按
+	// ToolFallbackModeJSON 降级，否则发送原生工具定义；优先于 StrictCapabilities
+	ToolFallbackModeAuto ToolFallbackMode = "auto"
+)
+
 // ResponseFormat 响应格式配置 (Structured Output)
+//
+// Type 为 "enum" 时模型只能输出 EnumValues 中的一个标签，适用于单标签分类：
+// Gemini 原生支持（text/x.enum，直接返回标签文本）；OpenAI 通过 json_schema
+// 模拟，返回 {"value": "<标签>"}。
 type ResponseFormat struct {
-	Type   string         `json:"type"`             // "json_schema", "json_object", "text"
-	Name   string         `json:"name,omitempty"`   // Schema 名称
-	Schema map[string]any `json:"schema,omitempty"` // JSON Schema 定义
+	Type       string         `json:"type"`                  // "json_schema", "json_object", "enum", "text"
+	Name       string         `json:"name,omitempty"`        // Schema 名称
+	Schema     map[string]any `json:"schema,omitempty"`      // JSON Schema 定义
+	EnumValues []string       `json:"enum_values,omitempty"` // 可选标签（Type 为 "enum" 时）
 }
 
 // ToolSchema 工具 Schema
@@ -67,18 +167,95 @@ type ToolSchema struct {
 
 // Response Provider 响应
 type Response struct {
-	Message      Message        `json:"message"`
-	FinishReason string         `json:"finish_reason"`
-	Model        string         `json:"model,omitempty"` // 实际使用的模型
-	Usage        *TokenUsage    `json:"usage,omitempty"`
-	Metadata     map[string]any `json:"metadata,omitempty"`
+	Message            Message        `json:"message"`
+	FinishReason       string         `json:"finish_reason"`
+	Model              string         `json:"model,omitempty"`              // 实际使用的模型
+	ResolvedModel      string         `json:"resolved_model,omitempty"`     // Provider 报告的模型版本（如 gemini-2.5-flash-002），未报告时为空
+	SystemFingerprint  string         `json:"system_fingerprint,omitempty"` // 后端配置指纹（OpenAI system_fingerprint），用于复现性追踪
+	StatusCode         int            `json:"status_code,omitempty"`        // HTTP 状态码
+	Latency            time.Duration  `json:"latency,omitempty"`            // 请求往返耗时（重试时为最后一次尝试，不含并发限流与重试等待）
+	Reasoning          string         `json:"reasoning,omitempty"`          // 推理/思考内容（来自 ThinkingBlock）
+	Usage              *TokenUsage    `json:"usage,omitempty"`
+	Citations          []Citation     `json:"citations,omitempty"`            // 引用来源（Gemini grounding / Anthropic citations）
+	Logprobs           []TokenLogprob `json:"logprobs,omitempty"`             // 输出 token 的对数概率（Options.Logprobs）
+	ToolCallsTruncated bool           `json:"tool_calls_truncated,omitempty"` // 工具调用超过 Options.MaxToolCalls 被截断
+	Metadata           map[string]any `json:"metadata,omitempty"`
+
+	// 提示词 token 明细（仅 Options.ReturnPromptTokens 时填充）
+	PromptTokensDetail *PromptTokensDetail `json:"prompt_tokens_detail,omitempty"`
+
+	// 缓存状态（由 Usage 推导）
+	CacheHit         bool  `json:"cache_hit,omitempty"`          // 是否命中缓存
+	CacheReadTokens  int64 `json:"cache_read_tokens,omitempty"`  // 从缓存读取的 tokens
+	CacheWriteTokens int64 `json:"cache_write_tokens,omitempty"` // 写入缓存的 tokens
+}
+
+// TokenLogprob 输出 token 的对数概率
+type TokenLogprob struct {
+	Token       string         `json:"token"`
+	Logprob     float64        `json:"logprob"`
+	Bytes       []int          `json:"bytes,omitempty"`        // token 的 UTF-8 字节（token 不是完整字符时用于拼接）
+	TopLogprobs []TokenLogprob `json:"top_logprobs,omitempty"` // 该位置概率最高的候选（Options.TopLogprobs）
+}
+
+// Citation 回答引用的来源
+//
+// StartIndex / EndIndex 为被该来源支持的回答片段在可见文本（全部 TextBlock 拼接）中的
+// 字节偏移，未知时均为 0。Text 为来源中被引用的原文（Anthropic cited_text），
+// 来源未提供时为被支持的回答片段（Gemini segment.text）。
+type Citation struct {
+	SourceURI  string `json:"source_uri,omitempty"`
+	Title      string `json:"title,omitempty"`
+	StartIndex int    `json:"start_index,omitempty"`
+	EndIndex   int    `json:"end_index,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
+
+// PromptTokensDetail 提示词 token 明细（缓存边界调试）
+//
+// Segments 按 Provider 缓存前缀的顺序排列（工具定义、系统提示、各条消息），
+// End 为该段结束处的累计 token 偏移，可与缓存命中的 token 数（Usage.CachedTokens）
+// 对照，判断缓存前缀止于哪一段。
+//
+// Estimated 为 true 时各数值来自本地启发式估算，与实际分词存在偏差；
+// Total 可与 Usage.InputTokens 对照估算误差。
+type PromptTokensDetail struct {
+	Total     int64                `json:"total"`
+	Segments  []PromptTokenSegment `json:"segments,omitempty"`
+	Tokens    []string             `json:"tokens,omitempty"` // 分词结果（仅 Provider 原生返回时）
+	Estimated bool                 `json:"estimated,omitempty"`
+}
+
+// PromptTokenSegment 提示词中的一段
+type PromptTokenSegment struct {
+	Kind   string `json:"kind"`            // "tools"、"system"、"message" 或 "prompt"
+	Index  int    `json:"index,omitempty"` // 消息下标（Kind 为 "message" 时）
+	Tokens int64  `json:"tokens"`
+	End    int64  `json:"end"` // 累计 token 偏移
+}
+
+// SetCacheStatus 根据 Usage 填充缓存状态字段
+func (r *Response) SetCacheStatus() {
+	if r.Usage == nil {
+		return
+	}
+	r.CacheReadTokens = r.Usage.CachedTokens
+	r.CacheWriteTokens = r.Usage.CacheWriteTokens
+	r.CacheHit = r.CacheReadTokens > 0
 }
 
 // TokenUsage Token 使用量
 type TokenUsage struct {
-	InputTokens     int64 `json:"input_tokens"`
-	OutputTokens    int64 `json:"output_tokens"`
-	TotalTokens     int64 `json:"total_tokens"`
-	ReasoningTokens int64 `json:"reasoning_tokens,omitempty"` // 推理 tokens (DeepSeek R1, o1/o3 等)
-	CachedTokens    int64 `json:"cached_tokens,omitempty"`    // Prompt Caching tokens
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	ReasoningTokens  int64 `json:"reasoning_tokens,omitempty"`   // 推理 tokens (DeepSeek R1, o1/o3 等)
+	CachedTokens     int64 `json:"cached_tokens,omitempty"`      // Prompt Caching tokens
+	CacheWriteTokens int64 `json:"cache_write_tokens,omitempty"` // 写入缓存的 tokens (Anthropic cache_creation_input_tokens)
+
+	AcceptedPredictionTokens int64 `json:"accepted_prediction_tokens,omitempty"` // 被采纳的预测 tokens (OpenAI Predicted Outputs)
+	RejectedPredictionTokens int64 `json:"rejected_prediction_tokens,omitempty"` // 被拒绝的预测 tokens (OpenAI Predicted Outputs)
+
+	// 用量为本地估算（如流被取消、未收到服务端用量时由 core.CollectStream 估算）
+	Estimated bool `json:"estimated,omitempty"`
 }